@@ -3,6 +3,8 @@ package command
 import (
 	"context"
 	"fmt"
+	"runtime"
+	"strconv"
 
 	cmpinstall "github.com/posener/complete/cmd/install"
 	"github.com/urfave/cli/v2"
@@ -28,10 +30,10 @@ var app = &cli.App{
 			Name:  "json",
 			Usage: "enable JSON formatted output",
 		},
-		&cli.IntFlag{
+		&cli.StringFlag{
 			Name:  "numworkers",
-			Value: defaultWorkerCount,
-			Usage: "number of workers execute operation on each object",
+			Value: strconv.Itoa(defaultWorkerCount),
+			Usage: "number of workers execute operation on each object; \"auto\" derives it from the number of CPUs and prints it with --log debug",
 		},
 		&cli.IntFlag{
 			Name:    "retry-count",
@@ -39,19 +41,60 @@ var app = &cli.App{
 			Value:   defaultRetryCount,
 			Usage:   "number of times that a request will be retried for failures",
 		},
+		&cli.DurationFlag{
+			Name:  "max-retry-duration",
+			Usage: "stop retrying a request once this much time has passed since its first attempt, e.g. 2m; no limit if not set",
+		},
+		&cli.Float64Flag{
+			Name:  "retry-budget",
+			Usage: "stop retrying failed requests once the retries exceed this ratio of all requests, e.g. 0.1; no limit if not set",
+		},
 		&cli.StringFlag{
 			Name:    "endpoint-url",
 			Usage:   "override default S3 host for custom services",
-			EnvVars: []string{"S3_ENDPOINT_URL"},
+			EnvVars: []string{"S3_ENDPOINT_URL", "AWS_ENDPOINT_URL"},
+		},
+		&cli.BoolFlag{
+			Name:  "use-accelerate-endpoint",
+			Usage: "use S3 Transfer Acceleration endpoint; it can not be used with --endpoint-url",
 		},
 		&cli.BoolFlag{
 			Name:  "no-verify-ssl",
 			Usage: "disable SSL certificate verification",
 		},
+		&cli.IntFlag{
+			Name:  "max-idle-conns",
+			Usage: "maximum number of idle connections kept for reuse; no limit if not set",
+		},
+		&cli.IntFlag{
+			Name:  "max-idle-conns-per-host",
+			Usage: "maximum number of idle connections kept for reuse per host; defaults to the number of workers",
+		},
+		&cli.DurationFlag{
+			Name:  "dial-timeout",
+			Usage: "timeout for establishing a connection to the remote storage, e.g. 10s (default: 30s)",
+		},
+		&cli.DurationFlag{
+			Name:  "tls-handshake-timeout",
+			Usage: "timeout for the TLS handshake with the remote storage, e.g. 5s (default: 10s)",
+		},
 		&cli.StringFlag{
 			Name:  "log",
 			Value: "info",
-			Usage: "log level: (debug, info, error)",
+			Usage: "log level: (trace, debug, info, error); trace also prints the requests of the AWS SDK",
+		},
+		&cli.StringFlag{
+			Name:  "log-file",
+			Usage: "append a JSON line for each successful operation to given file, with its source, destination, size and time",
+		},
+		&cli.StringFlag{
+			Name:  "resume",
+			Usage: "skip the operations recorded in given file of --log-file, e.g. to resume an interrupted run",
+		},
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Aliases: []string{"q", "only-show-errors"},
+			Usage:   "do not print the successful operations; same as --log error",
 		},
 		&cli.BoolFlag{
 			Name:  "install-completion",
@@ -69,16 +112,75 @@ var app = &cli.App{
 			Name:  "no-sign-request",
 			Usage: "do not sign requests: credentials will not be loaded if --no-sign-request is provided",
 		},
+		&cli.DurationFlag{
+			Name:  "op-timeout",
+			Usage: "timeout for each object transfer of cp, mv, sync and cat commands, e.g. 5m; no timeout if not set",
+		},
+		&cli.StringFlag{
+			Name:    "profile",
+			Usage:   "use the specified profile from the shared credentials file",
+			EnvVars: []string{"AWS_PROFILE"},
+		},
+		&cli.StringFlag{
+			Name:  "assume-role-arn",
+			Usage: "assume the IAM role of given ARN with the credentials of the profile, e.g. for cross-account access",
+		},
+		&cli.StringFlag{
+			Name:  "external-id",
+			Usage: "external id of the role of --assume-role-arn, if it requires one",
+		},
+		&cli.StringFlag{
+			Name:  "role-session-name",
+			Usage: "session name of the role of --assume-role-arn; generated if not set",
+		},
+		&cli.Int64Flag{
+			Name:  "rate-limit",
+			Usage: "limit the total throughput of all transfers to given bytes per second; no limit if not set",
+		},
+		&cli.Int64Flag{
+			Name:  "request-rate",
+			Usage: "limit the number of operations started per second, e.g. to stay under the request rate limits of S3; no limit if not set",
+		},
+		&cli.Int64Flag{
+			Name:  "max-memory",
+			Usage: "limit the memory used by the part buffers of all uploads to given MiB; no limit if not set",
+		},
+		&cli.DurationFlag{
+			Name:  "stats-interval",
+			Usage: "print the number of active, queued, completed and failed operations to stderr at every interval, e.g. 10s; disabled if not set",
+		},
+		&cli.StringFlag{
+			Name:  "request-payer",
+			Usage: "who pays for the requests, set to \"requester\" to access requester pays buckets",
+		},
+		&cli.StringFlag{
+			Name:  "region",
+			Usage: "region of the remote storage; bucket region is auto-detected if not set",
+		},
 	},
 	Before: func(c *cli.Context) error {
 		retryCount := c.Int("retry-count")
-		workerCount := c.Int("numworkers")
 		printJSON := c.Bool("json")
 		logLevel := c.String("log")
+		if c.Bool("quiet") {
+			logLevel = "error"
+		}
 		isStat := c.Bool("stat")
 
-		log.Init(logLevel, printJSON)
-		parallel.Init(workerCount)
+		log.Init(logLevel, printJSON, logOptions...)
+
+		// the workers are closed by After even if the flags are invalid, so
+		// they are initialized before the validation.
+		workerCount, err := parseNumWorkers(c.String("numworkers"))
+		parallel.Init(workerCount, c.Int64("rate-limit"), c.Int64("request-rate"), c.Int64("max-memory")*megabytes)
+		if err != nil {
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+		if c.String("numworkers") == autoNumWorkers {
+			msg := fmt.Sprintf("numworkers is set to %d for %d CPUs", workerCount, runtime.NumCPU())
+			log.Debug(log.DebugMessage{Err: msg})
+		}
 
 		if retryCount < 0 {
 			err := fmt.Errorf("retry count cannot be a negative value")
@@ -86,10 +188,106 @@ var app = &cli.App{
 			return err
 		}
 
+		if c.Int64("rate-limit") < 0 {
+			err := fmt.Errorf("rate limit cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Int64("request-rate") < 0 {
+			err := fmt.Errorf("request rate cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Int64("max-memory") < 0 {
+			err := fmt.Errorf("max memory cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Int("max-idle-conns") < 0 || c.Int("max-idle-conns-per-host") < 0 {
+			err := fmt.Errorf("idle connection count cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Duration("dial-timeout") < 0 || c.Duration("tls-handshake-timeout") < 0 {
+			err := fmt.Errorf("connection timeout cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Float64("retry-budget") < 0 {
+			err := fmt.Errorf("retry budget cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Duration("max-retry-duration") < 0 {
+			err := fmt.Errorf("max retry duration cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Duration("op-timeout") < 0 {
+			err := fmt.Errorf("operation timeout cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if payer := c.String("request-payer"); payer != "" && payer != "requester" {
+			err := fmt.Errorf("request payer must be \"requester\", got %q", payer)
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if err := validateAssumeRole(c); err != nil {
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Bool("use-accelerate-endpoint") && c.String("endpoint-url") != "" {
+			err := fmt.Errorf("--use-accelerate-endpoint can not be used with --endpoint-url")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		if c.Duration("stats-interval") < 0 {
+			err := fmt.Errorf("stats interval cannot be a negative value")
+			printError(givenCommand(c), c.Command.Name, err)
+			return err
+		}
+
+		// the resumed operations are read before the log file is opened,
+		// since they can be the same file.
+		if path := c.String("resume"); path != "" {
+			completed, err = readCompletedOperations(path)
+			if err != nil {
+				err = fmt.Errorf("reading resume file failed: %v", err)
+				printError(givenCommand(c), c.Command.Name, err)
+				return err
+			}
+		}
+
+		// nothing is done in dry run, so there is nothing to record.
+		if path := c.String("log-file"); path != "" && !c.Bool("dry-run") {
+			manifest, err = openManifest(path)
+			if err != nil {
+				err = fmt.Errorf("opening log file failed: %v", err)
+				printError(givenCommand(c), c.Command.Name, err)
+				return err
+			}
+		}
+
 		if isStat {
 			stat.InitStat()
 		}
 
+		if interval := c.Duration("stats-interval"); interval > 0 || reportOnSignal {
+			startProgress(interval, isStat)
+		}
+
 		return nil
 	},
 	CommandNotFound: func(c *cli.Context, command string) {
@@ -97,9 +295,11 @@ var app = &cli.App{
 			Command: command,
 			Err:     "command not found",
 		}
-		log.Error(msg)
+		logError(msg)
 
 		// After callback is not called if app exists with cli.Exit.
+		stopProgress()
+		closeManifest()
 		parallel.Close()
 		log.Close()
 	},
@@ -121,10 +321,13 @@ var app = &cli.App{
 		return cli.ShowAppHelp(c)
 	},
 	After: func(c *cli.Context) error {
+		stopProgress()
+
 		if c.Bool("stat") {
-			log.Info(stat.Statistics())
+			log.Summary(stat.Statistics())
 		}
 
+		closeManifest()
 		parallel.Close()
 		log.Close()
 		return nil
@@ -133,19 +336,83 @@ var app = &cli.App{
 
 // NewStorageOpts creates storage.Options object from the given context.
 func NewStorageOpts(c *cli.Context) storage.Options {
-	return storage.Options{
-		MaxRetries:    c.Int("retry-count"),
-		Endpoint:      c.String("endpoint-url"),
-		NoVerifySSL:   c.Bool("no-verify-ssl"),
-		DryRun:        c.Bool("dry-run"),
-		NoSignRequest: c.Bool("no-sign-request"),
+	opts := storage.Options{
+		MaxRetries:          c.Int("retry-count"),
+		MaxRetryDuration:    c.Duration("max-retry-duration"),
+		RetryBudget:         c.Float64("retry-budget"),
+		Endpoint:            c.String("endpoint-url"),
+		UseAccelerate:       c.Bool("use-accelerate-endpoint"),
+		NoVerifySSL:         c.Bool("no-verify-ssl"),
+		MaxIdleConns:        c.Int("max-idle-conns"),
+		MaxIdleConnsPerHost: c.Int("max-idle-conns-per-host"),
+		DialTimeout:         c.Duration("dial-timeout"),
+		TLSHandshakeTimeout: c.Duration("tls-handshake-timeout"),
+		DryRun:              c.Bool("dry-run"),
+		NoSignRequest:       c.Bool("no-sign-request"),
+		RequestPayer:        c.String("request-payer"),
+		Profile:             c.String("profile"),
+		AssumeRoleARN:       c.String("assume-role-arn"),
+		ExternalID:          c.String("external-id"),
+		RoleSessionName:     c.String("role-session-name"),
+		LogLevel:            c.String("log"),
 	}
+	// every worker can reuse an idle connection unless told otherwise.
+	if !c.IsSet("max-idle-conns-per-host") {
+		opts.MaxIdleConnsPerHost = numWorkers(c)
+	}
+	opts.SetRegion(c.String("region"))
+	return opts
+}
+
+// validateAssumeRole returns an error if the flags of assuming a role are
+// given without a role, or with anonymous access.
+func validateAssumeRole(c *cli.Context) error {
+	if c.String("assume-role-arn") == "" {
+		for _, flag := range []string{"external-id", "role-session-name"} {
+			if c.String(flag) != "" {
+				return fmt.Errorf("--%v can only be used with --assume-role-arn", flag)
+			}
+		}
+		return nil
+	}
+
+	if c.Bool("no-sign-request") {
+		return fmt.Errorf("--assume-role-arn can not be used with --no-sign-request")
+	}
+	return nil
+}
+
+// autoNumWorkers is the value of --numworkers flag to derive the worker count
+// from the number of CPUs.
+const autoNumWorkers = "auto"
+
+// parseNumWorkers parses the value of --numworkers flag, which is either a
+// number or "auto". A negative number is a multiple of the number of CPUs.
+func parseNumWorkers(value string) (int, error) {
+	if value == autoNumWorkers {
+		return parallel.AutoWorkerCount(), nil
+	}
+
+	workers, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("numworkers must be a number or %q, got %q", autoNumWorkers, value)
+	}
+	return workers, nil
+}
+
+// numWorkers returns the worker count of --numworkers flag, which is already
+// validated by the app.
+func numWorkers(c *cli.Context) int {
+	workers, _ := parseNumWorkers(c.String("numworkers"))
+	return workers
 }
 
 func Commands() []*cli.Command {
 	return []*cli.Command{
 		NewListCommand(),
+		NewVersionListCommand(),
 		NewCopyCommand(),
+		NewSyncCommand(),
 		NewDeleteCommand(),
 		NewMoveCommand(),
 		NewMakeBucketCommand(),
@@ -153,6 +420,12 @@ func Commands() []*cli.Command {
 		NewSelectCommand(),
 		NewSizeCommand(),
 		NewCatCommand(),
+		NewHeadCommand(),
+		NewCheckCommand(),
+		NewExpandCommand(),
+		NewPresignCommand(),
+		NewSetTagsCommand(),
+		NewSetACLCommand(),
 		NewRunCommand(),
 		NewVersionCommand(),
 	}
@@ -176,5 +449,7 @@ func Main(ctx context.Context, args []string) error {
 		return nil
 	}
 
+	reportOnSignal = true
+
 	return app.RunContext(ctx, args)
 }
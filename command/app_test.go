@@ -0,0 +1,126 @@
+package command
+
+import (
+	"flag"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestParseNumWorkers(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name      string
+		value     string
+		expected  int
+		expectErr bool
+	}{
+		{
+			name:     "number",
+			value:    "64",
+			expected: 64,
+		},
+		{
+			// negative values are resolved by the worker pool.
+			name:     "multiple of cpus",
+			value:    "-2",
+			expected: -2,
+		},
+		{
+			name:     "auto",
+			value:    "auto",
+			expected: runtime.NumCPU() * 32,
+		},
+		{
+			name:      "invalid",
+			value:     "many",
+			expectErr: true,
+		},
+		{
+			name:      "empty",
+			value:     "",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseNumWorkers(tc.value)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v workers", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %v workers, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestEndpointURLFromEnv(t *testing.T) {
+	testcases := []struct {
+		name     string
+		env      map[string]string
+		flags    []string
+		expected string
+	}{
+		{
+			name:     "aws endpoint env",
+			env:      map[string]string{"AWS_ENDPOINT_URL": "http://aws.local"},
+			expected: "http://aws.local",
+		},
+		{
+			name: "s3 endpoint env takes precedence",
+			env: map[string]string{
+				"S3_ENDPOINT_URL":  "http://s3.local",
+				"AWS_ENDPOINT_URL": "http://aws.local",
+			},
+			expected: "http://s3.local",
+		},
+		{
+			name:     "flag takes precedence",
+			env:      map[string]string{"AWS_ENDPOINT_URL": "http://aws.local"},
+			flags:    []string{"--endpoint-url", "http://flag.local"},
+			expected: "http://flag.local",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range []string{"S3_ENDPOINT_URL", "AWS_ENDPOINT_URL"} {
+				os.Unsetenv(name)
+				if value, ok := tc.env[name]; ok {
+					os.Setenv(name, value)
+				}
+				defer os.Unsetenv(name)
+			}
+
+			set := flag.NewFlagSet(appName, flag.ContinueOnError)
+			for _, f := range app.Flags {
+				if err := f.Apply(set); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if err := set.Parse(tc.flags); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := NewStorageOpts(cli.NewContext(app, set, nil)).Endpoint
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
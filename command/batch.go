@@ -0,0 +1,133 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/parallel"
+)
+
+// logOptions are the options of the global logger given to NewBatch. The
+// command line uses the default logger.
+var logOptions []log.Option
+
+// Batch runs commands concurrently like run command does. It is the entrypoint
+// for Go programs embedding s5cmd, which can submit commands as argument lists
+// instead of formatting command lines to be parsed again.
+//
+// Batch initializes the global logger and workers like the command line does,
+// so a program should create a single Batch.
+type Batch struct {
+	parent *cli.Context
+	pm     *parallel.Manager
+	waiter *parallel.Waiter
+
+	// merror is written by the goroutine reading the errors of the waiter. It
+	// is read after errDoneCh is closed.
+	merror    error
+	errDoneCh chan bool
+}
+
+// NewBatch creates a Batch with the given global flags, e.g.
+// []string{"--numworkers", "32", "--json"}. The commands are canceled when ctx
+// is canceled. The options configure the global logger, e.g. log.WithOutput
+// captures the messages of the commands instead of printing them.
+func NewBatch(ctx context.Context, flags []string, opts ...log.Option) (*Batch, error) {
+	app.Commands = Commands()
+	app.Setup()
+
+	set := flag.NewFlagSet(appName, flag.ContinueOnError)
+	for _, f := range app.Flags {
+		if err := f.Apply(set); err != nil {
+			return nil, err
+		}
+	}
+	if err := set.Parse(flags); err != nil {
+		return nil, err
+	}
+	if set.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %q, expected only global flags", set.Args())
+	}
+
+	c := cli.NewContext(app, set, nil)
+	c.Context = ctx
+	logOptions = opts
+	if err := app.Before(c); err != nil {
+		return nil, err
+	}
+
+	b := &Batch{
+		parent:    c,
+		pm:        parallel.New(numWorkers(c)),
+		waiter:    parallel.NewWaiter(ctx),
+		errDoneCh: make(chan bool),
+	}
+
+	go func() {
+		defer close(b.errDoneCh)
+		for err := range b.waiter.Err() {
+			// the errors are already printed by the commands.
+			b.merror = multierror.Append(b.merror, err)
+		}
+	}()
+
+	return b, nil
+}
+
+// Submit starts running the given command and its arguments, e.g.
+// []string{"cp", "s3://bucket/*", "dir/"}. The arguments are not parsed as a
+// shell line, so they must not be quoted. It blocks while all workers are
+// busy. The errors of the command are returned by Close.
+func (b *Batch) Submit(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected a command")
+	}
+
+	name := args[0]
+	if name == "run" {
+		return fmt.Errorf("%q command is not permitted in a batch", name)
+	}
+
+	cmd := AppCommand(name)
+	if cmd == nil {
+		return fmt.Errorf("%q command not found", name)
+	}
+
+	// the command name is not a flag, so parsing stops at the first argument
+	// and all arguments are passed to the command.
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+
+	b.pm.Run(func() error {
+		return cmd.Run(cli.NewContext(app, set, b.parent))
+	}, b.waiter)
+	return nil
+}
+
+// Close waits for the submitted commands to finish and returns their errors.
+// It prints the statistics if --stat flag is given and closes the logger and
+// the file of --log-file, so the Batch can not be used afterwards.
+func (b *Batch) Close() error {
+	b.waiter.Wait()
+	<-b.errDoneCh
+	b.pm.Close()
+	stopProgress()
+
+	if b.parent.Bool("stat") {
+		log.Summary(stat.Statistics())
+	}
+
+	closeManifest()
+	parallel.Close()
+	log.Close()
+
+	return b.merror
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peak/s5cmd/log"
+)
+
+// The Batch closes the global logger, so it is created only once in the tests
+// of this package.
+func TestBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{"a.txt", "b.txt", "c.txt"}
+	for _, name := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the messages of the commands are captured instead of being printed.
+	var stdout, stderr bytes.Buffer
+	batch, err := NewBatch(
+		context.Background(),
+		[]string{"--numworkers", "2"},
+		log.WithOutput(&stdout, &stderr),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range files {
+		assert.NoError(t, batch.Submit([]string{"rm", filepath.Join(dir, name)}))
+	}
+
+	// invalid commands are rejected without being run.
+	assert.EqualError(t, batch.Submit(nil), "expected a command")
+	assert.EqualError(t, batch.Submit([]string{"unknown"}), `"unknown" command not found`)
+	assert.EqualError(t, batch.Submit([]string{"run", "commands.txt"}), `"run" command is not permitted in a batch`)
+
+	// the errors of the commands are returned by Close.
+	assert.NoError(t, batch.Submit([]string{"cp", filepath.Join(dir, "nonexistent.txt"), "s3://bucket/"}))
+
+	assert.Error(t, batch.Close())
+
+	for _, name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.True(t, os.IsNotExist(err), "expected %v to be deleted", name)
+	}
+
+	// the commands are run concurrently, so the order of the lines differs.
+	var expected []string
+	for _, name := range files {
+		expected = append(expected, fmt.Sprintf("rm %v", filepath.Join(dir, name)))
+	}
+	got := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	sort.Strings(got)
+	assert.Equal(t, expected, got)
+
+	nonexistent := filepath.Join(dir, "nonexistent.txt")
+	assert.Contains(t, stderr.String(), fmt.Sprintf(`ERROR "cp %v s3://bucket/"`, nonexistent))
+}
+
+func TestNewBatchInvalidFlags(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewBatch(context.Background(), []string{"--unknown-flag"})
+	assert.Error(t, err)
+
+	_, err = NewBatch(context.Background(), []string{"--json", "ls"})
+	assert.EqualError(t, err, `unexpected arguments ["ls"], expected only global flags`)
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/urfave/cli/v2"
 
 	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/parallel"
 	"github.com/peak/s5cmd/storage"
 	"github.com/peak/s5cmd/storage/url"
 )
@@ -55,6 +57,7 @@ func NewCatCommand() *cli.Command {
 				src:         src,
 				op:          op,
 				fullCommand: fullCommand,
+				opTimeout:   c.Duration("op-timeout"),
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -68,6 +71,9 @@ type Cat struct {
 	op          string
 	fullCommand string
 
+	// opTimeout is the timeout of reading the object.
+	opTimeout time.Duration
+
 	storageOpts storage.Options
 }
 
@@ -79,6 +85,12 @@ func (c Cat) Run(ctx context.Context) error {
 		return err
 	}
 
+	if c.opTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opTimeout)
+		defer cancel()
+	}
+
 	rc, err := client.Read(ctx, c.src)
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
@@ -86,7 +98,7 @@ func (c Cat) Run(ctx context.Context) error {
 	}
 	defer rc.Close()
 
-	_, err = io.Copy(os.Stdout, rc)
+	_, err = io.Copy(os.Stdout, parallel.Limiter().Reader(rc))
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
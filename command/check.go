@@ -0,0 +1,224 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+	"github.com/peak/s5cmd/strutil"
+)
+
+var checkHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} s3://bucket[/prefix/]
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Check that a bucket can be accessed, read and written with the given credentials
+		 > s5cmd {{.HelpName}} s3://bucket
+
+	2. Check the access to a prefix of a bucket
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/
+
+	3. Check the access with another profile in the region of the bucket
+		 > s5cmd --profile backup --region eu-west-1 {{.HelpName}} s3://bucket
+`
+
+func NewCheckCommand() *cli.Command {
+	return &cli.Command{
+		Name:               "check",
+		HelpName:           "check",
+		Usage:              "check access to a bucket by a round trip of a small object",
+		CustomHelpTemplate: checkHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateCheckCommand(c)
+			if err != nil {
+				printError(givenCommand(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			// the prefix is already validated.
+			dst, _ := url.New(c.Args().First())
+
+			return Check{
+				dst:         dst,
+				op:          c.Command.Name,
+				fullCommand: givenCommand(c),
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+}
+
+// Check holds check operation flags and states.
+type Check struct {
+	dst         *url.URL
+	op          string
+	fullCommand string
+
+	storageOpts storage.Options
+}
+
+// checkContent is the content of the object written and read by check.
+const checkContent = "s5cmd check"
+
+// checkStorage is the storage of the checks, which is implemented by
+// storage.S3.
+type checkStorage interface {
+	HeadBucket(ctx context.Context, name string) error
+	Put(ctx context.Context, reader io.Reader, to *url.URL, metadata storage.Metadata, concurrency int, partSize int64) error
+	Read(ctx context.Context, src *url.URL) (io.ReadCloser, error)
+	Delete(ctx context.Context, url *url.URL) error
+}
+
+// Run checks the access to the bucket, and prints the result of each check.
+func (c Check) Run(ctx context.Context) error {
+	client, err := storage.NewRemoteClient(ctx, c.dst, c.storageOpts)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	key := c.dst.Join(fmt.Sprintf(".s5cmd-check-%d", time.Now().UnixNano()))
+
+	var failed int
+	for _, msg := range runChecks(ctx, client, key, c.storageOpts.DryRun) {
+		if msg.Status == checkFailed {
+			failed++
+			stat.AddError(msg.Code)
+		}
+		log.Info(msg)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of the checks failed", failed)
+	}
+	return nil
+}
+
+// The statuses of the checks.
+const (
+	checkOK      = "ok"
+	checkFailed  = "failed"
+	checkSkipped = "skipped"
+)
+
+// runChecks checks the access to the bucket of key: the bucket is looked up,
+// a small object is written to key, read back and deleted. The checks after
+// a failed write are skipped since there is nothing to read or delete, and
+// so are all the writes in dry-run mode.
+func runChecks(ctx context.Context, client checkStorage, key *url.URL, dryRun bool) []CheckMessage {
+	var messages []CheckMessage
+
+	// check runs the check unless it is skipped, and reports whether it
+	// succeeded.
+	check := func(name string, u *url.URL, skip bool, fn func() error) bool {
+		msg := CheckMessage{Check: name, URL: u, Status: checkOK}
+		if skip {
+			msg.Status = checkSkipped
+		} else if err := fn(); err != nil {
+			msg.Status = checkFailed
+			msg.Error = cleanupError(err)
+			msg.Code = storage.ErrorCode(err)
+		}
+		messages = append(messages, msg)
+		return msg.Status == checkOK
+	}
+
+	bucket, _ := url.New(fmt.Sprintf("%v://%v", key.Scheme, key.Bucket))
+	check("head-bucket", bucket, false, func() error {
+		return client.HeadBucket(ctx, key.Bucket)
+	})
+
+	written := check("put", key, dryRun, func() error {
+		metadata := storage.NewMetadata().SetContentType("text/plain")
+		return client.Put(ctx, strings.NewReader(checkContent), key, metadata, 1, storage.MinPartSize)
+	})
+
+	check("get", key, !written, func() error {
+		body, err := client.Read(ctx, key)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		content, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(content, []byte(checkContent)) {
+			return fmt.Errorf("content of the object does not match the written content")
+		}
+		return nil
+	})
+
+	check("delete", key, !written, func() error {
+		return client.Delete(ctx, key)
+	})
+	return messages
+}
+
+// CheckMessage is the result of a check.
+type CheckMessage struct {
+	Check  string   `json:"check"`
+	URL    *url.URL `json:"url"`
+	Status string   `json:"status"`
+	Error  string   `json:"error,omitempty"`
+	Code   string   `json:"-"`
+}
+
+// String returns the string representation of CheckMessage.
+func (m CheckMessage) String() string {
+	s := fmt.Sprintf("%-7s %-11s %v", strings.ToUpper(m.Status), m.Check, m.URL)
+	if m.Error != "" {
+		s += ": " + m.Error
+	}
+	return s
+}
+
+// JSON returns the JSON representation of CheckMessage.
+func (m CheckMessage) JSON() string {
+	return strutil.JSON(m)
+}
+
+func validateCheckCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only 1 argument")
+	}
+
+	dst, err := url.New(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if !dst.IsRemote() {
+		return fmt.Errorf("target must be a remote bucket or prefix")
+	}
+
+	if !dst.IsBucket() && !dst.IsPrefix() {
+		return fmt.Errorf("target %q must be a bucket or a prefix", dst)
+	}
+
+	if dst.IsWildcard() {
+		return fmt.Errorf("target %q can not contain glob characters", dst)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package command
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+)
+
+// fakeCheckStorage is an in-memory storage of the checks, whose requests fail
+// with the given errors.
+type fakeCheckStorage struct {
+	headErr   error
+	putErr    error
+	readErr   error
+	deleteErr error
+
+	content  string
+	requests []string
+}
+
+func (f *fakeCheckStorage) HeadBucket(ctx context.Context, name string) error {
+	f.requests = append(f.requests, "HeadBucket")
+	return f.headErr
+}
+
+func (f *fakeCheckStorage) Put(ctx context.Context, reader io.Reader, to *url.URL, metadata storage.Metadata, concurrency int, partSize int64) error {
+	f.requests = append(f.requests, "PutObject")
+	if f.putErr != nil {
+		return f.putErr
+	}
+	content, err := ioutil.ReadAll(reader)
+	f.content = string(content)
+	return err
+}
+
+func (f *fakeCheckStorage) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
+	f.requests = append(f.requests, "GetObject")
+	if f.readErr != nil {
+		return nil, f.readErr
+	}
+	return ioutil.NopCloser(strings.NewReader(f.content)), nil
+}
+
+func (f *fakeCheckStorage) Delete(ctx context.Context, url *url.URL) error {
+	f.requests = append(f.requests, "DeleteObject")
+	return f.deleteErr
+}
+
+func TestRunChecks(t *testing.T) {
+	t.Parallel()
+
+	accessDenied := awserr.New("AccessDenied", "Access Denied", nil)
+
+	testcases := []struct {
+		name             string
+		storage          *fakeCheckStorage
+		dryRun           bool
+		expectedStatuses []string
+		expectedRequests []string
+	}{
+		{
+			name:             "all pass",
+			storage:          &fakeCheckStorage{},
+			expectedStatuses: []string{checkOK, checkOK, checkOK, checkOK},
+			expectedRequests: []string{"HeadBucket", "PutObject", "GetObject", "DeleteObject"},
+		},
+		{
+			name:             "no access to the bucket",
+			storage:          &fakeCheckStorage{headErr: awserr.New("Forbidden", "Forbidden", nil), putErr: accessDenied},
+			expectedStatuses: []string{checkFailed, checkFailed, checkSkipped, checkSkipped},
+			expectedRequests: []string{"HeadBucket", "PutObject"},
+		},
+		{
+			name:             "no write permission",
+			storage:          &fakeCheckStorage{putErr: accessDenied},
+			expectedStatuses: []string{checkOK, checkFailed, checkSkipped, checkSkipped},
+			expectedRequests: []string{"HeadBucket", "PutObject"},
+		},
+		{
+			name:             "no read permission",
+			storage:          &fakeCheckStorage{readErr: accessDenied},
+			expectedStatuses: []string{checkOK, checkOK, checkFailed, checkOK},
+			expectedRequests: []string{"HeadBucket", "PutObject", "GetObject", "DeleteObject"},
+		},
+		{
+			name:             "no delete permission",
+			storage:          &fakeCheckStorage{deleteErr: accessDenied},
+			expectedStatuses: []string{checkOK, checkOK, checkOK, checkFailed},
+			expectedRequests: []string{"HeadBucket", "PutObject", "GetObject", "DeleteObject"},
+		},
+		{
+			name:             "dry run",
+			storage:          &fakeCheckStorage{},
+			dryRun:           true,
+			expectedStatuses: []string{checkOK, checkSkipped, checkSkipped, checkSkipped},
+			expectedRequests: []string{"HeadBucket"},
+		},
+	}
+
+	key, err := url.New("s3://bucket/prefix/.s5cmd-check-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			messages := runChecks(context.Background(), tc.storage, key, tc.dryRun)
+
+			var checks, statuses []string
+			for _, msg := range messages {
+				checks = append(checks, msg.Check)
+				statuses = append(statuses, msg.Status)
+
+				if msg.Status == checkFailed {
+					assert.NotEmpty(t, msg.Error)
+					assert.NotEmpty(t, msg.Code)
+				}
+			}
+			assert.Equal(t, []string{"head-bucket", "put", "get", "delete"}, checks)
+			assert.Equal(t, tc.expectedStatuses, statuses)
+			assert.Equal(t, tc.expectedRequests, tc.storage.requests)
+		})
+	}
+}
+
+func TestRunChecksContentMismatch(t *testing.T) {
+	t.Parallel()
+
+	key, _ := url.New("s3://bucket/.s5cmd-check-1")
+
+	client := &fakeCheckStorage{}
+	messages := runChecks(context.Background(), &mismatchingStorage{client}, key, false)
+
+	assert.Equal(t, checkFailed, messages[2].Status)
+	assert.Equal(t, "content of the object does not match the written content", messages[2].Error)
+	assert.Equal(t, checkOK, messages[3].Status)
+}
+
+// mismatchingStorage returns another content than the written one.
+type mismatchingStorage struct {
+	*fakeCheckStorage
+}
+
+func (m *mismatchingStorage) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("other content")), nil
+}
+
+func TestCheckMessageString(t *testing.T) {
+	t.Parallel()
+
+	u, _ := url.New("s3://bucket/.s5cmd-check-1")
+
+	msg := CheckMessage{Check: "put", URL: u, Status: checkFailed, Error: "AccessDenied: Access Denied"}
+	assert.Equal(t, "FAILED  put         s3://bucket/.s5cmd-check-1: AccessDenied: Access Denied", msg.String())
+	assert.Equal(t, `{"check":"put","url":"s3://bucket/.s5cmd-check-1","status":"failed","error":"AccessDenied: Access Denied"}`, msg.JSON())
+
+	msg = CheckMessage{Check: "head-bucket", URL: u, Status: checkOK}
+	assert.Equal(t, "OK      head-bucket s3://bucket/.s5cmd-check-1", msg.String())
+}
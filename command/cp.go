@@ -1,7 +1,11 @@
 package command
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,7 +13,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -18,10 +24,14 @@ import (
 	"github.com/peak/s5cmd/log"
 	"github.com/peak/s5cmd/log/stat"
 	"github.com/peak/s5cmd/parallel"
+	"github.com/peak/s5cmd/progress"
 	"github.com/peak/s5cmd/storage"
 	"github.com/peak/s5cmd/storage/url"
 )
 
+// stdinSource is the source argument to upload the standard input.
+const stdinSource = "-"
+
 const (
 	defaultCopyConcurrency = 5
 	defaultPartSize        = 50 // MiB
@@ -86,23 +96,60 @@ Examples:
 	16. Upload a file to S3 bucket with expires header
 		 > s5cmd {{.HelpName}} --expires "2024-10-01T20:30:00Z" myfile.gz s3://bucket/
 
-	17. Upload a file to S3 bucket with cache-control header
+	17. Upload a file to S3 bucket with expires header a week from now
+		 > s5cmd {{.HelpName}} --expires 7d myfile.gz s3://bucket/
+
+	18. Upload a file to S3 bucket with cache-control header
 		 > s5cmd {{.HelpName}} --cache-control "public, max-age=345600" myfile.gz s3://bucket/
 
-	18. Copy all files to S3 bucket but exclude the ones with txt and gz extension
+	19. Upload a file to S3 bucket to be downloaded with a different name
+		 > s5cmd {{.HelpName}} --content-disposition 'attachment; filename="report.pdf"' 7f3a.pdf s3://bucket/
+
+	20. Copy all files to S3 bucket but exclude the ones with txt and gz extension
 		 > s5cmd {{.HelpName}} --exclude "*.txt" --exclude "*.gz" dir/ s3://bucket
 
-	19. Copy all files from S3 bucket to another S3 bucket but exclude the ones starts with log
+	21. Copy all files from S3 bucket to another S3 bucket but exclude the ones starts with log
 		 > s5cmd {{.HelpName}} --exclude "log*" s3://bucket/* s3://destbucket
+
+	22. Copy only the files with txt extension from S3 bucket to a local directory
+		 > s5cmd {{.HelpName}} --exclude "*" --include "*.txt" s3://bucket/* dir/
+
+	23. Copy all files in a directory to another local directory
+		 > s5cmd {{.HelpName}} 'dir/*' backup-dir/
+
+	24. Download an S3 object only if it is changed since a download with the given ETag
+		 > s5cmd {{.HelpName}} --if-none-match ETAG s3://bucket/object.gz .
+
+	25. Upload the output of a command to an S3 object
+		 > pg_dump mydb | s5cmd {{.HelpName}} --content-type application/sql - s3://bucket/backups/mydb.sql
+
+	26. Download all objects under an S3 prefix, like "aws s3 cp --recursive"
+		 > s5cmd {{.HelpName}} --recursive s3://bucket/prefix target-directory/
+
+	27. Upload a file to S3 bucket with object lock, retaining it for a year
+		 > s5cmd {{.HelpName}} --object-lock-mode COMPLIANCE --object-lock-retain-until 365d myfile.gz s3://bucket/
+
+	28. Copy all objects under an S3 prefix to another prefix by their names, without their directories
+		 > s5cmd {{.HelpName}} "s3://bucket/2023/*" "s3://bucket/archive/{basename}"
+
+	29. Upload a website, setting the cache headers of the files by their names from the rules in "headers.txt"
+		 > s5cmd {{.HelpName}} --metadata-from headers.txt "site/*" s3://bucket/
+
+	30. Download the first kilobyte of an S3 object
+		 > s5cmd {{.HelpName}} --range bytes=0-1023 s3://bucket/object.gz header.gz
 `
 
 func NewCopyCommandFlags() []cli.Flag {
-	return []cli.Flag{
+	flags := []cli.Flag{
 		&cli.BoolFlag{
 			Name:    "no-clobber",
 			Aliases: []string{"n"},
 			Usage:   "do not overwrite destination if already exists",
 		},
+		&cli.BoolFlag{
+			Name:  "overwrite",
+			Usage: "overwrite destination if already exists; this is the default, it can not be used with conditional flags",
+		},
 		&cli.BoolFlag{
 			Name:    "if-size-differ",
 			Aliases: []string{"s"},
@@ -113,6 +160,18 @@ func NewCopyCommandFlags() []cli.Flag {
 			Aliases: []string{"u"},
 			Usage:   "only overwrite destination if source modtime is newer",
 		},
+		&cli.StringFlag{
+			Name:  "if-match",
+			Usage: "only download source if its ETag matches, fail otherwise",
+		},
+		&cli.StringFlag{
+			Name:  "if-none-match",
+			Usage: "only download source if its ETag does not match, skip otherwise",
+		},
+		&cli.StringFlag{
+			Name:  "range",
+			Usage: "only download the given byte range of source, e.g. bytes=0-1023, bytes=1024- or bytes=-512",
+		},
 		&cli.BoolFlag{
 			Name:    "flatten",
 			Aliases: []string{"f"},
@@ -122,9 +181,25 @@ func NewCopyCommandFlags() []cli.Flag {
 			Name:  "no-follow-symlinks",
 			Usage: "do not follow symbolic links",
 		},
+		&cli.BoolFlag{
+			Name:  "check-md5",
+			Usage: "verify md5 checksum of transferred objects against their ETag; skipped for multipart uploaded and SSE-KMS encrypted objects",
+		},
+		&cli.BoolFlag{
+			Name:  "preserve-mtime",
+			Usage: "set modification time of downloaded files to the modification time of source objects; store modification time of uploaded files in object metadata",
+		},
+		&cli.BoolFlag{
+			Name:  "compress",
+			Usage: "compress uploaded files with gzip and set Content-Encoding of the objects to gzip",
+		},
+		&cli.BoolFlag{
+			Name:  "decompress",
+			Usage: "decompress downloaded objects whose Content-Encoding is gzip",
+		},
 		&cli.StringFlag{
 			Name:  "storage-class",
-			Usage: "set storage class for target ('STANDARD','REDUCED_REDUNDANCY','GLACIER','STANDARD_IA','ONEZONE_IA','INTELLIGENT_TIERING','DEEP_ARCHIVE')",
+			Usage: "set storage class for target ('STANDARD','REDUCED_REDUNDANCY','GLACIER','STANDARD_IA','ONEZONE_IA','INTELLIGENT_TIERING','DEEP_ARCHIVE','OUTPOSTS')",
 		},
 		&cli.IntFlag{
 			Name:    "concurrency",
@@ -138,9 +213,13 @@ func NewCopyCommandFlags() []cli.Flag {
 			Value:   defaultPartSize,
 			Usage:   "size of each part transferred between host and remote server, in MiB",
 		},
+		&cli.IntFlag{
+			Name:  "multipart-threshold",
+			Usage: "size of the largest object uploaded with a single request, in MiB; larger objects are uploaded in parts; 0 uses the part size",
+		},
 		&cli.StringFlag{
 			Name:  "sse",
-			Usage: "perform server side encryption of the data at its destination, e.g. aws:kms",
+			Usage: "perform server side encryption of the data at its destination ('AES256','aws:kms'); aws:kms is used if --sse-kms-key-id is given",
 		},
 		&cli.StringFlag{
 			Name:  "sse-kms-key-id",
@@ -148,7 +227,7 @@ func NewCopyCommandFlags() []cli.Flag {
 		},
 		&cli.StringFlag{
 			Name:  "acl",
-			Usage: "set acl for target: defines granted accesses and their types on different accounts/groups, e.g. cp --acl 'public-read'",
+			Usage: "set canned acl for target: defines granted accesses and their types on different accounts/groups ('private','public-read','public-read-write','authenticated-read','aws-exec-read','bucket-owner-read','bucket-owner-full-control'), e.g. cp --acl 'public-read'",
 		},
 		&cli.StringFlag{
 			Name:  "cache-control",
@@ -156,7 +235,43 @@ func NewCopyCommandFlags() []cli.Flag {
 		},
 		&cli.StringFlag{
 			Name:  "expires",
-			Usage: "set expires for target (uses RFC3339 format): defines expires header for object, e.g. cp  --expires '2024-10-01T20:30:00Z'",
+			Usage: "set expires for target (uses RFC3339 or RFC1123 format, or a duration from now): defines expires header for object, e.g. cp --expires '2024-10-01T20:30:00Z', --expires 'Tue, 01 Oct 2024 20:30:00 GMT' or --expires 7d",
+		},
+		&cli.StringFlag{
+			Name:  "object-lock-mode",
+			Usage: "set object lock retention mode for target ('GOVERNANCE','COMPLIANCE'): requires --object-lock-retain-until and a bucket with object lock enabled, e.g. cp --object-lock-mode 'GOVERNANCE'",
+		},
+		&cli.StringFlag{
+			Name:  "object-lock-retain-until",
+			Usage: "set the date until which the target is retained by object lock (uses RFC3339 or RFC1123 format, or a duration from now): requires --object-lock-mode, e.g. cp --object-lock-retain-until '2030-01-01T00:00:00Z' or --object-lock-retain-until 365d",
+		},
+		&cli.BoolFlag{
+			Name:  "legal-hold",
+			Usage: "place an object lock legal hold on target, which prevents it from being deleted until the hold is removed",
+		},
+		&cli.StringFlag{
+			Name:  "metadata-from",
+			Usage: "set the headers of the uploaded files from the rules in given file, one 'pattern => header: value' rule per line, e.g. '*.html => Cache-Control: no-cache'; the first matching pattern wins",
+		},
+		&cli.StringFlag{
+			Name:  "content-type",
+			Usage: "set content type for target: defines content type header for object, e.g. cp --content-type 'text/html'; guessed from the file, or from the object name for standard input, if not set",
+		},
+		&cli.StringFlag{
+			Name:  "content-disposition",
+			Usage: "set content disposition for target: defines content disposition header for object, e.g. cp --content-disposition 'attachment; filename=\"report.pdf\"'",
+		},
+		&cli.StringFlag{
+			Name:  "content-language",
+			Usage: "set content language for target: defines content language header for object, e.g. cp --content-language 'en-US'",
+		},
+		&cli.StringSliceFlag{
+			Name:  "metadata",
+			Usage: "set user defined metadata for target in key=value format, can be specified multiple times, e.g. cp --metadata 'owner=john'",
+		},
+		&cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "set tag for target in key=value format, can be specified multiple times, e.g. cp --tag 'env=prod'",
 		},
 		&cli.BoolFlag{
 			Name:  "force-glacier-transfer",
@@ -170,15 +285,50 @@ func NewCopyCommandFlags() []cli.Flag {
 			Name:  "destination-region",
 			Usage: "set the region of destination bucket: the region of the destination bucket will be automatically discovered if --destination-region is not specified",
 		},
+		&cli.StringFlag{
+			Name:  "source-profile",
+			Usage: "use the specified profile from the shared credentials file for the source; remote copies are streamed through s5cmd if it differs from the destination profile",
+		},
+		&cli.StringFlag{
+			Name:  "destination-profile",
+			Usage: "use the specified profile from the shared credentials file for the destination; remote copies are streamed through s5cmd if it differs from the source profile",
+		},
+		&cli.BoolFlag{
+			Name:  "preserve-tags",
+			Usage: "set the tags of the source objects on target when remote copies are streamed through s5cmd; S3 copies keep the tags anyway",
+		},
 		&cli.StringSliceFlag{
 			Name:  "exclude",
 			Usage: "exclude objects with given pattern",
 		},
+		&cli.StringSliceFlag{
+			Name:  "include",
+			Usage: "include objects with given pattern even if they match an exclude pattern",
+		},
+		&cli.StringSliceFlag{
+			Name:  "exclude-from",
+			Usage: "exclude objects with the patterns in given file, one pattern per line",
+		},
+		&cli.StringSliceFlag{
+			Name:  "include-from",
+			Usage: "include objects with the patterns in given file, one pattern per line, even if they match an exclude pattern",
+		},
 		&cli.BoolFlag{
 			Name:  "raw",
 			Usage: "disable the wildcard operations, useful with filenames that contains glob characters.",
 		},
+		&cli.BoolFlag{
+			Name:    "recursive",
+			Aliases: []string{"r"},
+			Usage:   "copy all objects under the given bucket or prefix, e.g. cp -r s3://bucket/prefix dir/",
+		},
+		&cli.BoolFlag{
+			Name:    "show-progress",
+			Aliases: []string{"sp"},
+			Usage:   "show a progress bar of the transfers; ignored if --json is set",
+		},
 	}
+	return append(flags, objectFilterFlags()...)
 }
 
 func NewCopyCommand() *cli.Command {
@@ -217,32 +367,90 @@ type Copy struct {
 	noClobber            bool
 	ifSizeDiffer         bool
 	ifSourceNewer        bool
+	ifMatch              string
+	ifNoneMatch          string
+	byteRange            string
 	flatten              bool
 	followSymlinks       bool
+	preserveMtime        bool
+	checkMD5             bool
+	compress             bool
+	decompress           bool
 	storageClass         storage.StorageClass
 	encryptionMethod     string
 	encryptionKeyID      string
 	acl                  string
 	forceGlacierTransfer bool
 	exclude              []string
+	include              []string
+	filter               objectFilter
 	raw                  bool
 	cacheControl         string
 	expires              string
+	objectLockMode       string
+	objectLockUntil      string
+	legalHold            bool
+	contentType          string
+	contentDisposition   string
+	contentLanguage      string
+	metadata             map[string]string
+	metadataRules        []metadataRule
+	tags                 map[string]string
+	showProgress         bool
 
 	// region settings
 	srcRegion string
 	dstRegion string
 
+	// credential settings, --profile is used if they are empty.
+	srcProfile string
+	dstProfile string
+
+	// preserveTags copies the tags of the source objects in streamed copies.
+	preserveTags bool
+
 	// s3 options
 	concurrency int
 	partSize    int64
 	storageOpts storage.Options
+
+	// multipartThreshold is the size of the largest object uploaded with a
+	// single request. It is the part size if zero.
+	multipartThreshold int64
+
+	// opTimeout is the timeout of each object transfer.
+	opTimeout time.Duration
+
+	progressbar progress.ProgressBar
 }
 
 // NewCopy creates Copy from cli.Context.
 func NewCopy(c *cli.Context, deleteSource bool) Copy {
+	// progress bar would be mixed with the JSON output.
+	showProgress := c.Bool("show-progress") && !c.Bool("json")
+
+	// metadata flags are already validated.
+	metadata, _ := parseMetadata(c.StringSlice("metadata"))
+	metadataRules, _ := parseMetadataRules(c)
+	tags, _ := parseTags(c.StringSlice("tag"))
+	exclude, include, _ := parseFilters(c)
+	filter, _ := parseObjectFilter(c, time.Now())
+	expires, _ := parseExpires(c.String("expires"), time.Now())
+	objectLockMode, objectLockUntil, _ := parseObjectLock(c.String("object-lock-mode"), c.String("object-lock-retain-until"), time.Now())
+
+	// providing a KMS key means SSE-KMS encryption.
+	encryptionMethod := c.String("sse")
+	if encryptionMethod == "" && c.String("sse-kms-key-id") != "" {
+		encryptionMethod = "aws:kms"
+	}
+
+	var progressbar progress.ProgressBar = progress.NoOp{}
+	if showProgress {
+		progressbar = progress.New(os.Stderr)
+	}
+
 	return Copy{
-		src:          c.Args().Get(0),
+		src:          recursiveSources(c.Bool("recursive"), c.Args().Get(0))[0],
 		dst:          c.Args().Get(1),
 		op:           c.Command.Name,
 		fullCommand:  givenCommand(c),
@@ -251,24 +459,51 @@ func NewCopy(c *cli.Context, deleteSource bool) Copy {
 		noClobber:            c.Bool("no-clobber"),
 		ifSizeDiffer:         c.Bool("if-size-differ"),
 		ifSourceNewer:        c.Bool("if-source-newer"),
+		ifMatch:              c.String("if-match"),
+		ifNoneMatch:          c.String("if-none-match"),
+		byteRange:            c.String("range"),
 		flatten:              c.Bool("flatten"),
 		followSymlinks:       !c.Bool("no-follow-symlinks"),
+		preserveMtime:        c.Bool("preserve-mtime"),
+		checkMD5:             c.Bool("check-md5"),
+		compress:             c.Bool("compress"),
+		decompress:           c.Bool("decompress"),
 		storageClass:         storage.StorageClass(c.String("storage-class")),
 		concurrency:          c.Int("concurrency"),
 		partSize:             c.Int64("part-size") * megabytes,
-		encryptionMethod:     c.String("sse"),
+		multipartThreshold:   c.Int64("multipart-threshold") * megabytes,
+		encryptionMethod:     encryptionMethod,
 		encryptionKeyID:      c.String("sse-kms-key-id"),
 		acl:                  c.String("acl"),
 		forceGlacierTransfer: c.Bool("force-glacier-transfer"),
-		exclude:              c.StringSlice("exclude"),
+		exclude:              exclude,
+		include:              include,
+		filter:               filter,
 		raw:                  c.Bool("raw"),
 		cacheControl:         c.String("cache-control"),
-		expires:              c.String("expires"),
+		expires:              expires,
+		objectLockMode:       objectLockMode,
+		objectLockUntil:      objectLockUntil,
+		legalHold:            c.Bool("legal-hold"),
+		contentType:          c.String("content-type"),
+		contentDisposition:   c.String("content-disposition"),
+		contentLanguage:      c.String("content-language"),
+		metadata:             metadata,
+		metadataRules:        metadataRules,
+		tags:                 tags,
+		showProgress:         showProgress,
 		// region settings
 		srcRegion: c.String("source-region"),
 		dstRegion: c.String("destination-region"),
+		// credential settings
+		srcProfile: c.String("source-profile"),
+		dstProfile: c.String("destination-profile"),
+
+		preserveTags: c.Bool("preserve-tags"),
 
 		storageOpts: NewStorageOpts(c),
+		opTimeout:   c.Duration("op-timeout"),
+		progressbar: progressbar,
 	}
 }
 
@@ -292,25 +527,43 @@ func (c Copy) Run(ctx context.Context) error {
 		return err
 	}
 
-	// override source region if set
-	if c.srcRegion != "" {
-		c.storageOpts.SetRegion(c.srcRegion)
+	// the standard input is a single object without a size, so there is
+	// nothing to expand.
+	if c.src == stdinSource {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+
+		err := c.doStdinUpload(ctx, srcurl, dsturl)
+		if err != nil {
+			err = &errorpkg.Error{
+				Op:  c.op,
+				Src: srcurl,
+				Dst: dsturl,
+				Err: err,
+			}
+			printError(c.fullCommand, c.op, err)
+		}
+		return err
 	}
 
-	client, err := storage.NewClient(ctx, srcurl, c.storageOpts)
+	client, err := storage.NewClient(ctx, srcurl, c.srcStorageOpts())
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
 	}
 
-	objch, err := expandSource(ctx, client, c.followSymlinks, srcurl)
+	// the source is not listed any further once the manager is drained, the
+	// running transfers keep ctx.
+	listCtx, cancelList := parallel.WithDrain(ctx)
+	defer cancelList()
 
+	objch, err := expandSource(listCtx, client, c.followSymlinks, srcurl)
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
 	}
 
-	waiter := parallel.NewWaiter()
+	waiter := parallel.NewWaiter(ctx)
 
 	// create two different error objects instead of single object to avoid the
 	// data race for merror object, since there is a goroutine running,
@@ -335,17 +588,25 @@ func (c Copy) Run(ctx context.Context) error {
 		}
 	}()
 
-	isBatch := srcurl.IsWildcard()
-	if !isBatch && !srcurl.IsRemote() {
-		obj, _ := client.Stat(ctx, srcurl)
-		isBatch = obj != nil && obj.Type.IsDir()
-	}
-
-	excludePatterns, err := createExcludesFromWildcard(c.exclude)
+	selector, err := newSourceSelector(ctx, client, srcurl, c.exclude, c.include, c.filter)
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
 	}
+	isBatch := selector.isBatch
+
+	c.progressbar.Start()
+	defer c.progressbar.Finish()
+
+	// flattened maps the names of the flattened objects to their sources,
+	// since objects from different directories may have the same name.
+	flattened := map[string]*url.URL{}
+
+	// templated maps the destinations expanded from a template to their
+	// sources, since a template may expand to the same destination, e.g.
+	// {basename} of the objects in different directories.
+	isTemplate := isDestinationTemplate(dsturl)
+	templated := map[string]*url.URL{}
 
 	for object := range objch {
 		if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
@@ -365,16 +626,40 @@ func (c Copy) Run(ctx context.Context) error {
 			continue
 		}
 
-		if isURLExcluded(excludePatterns, object.URL.Path, srcurl.Prefix) {
+		if !selector.match(object) {
 			continue
 		}
 
 		srcurl := object.URL
+		if isTemplate {
+			dst := expandDestinationTemplate(dsturl, srcurl).String()
+			if prev, ok := templated[dst]; ok {
+				err := fmt.Errorf("%q and %q have the same destination %q", prev, srcurl, dst)
+				merrorObjects = multierror.Append(merrorObjects, err)
+				printError(c.fullCommand, c.op, err)
+				continue
+			}
+			templated[dst] = srcurl
+		}
+
+		if isBatch && c.flatten {
+			name := srcurl.Base()
+			if prev, ok := flattened[name]; ok {
+				err := fmt.Errorf("%q and %q have the same name %q in the flattened destination", prev, srcurl, name)
+				merrorObjects = multierror.Append(merrorObjects, err)
+				printError(c.fullCommand, c.op, err)
+				continue
+			}
+			flattened[name] = srcurl
+		}
+
 		var task parallel.Task
 
 		switch {
-		case srcurl.Type == dsturl.Type: // local->local or remote->remote
-			task = c.prepareCopyTask(ctx, srcurl, dsturl, isBatch)
+		case !srcurl.IsRemote() && !dsturl.IsRemote(): // local->local
+			task = c.prepareLocalCopyTask(ctx, srcurl, dsturl, isBatch)
+		case srcurl.Type == dsturl.Type: // remote->remote
+			task = c.prepareCopyTask(ctx, srcurl, dsturl, object.Size, isBatch)
 		case srcurl.IsRemote(): // remote->local
 			task = c.prepareDownloadTask(ctx, srcurl, dsturl, isBatch)
 		case dsturl.IsRemote(): // local->remote
@@ -383,7 +668,7 @@ func (c Copy) Run(ctx context.Context) error {
 			panic("unexpected src-dst pair")
 		}
 
-		parallel.Run(task, waiter)
+		parallel.Run(c.trackProgress(task, object.Size), waiter)
 	}
 
 	waiter.Wait()
@@ -392,15 +677,107 @@ func (c Copy) Run(ctx context.Context) error {
 	return multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
 }
 
+// srcStorageOpts returns the storage options of the source clients. Objects of
+// the source are listed and read in the region set by --source-region, with the
+// profile set by --source-profile.
+func (c Copy) srcStorageOpts() storage.Options {
+	opts := c.storageOpts
+	if c.srcRegion != "" {
+		opts.SetRegion(c.srcRegion)
+	}
+	if c.srcProfile != "" {
+		opts.Profile = c.srcProfile
+	}
+	return opts
+}
+
+// dstStorageOpts returns the storage options of the destination clients.
+// Remote copies are sent to the destination region, which is set by
+// --destination-region, with the profile set by --destination-profile.
+func (c Copy) dstStorageOpts() storage.Options {
+	opts := c.storageOpts
+	if c.dstRegion != "" {
+		opts.SetRegion(c.dstRegion)
+	}
+	if c.dstProfile != "" {
+		opts.Profile = c.dstProfile
+	}
+	return opts
+}
+
+// streamsRemoteCopy reports whether remote copies are streamed through s5cmd
+// instead of being copied by S3. S3 reads the source of a copy with the
+// credentials of the destination, which can not read the objects of another
+// account that does not share its credentials.
+func (c Copy) streamsRemoteCopy() bool {
+	return c.srcStorageOpts().Profile != c.dstStorageOpts().Profile
+}
+
+// withTimeout returns a copy of ctx which is canceled when the operation
+// timeout is exceeded.
+func (c Copy) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.opTimeout)
+}
+
+// trackProgress adds an object with the given size to the progress bar and
+// marks it as completed once the task succeeds. The objects which fail are
+// left uncompleted, so the bar does not reach 100% if any of them fails.
+func (c Copy) trackProgress(task parallel.Task, size int64) parallel.Task {
+	c.progressbar.AddTotalObject(size)
+	return func() error {
+		if err := task(); err != nil {
+			return err
+		}
+		c.progressbar.AddCompletedObject(size)
+		return nil
+	}
+}
+
+// prepareCopyTask returns the task of a remote copy. The size of the source
+// is known only if it is listed in a batch.
 func (c Copy) prepareCopyTask(
 	ctx context.Context,
 	srcurl *url.URL,
 	dsturl *url.URL,
+	size int64,
 	isBatch bool,
 ) func() error {
 	return func() error {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+
 		dsturl = prepareRemoteDestination(srcurl, dsturl, c.flatten, isBatch)
-		err := c.doCopy(ctx, srcurl, dsturl)
+		err := c.doCopy(ctx, srcurl, dsturl, size, isBatch)
+		if err != nil {
+			return &errorpkg.Error{
+				Op:  c.op,
+				Src: srcurl,
+				Dst: dsturl,
+				Err: err,
+			}
+		}
+		return nil
+	}
+}
+
+func (c Copy) prepareLocalCopyTask(
+	ctx context.Context,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	isBatch bool,
+) func() error {
+	return func() error {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+
+		dsturl, err := prepareLocalDestination(ctx, srcurl, dsturl, c.flatten, isBatch, c.storageOpts)
+		if err != nil {
+			return err
+		}
+		err = c.doLocalCopy(ctx, srcurl, dsturl)
 		if err != nil {
 			return &errorpkg.Error{
 				Op:  c.op,
@@ -420,6 +797,9 @@ func (c Copy) prepareDownloadTask(
 	isBatch bool,
 ) func() error {
 	return func() error {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+
 		dsturl, err := prepareLocalDestination(ctx, srcurl, dsturl, c.flatten, isBatch, c.storageOpts)
 		if err != nil {
 			return err
@@ -444,8 +824,12 @@ func (c Copy) prepareUploadTask(
 	isBatch bool,
 ) func() error {
 	return func() error {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+
+		rule := matchMetadataRule(c.metadataRules, metadataRuleName(srcurl, isBatch))
 		dsturl = prepareRemoteDestination(srcurl, dsturl, c.flatten, isBatch)
-		err := c.doUpload(ctx, srcurl, dsturl)
+		err := c.doUpload(ctx, srcurl, dsturl, rule)
 		if err != nil {
 			return &errorpkg.Error{
 				Op:  c.op,
@@ -460,7 +844,7 @@ func (c Copy) prepareUploadTask(
 
 // doDownload is used to fetch a remote object and save as a local object.
 func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
-	srcClient, err := storage.NewRemoteClient(ctx, srcurl, c.storageOpts)
+	srcClient, err := storage.NewRemoteClient(ctx, srcurl, c.srcStorageOpts())
 	if err != nil {
 		return err
 	}
@@ -468,26 +852,102 @@ func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL)
 	dstClient := storage.NewLocalClient(c.storageOpts)
 
 	err = c.shouldOverride(ctx, srcurl, dsturl)
+	if err == nil {
+		err = c.checkPreconditions(ctx, srcClient, srcurl)
+	}
 	if err != nil {
 		// FIXME(ig): rename
 		if errorpkg.IsWarning(err) {
+			stat.AddSkipped()
 			printDebug(c.op, srcurl, dsturl, err)
 			return nil
 		}
 		return err
 	}
 
-	file, err := dstClient.Create(dsturl.Absolute())
+	// the object is written to a temporary file next to the destination and
+	// renamed once it is complete, so that a partially written file never
+	// appears at the destination.
+	partial := partialPath(dsturl.Absolute())
+	file, err := dstClient.Create(partial)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	size, err := srcClient.Get(ctx, srcurl, file, c.concurrency, c.partSize)
+	completed := false
+	defer func() {
+		if !completed {
+			// an open file can not be removed on Windows.
+			file.Close()
+			_ = os.Remove(partial)
+		}
+	}()
+
+	var writer io.WriterAt = parallel.Limiter().WriterAt(file)
+	if c.showProgress {
+		w := progress.NewWriterAt(writer, c.progressbar)
+		defer w.Done()
+		writer = w
+	}
+
+	transfer := stat.StartTransfer(stat.Download)
+	size, err := srcClient.Get(ctx, srcurl, writer, c.preconditions(), c.byteRange, c.concurrency, c.partSize)
+	transfer.End()
 	if err != nil {
-		_ = dstClient.Delete(ctx, dsturl)
+		// the object is modified after its preconditions are checked.
+		if errorpkg.IsWarning(err) {
+			stat.AddSkipped()
+			printDebug(c.op, srcurl, dsturl, err)
+			return nil
+		}
 		return err
 	}
+	transfer.AddBytes(size)
+
+	if c.checkMD5 && !c.storageOpts.DryRun {
+		if err := c.verifyMD5(ctx, srcClient, srcurl, partial, srcurl, dsturl); err != nil {
+			return err
+		}
+	}
+
+	// the parts of the object are written concurrently, so the file is
+	// decompressed after it is downloaded.
+	if c.decompress && !c.storageOpts.DryRun {
+		obj, err := srcClient.Stat(ctx, srcurl)
+		if err != nil {
+			return err
+		}
+
+		if obj.Metadata.ContentEncoding() == gzipEncoding {
+			// the file is replaced by the decompressed one.
+			file.Close()
+			if err := gunzipFile(partial); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.preserveMtime && !c.storageOpts.DryRun {
+		obj, err := srcClient.Stat(ctx, srcurl)
+		if err != nil {
+			return err
+		}
+
+		mtime := downloadModTime(obj)
+		if err := dstClient.Chtimes(partial, mtime, mtime); err != nil {
+			return err
+		}
+	}
+
+	if !c.storageOpts.DryRun {
+		// an open file can not be renamed on Windows.
+		file.Close()
+		if err := os.Rename(partial, dsturl.Absolute()); err != nil {
+			return err
+		}
+	}
+	completed = true
 
 	if c.deleteSource {
 		_ = srcClient.Delete(ctx, srcurl)
@@ -502,11 +962,12 @@ func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL)
 		},
 	}
 	log.Info(msg)
+	manifest.record(msg)
 
 	return nil
 }
 
-func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
+func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL, rule *metadataRule) error {
 	srcClient := storage.NewLocalClient(c.storageOpts)
 
 	file, err := srcClient.Open(srcurl.Absolute())
@@ -518,37 +979,67 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) er
 	err = c.shouldOverride(ctx, srcurl, dsturl)
 	if err != nil {
 		if errorpkg.IsWarning(err) {
+			stat.AddSkipped()
 			printDebug(c.op, srcurl, dsturl, err)
 			return nil
 		}
 		return err
 	}
 
-	// override destination region if set
-	if c.dstRegion != "" {
-		c.storageOpts.SetRegion(c.dstRegion)
-	}
-	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.storageOpts)
+	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.dstStorageOpts())
 	if err != nil {
 		return err
 	}
 
-	metadata := storage.NewMetadata().
-		SetContentType(guessContentType(file)).
-		SetStorageClass(string(c.storageClass)).
-		SetSSE(c.encryptionMethod).
-		SetSSEKeyID(c.encryptionKeyID).
-		SetACL(c.acl).
-		SetCacheControl(c.cacheControl).
-		SetExpires(c.expires)
+	contentType := c.contentType
+	if contentType == "" {
+		contentType = guessContentType(file)
+	}
+
+	metadata := c.uploadMetadata(contentType)
+	rule.apply(metadata)
+	if c.preserveMtime {
+		obj, err := srcClient.Stat(ctx, srcurl)
+		if err != nil {
+			return err
+		}
+		metadata.SetUserDefined(mtimeMetadataKey, formatModTime(*obj.ModTime))
+	}
+
+	reader, closeReader := c.uploadReader(file, metadata)
+	defer closeReader()
+
+	size := int64(-1)
+	if info, err := file.Stat(); err == nil && !c.compress {
+		size = info.Size()
+	}
+	partSize := uploadPartSize(size, c.multipartThreshold, c.partSize)
+	concurrency, releaseMemory, err := c.reserveUploadMemory(ctx, reader, size, partSize)
+	if err != nil {
+		return err
+	}
+	defer releaseMemory()
 
-	err = dstClient.Put(ctx, file, dsturl, metadata, c.concurrency, c.partSize)
+	transfer := stat.StartTransfer(stat.Upload)
+	err = dstClient.Put(ctx, reader, dsturl, metadata, concurrency, partSize)
+	transfer.End()
 	if err != nil {
 		return err
 	}
 
+	if c.checkMD5 && !c.storageOpts.DryRun {
+		if err := c.verifyMD5(ctx, dstClient, dsturl, srcurl.Absolute(), srcurl, dsturl); err != nil {
+			return err
+		}
+	}
+
 	obj, _ := srcClient.Stat(ctx, srcurl)
-	size := obj.Size
+	size = obj.Size
+
+	// nothing is transferred in dry-run mode.
+	if !c.storageOpts.DryRun {
+		transfer.AddBytes(size)
+	}
 
 	if c.deleteSource {
 		// close the file before deleting
@@ -568,88 +1059,501 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) er
 		},
 	}
 	log.Info(msg)
+	manifest.record(msg)
 
 	return nil
 }
 
-func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL) error {
-	// override destination region if set
-	if c.dstRegion != "" {
-		c.storageOpts.SetRegion(c.dstRegion)
-	}
-	dstClient, err := storage.NewClient(ctx, dsturl, c.storageOpts)
+// doStdinUpload uploads the standard input to the remote object. The size of
+// the input is not known, so it is uploaded in parts of --part-size, and its
+// content type is guessed from the extension of the object.
+func (c Copy) doStdinUpload(ctx context.Context, srcurl, dsturl *url.URL) error {
+	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.dstStorageOpts())
 	if err != nil {
 		return err
 	}
 
-	metadata := storage.NewMetadata().
-		SetStorageClass(string(c.storageClass)).
-		SetSSE(c.encryptionMethod).
-		SetSSEKeyID(c.encryptionKeyID).
-		SetACL(c.acl).
-		SetCacheControl(c.cacheControl).
-		SetExpires(c.expires)
+	contentType := c.contentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(dsturl.Path))
+	}
+	metadata := c.uploadMetadata(contentType)
 
-	err = c.shouldOverride(ctx, srcurl, dsturl)
+	input := &countingReader{reader: os.Stdin}
+	reader, closeReader := c.uploadReader(input, metadata)
+	defer closeReader()
+
+	concurrency, releaseMemory, err := c.reserveUploadMemory(ctx, reader, -1, c.partSize)
 	if err != nil {
-		if errorpkg.IsWarning(err) {
-			printDebug(c.op, srcurl, dsturl, err)
-			return nil
-		}
 		return err
 	}
+	defer releaseMemory()
 
-	err = dstClient.Copy(ctx, srcurl, dsturl, metadata)
+	transfer := stat.StartTransfer(stat.Upload)
+	err = dstClient.Put(ctx, reader, dsturl, metadata, concurrency, c.partSize)
+	transfer.End()
 	if err != nil {
 		return err
 	}
-
-	if c.deleteSource {
-		srcClient, err := storage.NewClient(ctx, srcurl, c.storageOpts)
-		if err != nil {
-			return err
-		}
-		if err := srcClient.Delete(ctx, srcurl); err != nil {
-			return err
-		}
-	}
+	transfer.AddBytes(input.size)
 
 	msg := log.InfoMessage{
 		Operation:   c.op,
 		Source:      srcurl,
 		Destination: dsturl,
 		Object: &storage.Object{
-			URL:          dsturl,
+			Size:         input.size,
 			StorageClass: c.storageClass,
 		},
 	}
 	log.Info(msg)
+	manifest.record(msg)
 
 	return nil
 }
 
-// shouldOverride function checks if the destination should be overridden if
-// the source-destination pair and given copy flags conform to the
-// override criteria. For example; "cp -n -s <src> <dst>" should not override
-// the <dst> if <src> and <dst> filenames are the same, except if the size
-// differs.
-func (c Copy) shouldOverride(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
-	// if not asked to override, ignore.
-	if !c.noClobber && !c.ifSizeDiffer && !c.ifSourceNewer {
-		return nil
+// uploadMetadata returns the metadata of the uploaded objects set by the
+// flags.
+func (c Copy) uploadMetadata(contentType string) storage.Metadata {
+	metadata := storage.NewMetadata().
+		SetContentType(contentType).
+		SetContentDisposition(c.contentDisposition).
+		SetContentLanguage(c.contentLanguage).
+		SetStorageClass(string(c.storageClass)).
+		SetSSE(c.encryptionMethod).
+		SetSSEKeyID(c.encryptionKeyID).
+		SetACL(c.acl).
+		SetCacheControl(c.cacheControl).
+		SetExpires(c.expires).
+		SetObjectLock(c.objectLockMode, c.objectLockUntil).
+		SetObjectLockLegalHold(c.legalHold).
+		SetTagging(c.tags)
+
+	for key, value := range c.metadata {
+		metadata.SetUserDefined(key, value)
 	}
+	return metadata
+}
 
-	srcClient, err := storage.NewClient(ctx, srcurl, c.storageOpts)
-	if err != nil {
-		return err
+// uploadReader wraps the reader of an upload to show its progress, compress
+// it and limit its throughput. The returned function must be called once the
+// upload is done. The content encoding is set on metadata if it is compressed.
+func (c Copy) uploadReader(reader io.Reader, metadata storage.Metadata) (io.Reader, func()) {
+	var closers []func()
+
+	if c.showProgress {
+		r := progress.NewReader(reader, c.progressbar)
+		closers = append(closers, r.Done)
+		reader = r
 	}
 
-	srcObj, err := getObject(ctx, srcurl, srcClient)
-	if err != nil {
-		return err
+	if c.compress {
+		metadata.SetContentEncoding(gzipEncoding)
+
+		r := gzipReader(reader)
+		closers = append(closers, func() { r.Close() })
+		reader = r
 	}
 
-	dstClient, err := storage.NewClient(ctx, dsturl, c.storageOpts)
+	closeReader := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+	return parallel.Limiter().Reader(reader), closeReader
+}
+
+// reserveUploadMemory acquires the memory of the part buffers of an upload
+// of the given reader, size bytes or unknown if negative, in parts of partSize
+// from the memory limit of --max-memory flag. It returns the concurrency of the
+// upload which fits in the limit and a function releasing the memory.
+func (c Copy) reserveUploadMemory(ctx context.Context, reader io.Reader, size, partSize int64) (int, func(), error) {
+	limiter := parallel.Memory()
+
+	// the uploader reads the parts of seekable files without buffering them.
+	_, seekable := reader.(interface {
+		io.ReaderAt
+		io.Seeker
+	})
+	if limiter == nil || seekable {
+		return c.concurrency, func() {}, nil
+	}
+
+	concurrency, n := uploadBuffers(size, limiter.Limit(), c.concurrency, partSize)
+	if err := limiter.Acquire(ctx, n); err != nil {
+		return 0, nil, err
+	}
+	return concurrency, func() { limiter.Release(n) }, nil
+}
+
+// uploadPartSize returns the part size of an upload of size bytes, unknown if
+// negative. The uploader sends the objects which fit in a part with a single
+// request, so the part size is raised to the size of the objects up to the
+// multipart threshold, and lowered to split the larger objects into parts.
+// The part size is not changed if the threshold is not given.
+func uploadPartSize(size, threshold, partSize int64) int64 {
+	if threshold <= 0 || size < 0 {
+		return partSize
+	}
+
+	if size <= threshold {
+		if size > partSize {
+			return size
+		}
+		return partSize
+	}
+
+	if size <= partSize {
+		// the parts, except the last one, can not be smaller than the
+		// minimum part size.
+		half := (size + 1) / 2
+		if half < storage.MinPartSize {
+			half = storage.MinPartSize
+		}
+		return half
+	}
+	return partSize
+}
+
+// uploadBuffers returns the concurrency of an upload of size bytes, unknown if
+// negative, whose part buffers fit in limit bytes, and the total size of its
+// buffers. The uploader buffers each part being uploaded and the part read
+// next, so the concurrency is at least 1 even if two parts exceed the limit.
+func uploadBuffers(size, limit int64, concurrency int, partSize int64) (int, int64) {
+	if partSize <= 0 {
+		return concurrency, 0
+	}
+
+	if fit := int(limit/partSize) - 1; concurrency > fit {
+		concurrency = fit
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	buffers := int64(concurrency) + 1
+	if size >= 0 {
+		parts := (size + partSize - 1) / partSize
+		if parts < 1 {
+			parts = 1
+		}
+		if parts < buffers {
+			buffers = parts
+		}
+	}
+
+	n := buffers * partSize
+	if n > limit {
+		n = limit
+	}
+	return concurrency, n
+}
+
+func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL, size int64, sizeKnown bool) error {
+	dstClient, err := storage.NewClient(ctx, dsturl, c.dstStorageOpts())
+	if err != nil {
+		return err
+	}
+
+	metadata := storage.NewMetadata().
+		SetContentType(c.contentType).
+		SetContentDisposition(c.contentDisposition).
+		SetContentLanguage(c.contentLanguage).
+		SetStorageClass(string(c.storageClass)).
+		SetSSE(c.encryptionMethod).
+		SetSSEKeyID(c.encryptionKeyID).
+		SetACL(c.acl).
+		SetCacheControl(c.cacheControl).
+		SetExpires(c.expires).
+		SetObjectLock(c.objectLockMode, c.objectLockUntil).
+		SetObjectLockLegalHold(c.legalHold).
+		SetTagging(c.tags)
+
+	for key, value := range c.metadata {
+		metadata.SetUserDefined(key, value)
+	}
+
+	err = c.shouldOverride(ctx, srcurl, dsturl)
+	if err != nil {
+		if errorpkg.IsWarning(err) {
+			stat.AddSkipped()
+			printDebug(c.op, srcurl, dsturl, err)
+			return nil
+		}
+		return err
+	}
+
+	if c.streamsRemoteCopy() {
+		err = c.doStreamingCopy(ctx, srcurl, dsturl, metadata)
+	} else {
+		err = c.doServerSideCopy(ctx, dstClient, srcurl, dsturl, metadata, size, sizeKnown)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.deleteSource {
+		srcClient, err := storage.NewClient(ctx, srcurl, c.srcStorageOpts())
+		if err != nil {
+			return err
+		}
+		if err := srcClient.Delete(ctx, srcurl); err != nil {
+			return err
+		}
+	}
+
+	msg := log.InfoMessage{
+		Operation:   c.op,
+		Source:      srcurl,
+		Destination: dsturl,
+		Object: &storage.Object{
+			URL:          dsturl,
+			StorageClass: c.storageClass,
+		},
+	}
+	log.Info(msg)
+	manifest.record(msg)
+
+	return nil
+}
+
+// doStreamingCopy copies a remote object by reading it with the source
+// credentials and uploading it with the destination credentials. Like S3
+// copies, the metadata of the source is kept unless the flags replace it.
+// The tags of the source are read with an additional request, so they are
+// only kept with --preserve-tags.
+func (c Copy) doStreamingCopy(ctx context.Context, srcurl, dsturl *url.URL, metadata storage.Metadata) error {
+	srcClient, err := storage.NewRemoteClient(ctx, srcurl, c.srcStorageOpts())
+	if err != nil {
+		return err
+	}
+
+	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.dstStorageOpts())
+	if err != nil {
+		return err
+	}
+
+	srcObj, err := srcClient.Stat(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+
+	if !replacesMetadata(metadata) {
+		copySourceMetadata(metadata, srcObj.Metadata)
+	}
+
+	if c.preserveTags {
+		tags, err := srcClient.GetTagging(ctx, srcurl)
+		if err != nil {
+			return err
+		}
+		metadata.SetTagging(tags)
+	}
+
+	body, err := srcClient.Read(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	reader, closeReader := c.uploadReader(body, metadata)
+	defer closeReader()
+
+	partSize := uploadPartSize(srcObj.Size, c.multipartThreshold, c.partSize)
+	concurrency, releaseMemory, err := c.reserveUploadMemory(ctx, reader, srcObj.Size, partSize)
+	if err != nil {
+		return err
+	}
+	defer releaseMemory()
+
+	transfer := stat.StartTransfer(stat.Copy)
+	err = dstClient.Put(ctx, reader, dsturl, metadata, concurrency, partSize)
+	transfer.End()
+	if err != nil {
+		return err
+	}
+	transfer.AddBytes(srcObj.Size)
+	return nil
+}
+
+// doServerSideCopy copies a remote object on the server side. The objects
+// larger than the limit of a single copy request are copied in parts. The
+// size of the source is looked up unless it is known from the listing. It is
+// not looked up in dry-run mode since nothing is copied.
+func (c Copy) doServerSideCopy(
+	ctx context.Context,
+	dstClient storage.Storage,
+	srcurl, dsturl *url.URL,
+	metadata storage.Metadata,
+	size int64,
+	sizeKnown bool,
+) error {
+	if !c.storageOpts.DryRun && (!sizeKnown || size > storage.MaxCopyObjectSize) {
+		srcClient, err := storage.NewRemoteClient(ctx, srcurl, c.srcStorageOpts())
+		if err != nil {
+			return err
+		}
+
+		// the error of the copy is returned if the source can not be
+		// looked up, e.g. it does not exist.
+		srcObj, err := srcClient.Stat(ctx, srcurl)
+		if err == nil && srcObj.Size > storage.MaxCopyObjectSize {
+			return c.doMultipartCopy(ctx, srcurl, dsturl, metadata, srcObj)
+		}
+	}
+
+	return dstClient.Copy(ctx, srcurl, dsturl, metadata)
+}
+
+// doMultipartCopy copies a remote object larger than the limit of a single
+// copy request in parts. Like single copies, the metadata of the source is
+// kept unless the flags replace it.
+func (c Copy) doMultipartCopy(
+	ctx context.Context,
+	srcurl, dsturl *url.URL,
+	metadata storage.Metadata,
+	srcObj *storage.Object,
+) error {
+	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.dstStorageOpts())
+	if err != nil {
+		return err
+	}
+
+	if !replacesMetadata(metadata) {
+		copySourceMetadata(metadata, srcObj.Metadata)
+	}
+
+	return dstClient.MultipartCopy(ctx, srcurl, dsturl, metadata, srcObj.Size, c.concurrency, c.partSize)
+}
+
+// copySourceMetadata sets the metadata of the source object which is kept by
+// the copies that are not done by a single copy request. Expires is not copied
+// since it is not returned in the format accepted by the uploads.
+func copySourceMetadata(metadata, source storage.Metadata) {
+	for _, key := range []string{"ContentType", "ContentEncoding", "ContentDisposition", "ContentLanguage", "CacheControl"} {
+		if value, ok := source[key]; ok {
+			metadata[key] = value
+		}
+	}
+	for key, value := range source.UserDefined() {
+		metadata.SetUserDefined(key, value)
+	}
+}
+
+// replacesMetadata reports whether the given metadata of a copy replaces the
+// metadata of the source object.
+func replacesMetadata(metadata storage.Metadata) bool {
+	if len(metadata.UserDefined()) > 0 {
+		return true
+	}
+	for _, value := range []string{
+		metadata.ContentType(),
+		metadata.ContentDisposition(),
+		metadata.ContentLanguage(),
+		metadata.CacheControl(),
+		metadata.Expires(),
+	} {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// doLocalCopy copies a local file to a local destination, or renames it if
+// the source is deleted.
+func (c Copy) doLocalCopy(ctx context.Context, srcurl, dsturl *url.URL) error {
+	client := storage.NewLocalClient(c.storageOpts)
+
+	err := c.shouldOverride(ctx, srcurl, dsturl)
+	if err != nil {
+		if errorpkg.IsWarning(err) {
+			stat.AddSkipped()
+			printDebug(c.op, srcurl, dsturl, err)
+			return nil
+		}
+		return err
+	}
+
+	obj, err := client.Stat(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+
+	if c.deleteSource {
+		err = client.Rename(ctx, srcurl, dsturl)
+	} else {
+		err = client.Copy(ctx, srcurl, dsturl, storage.NewMetadata())
+	}
+	if err != nil {
+		return err
+	}
+
+	msg := log.InfoMessage{
+		Operation:   c.op,
+		Source:      srcurl,
+		Destination: dsturl,
+		Object: &storage.Object{
+			Size: obj.Size,
+		},
+	}
+	log.Info(msg)
+	manifest.record(msg)
+
+	return nil
+}
+
+func (c Copy) preconditions() storage.Preconditions {
+	return storage.Preconditions{
+		IfMatch:     c.ifMatch,
+		IfNoneMatch: c.ifNoneMatch,
+	}
+}
+
+// checkPreconditions checks the ETag of the source object against
+// --if-match and --if-none-match flags. They are checked before the
+// destination file is created, so that an existing file is not truncated if
+// the object is not downloaded.
+func (c Copy) checkPreconditions(ctx context.Context, srcClient *storage.S3, srcurl *url.URL) error {
+	preconditions := c.preconditions()
+	if preconditions == (storage.Preconditions{}) {
+		return nil
+	}
+
+	obj, err := srcClient.Stat(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+	return preconditions.Check(obj.Etag)
+}
+
+// shouldOverride function checks if the destination should be overridden if
+// the source-destination pair and given copy flags conform to the
+// override criteria. For example; "cp -n -s <src> <dst>" should not override
+// the <dst> if <src> and <dst> filenames are the same, except if the size
+// differs.
+func (c Copy) shouldOverride(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
+	// the operations completed by a resumed run are not repeated.
+	if completed.contains(c.op, srcurl, dsturl) {
+		return errorpkg.ErrOperationCompleted
+	}
+
+	// if not asked to override, ignore.
+	if !c.noClobber && !c.ifSizeDiffer && !c.ifSourceNewer {
+		return nil
+	}
+
+	srcClient, err := storage.NewClient(ctx, srcurl, c.srcStorageOpts())
+	if err != nil {
+		return err
+	}
+
+	srcObj, err := getObject(ctx, srcurl, srcClient)
+	if err != nil {
+		return err
+	}
+
+	dstClient, err := storage.NewClient(ctx, dsturl, c.dstStorageOpts())
 	if err != nil {
 		return err
 	}
@@ -698,6 +1602,13 @@ func prepareRemoteDestination(
 	flatten bool,
 	isBatch bool,
 ) *url.URL {
+	// the destination of a template is the object itself, like the
+	// destination of a single object.
+	if isDestinationTemplate(dsturl) {
+		dsturl = expandDestinationTemplate(dsturl, srcurl)
+		isBatch = false
+	}
+
 	objname := srcurl.Base()
 	if isBatch && !flatten {
 		objname = srcurl.Relative()
@@ -719,6 +1630,11 @@ func prepareLocalDestination(
 	isBatch bool,
 	storageOpts storage.Options,
 ) (*url.URL, error) {
+	if isDestinationTemplate(dsturl) {
+		dsturl = expandDestinationTemplate(dsturl, srcurl)
+		isBatch = false
+	}
+
 	objname := srcurl.Base()
 	if isBatch && !flatten {
 		objname = srcurl.Relative()
@@ -779,8 +1695,12 @@ func validateCopyCommand(c *cli.Context) error {
 		return fmt.Errorf("expected source and destination arguments")
 	}
 
+	if c.Bool("recursive") && c.Bool("raw") {
+		return fmt.Errorf("--recursive can not be used with --raw")
+	}
+
 	ctx := c.Context
-	src := c.Args().Get(0)
+	src := recursiveSources(c.Bool("recursive"), c.Args().Get(0))[0]
 	dst := c.Args().Get(1)
 
 	srcurl, err := url.New(src, url.WithRaw(c.Bool("raw")))
@@ -798,6 +1718,140 @@ func validateCopyCommand(c *cli.Context) error {
 		return fmt.Errorf("target %q can not contain glob characters", dst)
 	}
 
+	// new versions are created by S3, they can not be written to.
+	if dsturl.VersionID != "" {
+		return fmt.Errorf("target %q can not have a version id", dst)
+	}
+
+	// the objects are named by the template instead.
+	if isDestinationTemplate(dsturl) && c.Bool("flatten") {
+		return fmt.Errorf("--flatten can not be used with a destination template")
+	}
+
+	if err := validateStorageClass(c.String("storage-class")); err != nil {
+		return err
+	}
+
+	if err := validateEncryption(c.String("sse"), c.String("sse-kms-key-id")); err != nil {
+		return err
+	}
+
+	if _, err := parseMetadata(c.StringSlice("metadata")); err != nil {
+		return err
+	}
+
+	if err := validateMetadataRules(c, srcurl, dsturl); err != nil {
+		return err
+	}
+
+	if _, err := parseExpires(c.String("expires"), time.Now()); err != nil {
+		return err
+	}
+
+	if _, err := parseTags(c.StringSlice("tag")); err != nil {
+		return err
+	}
+
+	if err := validateObjectLock(c, dsturl); err != nil {
+		return err
+	}
+
+	if _, _, err := parseFilters(c); err != nil {
+		return err
+	}
+
+	if err := validateObjectFilterSources(c, srcurl); err != nil {
+		return err
+	}
+
+	if err := validateACL(c.String("acl")); err != nil {
+		return err
+	}
+
+	if c.Bool("overwrite") {
+		for _, flag := range []string{"no-clobber", "if-size-differ", "if-source-newer"} {
+			if c.Bool(flag) {
+				return fmt.Errorf("--overwrite can not be used with --%v", flag)
+			}
+		}
+	}
+
+	if err := validateMultipartThreshold(c.Int64("multipart-threshold")); err != nil {
+		return err
+	}
+
+	if c.Int64("multipart-threshold") != 0 && !dsturl.IsRemote() {
+		return fmt.Errorf("--multipart-threshold can only be used for uploads and remote copies")
+	}
+
+	if c.Bool("compress") && (srcurl.IsRemote() || !dsturl.IsRemote()) {
+		return fmt.Errorf("--compress can only be used for uploads")
+	}
+
+	for _, flag := range []string{"if-match", "if-none-match"} {
+		if c.String(flag) != "" && (!srcurl.IsRemote() || dsturl.IsRemote()) {
+			return fmt.Errorf("--%v can only be used for downloads", flag)
+		}
+	}
+
+	if c.String("source-profile") != "" && !srcurl.IsRemote() {
+		return fmt.Errorf("--source-profile can only be used with remote sources")
+	}
+
+	if c.String("destination-profile") != "" && !dsturl.IsRemote() {
+		return fmt.Errorf("--destination-profile can only be used with remote destinations")
+	}
+
+	if c.Bool("preserve-tags") && (!srcurl.IsRemote() || !dsturl.IsRemote()) {
+		return fmt.Errorf("--preserve-tags can only be used for remote copies")
+	}
+
+	if c.Bool("preserve-tags") && len(c.StringSlice("tag")) > 0 {
+		return fmt.Errorf("--preserve-tags can not be used with --tag")
+	}
+
+	for _, flag := range []string{"content-disposition", "content-language"} {
+		if c.String(flag) != "" && !dsturl.IsRemote() {
+			return fmt.Errorf("--%v can only be used for uploads and remote copies", flag)
+		}
+	}
+
+	if c.Bool("decompress") && (!srcurl.IsRemote() || dsturl.IsRemote()) {
+		return fmt.Errorf("--decompress can only be used for downloads")
+	}
+
+	if err := validateRange(c.String("range")); err != nil {
+		return err
+	}
+
+	if c.String("range") != "" {
+		if !srcurl.IsRemote() || dsturl.IsRemote() {
+			return fmt.Errorf("--range can only be used for downloads")
+		}
+
+		// the rest of the object would be lost.
+		if c.Command.Name == "mv" {
+			return fmt.Errorf("--range can not be used with mv")
+		}
+
+		// a part of an object is neither its checksum nor a complete gzip
+		// stream.
+		for _, flag := range []string{"check-md5", "decompress"} {
+			if c.Bool(flag) {
+				return fmt.Errorf("--%v can not be used with --range", flag)
+			}
+		}
+	}
+
+	// ETag of a compressed object is not the checksum of the file.
+	if c.Bool("compress") && c.Bool("check-md5") {
+		return fmt.Errorf("--check-md5 can not be used with --compress")
+	}
+
+	if src == stdinSource {
+		return validateStdinUpload(c, dsturl)
+	}
+
 	// we don't operate on S3 prefixes for copy and delete operations.
 	if srcurl.IsBucket() || srcurl.IsPrefix() {
 		return fmt.Errorf("source argument must contain wildcard character")
@@ -805,27 +1859,333 @@ func validateCopyCommand(c *cli.Context) error {
 
 	// 'cp dir/* s3://bucket/prefix': expect a trailing slash to avoid any
 	// surprises.
-	if srcurl.IsWildcard() && dsturl.IsRemote() && !dsturl.IsPrefix() && !dsturl.IsBucket() {
+	if srcurl.IsWildcard() && dsturl.IsRemote() && !dsturl.IsPrefix() && !dsturl.IsBucket() && !isDestinationTemplate(dsturl) {
 		return fmt.Errorf("target %q must be a bucket or a prefix", dsturl)
 	}
 
-	switch {
-	case srcurl.Type == dsturl.Type:
-		return validateCopy(srcurl, dsturl)
-	case dsturl.IsRemote():
+	if !srcurl.IsRemote() && dsturl.IsRemote() {
 		return validateUpload(ctx, srcurl, dsturl, NewStorageOpts(c))
+	}
+	return nil
+}
+
+// validateStdinUpload validates the flags and the destination of an upload
+// from the standard input, which has no name, size or modification time.
+func validateStdinUpload(c *cli.Context, dsturl *url.URL) error {
+	if c.Command.Name == "mv" {
+		return fmt.Errorf("standard input can not be moved")
+	}
+
+	if !dsturl.IsRemote() {
+		return fmt.Errorf("standard input can only be uploaded to a remote object")
+	}
+
+	if dsturl.IsBucket() || dsturl.IsPrefix() {
+		return fmt.Errorf("target %q must be an object when uploading standard input", dsturl)
+	}
+
+	if isDestinationTemplate(dsturl) {
+		return fmt.Errorf("destination templates can not be used when uploading standard input")
+	}
+
+	for _, flag := range []string{"no-clobber", "if-size-differ", "if-source-newer", "preserve-mtime", "check-md5"} {
+		if c.Bool(flag) {
+			return fmt.Errorf("--%v can not be used when uploading standard input", flag)
+		}
+	}
+	return nil
+}
+
+// validateObjectLock validates the object lock flags, which can only be set
+// on remote destinations.
+func validateObjectLock(c *cli.Context, dsturl *url.URL) error {
+	if _, _, err := parseObjectLock(c.String("object-lock-mode"), c.String("object-lock-retain-until"), time.Now()); err != nil {
+		return err
+	}
+
+	for _, flag := range []string{"object-lock-mode", "legal-hold"} {
+		if c.IsSet(flag) && !dsturl.IsRemote() {
+			return fmt.Errorf("--%v can only be used for uploads and remote copies", flag)
+		}
+	}
+	return nil
+}
+
+func validateStorageClass(class string) error {
+	if class == "" {
+		return nil
+	}
+
+	if !storage.StorageClass(class).IsValid() {
+		return fmt.Errorf("invalid storage class %q", class)
+	}
+	return nil
+}
+
+func validateACL(acl string) error {
+	if acl == "" {
+		return nil
+	}
+
+	if !storage.IsValidACL(acl) {
+		return fmt.Errorf("invalid acl %q", acl)
+	}
+	return nil
+}
+
+func validateEncryption(method, keyID string) error {
+	switch method {
+	case "", "AES256", "aws:kms":
 	default:
+		return fmt.Errorf("invalid server side encryption method %q", method)
+	}
+
+	if keyID != "" && method == "AES256" {
+		return fmt.Errorf("--sse-kms-key-id can not be used with %v encryption", method)
+	}
+	return nil
+}
+
+// verifyMD5 compares the md5 checksum of the local file at path with the ETag
+// of the remote object. The comparison is skipped with a debug message if the
+// ETag is not the md5 checksum of the object.
+func (c Copy) verifyMD5(
+	ctx context.Context,
+	client storage.Storage,
+	remoteurl *url.URL,
+	path string,
+	srcurl, dsturl *url.URL,
+) error {
+	obj, err := client.Stat(ctx, remoteurl)
+	if err != nil {
+		return err
+	}
+
+	if !isMD5Verifiable(obj) {
+		err := fmt.Errorf("md5 checksum is not verified, ETag of multipart uploaded or SSE-KMS encrypted objects is not an md5 checksum")
+		printDebug(c.op, srcurl, dsturl, err)
 		return nil
 	}
+	return compareMD5(path, obj.Etag)
+}
+
+// isMD5Verifiable reports whether the ETag of the object is its md5 checksum.
+// ETags of multipart uploaded objects contain the number of parts after a
+// dash.
+func isMD5Verifiable(obj *storage.Object) bool {
+	return !strings.Contains(obj.Etag, "-") && obj.Metadata.SSE() != "aws:kms"
+}
+
+// compareMD5 compares the md5 checksum of the file at path with the given
+// ETag.
+func compareMD5(path, etag string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	checksum := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(checksum, etag) {
+		return fmt.Errorf("md5 checksum %q of %q does not match ETag %q", checksum, path, etag)
+	}
+	return nil
+}
+
+// mtimeMetadataKey is the user defined metadata key which stores the
+// modification time of the uploaded files as seconds since the Unix epoch,
+// e.g. "1600000000.123456789". The format is compatible with rclone.
+const mtimeMetadataKey = "mtime"
+
+// formatModTime formats the given modification time to be stored as
+// metadata.
+func formatModTime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// parseModTime parses a modification time stored as metadata.
+func parseModTime(value string) (time.Time, error) {
+	parts := strings.SplitN(value, ".", 2)
+
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid modification time %q", value)
+	}
+
+	var nsec int64
+	if len(parts) == 2 {
+		// pad or truncate the fraction to nanoseconds.
+		frac := (parts[1] + "000000000")[:9]
+		nsec, err = strconv.ParseInt(frac, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid modification time %q", value)
+		}
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// downloadModTime returns the modification time which is stored in the
+// metadata of the given object if it is uploaded with --preserve-mtime,
+// otherwise the last modification time of the object.
+func downloadModTime(obj *storage.Object) time.Time {
+	for key, value := range obj.Metadata.UserDefined() {
+		if !strings.EqualFold(key, mtimeMetadataKey) {
+			continue
+		}
+		if mtime, err := parseModTime(value); err == nil {
+			return mtime
+		}
+	}
+	return *obj.ModTime
+}
+
+// parseMetadata parses the user defined metadata given in key=value format.
+func parseMetadata(values []string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, value := range values {
+		kv := strings.SplitN(value, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("metadata %q must be in key=value format", value)
+		}
+
+		key := strings.TrimSpace(kv[0])
+		if key == "" {
+			return nil, fmt.Errorf("metadata %q must have a non-empty key", value)
+		}
+		metadata[key] = kv[1]
+	}
+	return metadata, nil
+}
+
+var expiresLayouts = []string{
+	time.RFC3339,
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// parseExpires parses the value of --expires, which is either a time in RFC3339
+// or RFC1123 format, or a duration from now, e.g. "12h" or "7d". The time is
+// returned in RFC3339 format, which is expected by the storage. It returns an
+// empty string if the value is empty.
+func parseExpires(s string, now time.Time) (string, error) {
+	return parseTimeFromNow("expires", s, now)
 }
 
-func validateCopy(srcurl, dsturl *url.URL) error {
-	if srcurl.IsRemote() || dsturl.IsRemote() {
+// parseTimeFromNow parses the value of the given time flag in the formats of
+// --expires.
+func parseTimeFromNow(flag, s string, now time.Time) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	if d, err := parseDuration(s); err == nil {
+		if d < 0 {
+			return "", fmt.Errorf("invalid --%v value %q: duration can not be negative", flag, s)
+		}
+		return now.Add(d).UTC().Format(time.RFC3339), nil
+	}
+
+	for _, layout := range expiresLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC().Format(time.RFC3339), nil
+		}
+	}
+	return "", fmt.Errorf("invalid --%v value %q: must be a date, e.g. 2024-10-01T20:30:00Z or \"Tue, 01 Oct 2024 20:30:00 GMT\", or a duration, e.g. 7d", flag, s)
+}
+
+// parseObjectLock parses the retention mode and date of --object-lock-mode
+// and --object-lock-retain-until. S3 requires both of them to retain an
+// object, and the date to be in the future. The mode is returned in upper
+// case and the date in RFC3339 format.
+func parseObjectLock(mode, retainUntil string, now time.Time) (string, string, error) {
+	if mode == "" && retainUntil == "" {
+		return "", "", nil
+	}
+
+	mode = strings.ToUpper(mode)
+	switch mode {
+	case "":
+		return "", "", fmt.Errorf("--object-lock-retain-until can only be used with --object-lock-mode")
+	case storage.ObjectLockModeGovernance, storage.ObjectLockModeCompliance:
+	default:
+		return "", "", fmt.Errorf("invalid --object-lock-mode value %q: must be %v or %v", mode, storage.ObjectLockModeGovernance, storage.ObjectLockModeCompliance)
+	}
+
+	if retainUntil == "" {
+		return "", "", fmt.Errorf("--object-lock-mode can only be used with --object-lock-retain-until")
+	}
+
+	date, err := parseTimeFromNow("object-lock-retain-until", retainUntil, now)
+	if err != nil {
+		return "", "", err
+	}
+
+	// the date is already formatted.
+	if t, _ := time.Parse(time.RFC3339, date); !t.After(now) {
+		return "", "", fmt.Errorf("invalid --object-lock-retain-until value %q: must be in the future", retainUntil)
+	}
+	return mode, date, nil
+}
+
+// validateRange validates the value of --range flag. It must be a single
+// byte range as in the Range header of HTTP, i.e. "bytes=first-last",
+// "bytes=first-" or "bytes=-length". S3 does not support multiple ranges.
+func validateRange(s string) error {
+	if s == "" {
 		return nil
 	}
 
-	// we don't support local->local copies
-	return fmt.Errorf("local->local copy operations are not permitted")
+	invalid := fmt.Errorf("invalid --range value %q: must be bytes=first-last, bytes=first- or bytes=-length", s)
+
+	spec := strings.TrimPrefix(s, "bytes=")
+	if spec == s {
+		return invalid
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 || (parts[0] == "" && parts[1] == "") {
+		return invalid
+	}
+
+	// parseOffset parses a non-empty part of the range, which consists of
+	// digits only.
+	parseOffset := func(part string) (int64, bool) {
+		if part == "" || strings.TrimLeft(part, "0123456789") != "" {
+			return 0, false
+		}
+		n, err := strconv.ParseInt(part, 10, 64)
+		return n, err == nil
+	}
+
+	if parts[0] == "" {
+		if length, ok := parseOffset(parts[1]); !ok || length == 0 {
+			return invalid
+		}
+		return nil
+	}
+
+	first, ok := parseOffset(parts[0])
+	if !ok {
+		return invalid
+	}
+
+	if parts[1] == "" {
+		return nil
+	}
+
+	last, ok := parseOffset(parts[1])
+	if !ok {
+		return invalid
+	}
+	if last < first {
+		return fmt.Errorf("invalid --range value %q: last byte can not be before the first byte", s)
+	}
+	return nil
 }
 
 func validateUpload(ctx context.Context, srcurl, dsturl *url.URL, storageOpts storage.Options) error {
@@ -866,6 +2226,88 @@ func guessContentType(file *os.File) string {
 	return contentType
 }
 
+// countingReader counts the bytes read from the reader, e.g. to get the size
+// of the standard input once it is uploaded.
+type countingReader struct {
+	reader io.Reader
+	size   int64
+}
+
+// Read implements io.Reader.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// partialPath returns the path of the temporary file a download to the given
+// path is written to. The name is random, so that concurrent downloads to the
+// same path do not write to the same file.
+func partialPath(path string) string {
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	return fmt.Sprintf("%v.%x.partial", path, suffix)
+}
+
+// gzipEncoding is the Content-Encoding of the objects compressed with gzip.
+const gzipEncoding = "gzip"
+
+// gzipReader returns a reader of the gzip compressed content of r. It must be
+// closed if it is not read until the end, to stop compressing.
+func gzipReader(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		gw := gzip.NewWriter(pw)
+		_, err := io.Copy(gw, r)
+		if err == nil {
+			err = gw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// gunzipFile decompresses the gzip compressed file at the given path in place.
+func gunzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	gr, err := gzip.NewReader(src)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	dst, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+
+	if _, err := io.Copy(dst, gr); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(dst.Name(), info.Mode()); err != nil {
+		return err
+	}
+
+	// an open file can not be replaced on Windows.
+	src.Close()
+	return os.Rename(dst.Name(), path)
+}
+
 func givenCommand(c *cli.Context) string {
 	cmd := c.Command.FullName()
 	if c.Args().Len() > 0 {
@@ -874,3 +2316,21 @@ func givenCommand(c *cli.Context) string {
 
 	return cmd
 }
+
+// validateMultipartThreshold returns an error if the --multipart-threshold
+// value, in MiB, is neither zero nor between the minimum part size and the
+// largest object which can be uploaded with a single request.
+func validateMultipartThreshold(threshold int64) error {
+	const (
+		minThreshold = storage.MinPartSize / megabytes
+		maxThreshold = storage.MaxPutObjectSize / megabytes
+	)
+
+	if threshold == 0 {
+		return nil
+	}
+	if threshold < minThreshold || threshold > maxThreshold {
+		return fmt.Errorf("--multipart-threshold must be between %d and %d MiB", minThreshold, maxThreshold)
+	}
+	return nil
+}
@@ -1,12 +1,23 @@
 package command
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/peak/s5cmd/progress"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
 )
 
 func TestGuessContentType(t *testing.T) {
@@ -62,3 +73,850 @@ func TestGuessContentType(t *testing.T) {
 		os.Remove(f.Name())
 	}
 }
+
+func TestGzipRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	random := make([]byte, 1<<20)
+	rand.New(rand.NewSource(0)).Read(random)
+
+	testcases := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "empty"},
+		{name: "text", content: []byte(strings.Repeat("this is a log line\n", 10000))},
+		{name: "random", content: random},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir, err := ioutil.TempDir("", "s5cmd-gzip")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			r := gzipReader(bytes.NewReader(tc.content))
+			compressed, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(dir, "file.txt")
+			if err := ioutil.WriteFile(path, compressed, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := gunzipFile(path); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, bytes.Equal(got, tc.content), "decompressed content differs")
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+			// the temporary file is removed.
+			files, _ := ioutil.ReadDir(dir)
+			assert.Equal(t, 1, len(files))
+		})
+	}
+}
+
+func TestGunzipFileInvalid(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "s5cmd-gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not compressed")
+	f.Close()
+
+	assert.Error(t, gunzipFile(f.Name()))
+
+	// the file is not modified.
+	got, _ := ioutil.ReadFile(f.Name())
+	assert.Equal(t, "not compressed", string(got))
+}
+
+func TestValidateACL(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name    string
+		acl     string
+		wantErr bool
+	}{
+		{name: "no acl"},
+		{name: "private", acl: "private"},
+		{name: "public-read", acl: "public-read"},
+		{name: "bucket-owner-full-control", acl: "bucket-owner-full-control"},
+		{name: "unknown acl", acl: "public-write", wantErr: true},
+		{name: "case sensitive", acl: "Private", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateACL(tc.acl)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateStorageClass(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name    string
+		class   string
+		wantErr bool
+	}{
+		{name: "no storage class"},
+		{name: "STANDARD_IA", class: "STANDARD_IA"},
+		{name: "unknown to the sdk", class: "GLACIER_IR"},
+		{name: "custom class", class: "cold-tier.1"},
+		{name: "space", class: "STANDARD IA", wantErr: true},
+		{name: "control character", class: "STANDARD\n", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateStorageClass(tc.class)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateEncryption(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name    string
+		method  string
+		keyID   string
+		wantErr bool
+	}{
+		{name: "no encryption"},
+		{name: "AES256", method: "AES256"},
+		{name: "aws:kms", method: "aws:kms"},
+		{name: "aws:kms with key", method: "aws:kms", keyID: "key"},
+		{name: "key without method", keyID: "key"},
+		{name: "AES256 with key", method: "AES256", keyID: "key", wantErr: true},
+		{name: "unknown method", method: "aes256", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateEncryption(tc.method, tc.keyID)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestModTimeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "seconds", value: "1600000000", want: time.Unix(1600000000, 0)},
+		{name: "nanoseconds", value: "1600000000.123456789", want: time.Unix(1600000000, 123456789)},
+		{name: "short fraction", value: "1600000000.5", want: time.Unix(1600000000, 500000000)},
+		{name: "long fraction", value: "1600000000.1234567891", want: time.Unix(1600000000, 123456789)},
+		{name: "not a number", value: "yesterday", wantErr: true},
+		{name: "invalid fraction", value: "1600000000.x", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseModTime(tc.value)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tc.want.Equal(got), "expected %v, got %v", tc.want, got)
+
+			parsed, err := parseModTime(formatModTime(got))
+			assert.NoError(t, err)
+			assert.True(t, got.Equal(parsed), "expected %v, got %v", got, parsed)
+		})
+	}
+}
+
+func TestDownloadModTime(t *testing.T) {
+	t.Parallel()
+
+	lastModified := time.Unix(1600000000, 0)
+	obj := &storage.Object{ModTime: &lastModified}
+	assert.True(t, lastModified.Equal(downloadModTime(obj)))
+
+	obj.Metadata = storage.NewMetadata().SetUserDefined("Mtime", "1500000000.25")
+	assert.True(t, time.Unix(1500000000, 250000000).Equal(downloadModTime(obj)))
+
+	// invalid metadata is ignored.
+	obj.Metadata = storage.NewMetadata().SetUserDefined("Mtime", "invalid")
+	assert.True(t, lastModified.Equal(downloadModTime(obj)))
+}
+
+func TestCompareMD5(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "s5cmd-md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString("content"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// md5 checksum of "content"
+	assert.NoError(t, compareMD5(f.Name(), "9a0364b9e99bb480dd25e1f0284c8555"))
+	assert.NoError(t, compareMD5(f.Name(), "9A0364B9E99BB480DD25E1F0284C8555"))
+	assert.Error(t, compareMD5(f.Name(), "d41d8cd98f00b204e9800998ecf8427e"))
+	assert.Error(t, compareMD5(f.Name()+"-nonexistent", "9a0364b9e99bb480dd25e1f0284c8555"))
+}
+
+func TestIsMD5Verifiable(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name string
+		obj  *storage.Object
+		want bool
+	}{
+		{
+			name: "single part",
+			obj:  &storage.Object{Etag: "9a0364b9e99bb480dd25e1f0284c8555"},
+			want: true,
+		},
+		{
+			name: "multipart",
+			obj:  &storage.Object{Etag: "9a0364b9e99bb480dd25e1f0284c8555-3"},
+		},
+		{
+			name: "sse-kms",
+			obj: &storage.Object{
+				Etag:     "9a0364b9e99bb480dd25e1f0284c8555",
+				Metadata: storage.NewMetadata().SetSSE("aws:kms"),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, isMD5Verifiable(tc.obj))
+		})
+	}
+}
+
+func TestCopyStorageOptsRegion(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name      string
+		srcRegion string
+		dstRegion string
+
+		expectedSrcRegion string
+		expectedDstRegion string
+	}{
+		{
+			name: "regions are detected",
+		},
+		{
+			name:              "only source region is set",
+			srcRegion:         "eu-west-1",
+			expectedSrcRegion: "eu-west-1",
+		},
+		{
+			name:              "only destination region is set",
+			dstRegion:         "us-west-2",
+			expectedDstRegion: "us-west-2",
+		},
+		{
+			name:              "source and destination regions differ",
+			srcRegion:         "eu-west-1",
+			dstRegion:         "us-west-2",
+			expectedSrcRegion: "eu-west-1",
+			expectedDstRegion: "us-west-2",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := Copy{srcRegion: tc.srcRegion, dstRegion: tc.dstRegion}
+
+			// the options of the source and the destination must not leak into
+			// each other, regardless of the order they are created.
+			dstOpts := c.dstStorageOpts()
+			srcOpts := c.srcStorageOpts()
+
+			assert.Equal(t, tc.expectedSrcRegion, srcOpts.Region())
+			assert.Equal(t, tc.expectedDstRegion, dstOpts.Region())
+			assert.Equal(t, "", c.storageOpts.Region())
+		})
+	}
+}
+
+func TestCopyStorageOptsProfile(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name       string
+		profile    string
+		srcProfile string
+		dstProfile string
+
+		expectedSrcProfile string
+		expectedDstProfile string
+		expectedStreaming  bool
+	}{
+		{
+			name: "no profiles",
+		},
+		{
+			name:               "global profile",
+			profile:            "default",
+			expectedSrcProfile: "default",
+			expectedDstProfile: "default",
+		},
+		{
+			name:               "same source and destination profiles",
+			profile:            "default",
+			srcProfile:         "account",
+			dstProfile:         "account",
+			expectedSrcProfile: "account",
+			expectedDstProfile: "account",
+		},
+		{
+			name:               "source profile differs from global profile",
+			profile:            "default",
+			srcProfile:         "source",
+			expectedSrcProfile: "source",
+			expectedDstProfile: "default",
+			expectedStreaming:  true,
+		},
+		{
+			name:               "destination profile differs from global profile",
+			profile:            "default",
+			dstProfile:         "destination",
+			expectedSrcProfile: "default",
+			expectedDstProfile: "destination",
+			expectedStreaming:  true,
+		},
+		{
+			name:               "source and destination profiles differ",
+			srcProfile:         "source",
+			dstProfile:         "destination",
+			expectedSrcProfile: "source",
+			expectedDstProfile: "destination",
+			expectedStreaming:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := Copy{
+				srcProfile:  tc.srcProfile,
+				dstProfile:  tc.dstProfile,
+				storageOpts: storage.Options{Profile: tc.profile},
+			}
+
+			assert.Equal(t, tc.expectedSrcProfile, c.srcStorageOpts().Profile)
+			assert.Equal(t, tc.expectedDstProfile, c.dstStorageOpts().Profile)
+			assert.Equal(t, tc.expectedStreaming, c.streamsRemoteCopy())
+			assert.Equal(t, tc.profile, c.storageOpts.Profile)
+		})
+	}
+}
+
+func TestReplacesMetadata(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, replacesMetadata(storage.NewMetadata().SetContentType("").SetStorageClass("STANDARD")))
+	assert.True(t, replacesMetadata(storage.NewMetadata().SetContentType("text/plain")))
+	assert.True(t, replacesMetadata(storage.NewMetadata().SetCacheControl("no-cache")))
+	assert.True(t, replacesMetadata(storage.NewMetadata().SetUserDefined("key", "value")))
+}
+
+func TestUploadBuffers(t *testing.T) {
+	t.Parallel()
+
+	const partSize = 5 * megabytes
+
+	testcases := []struct {
+		name                string
+		size                int64
+		limit               int64
+		concurrency         int
+		expectedConcurrency int
+		expectedBytes       int64
+	}{
+		{
+			name:                "unknown size",
+			size:                -1,
+			limit:               100 * megabytes,
+			concurrency:         5,
+			expectedConcurrency: 5,
+			expectedBytes:       6 * partSize,
+		},
+		{
+			name:                "single part",
+			size:                megabytes,
+			limit:               100 * megabytes,
+			concurrency:         5,
+			expectedConcurrency: 5,
+			expectedBytes:       partSize,
+		},
+		{
+			name:                "empty file",
+			size:                0,
+			limit:               100 * megabytes,
+			concurrency:         5,
+			expectedConcurrency: 5,
+			expectedBytes:       partSize,
+		},
+		{
+			name:                "fewer parts than concurrency",
+			size:                2*partSize + 1,
+			limit:               100 * megabytes,
+			concurrency:         5,
+			expectedConcurrency: 5,
+			expectedBytes:       3 * partSize,
+		},
+		{
+			name:                "concurrency reduced to fit the limit",
+			size:                -1,
+			limit:               20 * megabytes,
+			concurrency:         5,
+			expectedConcurrency: 3,
+			expectedBytes:       4 * partSize,
+		},
+		{
+			name:                "limit smaller than two parts",
+			size:                -1,
+			limit:               8 * megabytes,
+			concurrency:         5,
+			expectedConcurrency: 1,
+			expectedBytes:       8 * megabytes,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			concurrency, n := uploadBuffers(tc.size, tc.limit, tc.concurrency, partSize)
+			assert.Equal(t, tc.expectedConcurrency, concurrency)
+			assert.Equal(t, tc.expectedBytes, n)
+			assert.LessOrEqual(t, n, tc.limit)
+		})
+	}
+}
+
+func TestUploadPartSize(t *testing.T) {
+	t.Parallel()
+
+	const (
+		partSize  = 50 * megabytes
+		threshold = 100 * megabytes
+	)
+
+	testcases := []struct {
+		name      string
+		size      int64
+		threshold int64
+		expected  int64
+	}{
+		{
+			name:     "no threshold",
+			size:     threshold,
+			expected: partSize,
+		},
+		{
+			name:      "unknown size",
+			size:      -1,
+			threshold: threshold,
+			expected:  partSize,
+		},
+		{
+			name:      "smaller than the part size and the threshold",
+			size:      megabytes,
+			threshold: threshold,
+			expected:  partSize,
+		},
+		{
+			name:      "larger than the part size, smaller than the threshold",
+			size:      threshold - 1,
+			threshold: threshold,
+			expected:  threshold - 1,
+		},
+		{
+			name:      "at the threshold",
+			size:      threshold,
+			threshold: threshold,
+			expected:  threshold,
+		},
+		{
+			name:      "one byte over the threshold",
+			size:      threshold + 1,
+			threshold: threshold,
+			expected:  partSize,
+		},
+		{
+			name:      "smaller than the part size, over the threshold",
+			size:      20*megabytes + 1,
+			threshold: 20 * megabytes,
+			expected:  10*megabytes + 1,
+		},
+		{
+			name:      "part size over the threshold raised to the minimum part size",
+			size:      6 * megabytes,
+			threshold: 5 * megabytes,
+			expected:  storage.MinPartSize,
+		},
+		{
+			name:      "equal to the part size, over the threshold",
+			size:      partSize,
+			threshold: 10 * megabytes,
+			expected:  partSize / 2,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := uploadPartSize(tc.size, tc.threshold, partSize)
+			assert.Equal(t, tc.expected, got)
+
+			// the uploader sends the objects which fit in a part with a
+			// single request.
+			if tc.threshold > 0 && tc.size >= 0 {
+				assert.Equal(t, tc.size <= tc.threshold, tc.size <= got)
+			}
+		})
+	}
+}
+
+func TestValidateMultipartThreshold(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateMultipartThreshold(0))
+	assert.NoError(t, validateMultipartThreshold(5))
+	assert.NoError(t, validateMultipartThreshold(5120))
+	assert.Error(t, validateMultipartThreshold(4))
+	assert.Error(t, validateMultipartThreshold(5121))
+	assert.Error(t, validateMultipartThreshold(-1))
+}
+
+func TestValidateRange(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		value     string
+		expectErr bool
+	}{
+		{value: ""},
+		{value: "bytes=0-1023"},
+		{value: "bytes=0-0"},
+		{value: "bytes=1024-"},
+		{value: "bytes=-512"},
+		{value: "0-1023", expectErr: true},
+		{value: "bits=0-1023", expectErr: true},
+		{value: "bytes=", expectErr: true},
+		{value: "bytes=-", expectErr: true},
+		{value: "bytes=-0", expectErr: true},
+		{value: "bytes=10", expectErr: true},
+		{value: "bytes=10-5", expectErr: true},
+		{value: "bytes=a-b", expectErr: true},
+		{value: "bytes=+1-5", expectErr: true},
+		{value: "bytes= 0-5", expectErr: true},
+		{value: "bytes=0-5,10-15", expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		err := validateRange(tc.value)
+		if tc.expectErr {
+			assert.Error(t, err, tc.value)
+		} else {
+			assert.NoError(t, err, tc.value)
+		}
+	}
+}
+
+func TestParseExpires(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		value     string
+		expected  string
+		expectErr bool
+	}{
+		{value: "", expected: ""},
+		{value: "2024-10-01T20:30:00Z", expected: "2024-10-01T20:30:00Z"},
+		{value: "2024-10-01T20:30:00+03:00", expected: "2024-10-01T17:30:00Z"},
+		{value: "Tue, 01 Oct 2024 20:30:00 GMT", expected: "2024-10-01T20:30:00Z"},
+		{value: "Tue, 01 Oct 2024 20:30:00 +0200", expected: "2024-10-01T18:30:00Z"},
+		{value: "12h", expected: "2024-10-02T00:00:00Z"},
+		{value: "1h30m", expected: "2024-10-01T13:30:00Z"},
+		{value: "7d", expected: "2024-10-08T12:00:00Z"},
+		{value: "2w", expected: "2024-10-15T12:00:00Z"},
+		{value: "-1h", expectErr: true},
+		{value: "2024-10-01", expectErr: true},
+		{value: "tomorrow", expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseExpires(tc.value, now)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseObjectLock(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name                string
+		mode                string
+		retainUntil         string
+		expectedMode        string
+		expectedRetainUntil string
+		expectErr           bool
+	}{
+		{name: "no object lock"},
+		{
+			name:                "governance until a date",
+			mode:                "GOVERNANCE",
+			retainUntil:         "2030-01-01T00:00:00Z",
+			expectedMode:        "GOVERNANCE",
+			expectedRetainUntil: "2030-01-01T00:00:00Z",
+		},
+		{
+			name:                "compliance for a duration",
+			mode:                "compliance",
+			retainUntil:         "365d",
+			expectedMode:        "COMPLIANCE",
+			expectedRetainUntil: "2025-10-01T12:00:00Z",
+		},
+		{name: "invalid mode", mode: "LEGAL", retainUntil: "7d", expectErr: true},
+		{name: "mode without date", mode: "GOVERNANCE", expectErr: true},
+		{name: "date without mode", retainUntil: "7d", expectErr: true},
+		{name: "invalid date", mode: "GOVERNANCE", retainUntil: "someday", expectErr: true},
+		{name: "date in the past", mode: "GOVERNANCE", retainUntil: "2024-01-01T00:00:00Z", expectErr: true},
+		{name: "date of now", mode: "GOVERNANCE", retainUntil: "0s", expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mode, retainUntil, err := parseObjectLock(tc.mode, tc.retainUntil, now)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q %q", mode, retainUntil)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mode != tc.expectedMode {
+				t.Errorf("expected mode %q, got %q", tc.expectedMode, mode)
+			}
+			if retainUntil != tc.expectedRetainUntil {
+				t.Errorf("expected date %q, got %q", tc.expectedRetainUntil, retainUntil)
+			}
+		})
+	}
+}
+
+func TestPrepareLocalDestination(t *testing.T) {
+	t.Parallel()
+
+	workdir, err := ioutil.TempDir("", "s5cmd-destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workdir)
+
+	if err := os.Mkdir(filepath.Join(workdir, "existingdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(workdir, "existingfile"), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srcurl, err := url.New("s3://bucket/prefix/key.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name     string
+		dst      string
+		expected string
+		// isDir is the path which is expected to be a directory afterwards.
+		isDir string
+	}{
+		{
+			name:     "existing directory",
+			dst:      "existingdir",
+			expected: "existingdir/key.txt",
+		},
+		{
+			name:     "existing directory with trailing slash",
+			dst:      "existingdir/",
+			expected: "existingdir/key.txt",
+		},
+		{
+			name:     "existing file",
+			dst:      "existingfile",
+			expected: "existingfile",
+		},
+		{
+			name:     "new file",
+			dst:      "newfile",
+			expected: "newfile",
+		},
+		{
+			name:     "new file in a new directory",
+			dst:      "newparent/newfile",
+			expected: "newparent/newfile",
+			isDir:    "newparent",
+		},
+		{
+			name:     "new directory with trailing slash",
+			dst:      "newdir/",
+			expected: "newdir/key.txt",
+			isDir:    "newdir",
+		},
+	}
+
+	for _, tc := range testcases {
+		// filepath.Join drops the trailing slash of the destination.
+		dst := filepath.Join(workdir, tc.dst)
+		if strings.HasSuffix(tc.dst, "/") {
+			dst += "/"
+		}
+
+		dsturl, err := url.New(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := prepareLocalDestination(context.Background(), srcurl, dsturl, false, false, storage.Options{})
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.name, err)
+		}
+		assert.Equal(t, filepath.Join(workdir, tc.expected), filepath.Clean(got.Absolute()), tc.name)
+
+		if tc.isDir != "" {
+			fi, err := os.Stat(filepath.Join(workdir, tc.isDir))
+			if assert.NoError(t, err, tc.name) {
+				assert.True(t, fi.IsDir(), tc.name)
+			}
+		}
+	}
+}
+
+// countingBar counts the objects reported to it.
+type countingBar struct {
+	progress.NoOp
+	total, completed int
+}
+
+func (b *countingBar) AddTotalObject(int64)     { b.total++ }
+func (b *countingBar) AddCompletedObject(int64) { b.completed++ }
+
+func TestTrackProgress(t *testing.T) {
+	t.Parallel()
+
+	bar := &countingBar{}
+	c := Copy{progressbar: bar}
+
+	ok := c.trackProgress(func() error { return nil }, 10)
+	failed := c.trackProgress(func() error { return errors.New("failed") }, 10)
+
+	assert.NoError(t, ok())
+	assert.Error(t, failed())
+
+	assert.Equal(t, 2, bar.total)
+	assert.Equal(t, 1, bar.completed, "failed objects must not be completed")
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -41,7 +42,7 @@ func NewSizeCommand() *cli.Command {
 		HelpName:           "du",
 		Usage:              "show object size usage",
 		CustomHelpTemplate: sizeHelpTemplate,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:    "group",
 				Aliases: []string{"g"},
@@ -56,7 +57,11 @@ func NewSizeCommand() *cli.Command {
 				Name:  "exclude",
 				Usage: "exclude objects with given pattern",
 			},
-		},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern even if they match an exclude pattern",
+			},
+		}, objectFilterFlags()...),
 		Before: func(c *cli.Context) error {
 			err := validateDUCommand(c)
 			if err != nil {
@@ -67,6 +72,9 @@ func NewSizeCommand() *cli.Command {
 		Action: func(c *cli.Context) (err error) {
 			defer stat.Collect(c.Command.FullName(), &err)()
 
+			// filters are already validated.
+			filter, _ := parseObjectFilter(c, time.Now())
+
 			return Size{
 				src:         c.Args().First(),
 				op:          c.Command.Name,
@@ -75,6 +83,8 @@ func NewSizeCommand() *cli.Command {
 				groupByClass: c.Bool("group"),
 				humanize:     c.Bool("humanize"),
 				exclude:      c.StringSlice("exclude"),
+				include:      c.StringSlice("include"),
+				filter:       filter,
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -92,6 +102,8 @@ type Size struct {
 	groupByClass bool
 	humanize     bool
 	exclude      []string
+	include      []string
+	filter       objectFilter
 
 	storageOpts storage.Options
 }
@@ -114,13 +126,19 @@ func (sz Size) Run(ctx context.Context) error {
 
 	var merror error
 
-	excludePatterns, err := createExcludesFromWildcard(sz.exclude)
+	excludePatterns, err := createRegexFromWildcard(sz.exclude)
 	if err != nil {
 		printError(sz.fullCommand, sz.op, err)
 		return err
 	}
 
-	for object := range client.List(ctx, srcurl, false) {
+	includePatterns, err := createRegexFromWildcard(sz.include)
+	if err != nil {
+		printError(sz.fullCommand, sz.op, err)
+		return err
+	}
+
+	for object := range listURL(ctx, client, false, srcurl) {
 		if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
 			continue
 		}
@@ -131,7 +149,11 @@ func (sz Size) Run(ctx context.Context) error {
 			continue
 		}
 
-		if isURLExcluded(excludePatterns, object.URL.Path, srcurl.Prefix) {
+		if isURLExcluded(excludePatterns, includePatterns, object.URL.Path, srcurl.Prefix) {
+			continue
+		}
+
+		if !sz.filter.match(object) {
 			continue
 		}
 
@@ -219,5 +241,9 @@ func validateDUCommand(c *cli.Context) error {
 	if c.Args().Len() != 1 {
 		return fmt.Errorf("expected only 1 argument")
 	}
+
+	if _, err := parseObjectFilter(c, time.Now()); err != nil {
+		return err
+	}
 	return nil
 }
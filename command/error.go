@@ -8,6 +8,8 @@ import (
 
 	errorpkg "github.com/peak/s5cmd/error"
 	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/storage"
 	"github.com/peak/s5cmd/storage/url"
 )
 
@@ -35,8 +37,9 @@ func printError(command, op string, err error) {
 				Err:       cleanupError(cerr.Err),
 				Command:   cerr.FullCommand(),
 				Operation: cerr.Op,
+				Code:      storage.ErrorCode(cerr.Err),
 			}
-			log.Error(msg)
+			logError(msg)
 			return
 		}
 	}
@@ -52,8 +55,9 @@ func printError(command, op string, err error) {
 						Err:       cleanupError(customErr.Err),
 						Command:   customErr.FullCommand(),
 						Operation: customErr.Op,
+						Code:      storage.ErrorCode(customErr.Err),
 					}
-					log.Error(msg)
+					logError(msg)
 					continue
 				}
 
@@ -61,9 +65,10 @@ func printError(command, op string, err error) {
 					Err:       cleanupError(err),
 					Command:   command,
 					Operation: op,
+					Code:      storage.ErrorCode(err),
 				}
 
-				log.Error(msg)
+				logError(msg)
 			}
 			return
 		}
@@ -74,7 +79,14 @@ func printError(command, op string, err error) {
 		Err:       cleanupError(err),
 		Command:   command,
 		Operation: op,
+		Code:      storage.ErrorCode(err),
 	}
+	logError(msg)
+}
+
+// logError prints the error message and counts its code for --stat output.
+func logError(msg log.ErrorMessage) {
+	stat.AddError(msg.Code)
 	log.Error(msg)
 }
 
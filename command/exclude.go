@@ -1,11 +1,66 @@
 package command
 
 import (
+	"bufio"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/urfave/cli/v2"
 )
 
+// parseFilters returns the patterns of --exclude and --include flags,
+// followed by the patterns read from the files of --exclude-from and
+// --include-from flags in the given order.
+func parseFilters(c *cli.Context) (exclude, include []string, err error) {
+	exclude, err = filterPatterns(c, "exclude")
+	if err != nil {
+		return nil, nil, err
+	}
+	include, err = filterPatterns(c, "include")
+	if err != nil {
+		return nil, nil, err
+	}
+	return exclude, include, nil
+}
+
+func filterPatterns(c *cli.Context, name string) ([]string, error) {
+	patterns := c.StringSlice(name)
+	for _, path := range c.StringSlice(name + "-from") {
+		filePatterns, err := readPatternFile(path)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, filePatterns...)
+	}
+	return patterns, nil
+}
+
+// readPatternFile reads the patterns in the given file, one pattern per line.
+// Blank lines and the lines starting with "#" are skipped.
+func readPatternFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
 func wildCardToRegexp(pattern string) string {
 	patternRegex := regexp.QuoteMeta(pattern)
 	patternRegex = strings.Replace(patternRegex, "\\?", ".", -1)
@@ -14,10 +69,10 @@ func wildCardToRegexp(pattern string) string {
 	return patternRegex
 }
 
-// createExcludesFromWildcard creates regex strings from wildcard.
-func createExcludesFromWildcard(inputExcludes []string) ([]*regexp.Regexp, error) {
+// createRegexFromWildcard creates regex strings from wildcard.
+func createRegexFromWildcard(wildcards []string) ([]*regexp.Regexp, error) {
 	var result []*regexp.Regexp
-	for _, input := range inputExcludes {
+	for _, input := range wildcards {
 		if input != "" {
 			regexVersion := wildCardToRegexp(input)
 			regexpCompiled, err := regexp.Compile(regexVersion)
@@ -30,8 +85,10 @@ func createExcludesFromWildcard(inputExcludes []string) ([]*regexp.Regexp, error
 	return result, nil
 }
 
-// isURLExcluded checks whether given urlPath matches any of the exclude patterns.
-func isURLExcluded(excludePatterns []*regexp.Regexp, urlPath, sourcePrefix string) bool {
+// isURLExcluded checks whether given urlPath matches any of the exclude
+// patterns. Include patterns take precedence: a urlPath matching any of them
+// is never excluded.
+func isURLExcluded(excludePatterns, includePatterns []*regexp.Regexp, urlPath, sourcePrefix string) bool {
 	if len(excludePatterns) == 0 {
 		return false
 	}
@@ -39,8 +96,14 @@ func isURLExcluded(excludePatterns []*regexp.Regexp, urlPath, sourcePrefix strin
 		sourcePrefix += "/"
 	}
 	sourcePrefix = filepath.ToSlash(sourcePrefix)
-	for _, excludePattern := range excludePatterns {
-		if excludePattern.MatchString(strings.TrimPrefix(urlPath, sourcePrefix)) {
+	relativePath := strings.TrimPrefix(urlPath, sourcePrefix)
+
+	return matchesAny(excludePatterns, relativePath) && !matchesAny(includePatterns, relativePath)
+}
+
+func matchesAny(patterns []*regexp.Regexp, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(path) {
 			return true
 		}
 	}
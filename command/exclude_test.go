@@ -1,6 +1,13 @@
 package command
 
-import "testing"
+import (
+	"flag"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/urfave/cli/v2"
+	"gotest.tools/v3/fs"
+)
 
 func Test_wildCardToRegexp(t *testing.T) {
 	t.Parallel()
@@ -33,3 +40,194 @@ func Test_wildCardToRegexp(t *testing.T) {
 		})
 	}
 }
+
+func TestIsURLExcluded(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		excludes []string
+		includes []string
+		urlPath  string
+		prefix   string
+		wanted   bool
+	}{
+		{
+			name:    "no patterns",
+			urlPath: "dir/file.txt",
+			prefix:  "dir",
+			wanted:  false,
+		},
+		{
+			name:     "excluded",
+			excludes: []string{"*.txt"},
+			urlPath:  "dir/file.txt",
+			prefix:   "dir",
+			wanted:   true,
+		},
+		{
+			name:     "not matching exclude",
+			excludes: []string{"*.gz"},
+			urlPath:  "dir/file.txt",
+			prefix:   "dir",
+			wanted:   false,
+		},
+		{
+			name:     "include without exclude has no effect",
+			includes: []string{"*.gz"},
+			urlPath:  "dir/file.txt",
+			prefix:   "dir",
+			wanted:   false,
+		},
+		{
+			name:     "include overrides exclude",
+			excludes: []string{"*"},
+			includes: []string{"*.txt"},
+			urlPath:  "dir/file.txt",
+			prefix:   "dir",
+			wanted:   false,
+		},
+		{
+			name:     "excluded when not matching include",
+			excludes: []string{"*"},
+			includes: []string{"*.txt"},
+			urlPath:  "dir/file.gz",
+			prefix:   "dir",
+			wanted:   true,
+		},
+		{
+			name:     "overlapping patterns with embedded slashes",
+			excludes: []string{"logs/*"},
+			includes: []string{"logs/2020/*.gz"},
+			urlPath:  "bucket/logs/2020/01/access.gz",
+			prefix:   "bucket/",
+			wanted:   false,
+		},
+		{
+			name:     "overlapping patterns with embedded slashes, not included",
+			excludes: []string{"logs/*"},
+			includes: []string{"logs/2020/*.gz"},
+			urlPath:  "bucket/logs/2021/01/access.gz",
+			prefix:   "bucket/",
+			wanted:   true,
+		},
+		{
+			name:     "patterns are relative to the source prefix",
+			excludes: []string{"logs/*"},
+			urlPath:  "bucket/archive/logs/access.gz",
+			prefix:   "bucket/",
+			wanted:   false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			excludePatterns, err := createRegexFromWildcard(tt.excludes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			includePatterns, err := createRegexFromWildcard(tt.includes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := isURLExcluded(excludePatterns, includePatterns, tt.urlPath, tt.prefix); got != tt.wanted {
+				t.Errorf("isURLExcluded() = %v, want %v", got, tt.wanted)
+			}
+		})
+	}
+}
+
+func TestReadPatternFile(t *testing.T) {
+	t.Parallel()
+
+	const content = `# logs are archived elsewhere
+*.log
+
+  tmp/*  
+#*.gz
+*.bak
+`
+
+	workdir := fs.NewDir(t, "patterns", fs.WithFile("exclude.txt", content))
+	defer workdir.Remove()
+
+	got, err := readPatternFile(workdir.Join("exclude.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"*.log", "tmp/*", "*.bak"}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+
+	if _, err := readPatternFile(workdir.Join("missing.txt")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	t.Parallel()
+
+	workdir := fs.NewDir(
+		t,
+		"patterns",
+		fs.WithFile("exclude1.txt", "*.log\n"),
+		fs.WithFile("exclude2.txt", "*.tmp\n"),
+		fs.WithFile("include.txt", "# keep the audit logs\naudit/*.log\n"),
+	)
+	defer workdir.Remove()
+
+	set := flag.NewFlagSet("rm", flag.ContinueOnError)
+	for _, f := range NewDeleteCommand().Flags {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := set.Parse([]string{
+		"--exclude-from", workdir.Join("exclude1.txt"),
+		"--exclude", "*.bak",
+		"--exclude-from", workdir.Join("exclude2.txt"),
+		"--include-from", workdir.Join("include.txt"),
+		"s3://bucket/*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exclude, include, err := parseFilters(cli.NewContext(app, set, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// inline patterns come first, then the files in the given order.
+	if diff := cmp.Diff([]string{"*.bak", "*.log", "*.tmp"}, exclude); diff != "" {
+		t.Errorf("exclude (-want +got):\n%v", diff)
+	}
+	if diff := cmp.Diff([]string{"audit/*.log"}, include); diff != "" {
+		t.Errorf("include (-want +got):\n%v", diff)
+	}
+
+	excludePatterns, err := createRegexFromWildcard(exclude)
+	if err != nil {
+		t.Fatal(err)
+	}
+	includePatterns, err := createRegexFromWildcard(include)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, excluded := range map[string]bool{
+		"bucket/backup.bak":      true,
+		"bucket/access.log":      true,
+		"bucket/audit/login.log": false,
+		"bucket/data.csv":        false,
+	} {
+		if got := isURLExcluded(excludePatterns, includePatterns, path, "bucket"); got != excluded {
+			t.Errorf("isURLExcluded(%q) = %v, want %v", path, got, excluded)
+		}
+	}
+}
@@ -2,13 +2,305 @@ package command
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
 
 	"github.com/peak/s5cmd/atomic"
+	errorpkg "github.com/peak/s5cmd/error"
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
 	"github.com/peak/s5cmd/storage"
 	"github.com/peak/s5cmd/storage/url"
+	"github.com/peak/s5cmd/strutil"
 )
 
+var expandHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] source
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Print the objects that a wildcard matches
+		 > s5cmd {{.HelpName}} "s3://bucket/prefix/*.log"
+
+	2. Print the objects that "cp" would copy, excluding the ones with .tmp extension
+		 > s5cmd {{.HelpName}} --exclude "*.tmp" "s3://bucket/prefix/*"
+
+	3. Print the objects under a prefix, but not the ones under "prefix-other"
+		 > s5cmd {{.HelpName}} --recursive s3://bucket/prefix
+
+	4. Print the files in a directory that would be uploaded
+		 > s5cmd {{.HelpName}} dir/
+`
+
+func NewExpandCommand() *cli.Command {
+	return &cli.Command{
+		Name:     "expand",
+		HelpName: "expand",
+		Usage:    "print the objects that a source argument of cp matches",
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "raw",
+				Usage: "disable the wildcard operations, useful with filenames that contains glob characters.",
+			},
+			&cli.BoolFlag{
+				Name:    "recursive",
+				Aliases: []string{"r"},
+				Usage:   "expand to all objects under the given buckets and prefixes, e.g. expand -r s3://bucket/prefix",
+			},
+			&cli.BoolFlag{
+				Name:  "no-follow-symlinks",
+				Usage: "do not follow symbolic links",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "exclude objects with given pattern",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern even if they match an exclude pattern",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude-from",
+				Usage: "exclude objects with the patterns in given file, one pattern per line",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include-from",
+				Usage: "include objects with the patterns in given file, one pattern per line, even if they match an exclude pattern",
+			},
+		}, objectFilterFlags()...),
+		CustomHelpTemplate: expandHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateExpandCommand(c)
+			if err != nil {
+				printError(givenCommand(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			// filter files and filters are already validated.
+			exclude, include, _ := parseFilters(c)
+			filter, _ := parseObjectFilter(c, time.Now())
+
+			return Expand{
+				src:         recursiveSources(c.Bool("recursive"), c.Args().First())[0],
+				op:          c.Command.Name,
+				fullCommand: givenCommand(c),
+
+				// flags
+				raw:            c.Bool("raw"),
+				followSymlinks: !c.Bool("no-follow-symlinks"),
+				exclude:        exclude,
+				include:        include,
+				filter:         filter,
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+}
+
+// Expand holds expand operation flags and states.
+type Expand struct {
+	src         string
+	op          string
+	fullCommand string
+
+	// flag options
+	raw            bool
+	followSymlinks bool
+	exclude        []string
+	include        []string
+	filter         objectFilter
+
+	// storage options
+	storageOpts storage.Options
+}
+
+// Run prints the objects which the source matches. They are expanded and
+// selected the same way as the source of cp.
+func (e Expand) Run(ctx context.Context) error {
+	srcurl, err := url.New(e.src, url.WithRaw(e.raw))
+	if err != nil {
+		printError(e.fullCommand, e.op, err)
+		return err
+	}
+
+	client, err := storage.NewClient(ctx, srcurl, e.storageOpts)
+	if err != nil {
+		printError(e.fullCommand, e.op, err)
+		return err
+	}
+
+	objch, err := expandSource(ctx, client, e.followSymlinks, srcurl)
+	if err != nil {
+		printError(e.fullCommand, e.op, err)
+		return err
+	}
+
+	selector, err := newSourceSelector(ctx, client, srcurl, e.exclude, e.include, e.filter)
+	if err != nil {
+		printError(e.fullCommand, e.op, err)
+		return err
+	}
+
+	var merror error
+	for object := range objch {
+		if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
+			continue
+		}
+
+		if err := object.Err; err != nil {
+			merror = multierror.Append(merror, err)
+			printError(e.fullCommand, e.op, err)
+			continue
+		}
+
+		if !selector.match(object) {
+			continue
+		}
+
+		log.Info(ExpandMessage{Source: object.URL})
+	}
+
+	return merror
+}
+
+// ExpandMessage is an object which a source argument matches.
+type ExpandMessage struct {
+	Source *url.URL `json:"source"`
+}
+
+// String returns the string representation of ExpandMessage.
+func (m ExpandMessage) String() string {
+	return m.Source.String()
+}
+
+// JSON returns the JSON representation of ExpandMessage.
+func (m ExpandMessage) JSON() string {
+	return strutil.JSON(m)
+}
+
+func validateExpandCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only 1 argument")
+	}
+
+	if c.Bool("recursive") && c.Bool("raw") {
+		return fmt.Errorf("--recursive can not be used with --raw")
+	}
+
+	src := recursiveSources(c.Bool("recursive"), c.Args().First())[0]
+	srcurl, err := url.New(src, url.WithRaw(c.Bool("raw")))
+	if err != nil {
+		return err
+	}
+
+	// as in cp, the objects of prefixes are expanded by wildcards.
+	if srcurl.IsBucket() || srcurl.IsPrefix() {
+		return fmt.Errorf("source argument must contain wildcard character")
+	}
+
+	if _, _, err := parseFilters(c); err != nil {
+		return err
+	}
+
+	return validateObjectFilterSources(c, srcurl)
+}
+
+// sourceSelector selects the objects expanded from the source of a transfer
+// which are operated on, by the patterns of --exclude and --include flags,
+// and by the object filters if the source is a batch.
+type sourceSelector struct {
+	srcurl *url.URL
+	// isBatch is set if the source is a wildcard or a local directory.
+	isBatch bool
+	exclude []*regexp.Regexp
+	include []*regexp.Regexp
+	filter  objectFilter
+}
+
+// newSourceSelector returns the selector of the objects expanded from
+// srcurl.
+func newSourceSelector(
+	ctx context.Context,
+	client storage.Storage,
+	srcurl *url.URL,
+	exclude, include []string,
+	filter objectFilter,
+) (sourceSelector, error) {
+	isBatch := srcurl.IsWildcard()
+	if !isBatch && !srcurl.IsRemote() {
+		obj, _ := client.Stat(ctx, srcurl)
+		isBatch = obj != nil && obj.Type.IsDir()
+	}
+
+	excludePatterns, err := createRegexFromWildcard(exclude)
+	if err != nil {
+		return sourceSelector{}, err
+	}
+
+	includePatterns, err := createRegexFromWildcard(include)
+	if err != nil {
+		return sourceSelector{}, err
+	}
+
+	return sourceSelector{
+		srcurl:  srcurl,
+		isBatch: isBatch,
+		exclude: excludePatterns,
+		include: includePatterns,
+		filter:  filter,
+	}, nil
+}
+
+// match reports whether the object is operated on.
+func (s sourceSelector) match(object *storage.Object) bool {
+	if isURLExcluded(s.exclude, s.include, object.URL.Path, s.srcurl.Prefix) {
+		return false
+	}
+	return !s.isBatch || s.filter.match(object)
+}
+
+// recursiveSources returns the sources of a command given with --recursive.
+// A remote bucket or prefix without a wildcard selects all objects under it,
+// e.g. s3://bucket/prefix selects s3://bucket/prefix/*. The wildcard is
+// appended after a separator, so that the objects of a sibling prefix, such
+// as s3://bucket/prefix-other, are not selected. Local directories are walked
+// without --recursive, so the other sources are returned as they are.
+func recursiveSources(recursive bool, srcs ...string) []string {
+	if !recursive {
+		return srcs
+	}
+
+	result := make([]string, 0, len(srcs))
+	for _, src := range srcs {
+		srcurl, err := url.New(src)
+		if err != nil || !srcurl.IsRemote() || srcurl.IsWildcard() || srcurl.VersionID != "" {
+			result = append(result, src)
+			continue
+		}
+
+		if !strings.HasSuffix(src, "/") {
+			src += "/"
+		}
+		result = append(result, src+"*")
+	}
+	return result
+}
+
 // expandSource returns the full list of objects from the given src argument.
 // If src is an expandable URL, such as directory, prefix or a glob, all
 // objects are returned by walking the source.
@@ -18,6 +310,16 @@ func expandSource(
 	followSymlinks bool,
 	srcurl *url.URL,
 ) (<-chan *storage.Object, error) {
+	expansions, err := srcurl.ExpandBraces()
+	if err != nil {
+		return nil, err
+	}
+	if expansions != nil {
+		return listExpansions(ctx, srcurl, expansions, func(expansion *url.URL) (<-chan *storage.Object, error) {
+			return expandSource(ctx, client, followSymlinks, expansion)
+		}), nil
+	}
+
 	var isDir bool
 	// if the source is local, we send a Stat call to know if  we have
 	// directory or file to walk. For remote storage, we don't want to send
@@ -90,3 +392,79 @@ func expandSources(
 
 	return ch
 }
+
+// listURL lists the objects of srcurl like storage.List does. If srcurl has
+// brace groups, the objects of its expansions are listed instead.
+func listURL(
+	ctx context.Context,
+	client storage.Storage,
+	followSymlinks bool,
+	srcurl *url.URL,
+) <-chan *storage.Object {
+	expansions, err := srcurl.ExpandBraces()
+	if err != nil {
+		ch := make(chan *storage.Object, 1)
+		ch <- &storage.Object{Err: err}
+		close(ch)
+		return ch
+	}
+	if expansions == nil {
+		return client.List(ctx, srcurl, followSymlinks)
+	}
+
+	return listExpansions(ctx, srcurl, expansions, func(expansion *url.URL) (<-chan *storage.Object, error) {
+		return client.List(ctx, expansion, followSymlinks), nil
+	})
+}
+
+// listExpansions lists the brace expansions of srcurl one after another with
+// the list function. The objects matched by more than one expansion are sent
+// once and their relative paths are calculated against srcurl, so that the
+// paths after the braces are kept. ErrNoObjectFound is sent only if none of
+// the expansions has an object or an error.
+func listExpansions(
+	ctx context.Context,
+	srcurl *url.URL,
+	expansions []*url.URL,
+	list func(*url.URL) (<-chan *storage.Object, error),
+) <-chan *storage.Object {
+	ch := make(chan *storage.Object)
+
+	go func() {
+		defer close(ch)
+
+		var found bool
+		seen := map[string]bool{}
+		for _, expansion := range expansions {
+			objch, err := list(expansion)
+			if err != nil {
+				ch <- &storage.Object{Err: err}
+				found = true
+				continue
+			}
+
+			for object := range objch {
+				if object.Err == storage.ErrNoObjectFound {
+					continue
+				}
+
+				if object.Err == nil {
+					key := object.URL.String()
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					srcurl.Relativize(object.URL)
+				}
+				ch <- object
+				found = true
+			}
+		}
+
+		if !found {
+			ch <- &storage.Object{Err: storage.ErrNoObjectFound}
+		}
+	}()
+
+	return ch
+}
@@ -326,6 +326,40 @@ func TestExpandSource_Do_Not_Follow_Symlinks(t *testing.T) {
 	assert.Equal(t, []string{workdirJoin}, expected)
 }
 
+func TestRecursiveSources(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		src      string
+		expected string
+	}{
+		{src: "s3://bucket", expected: "s3://bucket/*"},
+		{src: "s3://bucket/", expected: "s3://bucket/*"},
+		{src: "s3://bucket/prefix", expected: "s3://bucket/prefix/*"},
+		{src: "s3://bucket/prefix/", expected: "s3://bucket/prefix/*"},
+		{src: "s3://bucket/prefix/*.txt", expected: "s3://bucket/prefix/*.txt"},
+		{src: "s3://bucket/key?versionId=1", expected: "s3://bucket/key?versionId=1"},
+		{src: "dir", expected: "dir"},
+		{src: "dir/", expected: "dir/"},
+		{src: "-", expected: "-"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, []string{tc.expected}, recursiveSources(true, tc.src), tc.src)
+		assert.Equal(t, []string{tc.src}, recursiveSources(false, tc.src), tc.src)
+	}
+
+	// the objects of a sibling prefix are not matched.
+	srcurl, err := url.New(recursiveSources(true, "s3://bucket/prefix")[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.True(t, srcurl.Match("prefix/file.txt"))
+	assert.True(t, srcurl.Match("prefix/dir/file.txt"))
+	assert.False(t, srcurl.Match("prefix-sibling/file.txt"))
+	assert.False(t, srcurl.Match("prefix"))
+}
+
 func keys(urls map[string][]*storage.Object) []string {
 	var urlKeys []string
 	for key := range urls {
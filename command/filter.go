@@ -0,0 +1,242 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+)
+
+// objectFilterFlags are the flags of the commands which filter the listed
+// objects by their sizes and modification times.
+func objectFilterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "min-size",
+			Usage: "only process the objects of at least this size, e.g. 100, 10KB, 1.5GB",
+		},
+		&cli.StringFlag{
+			Name:  "max-size",
+			Usage: "only process the objects of at most this size, e.g. 100, 10KB, 1.5GB",
+		},
+		&cli.StringFlag{
+			Name:  "newer-than",
+			Usage: "only process the objects modified after this time, e.g. 2023-01-02, 2023-01-02T15:04:05Z, 7d, 12h",
+		},
+		&cli.StringFlag{
+			Name:  "older-than",
+			Usage: "only process the objects modified before this time, e.g. 2023-01-02, 2023-01-02T15:04:05Z, 7d, 12h",
+		},
+	}
+}
+
+// objectFilter selects the listed objects by their sizes and modification
+// times. The zero value selects all objects.
+type objectFilter struct {
+	minSize    int64
+	maxSize    int64
+	hasMaxSize bool
+	newerThan  time.Time
+	olderThan  time.Time
+}
+
+// parseObjectFilter parses the filter flags. Relative times, such as "7d",
+// are calculated back from now.
+func parseObjectFilter(c *cli.Context, now time.Time) (objectFilter, error) {
+	var (
+		filter objectFilter
+		err    error
+	)
+
+	if value := c.String("min-size"); value != "" {
+		filter.minSize, err = parseSize(value)
+		if err != nil {
+			return objectFilter{}, fmt.Errorf("invalid --min-size value %q: %v", value, err)
+		}
+	}
+
+	if value := c.String("max-size"); value != "" {
+		filter.maxSize, err = parseSize(value)
+		if err != nil {
+			return objectFilter{}, fmt.Errorf("invalid --max-size value %q: %v", value, err)
+		}
+		filter.hasMaxSize = true
+	}
+
+	if filter.hasMaxSize && filter.minSize > filter.maxSize {
+		return objectFilter{}, fmt.Errorf("--min-size can not be greater than --max-size")
+	}
+
+	if value := c.String("newer-than"); value != "" {
+		filter.newerThan, err = parseTime(value, now)
+		if err != nil {
+			return objectFilter{}, fmt.Errorf("invalid --newer-than value %q: %v", value, err)
+		}
+	}
+
+	if value := c.String("older-than"); value != "" {
+		filter.olderThan, err = parseTime(value, now)
+		if err != nil {
+			return objectFilter{}, fmt.Errorf("invalid --older-than value %q: %v", value, err)
+		}
+	}
+
+	return filter, nil
+}
+
+// isSet reports whether the filter skips any objects.
+func (f objectFilter) isSet() bool {
+	return f != objectFilter{}
+}
+
+// match reports whether the object is selected by the filter. Directories
+// have no size or modification time, so they are not selected by a filter
+// which is set.
+func (f objectFilter) match(object *storage.Object) bool {
+	if !f.isSet() {
+		return true
+	}
+
+	if object.Type.IsDir() {
+		return false
+	}
+
+	if object.Size < f.minSize {
+		return false
+	}
+
+	if f.hasMaxSize && object.Size > f.maxSize {
+		return false
+	}
+
+	if f.newerThan.IsZero() && f.olderThan.IsZero() {
+		return true
+	}
+
+	if object.ModTime == nil {
+		return false
+	}
+
+	if !f.newerThan.IsZero() && !object.ModTime.After(f.newerThan) {
+		return false
+	}
+
+	if !f.olderThan.IsZero() && !object.ModTime.Before(f.olderThan) {
+		return false
+	}
+
+	return true
+}
+
+// validateObjectFilterSources returns an error if the filter flags are given
+// for a source which is not listed. The objects given by their names are
+// not listed, so their sizes and modification times are not known.
+func validateObjectFilterSources(c *cli.Context, srcurls ...*url.URL) error {
+	filter, err := parseObjectFilter(c, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !filter.isSet() {
+		return nil
+	}
+
+	for _, srcurl := range srcurls {
+		if srcurl.IsWildcard() {
+			continue
+		}
+
+		if !srcurl.IsRemote() {
+			fi, err := os.Stat(srcurl.Absolute())
+			if err == nil && fi.IsDir() {
+				continue
+			}
+		}
+
+		return fmt.Errorf("size and date filters can only be used with wildcard or directory sources")
+	}
+	return nil
+}
+
+var sizeUnits = [...]struct {
+	suffix string
+	size   float64
+}{
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"T", 1 << 40},
+}
+
+// parseSize parses a size in bytes with an optional unit, e.g. "100", "10K",
+// "10KB" or "1.5GiB". The units are powers of 1024, like the sizes printed
+// by the commands with --humanize.
+func parseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(value, "B")
+	value = strings.TrimSuffix(value, "I")
+
+	multiplier := float64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("must be a non-negative size, e.g. 100, 10KB, 1.5GB")
+	}
+	return int64(n * multiplier), nil
+}
+
+// parseDuration parses a duration in the format of time.ParseDuration, e.g.
+// "1h30m", or a number of days or weeks, e.g. "7d" or "2w".
+func parseDuration(s string) (time.Duration, error) {
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"w", 7 * 24 * time.Hour},
+	} {
+		if !strings.HasSuffix(s, unit.suffix) {
+			continue
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSuffix(s, unit.suffix), 64); err == nil {
+			return time.Duration(n * float64(unit.size)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseTime parses an absolute time, e.g. "2023-01-02" or
+// "2023-01-02T15:04:05Z", or a duration back from now, e.g. "7d", "2w" or
+// "1h30m". Absolute times without a time zone are in UTC.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := parseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("must be a date, e.g. 2023-01-02, or a duration, e.g. 7d")
+}
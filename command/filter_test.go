@@ -0,0 +1,196 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/storage"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		value     string
+		expected  int64
+		expectErr bool
+	}{
+		{value: "100", expected: 100},
+		{value: "100B", expected: 100},
+		{value: "10K", expected: 10 << 10},
+		{value: "10KB", expected: 10 << 10},
+		{value: "10kb", expected: 10 << 10},
+		{value: "10MB", expected: 10 << 20},
+		{value: "1.5GiB", expected: 3 << 29},
+		{value: "1TB", expected: 1 << 40},
+		{value: "", expectErr: true},
+		{value: "GB", expectErr: true},
+		{value: "-1KB", expectErr: true},
+		{value: "ten", expectErr: true},
+		{value: "NaN", expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseSize(tc.value)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		value     string
+		expected  time.Time
+		expectErr bool
+	}{
+		{value: "7d", expected: now.AddDate(0, 0, -7)},
+		{value: "1.5d", expected: now.Add(-36 * time.Hour)},
+		{value: "2w", expected: now.AddDate(0, 0, -14)},
+		{value: "12h", expected: now.Add(-12 * time.Hour)},
+		{value: "1h30m", expected: now.Add(-90 * time.Minute)},
+		{value: "2023-01-02", expected: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{value: "2023-01-02T15:04:05", expected: time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{value: "2023-01-02T15:04:05+03:00", expected: time.Date(2023, 1, 2, 12, 4, 5, 0, time.UTC)},
+		{value: "", expectErr: true},
+		{value: "yesterday", expectErr: true},
+		{value: "d", expectErr: true},
+		{value: "2023-13-01", expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseTime(tc.value, now)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestObjectFilterMatch(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	lastWeek := now.AddDate(0, 0, -7)
+	lastMonth := now.AddDate(0, -1, 0)
+
+	small := &storage.Object{Size: 10, ModTime: &now}
+	large := &storage.Object{Size: 1 << 30, ModTime: &lastWeek}
+	old := &storage.Object{Size: 100, ModTime: &lastMonth}
+	unknown := &storage.Object{Size: 100}
+
+	testcases := []struct {
+		name     string
+		filter   objectFilter
+		expected map[*storage.Object]bool
+	}{
+		{
+			name:   "no filter",
+			filter: objectFilter{},
+			expected: map[*storage.Object]bool{
+				small: true, large: true, old: true, unknown: true,
+			},
+		},
+		{
+			name:   "min size",
+			filter: objectFilter{minSize: 100},
+			expected: map[*storage.Object]bool{
+				small: false, large: true, old: true, unknown: true,
+			},
+		},
+		{
+			name:   "max size",
+			filter: objectFilter{maxSize: 100, hasMaxSize: true},
+			expected: map[*storage.Object]bool{
+				small: true, large: false, old: true, unknown: true,
+			},
+		},
+		{
+			name:   "empty objects",
+			filter: objectFilter{maxSize: 0, hasMaxSize: true},
+			expected: map[*storage.Object]bool{
+				small: false, large: false, old: false, unknown: false,
+			},
+		},
+		{
+			name:   "newer than",
+			filter: objectFilter{newerThan: now.AddDate(0, 0, -10)},
+			expected: map[*storage.Object]bool{
+				small: true, large: true, old: false, unknown: false,
+			},
+		},
+		{
+			name:   "older than",
+			filter: objectFilter{olderThan: now.AddDate(0, 0, -1)},
+			expected: map[*storage.Object]bool{
+				small: false, large: true, old: true, unknown: false,
+			},
+		},
+		{
+			name: "size and date range",
+			filter: objectFilter{
+				minSize:   50,
+				newerThan: now.AddDate(0, 0, -10),
+				olderThan: now.AddDate(0, 0, -1),
+			},
+			expected: map[*storage.Object]bool{
+				small: false, large: true, old: false, unknown: false,
+			},
+		},
+		{
+			name: "size range",
+			filter: objectFilter{
+				minSize:    50,
+				maxSize:    1000,
+				hasMaxSize: true,
+			},
+			expected: map[*storage.Object]bool{
+				small: false, large: false, old: true, unknown: true,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			for object, expected := range tc.expected {
+				if got := tc.filter.match(object); got != expected {
+					t.Errorf("object of size %v modified at %v: expected %v, got %v", object.Size, object.ModTime, expected, got)
+				}
+			}
+		})
+	}
+}
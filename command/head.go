@@ -0,0 +1,202 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+	"github.com/peak/s5cmd/strutil"
+)
+
+var headHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] source
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Print the metadata of a remote object
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/object
+
+	2. Print the metadata of a remote object in JSON format
+		 > s5cmd --json {{.HelpName}} s3://bucket/prefix/object
+`
+
+func NewHeadCommand() *cli.Command {
+	return &cli.Command{
+		Name:               "head",
+		HelpName:           "head",
+		Usage:              "print remote object metadata",
+		CustomHelpTemplate: headHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateHeadCommand(c)
+			if err != nil {
+				printError(givenCommand(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			src, err := url.New(c.Args().Get(0))
+			op := c.Command.Name
+			fullCommand := givenCommand(c)
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			return Head{
+				src:         src,
+				op:          op,
+				fullCommand: fullCommand,
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+}
+
+// Head holds head operation flags and states.
+type Head struct {
+	src         *url.URL
+	op          string
+	fullCommand string
+
+	storageOpts storage.Options
+}
+
+// Run prints the metadata of the given source.
+func (h Head) Run(ctx context.Context) error {
+	client, err := storage.NewRemoteClient(ctx, h.src, h.storageOpts)
+	if err != nil {
+		printError(h.fullCommand, h.op, err)
+		return err
+	}
+
+	obj, err := client.Stat(ctx, h.src)
+	if err != nil {
+		printError(h.fullCommand, h.op, err)
+		return err
+	}
+
+	log.Info(HeadMessage{
+		Key:                h.src,
+		Size:               obj.Size,
+		ModTime:            *obj.ModTime,
+		Etag:               obj.Etag,
+		StorageClass:       obj.StorageClass,
+		ContentType:        obj.Metadata.ContentType(),
+		ContentDisposition: obj.Metadata.ContentDisposition(),
+		ContentLanguage:    obj.Metadata.ContentLanguage(),
+		CacheControl:       obj.Metadata.CacheControl(),
+		Expires:            obj.Metadata.Expires(),
+		SSE:                obj.Metadata.SSE(),
+		SSEKeyID:           obj.Metadata.SSEKeyID(),
+		Metadata:           obj.Metadata.UserDefined(),
+	})
+	return nil
+}
+
+// HeadMessage is a structure for logging head results.
+type HeadMessage struct {
+	Key                *url.URL             `json:"key"`
+	Size               int64                `json:"size"`
+	ModTime            time.Time            `json:"last_modified"`
+	Etag               string               `json:"etag,omitempty"`
+	StorageClass       storage.StorageClass `json:"storage_class,omitempty"`
+	ContentType        string               `json:"content_type,omitempty"`
+	ContentDisposition string               `json:"content_disposition,omitempty"`
+	ContentLanguage    string               `json:"content_language,omitempty"`
+	CacheControl       string               `json:"cache_control,omitempty"`
+	Expires            string               `json:"expires,omitempty"`
+	SSE                string               `json:"sse,omitempty"`
+	SSEKeyID           string               `json:"sse_kms_key_id,omitempty"`
+	Metadata           map[string]string    `json:"metadata,omitempty"`
+}
+
+// String returns the string representation of HeadMessage. Empty fields are
+// omitted.
+func (h HeadMessage) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 8, 1, ' ', 0)
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"Key", h.Key.String()},
+		{"Size", fmt.Sprintf("%d", h.Size)},
+		{"Last Modified", h.ModTime.Format(dateFormat)},
+		{"ETag", h.Etag},
+		{"Storage Class", string(h.StorageClass)},
+		{"Content Type", h.ContentType},
+		{"Content Disposition", h.ContentDisposition},
+		{"Content Language", h.ContentLanguage},
+		{"Cache Control", h.CacheControl},
+		{"Expires", h.Expires},
+		{"Encryption", h.SSE},
+		{"KMS Key ID", h.SSEKeyID},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", field.name, field.value)
+		}
+	}
+
+	keys := make([]string, 0, len(h.Metadata))
+	for key := range h.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "Metadata:\t%s=%s\n", key, h.Metadata[key])
+	}
+
+	w.Flush()
+
+	// the logger adds the trailing newline.
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// JSON returns the JSON representation of HeadMessage.
+func (h HeadMessage) JSON() string {
+	return strutil.JSON(h)
+}
+
+func validateHeadCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only one argument")
+	}
+
+	src, err := url.New(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	if !src.IsRemote() {
+		return fmt.Errorf("source must be a remote object")
+	}
+
+	if src.IsBucket() || src.IsPrefix() {
+		return fmt.Errorf("remote source must be an object")
+	}
+
+	if src.IsWildcard() {
+		return fmt.Errorf("remote source %q can not contain glob characters", src)
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package command
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -42,6 +44,15 @@ Examples:
 
 	6. List all objects in a bucket but exclude the ones with prefix abc
 		 > s5cmd {{.HelpName}} --exclude "abc*" s3://bucket/*
+
+	7. List all objects in a bucket, largest first
+		 > s5cmd {{.HelpName}} --sort size --reverse s3://bucket/*
+
+	8. List the first 100 objects in a bucket after the key "prefix/object"
+		 > s5cmd {{.HelpName}} --max-keys 100 --start-after prefix/object s3://bucket/*
+
+	9. List objects and prefixes in a bucket, grouping the keys by "-"
+		 > s5cmd {{.HelpName}} --delimiter - s3://bucket/
 `
 
 func NewListCommand() *cli.Command {
@@ -50,7 +61,7 @@ func NewListCommand() *cli.Command {
 		HelpName:           "ls",
 		Usage:              "list buckets and objects",
 		CustomHelpTemplate: listHelpTemplate,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:    "etag",
 				Aliases: []string{"e"},
@@ -70,7 +81,39 @@ func NewListCommand() *cli.Command {
 				Name:  "exclude",
 				Usage: "exclude objects with given pattern",
 			},
-		},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern even if they match an exclude pattern",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude-from",
+				Usage: "exclude objects with the patterns in given file, one pattern per line",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include-from",
+				Usage: "include objects with the patterns in given file, one pattern per line, even if they match an exclude pattern",
+			},
+			&cli.StringFlag{
+				Name:  "sort",
+				Usage: "sort the objects by (name, size, date); the objects are kept in memory and printed after the listing is finished",
+			},
+			&cli.BoolFlag{
+				Name:  "reverse",
+				Usage: "reverse the order of the sorted objects",
+			},
+			&cli.Int64Flag{
+				Name:  "max-keys",
+				Usage: "stop listing after the given number of objects and prefixes",
+			},
+			&cli.StringFlag{
+				Name:  "start-after",
+				Usage: "list the keys after the given key",
+			},
+			&cli.StringFlag{
+				Name:  "delimiter",
+				Usage: "group the keys up to the given delimiter as prefixes, i.e. directories",
+			},
+		}, objectFilterFlags()...),
 		Before: func(c *cli.Context) error {
 			err := validateLSCommand(c)
 			if err != nil {
@@ -88,6 +131,10 @@ func NewListCommand() *cli.Command {
 				return err
 			}
 
+			// filter files and filters are already validated.
+			exclude, include, _ := parseFilters(c)
+			filter, _ := parseObjectFilter(c, time.Now())
+
 			return List{
 				src:         c.Args().First(),
 				op:          c.Command.Name,
@@ -96,7 +143,14 @@ func NewListCommand() *cli.Command {
 				showEtag:         c.Bool("etag"),
 				humanize:         c.Bool("humanize"),
 				showStorageClass: c.Bool("storage-class"),
-				exclude:          c.StringSlice("exclude"),
+				exclude:          exclude,
+				include:          include,
+				filter:           filter,
+				sortBy:           c.String("sort"),
+				reverse:          c.Bool("reverse"),
+				maxKeys:          c.Int64("max-keys"),
+				startAfter:       c.String("start-after"),
+				delimiter:        c.String("delimiter"),
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -115,6 +169,13 @@ type List struct {
 	humanize         bool
 	showStorageClass bool
 	exclude          []string
+	include          []string
+	filter           objectFilter
+	sortBy           string
+	reverse          bool
+	maxKeys          int64
+	startAfter       string
+	delimiter        string
 
 	storageOpts storage.Options
 }
@@ -148,6 +209,12 @@ func (l List) Run(ctx context.Context) error {
 		return err
 	}
 
+	srcurl.MaxKeys = l.maxKeys
+	srcurl.StartAfter = l.startAfter
+	if l.delimiter != "" {
+		srcurl.Delimiter = l.delimiter
+	}
+
 	client, err := storage.NewClient(ctx, srcurl, l.storageOpts)
 	if err != nil {
 		printError(l.fullCommand, l.op, err)
@@ -156,13 +223,23 @@ func (l List) Run(ctx context.Context) error {
 
 	var merror error
 
-	excludePatterns, err := createExcludesFromWildcard(l.exclude)
+	excludePatterns, err := createRegexFromWildcard(l.exclude)
 	if err != nil {
 		printError(l.fullCommand, l.op, err)
 		return err
 	}
 
-	for object := range client.List(ctx, srcurl, false) {
+	includePatterns, err := createRegexFromWildcard(l.include)
+	if err != nil {
+		printError(l.fullCommand, l.op, err)
+		return err
+	}
+
+	// the objects are buffered to be sorted, otherwise they are printed as
+	// they are listed.
+	var objects []*storage.Object
+
+	for object := range listURL(ctx, client, false, srcurl) {
 		if errorpkg.IsCancelation(object.Err) {
 			continue
 		}
@@ -173,23 +250,92 @@ func (l List) Run(ctx context.Context) error {
 			continue
 		}
 
-		if isURLExcluded(excludePatterns, object.URL.Path, srcurl.Prefix) {
+		if isURLExcluded(excludePatterns, includePatterns, object.URL.Path, srcurl.Prefix) {
+			continue
+		}
+
+		if !l.filter.match(object) {
 			continue
 		}
 
-		msg := ListMessage{
-			Object:           object,
-			showEtag:         l.showEtag,
-			showHumanized:    l.humanize,
-			showStorageClass: l.showStorageClass,
+		if l.sortBy != "" {
+			objects = append(objects, object)
+			continue
 		}
 
-		log.Info(msg)
+		l.print(object)
+	}
+
+	sortObjects(objects, l.sortBy, l.reverse)
+	for _, object := range objects {
+		l.print(object)
 	}
 
 	return merror
 }
 
+func (l List) print(object *storage.Object) {
+	msg := ListMessage{
+		Object:           object,
+		showEtag:         l.showEtag,
+		showHumanized:    l.humanize,
+		showStorageClass: l.showStorageClass,
+	}
+
+	log.Info(msg)
+}
+
+const (
+	sortByName = "name"
+	sortBySize = "size"
+	sortByDate = "date"
+)
+
+// sortObjects sorts the objects by the given key. The objects with the same
+// size or date are sorted by name, so the order is the same on every run.
+// reverse reverses the whole order, including the ties.
+func sortObjects(objects []*storage.Object, by string, reverse bool) {
+	name := func(i, j int) bool {
+		return objects[i].URL.Relative() < objects[j].URL.Relative()
+	}
+
+	var less func(i, j int) bool
+	switch by {
+	case sortByName:
+		less = name
+	case sortBySize:
+		less = func(i, j int) bool {
+			if objects[i].Size != objects[j].Size {
+				return objects[i].Size < objects[j].Size
+			}
+			return name(i, j)
+		}
+	case sortByDate:
+		less = func(i, j int) bool {
+			// prefixes do not have a modification time.
+			var ti, tj time.Time
+			if objects[i].ModTime != nil {
+				ti = *objects[i].ModTime
+			}
+			if objects[j].ModTime != nil {
+				tj = *objects[j].ModTime
+			}
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return name(i, j)
+		}
+	default:
+		return
+	}
+
+	if reverse {
+		sort.Slice(objects, func(i, j int) bool { return less(j, i) })
+		return
+	}
+	sort.Slice(objects, less)
+}
+
 // ListMessage is a structure for logging ls results.
 type ListMessage struct {
 	Object *storage.Object `json:"object"`
@@ -260,5 +406,43 @@ func validateLSCommand(c *cli.Context) error {
 	if c.Args().Len() > 1 {
 		return fmt.Errorf("expected only 1 argument")
 	}
+
+	switch sortBy := c.String("sort"); sortBy {
+	case "", sortByName, sortBySize, sortByDate:
+	default:
+		return fmt.Errorf("invalid sort key %q", sortBy)
+	}
+
+	if c.Bool("reverse") && c.String("sort") == "" {
+		return fmt.Errorf("--reverse can only be used with --sort")
+	}
+
+	if _, _, err := parseFilters(c); err != nil {
+		return err
+	}
+
+	if _, err := parseObjectFilter(c, time.Now()); err != nil {
+		return err
+	}
+
+	if c.Int64("max-keys") < 0 {
+		return fmt.Errorf("max keys cannot be a negative value")
+	}
+
+	for _, flag := range []string{"max-keys", "start-after", "delimiter"} {
+		if !c.IsSet(flag) {
+			continue
+		}
+		if !c.Args().Present() {
+			return fmt.Errorf("--%v can not be used to list buckets", flag)
+		}
+		srcurl, err := url.New(c.Args().First())
+		if err != nil {
+			return err
+		}
+		if !srcurl.IsRemote() {
+			return fmt.Errorf("--%v can only be used for remote listings", flag)
+		}
+	}
 	return nil
 }
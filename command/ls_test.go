@@ -0,0 +1,101 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+)
+
+func TestSortObjects(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	before := now.Add(-time.Hour)
+
+	newObject := func(key string, size int64, modtime *time.Time) *storage.Object {
+		u, err := url.New("s3://bucket/" + key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return &storage.Object{URL: u, Size: size, ModTime: modtime}
+	}
+
+	testcases := []struct {
+		name     string
+		by       string
+		reverse  bool
+		expected []string
+	}{
+		{
+			name:     "name",
+			by:       sortByName,
+			expected: []string{"a", "b", "c", "d", "prefix/"},
+		},
+		{
+			name:     "name reverse",
+			by:       sortByName,
+			reverse:  true,
+			expected: []string{"prefix/", "d", "c", "b", "a"},
+		},
+		{
+			// a and c have the same size, prefix has no size.
+			name:     "size",
+			by:       sortBySize,
+			expected: []string{"prefix/", "b", "a", "c", "d"},
+		},
+		{
+			name:     "size reverse",
+			by:       sortBySize,
+			reverse:  true,
+			expected: []string{"d", "c", "a", "b", "prefix/"},
+		},
+		{
+			// b and d have the same modification time, prefix has none.
+			name:     "date",
+			by:       sortByDate,
+			expected: []string{"prefix/", "c", "b", "d", "a"},
+		},
+		{
+			name:     "date reverse",
+			by:       sortByDate,
+			reverse:  true,
+			expected: []string{"a", "d", "b", "c", "prefix/"},
+		},
+		{
+			name:     "not sorted",
+			by:       "",
+			expected: []string{"c", "a", "prefix/", "d", "b"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			later := now.Add(time.Hour)
+			objects := []*storage.Object{
+				newObject("c", 10, &before),
+				newObject("a", 10, &later),
+				newObject("prefix/", 0, nil),
+				newObject("d", 20, &now),
+				newObject("b", 5, &now),
+			}
+
+			sortObjects(objects, tc.by, tc.reverse)
+
+			var got []string
+			for _, object := range objects {
+				got = append(got, object.URL.Path)
+			}
+
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
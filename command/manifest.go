@@ -0,0 +1,169 @@
+package command
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+	"github.com/peak/s5cmd/strutil"
+)
+
+// manifest is the file given by --log-file. It is nil if the flag is not
+// given.
+var manifest *manifestWriter
+
+// manifestWriter writes a line for each successful operation, separately from
+// the output of the commands. The lines are written by all workers, so they
+// are serialized by a mutex.
+type manifestWriter struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// ManifestEntry is a line of the manifest file.
+type ManifestEntry struct {
+	Operation   string    `json:"operation"`
+	Source      *url.URL  `json:"source"`
+	Destination *url.URL  `json:"destination,omitempty"`
+	Size        int64     `json:"size"`
+	Time        time.Time `json:"time"`
+}
+
+// openManifest opens the manifest file at path. The lines are appended to the
+// file if it exists.
+func openManifest(path string) (*manifestWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &manifestWriter{w: f}, nil
+}
+
+// record writes the operation of the message to the manifest. It does nothing
+// if the manifest is nil. The size is known only if the object of the message
+// is a storage object.
+func (m *manifestWriter) record(msg log.InfoMessage) {
+	if m == nil {
+		return
+	}
+
+	entry := ManifestEntry{
+		Operation:   msg.Operation,
+		Source:      msg.Source,
+		Destination: msg.Destination,
+		Time:        time.Now().UTC(),
+	}
+	if obj, ok := msg.Object.(*storage.Object); ok && obj != nil {
+		entry.Size = obj.Size
+	}
+
+	// each line is written at once, so a line is either complete or missing
+	// if s5cmd is killed.
+	line := strutil.JSON(entry) + "\n"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := io.WriteString(m.w, line); err != nil {
+		err = fmt.Errorf("writing to log file failed: %v", err)
+		log.Error(log.ErrorMessage{Err: err.Error()})
+	}
+}
+
+// Close closes the manifest file. It does nothing if the manifest is nil.
+func (m *manifestWriter) Close() error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.w.Close()
+}
+
+// closeManifest closes the manifest file given by --log-file, if any, and
+// logs the error of closing it.
+func closeManifest() {
+	if err := manifest.Close(); err != nil {
+		err = fmt.Errorf("closing log file failed: %v", err)
+		log.Error(log.ErrorMessage{Err: err.Error()})
+	}
+	manifest = nil
+}
+
+// completed is the set of the operations read from the file given by
+// --resume. It is nil if the flag is not given.
+var completed completedOperations
+
+// operationKey identifies an operation of a manifest file.
+type operationKey struct {
+	operation   string
+	source      string
+	destination string
+}
+
+// completedOperations is the set of the operations recorded in a manifest
+// file by a previous run.
+type completedOperations map[operationKey]struct{}
+
+// readCompletedOperations reads the operations recorded in the manifest file
+// at path. The file is written with --log-file, so a missing file is the
+// manifest of a run which has not completed anything yet.
+func readCompletedOperations(path string) (completedOperations, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return completedOperations{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	operations := completedOperations{}
+
+	scanner := bufio.NewScanner(f)
+	for i := 1; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry struct {
+			Operation   string `json:"operation"`
+			Source      string `json:"source"`
+			Destination string `json:"destination"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %v", i, err)
+		}
+		operations[operationKey{entry.Operation, entry.Source, entry.Destination}] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// contains reports whether the operation is completed. dst is nil for the
+// operations without a destination, e.g. rm.
+func (c completedOperations) contains(op string, src, dst *url.URL) bool {
+	if len(c) == 0 {
+		return false
+	}
+
+	key := operationKey{operation: op, source: src.String()}
+	if dst != nil {
+		key.destination = dst.String()
+	}
+	_, ok := c[key]
+	return ok
+}
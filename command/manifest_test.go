@@ -0,0 +1,228 @@
+package command
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+)
+
+func TestManifestRecordConcurrently(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "s5cmd-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manifest.log")
+
+	m, err := openManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			src, _ := url.New(fmt.Sprintf("s3://bucket/file%d", i))
+			dst, _ := url.New(fmt.Sprintf("dir/file%d", i))
+			m.record(log.InfoMessage{
+				Operation:   "cp",
+				Source:      src,
+				Destination: dst,
+				Object:      &storage.Object{Size: int64(i)},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	seen := map[int64]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry struct {
+			Operation   string `json:"operation"`
+			Source      string `json:"source"`
+			Destination string `json:"destination"`
+			Size        int64  `json:"size"`
+			Time        string `json:"time"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+
+		if entry.Operation != "cp" {
+			t.Errorf("expected operation cp, got %q", entry.Operation)
+		}
+		if expected := fmt.Sprintf("s3://bucket/file%d", entry.Size); entry.Source != expected {
+			t.Errorf("expected source %q, got %q", expected, entry.Source)
+		}
+		if expected := fmt.Sprintf("dir/file%d", entry.Size); entry.Destination != expected {
+			t.Errorf("expected destination %q, got %q", expected, entry.Destination)
+		}
+		if entry.Time == "" {
+			t.Errorf("expected the time of the operation in %q", scanner.Text())
+		}
+		seen[entry.Size] = true
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(seen) != n {
+		t.Errorf("expected %d lines, got %d", n, len(seen))
+	}
+}
+
+func TestManifestAppends(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "s5cmd-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manifest.log")
+	src, _ := url.New("s3://bucket/object")
+
+	for i := 0; i < 2; i++ {
+		m, err := openManifest(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		m.record(log.InfoMessage{Operation: "rm", Source: src})
+		if err := m.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var lines int
+	for _, c := range content {
+		if c == '\n' {
+			lines++
+		}
+	}
+	if lines != 2 {
+		t.Errorf("expected 2 lines, got %d:\n%s", lines, content)
+	}
+}
+
+func TestManifestNil(t *testing.T) {
+	t.Parallel()
+
+	var m *manifestWriter
+	m.record(log.InfoMessage{Operation: "rm"})
+	if err := m.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestReadCompletedOperations(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "s5cmd-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manifest.log")
+	content := `{"operation":"cp","source":"s3://bucket/file1","destination":"dir/file1","size":1,"time":"2023-03-10T12:00:00Z"}
+
+{"operation":"rm","source":"s3://bucket/file2","size":0,"time":"2023-03-10T12:00:01Z"}
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	operations, err := readCompletedOperations(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mustURL := func(s string) *url.URL {
+		u, err := url.New(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	testcases := []struct {
+		op       string
+		src      *url.URL
+		dst      *url.URL
+		expected bool
+	}{
+		{op: "cp", src: mustURL("s3://bucket/file1"), dst: mustURL("dir/file1"), expected: true},
+		{op: "mv", src: mustURL("s3://bucket/file1"), dst: mustURL("dir/file1"), expected: false},
+		{op: "cp", src: mustURL("s3://bucket/file1"), dst: mustURL("dir/other"), expected: false},
+		{op: "cp", src: mustURL("s3://bucket/other"), dst: mustURL("dir/file1"), expected: false},
+		{op: "rm", src: mustURL("s3://bucket/file2"), expected: true},
+		{op: "rm", src: mustURL("s3://bucket/file1"), expected: false},
+	}
+
+	for _, tc := range testcases {
+		if got := operations.contains(tc.op, tc.src, tc.dst); got != tc.expected {
+			t.Errorf("%v %v %v: expected %v, got %v", tc.op, tc.src, tc.dst, tc.expected, got)
+		}
+	}
+}
+
+func TestReadCompletedOperationsMissingFile(t *testing.T) {
+	t.Parallel()
+
+	operations, err := readCompletedOperations(filepath.Join(os.TempDir(), "s5cmd-missing-manifest.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(operations) != 0 {
+		t.Errorf("expected no operations, got %v", operations)
+	}
+}
+
+func TestReadCompletedOperationsInvalidLine(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "s5cmd-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("cp s3://bucket/file dir/file\n")
+	f.Close()
+
+	if _, err := readCompletedOperations(f.Name()); err == nil {
+		t.Errorf("expected an error for an invalid line")
+	}
+}
@@ -0,0 +1,189 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+)
+
+// metadataRuleSeparator separates the pattern of a rule from its header.
+const metadataRuleSeparator = "=>"
+
+// userMetadataHeaderPrefix is the prefix of the headers of user defined
+// metadata in canonical form.
+const userMetadataHeaderPrefix = "X-Amz-Meta-"
+
+// metadataRuleHeaders are the headers which can be set by the rules of
+// --metadata-from, besides the user defined metadata of "X-Amz-Meta-"
+// headers.
+var metadataRuleHeaders = map[string]func(storage.Metadata, string) storage.Metadata{
+	"Cache-Control":       storage.Metadata.SetCacheControl,
+	"Content-Type":        storage.Metadata.SetContentType,
+	"Content-Disposition": storage.Metadata.SetContentDisposition,
+	"Content-Language":    storage.Metadata.SetContentLanguage,
+	"Content-Encoding":    storage.Metadata.SetContentEncoding,
+}
+
+// metadataRule sets the headers of the uploaded files which match its
+// pattern.
+type metadataRule struct {
+	pattern string
+	regex   *regexp.Regexp
+	// headers are in their canonical form, e.g. "Cache-Control".
+	headers map[string]string
+}
+
+// parseMetadataRules reads the rules of the file of --metadata-from flag. It
+// returns no rules if the flag is not given.
+func parseMetadataRules(c *cli.Context) ([]metadataRule, error) {
+	path := c.String("metadata-from")
+	if path == "" {
+		return nil, nil
+	}
+	return readMetadataRules(path)
+}
+
+// validateMetadataRules returns an error if the file of --metadata-from flag
+// can not be read, or if it is given for a transfer other than an upload.
+func validateMetadataRules(c *cli.Context, srcurl, dsturl *url.URL) error {
+	if c.String("metadata-from") == "" {
+		return nil
+	}
+
+	if srcurl.IsRemote() || !dsturl.IsRemote() {
+		return fmt.Errorf("--metadata-from can only be used for uploads")
+	}
+
+	_, err := parseMetadataRules(c)
+	return err
+}
+
+// readMetadataRules reads the rules of --metadata-from file, one header per
+// line in "pattern => header: value" format, e.g.
+//
+//	*.html => Cache-Control: no-cache
+//	*.html => Content-Language: en
+//	assets/* => Cache-Control: max-age=31536000
+//
+// The lines of the same pattern are the headers of a single rule, which is
+// placed at its first line. Blank lines and the lines starting with "#" are
+// skipped.
+func readMetadataRules(path string) ([]metadataRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []metadataRule
+	indexes := map[string]int{}
+
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern, header, value, err := parseMetadataRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%v (line: %v)", err, lineno)
+		}
+
+		i, ok := indexes[pattern]
+		if !ok {
+			regex, err := regexp.Compile(wildCardToRegexp(pattern))
+			if err != nil {
+				return nil, fmt.Errorf("%v (line: %v)", err, lineno)
+			}
+			i = len(rules)
+			indexes[pattern] = i
+			rules = append(rules, metadataRule{
+				pattern: pattern,
+				regex:   regex,
+				headers: map[string]string{},
+			})
+		}
+		rules[i].headers[header] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// parseMetadataRule parses a line of --metadata-from file.
+func parseMetadataRule(line string) (pattern, header, value string, err error) {
+	parts := strings.SplitN(line, metadataRuleSeparator, 2)
+	if len(parts) != 2 {
+		return "", "", "", fmt.Errorf("metadata rule %q must be in \"pattern => header: value\" format", line)
+	}
+
+	pattern = strings.TrimSpace(parts[0])
+	if pattern == "" {
+		return "", "", "", fmt.Errorf("metadata rule %q must have a pattern", line)
+	}
+
+	kv := strings.SplitN(parts[1], ":", 2)
+	if len(kv) != 2 {
+		return "", "", "", fmt.Errorf("metadata rule %q must be in \"pattern => header: value\" format", line)
+	}
+
+	header = http.CanonicalHeaderKey(strings.TrimSpace(kv[0]))
+	value = strings.TrimSpace(kv[1])
+	if value == "" {
+		return "", "", "", fmt.Errorf("metadata rule %q must have a value", line)
+	}
+
+	_, ok := metadataRuleHeaders[header]
+	if !ok && (!strings.HasPrefix(header, userMetadataHeaderPrefix) || header == userMetadataHeaderPrefix) {
+		return "", "", "", fmt.Errorf("metadata rule %q sets an unsupported header %q", line, header)
+	}
+	return pattern, header, value, nil
+}
+
+// matchMetadataRule returns the first rule which matches the name of the
+// file, or nil if none of them matches.
+func matchMetadataRule(rules []metadataRule, name string) *metadataRule {
+	name = filepath.ToSlash(name)
+	for i := range rules {
+		if rules[i].regex.MatchString(name) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// metadataRuleName returns the name of the uploaded file which is matched by
+// the rules. It is the path relative to the source directory in a batch, as
+// the patterns of --exclude, and the base name of a single file.
+func metadataRuleName(srcurl *url.URL, isBatch bool) string {
+	if isBatch {
+		return srcurl.Relative()
+	}
+	return srcurl.Base()
+}
+
+// apply sets the headers of the rule on the metadata. They replace the
+// values set by the flags.
+func (r *metadataRule) apply(metadata storage.Metadata) {
+	if r == nil {
+		return
+	}
+	for header, value := range r.headers {
+		if set, ok := metadataRuleHeaders[header]; ok {
+			set(metadata, value)
+			continue
+		}
+		metadata.SetUserDefined(strings.TrimPrefix(header, userMetadataHeaderPrefix), value)
+	}
+}
@@ -0,0 +1,147 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestReadMetadataRules(t *testing.T) {
+	t.Parallel()
+
+	file := fs.NewFile(t, "metadata-rules", fs.WithContent(`# cache the assets for a year
+assets/* => Cache-Control: max-age=31536000
+*.html => cache-control: no-cache
+
+*.html => x-amz-meta-owner: web
+*.html => Content-Type: text/html; charset=utf-8
+* => Cache-Control: max-age=3600
+`))
+	defer file.Remove()
+
+	rules, err := readMetadataRules(file.Path())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var patterns []string
+	for _, rule := range rules {
+		patterns = append(patterns, rule.pattern)
+	}
+	assert.Equal(t, []string{"assets/*", "*.html", "*"}, patterns)
+
+	assert.Equal(t, map[string]string{
+		"Cache-Control":    "no-cache",
+		"Content-Type":     "text/html; charset=utf-8",
+		"X-Amz-Meta-Owner": "web",
+	}, rules[1].headers)
+
+	testcases := []struct {
+		name     string
+		expected string
+	}{
+		// the first matching rule wins.
+		{name: "assets/index.html", expected: "assets/*"},
+		{name: "docs/index.html", expected: "*.html"},
+		{name: "index.html", expected: "*.html"},
+		{name: "robots.txt", expected: "*"},
+	}
+	for _, tc := range testcases {
+		rule := matchMetadataRule(rules, tc.name)
+		if assert.NotNil(t, rule, tc.name) {
+			assert.Equal(t, tc.expected, rule.pattern, tc.name)
+		}
+	}
+
+	assert.Nil(t, matchMetadataRule(rules[:2], "robots.txt"))
+}
+
+func TestReadMetadataRulesInvalid(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "no separator",
+			content:  "*.html Cache-Control: no-cache",
+			expected: `metadata rule "*.html Cache-Control: no-cache" must be in "pattern => header: value" format (line: 1)`,
+		},
+		{
+			name:     "no header",
+			content:  "# rules\n*.html => no-cache",
+			expected: `metadata rule "*.html => no-cache" must be in "pattern => header: value" format (line: 2)`,
+		},
+		{
+			name:     "no pattern",
+			content:  "=> Cache-Control: no-cache",
+			expected: `metadata rule "=> Cache-Control: no-cache" must have a pattern (line: 1)`,
+		},
+		{
+			name:     "no value",
+			content:  "*.html => Cache-Control:",
+			expected: `metadata rule "*.html => Cache-Control:" must have a value (line: 1)`,
+		},
+		{
+			name:     "unsupported header",
+			content:  "*.html => Expires: 7d",
+			expected: `metadata rule "*.html => Expires: 7d" sets an unsupported header "Expires" (line: 1)`,
+		},
+		{
+			name:     "user metadata without a key",
+			content:  "*.html => X-Amz-Meta-: web",
+			expected: `metadata rule "*.html => X-Amz-Meta-: web" sets an unsupported header "X-Amz-Meta-" (line: 1)`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			file := fs.NewFile(t, "metadata-rules", fs.WithContent(tc.content))
+			defer file.Remove()
+
+			_, err := readMetadataRules(file.Path())
+			if assert.Error(t, err) {
+				assert.Equal(t, tc.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestMetadataRuleApply(t *testing.T) {
+	t.Parallel()
+
+	c := Copy{
+		cacheControl:    "max-age=3600",
+		contentLanguage: "en",
+		metadata:        map[string]string{"owner": "ops", "team": "web"},
+	}
+
+	rule := &metadataRule{
+		headers: map[string]string{
+			"Cache-Control":    "no-cache",
+			"Content-Type":     "text/html",
+			"X-Amz-Meta-Owner": "site",
+		},
+	}
+
+	// the headers of the rule replace the flags, the others are kept.
+	metadata := c.uploadMetadata("text/plain")
+	rule.apply(metadata)
+
+	assert.Equal(t, "no-cache", metadata.CacheControl())
+	assert.Equal(t, "text/html", metadata.ContentType())
+	assert.Equal(t, "en", metadata.ContentLanguage())
+	assert.Equal(t, map[string]string{"Owner": "site", "team": "web"}, metadata.UserDefined())
+
+	// no rule keeps the flags.
+	metadata = c.uploadMetadata("text/plain")
+	var none *metadataRule
+	none.apply(metadata)
+	assert.Equal(t, c.uploadMetadata("text/plain"), metadata)
+}
@@ -36,6 +36,9 @@ Examples:
 
 	7. Move all files from S3 bucket to another S3 bucket but exclude the ones starts with log
 		 > s5cmd {{.HelpName}} --exclude "log*" s3://bucket/* s3://destbucket
+
+	8. Move all files in a directory to another local directory, possibly on another filesystem
+		 > s5cmd {{.HelpName}} 'dir/*' /mnt/disk/dir/
 `
 
 func NewMoveCommand() *cli.Command {
@@ -0,0 +1,183 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+	"github.com/peak/s5cmd/strutil"
+)
+
+const (
+	defaultPresignExpire = time.Hour
+
+	// maxPresignExpire is the maximum expiration time of a URL presigned
+	// with signature version 4.
+	maxPresignExpire = 7 * 24 * time.Hour
+)
+
+var presignHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] source
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Print a URL to download a remote object, which expires in 1 hour
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/object
+
+	2. Print a URL to download a remote object, which expires in 5 minutes
+		 > s5cmd {{.HelpName}} --expire 5m s3://bucket/prefix/object
+
+	3. Print a URL to upload an object to the given remote location
+		 > s5cmd {{.HelpName}} --method PUT s3://bucket/prefix/object
+`
+
+func NewPresignCommand() *cli.Command {
+	return &cli.Command{
+		Name:     "presign",
+		HelpName: "presign",
+		Usage:    "print a presigned URL of a remote object",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "expire",
+				Value: defaultPresignExpire,
+				Usage: "expiration time of the URL, can be at most 168h (7 days)",
+			},
+			&cli.StringFlag{
+				Name:  "method",
+				Value: http.MethodGet,
+				Usage: "HTTP method the URL is presigned for: (GET, PUT)",
+			},
+		},
+		CustomHelpTemplate: presignHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validatePresignCommand(c)
+			if err != nil {
+				printError(givenCommand(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			src, err := url.New(c.Args().Get(0))
+			op := c.Command.Name
+			fullCommand := givenCommand(c)
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			return Presign{
+				src:         src,
+				op:          op,
+				fullCommand: fullCommand,
+
+				// flags
+				expire: c.Duration("expire"),
+				method: strings.ToUpper(c.String("method")),
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+}
+
+// Presign holds presign operation flags and states.
+type Presign struct {
+	src         *url.URL
+	op          string
+	fullCommand string
+
+	// flag options
+	expire time.Duration
+	method string
+
+	storageOpts storage.Options
+}
+
+// Run prints a presigned URL of the given source.
+func (p Presign) Run(ctx context.Context) error {
+	client, err := storage.NewRemoteClient(ctx, p.src, p.storageOpts)
+	if err != nil {
+		printError(p.fullCommand, p.op, err)
+		return err
+	}
+
+	presigned, err := client.Presign(ctx, p.src, p.method, p.expire)
+	if err != nil {
+		printError(p.fullCommand, p.op, err)
+		return err
+	}
+
+	log.Info(PresignMessage{
+		Source: p.src,
+		Method: p.method,
+		URL:    presigned,
+	})
+	return nil
+}
+
+// PresignMessage is a structure for logging presign results.
+type PresignMessage struct {
+	Source *url.URL `json:"source"`
+	Method string   `json:"method"`
+	URL    string   `json:"url"`
+}
+
+// String returns the string representation of PresignMessage.
+func (p PresignMessage) String() string {
+	return p.URL
+}
+
+// JSON returns the JSON representation of PresignMessage.
+func (p PresignMessage) JSON() string {
+	return strutil.JSON(p)
+}
+
+func validatePresignCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only one argument")
+	}
+
+	src, err := url.New(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	if !src.IsRemote() {
+		return fmt.Errorf("source must be a remote object")
+	}
+
+	if src.IsBucket() || src.IsPrefix() {
+		return fmt.Errorf("remote source must be an object")
+	}
+
+	if src.IsWildcard() {
+		return fmt.Errorf("remote source %q can not contain glob characters", src)
+	}
+
+	expire := c.Duration("expire")
+	if expire <= 0 || expire > maxPresignExpire {
+		return fmt.Errorf("expire must be a positive duration of at most %v", maxPresignExpire)
+	}
+
+	switch strings.ToUpper(c.String("method")) {
+	case http.MethodGet, http.MethodPut:
+	default:
+		return fmt.Errorf("unsupported method %q, expected GET or PUT", c.String("method"))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/parallel"
+)
+
+// reportOnSignal enables the reports on the stats signal, SIGUSR1. It is only
+// set by Main, so that the programs embedding s5cmd keep the default handling
+// of the signal.
+var reportOnSignal bool
+
+// stopProgress stops the progress reports started by startProgress. It does
+// nothing if the reports are not started.
+var stopProgress = func() {}
+
+// startProgress prints the state of the workers and the tasks to standard
+// error at every interval, if interval is positive, and whenever the stats
+// signal is received, until stopProgress is called. The statistics collected
+// so far are also printed on the signal if printStat is true.
+func startProgress(interval time.Duration, printStat bool) {
+	sigch := make(chan os.Signal, 1)
+	if reportOnSignal {
+		notifyStatsSignal(sigch)
+	}
+
+	donech := make(chan struct{})
+	stoppedch := make(chan struct{})
+
+	go func() {
+		defer close(stoppedch)
+
+		// a nil channel never receives, so there are no periodic reports
+		// without an interval.
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-tick:
+				log.Progress(parallel.Statistics())
+			case <-sigch:
+				log.Progress(parallel.Statistics())
+				if printStat {
+					log.Progress(stat.Statistics())
+				}
+			case <-donech:
+				return
+			}
+		}
+	}()
+
+	// the reports must be stopped before the logger is closed.
+	stopProgress = func() {
+		signal.Stop(sigch)
+		close(donech)
+		<-stoppedch
+		stopProgress = func() {}
+	}
+}
@@ -2,9 +2,12 @@ package command
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
 
+	errorpkg "github.com/peak/s5cmd/error"
 	"github.com/peak/s5cmd/log"
 	"github.com/peak/s5cmd/log/stat"
 	"github.com/peak/s5cmd/storage"
@@ -23,6 +26,9 @@ Options:
 Examples:
 	1. Deletes S3 bucket with given name
 		 > s5cmd {{.HelpName}} s3://bucketname
+
+	2. Deletes all objects, including their versions, of S3 bucket with given name and then the bucket
+		 > s5cmd {{.HelpName}} --force s3://bucketname
 `
 
 func NewRemoveBucketCommand() *cli.Command {
@@ -31,6 +37,12 @@ func NewRemoveBucketCommand() *cli.Command {
 		HelpName:           "rb",
 		Usage:              "remove bucket",
 		CustomHelpTemplate: removeBucketHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "delete all objects and their versions in the bucket before removing it",
+			},
+		},
 		Before: func(c *cli.Context) error {
 			err := validateMBCommand(c) // uses same validation function with make bucket command.
 			if err != nil {
@@ -46,6 +58,9 @@ func NewRemoveBucketCommand() *cli.Command {
 				op:          c.Command.Name,
 				fullCommand: givenCommand(c),
 
+				// flags
+				force: c.Bool("force"),
+
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
 		},
@@ -58,6 +73,9 @@ type RemoveBucket struct {
 	op          string
 	fullCommand string
 
+	// flags
+	force bool
+
 	storageOpts storage.Options
 }
 
@@ -75,6 +93,12 @@ func (b RemoveBucket) Run(ctx context.Context) error {
 		return err
 	}
 
+	if b.force {
+		if err := b.emptyBucket(ctx, client, bucket); err != nil {
+			return err
+		}
+	}
+
 	if err := client.RemoveBucket(ctx, bucket.Bucket); err != nil {
 		printError(b.fullCommand, b.op, err)
 		return err
@@ -88,3 +112,58 @@ func (b RemoveBucket) Run(ctx context.Context) error {
 
 	return nil
 }
+
+// emptyBucket deletes all versions and delete markers of the objects in the
+// bucket in batches. The bucket is not removed if any of them can not be
+// deleted.
+func (b RemoveBucket) emptyBucket(ctx context.Context, client *storage.S3, bucket *url.URL) error {
+	srcurl, err := url.New(fmt.Sprintf("s3://%v/*", bucket.Bucket))
+	if err != nil {
+		printError(b.fullCommand, b.op, err)
+		return err
+	}
+
+	// see Delete.Run for why there are two error objects.
+	var (
+		merrorObjects error
+		merrorResult  error
+	)
+
+	urlch := make(chan *url.URL)
+	go func() {
+		defer close(urlch)
+
+		for object := range client.ListVersions(ctx, srcurl) {
+			if errorpkg.IsCancelation(object.Err) || object.Err == storage.ErrNoObjectFound {
+				continue
+			}
+
+			if err := object.Err; err != nil {
+				merrorObjects = multierror.Append(merrorObjects, err)
+				printError(b.fullCommand, b.op, err)
+				continue
+			}
+
+			urlch <- object.URL
+		}
+	}()
+
+	for obj := range client.MultiDelete(ctx, urlch) {
+		if err := obj.Err; err != nil {
+			if errorpkg.IsCancelation(obj.Err) {
+				continue
+			}
+
+			merrorResult = multierror.Append(merrorResult, obj.Err)
+			printError(b.fullCommand, b.op, obj.Err)
+			continue
+		}
+
+		log.Info(log.InfoMessage{
+			Operation: "rm",
+			Source:    obj.URL,
+		})
+	}
+
+	return multierror.Append(merrorResult, merrorObjects).ErrorOrNil()
+}
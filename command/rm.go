@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -10,6 +11,7 @@ import (
 	errorpkg "github.com/peak/s5cmd/error"
 	"github.com/peak/s5cmd/log"
 	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/parallel"
 	"github.com/peak/s5cmd/storage"
 	"github.com/peak/s5cmd/storage/url"
 )
@@ -38,6 +40,12 @@ Examples:
 	
 	5. Delete all matching objects but exclude the ones with .txt extension or starts with "main"
 		 > s5cmd {{.HelpName}} --exclude "*.txt" --exclude "main*" s3://bucketname/prefix/* 
+
+	6. Delete all objects with a prefix except the ones in "keep" folder, but still delete the ones with .log extension in it
+		 > s5cmd {{.HelpName}} --exclude "keep/*" --include "keep/*.log" s3://bucketname/prefix/*
+
+	7. Delete all objects under a prefix, but not the ones under "prefix-other"
+		 > s5cmd {{.HelpName}} --recursive s3://bucketname/prefix
 `
 
 func NewDeleteCommand() *cli.Command {
@@ -45,16 +53,33 @@ func NewDeleteCommand() *cli.Command {
 		Name:     "rm",
 		HelpName: "rm",
 		Usage:    "remove objects",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:  "raw",
 				Usage: "disable the wildcard operations, useful with filenames that contains glob characters.",
 			},
+			&cli.BoolFlag{
+				Name:    "recursive",
+				Aliases: []string{"r"},
+				Usage:   "remove all objects under the given buckets and prefixes, e.g. rm -r s3://bucket/prefix",
+			},
 			&cli.StringSliceFlag{
 				Name:  "exclude",
 				Usage: "exclude objects with given pattern",
 			},
-		},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern even if they match an exclude pattern",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude-from",
+				Usage: "exclude objects with the patterns in given file, one pattern per line",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include-from",
+				Usage: "include objects with the patterns in given file, one pattern per line, even if they match an exclude pattern",
+			},
+		}, objectFilterFlags()...),
 		CustomHelpTemplate: deleteHelpTemplate,
 		Before: func(c *cli.Context) error {
 			err := validateRMCommand(c)
@@ -64,15 +89,20 @@ func NewDeleteCommand() *cli.Command {
 			return err
 		},
 		Action: func(c *cli.Context) (err error) {
-			defer stat.Collect(c.Command.FullName(), &err)()
+			// filter files and filters are already validated.
+			exclude, include, _ := parseFilters(c)
+			filter, _ := parseObjectFilter(c, time.Now())
+
 			return Delete{
-				src:         c.Args().Slice(),
+				src:         recursiveSources(c.Bool("recursive"), c.Args().Slice()...),
 				op:          c.Command.Name,
 				fullCommand: givenCommand(c),
 
 				// flags
 				raw:     c.Bool("raw"),
-				exclude: c.StringSlice("exclude"),
+				exclude: exclude,
+				include: include,
+				filter:  filter,
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -88,14 +118,25 @@ type Delete struct {
 
 	// flag options
 	exclude []string
+	include []string
+	filter  objectFilter
 	raw     bool
 
 	// storage options
 	storageOpts storage.Options
 }
 
-// Run remove given sources.
-func (d Delete) Run(ctx context.Context) error {
+// Run remove given sources. The keys are counted as separate operations for
+// --stat once the sources are listed. The command is counted as a single
+// failed operation if it fails before.
+func (d Delete) Run(ctx context.Context) (err error) {
+	listed := false
+	defer func() {
+		if !listed {
+			stat.AddOperation(d.op, err)
+		}
+	}()
+
 	srcurls, err := newURLs(d.raw, d.src...)
 	if err != nil {
 		printError(d.fullCommand, d.op, err)
@@ -109,13 +150,26 @@ func (d Delete) Run(ctx context.Context) error {
 		return err
 	}
 
-	excludePatterns, err := createExcludesFromWildcard(d.exclude)
+	excludePatterns, err := createRegexFromWildcard(d.exclude)
+	if err != nil {
+		printError(d.fullCommand, d.op, err)
+		return err
+	}
+
+	includePatterns, err := createRegexFromWildcard(d.include)
 	if err != nil {
 		printError(d.fullCommand, d.op, err)
 		return err
 	}
 
-	objch := expandSources(ctx, client, false, srcurls...)
+	// the sources are not listed any further once the manager is drained,
+	// the running deletions keep ctx.
+	listCtx, cancelList := parallel.WithDrain(ctx)
+	defer cancelList()
+
+	listed = true
+
+	objch := expandSources(listCtx, client, false, srcurls...)
 
 	// create two different error objects instead of single object to avoid the
 	// data race for merror object, since there is a goroutine running,
@@ -136,12 +190,28 @@ func (d Delete) Run(ctx context.Context) error {
 			}
 
 			if err := object.Err; err != nil {
+				stat.AddOperation(d.op, err)
 				merrorObjects = multierror.Append(merrorObjects, err)
 				printError(d.fullCommand, d.op, err)
 				continue
 			}
 
-			if isURLExcluded(excludePatterns, object.URL.Path, srcurl.Prefix) {
+			if isURLExcluded(excludePatterns, includePatterns, object.URL.Path, srcurl.Prefix) {
+				continue
+			}
+
+			if !d.filter.match(object) {
+				continue
+			}
+
+			// the removals completed by a resumed run are not repeated.
+			if completed.contains(d.op, object.URL, nil) {
+				stat.AddSkipped()
+				log.Debug(log.DebugMessage{
+					Command:   fmt.Sprintf("%v %v", d.op, object.URL),
+					Operation: d.op,
+					Err:       errorpkg.ErrOperationCompleted.Error(),
+				})
 				continue
 			}
 
@@ -157,16 +227,31 @@ func (d Delete) Run(ctx context.Context) error {
 				continue
 			}
 
-			merrorResult = multierror.Append(merrorResult, obj.Err)
-			printError(d.fullCommand, d.op, obj.Err)
+			stat.AddOperation(d.op, err)
+
+			// the keys which are not deleted by a partially failed request
+			// are reported separately.
+			if obj.URL != nil {
+				err = &errorpkg.Error{
+					Op:  d.op,
+					Src: obj.URL,
+					Err: err,
+				}
+			}
+
+			merrorResult = multierror.Append(merrorResult, err)
+			printError(d.fullCommand, d.op, err)
 			continue
 		}
 
+		stat.AddOperation(d.op, nil)
+
 		msg := log.InfoMessage{
 			Operation: d.op,
 			Source:    obj.URL,
 		}
 		log.Info(msg)
+		manifest.record(msg)
 	}
 
 	return multierror.Append(merrorResult, merrorObjects).ErrorOrNil()
@@ -190,11 +275,24 @@ func validateRMCommand(c *cli.Context) error {
 		return fmt.Errorf("expected at least 1 object to remove")
 	}
 
-	srcurls, err := newURLs(c.Bool("raw"), c.Args().Slice()...)
+	if c.Bool("recursive") && c.Bool("raw") {
+		return fmt.Errorf("--recursive can not be used with --raw")
+	}
+
+	srcs := recursiveSources(c.Bool("recursive"), c.Args().Slice()...)
+	srcurls, err := newURLs(c.Bool("raw"), srcs...)
 	if err != nil {
 		return err
 	}
 
+	if _, _, err := parseFilters(c); err != nil {
+		return err
+	}
+
+	if err := validateObjectFilterSources(c, srcurls...); err != nil {
+		return err
+	}
+
 	var (
 		firstBucket         string
 		hasRemote, hasLocal bool
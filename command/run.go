@@ -6,9 +6,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/kballard/go-shellquote"
 	"github.com/urfave/cli/v2"
 
@@ -19,7 +23,7 @@ var runHelpTemplate = `Name:
 	{{.HelpName}} - {{.Usage}}
 
 Usage:
-	{{.HelpName}} [file]
+	{{.HelpName}} [options] [file...]
 
 Options:
 	{{range .VisibleFlags}}{{.}}
@@ -30,6 +34,21 @@ Examples:
 
 	2. Read commands from standard input and execute in parallel.
 		 > cat commands.txt | s5cmd {{.HelpName}}
+
+	3. Run the commands declared in multiple files and standard input in parallel, "-" stands for standard input
+		 > generate-commands | s5cmd {{.HelpName}} first.txt - last.txt
+
+	4. Limit the number of workers of a single command by starting its line with "--numworkers N"
+		 > echo "--numworkers 4 cp 's3://bucket/large-files/*' dir/" | s5cmd {{.HelpName}}
+
+	5. Stop running the remaining commands after the first failure
+		 > s5cmd {{.HelpName}} --fail-fast commands.txt
+
+	6. Run the commands given inline, one per line
+		 > s5cmd {{.HelpName}} -c $'cp s3://bucket/a dir/\ncp s3://bucket/b dir/'
+
+	7. Read up to 1000 commands ahead while the workers are busy
+		 > generate-commands | s5cmd {{.HelpName}} --queue-size 1000
 `
 
 func NewRunCommand() *cli.Command {
@@ -38,6 +57,21 @@ func NewRunCommand() *cli.Command {
 		HelpName:           "run",
 		Usage:              "run commands in batch",
 		CustomHelpTemplate: runHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "fail-fast",
+				Usage: "skip the remaining commands after the first failure; the running commands are not canceled",
+			},
+			&cli.StringFlag{
+				Name:    "commands",
+				Aliases: []string{"c"},
+				Usage:   "run the given commands, one per line, before the commands of the files",
+			},
+			&cli.IntFlag{
+				Name:  "queue-size",
+				Usage: "number of the commands read ahead while all workers are busy",
+			},
+		},
 		Before: func(c *cli.Context) error {
 			err := validateRunCommand(c)
 			if err != nil {
@@ -46,95 +80,232 @@ func NewRunCommand() *cli.Command {
 			return err
 		},
 		Action: func(c *cli.Context) error {
-			reader := os.Stdin
-			if c.Args().Len() == 1 {
-				f, err := os.Open(c.Args().First())
-				if err != nil {
-					return err
-				}
-				defer f.Close()
+			// the inline commands are run first, then the commands of the
+			// files. standard input is read only if neither is given.
+			var openers []func() (io.ReadCloser, error)
+			if c.IsSet("commands") {
+				commands := c.String("commands")
+				openers = append(openers, func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(strings.NewReader(commands)), nil
+				})
+			}
 
-				reader = f
+			sources := c.Args().Slice()
+			if len(sources) == 0 && len(openers) == 0 {
+				sources = []string{"-"}
+			}
+			for _, source := range sources {
+				source := source
+				openers = append(openers, func() (io.ReadCloser, error) {
+					return openRunSource(source)
+				})
 			}
 
-			pm := parallel.New(c.Int("numworkers"))
+			pm := parallel.New(numWorkers(c))
 			defer pm.Close()
 
-			waiter := parallel.NewWaiter()
+			waiter := parallel.NewWaiter(c.Context)
+
+			// in fail-fast mode, the manager is drained on the first failure,
+			// so the running commands finish and the remaining ones are
+			// skipped.
+			var aborted int32
+			abort := func() {
+				if c.Bool("fail-fast") && atomic.CompareAndSwapInt32(&aborted, 0, 1) {
+					pm.Drain()
+				}
+			}
+
+			// create two different error objects instead of single object to
+			// avoid the data race for merror object, since there is a
+			// goroutine running, there might be a data race for a single
+			// error object.
+			var (
+				merrorWaiter error
+				merrorLines  error
+				skipped      int
+			)
 
 			var errDoneCh = make(chan bool)
 			go func() {
 				defer close(errDoneCh)
-				for range waiter.Err() {
-					// app.ExitErrHandler is called after each command.Run
-					// invocation. Ignore the errors returned from parallel.Run,
-					// just drain the channel for synchronization.
+				for err := range waiter.Err() {
+					// drained manager reports the skipped commands as
+					// canceled.
+					if err == context.Canceled && atomic.LoadInt32(&aborted) == 1 {
+						skipped++
+						continue
+					}
+
+					// the errors are already printed by the commands. They
+					// are only collected to report the failure via exit
+					// code.
+					merrorWaiter = multierror.Append(merrorWaiter, err)
+					abort()
 				}
 			}()
 
-			scanner := NewScanner(c.Context, reader)
-			lineno := -1
-			for line := range scanner.Scan() {
-				lineno++
+			// the commands are not read any further once global manager is
+			// drained by an interrupt, the running commands keep c.Context.
+			scanCtx, cancelScan := parallel.WithDrain(c.Context)
+			defer cancelScan()
 
-				// support inline comments
-				line = strings.Split(line, " #")[0]
-
-				line = strings.TrimSpace(line)
-				if line == "" {
-					continue
+			for _, open := range openers {
+				if scanCtx.Err() != nil {
+					break
 				}
 
-				if strings.HasPrefix(line, "#") {
+				reader, err := open()
+				if err != nil {
+					// continue with the remaining sources, the failure is
+					// reported via exit code.
+					printError(givenCommand(c), c.Command.Name, err)
+					merrorLines = multierror.Append(merrorLines, err)
+					abort()
 					continue
 				}
 
-				fields, err := shellquote.Split(line)
-				if err != nil {
-					return err
-				}
+				scanner := NewQueuedScanner(scanCtx, reader, c.Int("queue-size"))
+				// the commands read ahead are shown as queued by
+				// --stats-interval.
+				removeQueue := parallel.AddQueue(scanner.Len)
+				lineno := -1
+				for line := range scanner.Scan() {
+					lineno++
+					lineno := lineno
+
+					// the queued lines are discarded once the run is
+					// canceled or drained. the queue is still consumed
+					// until the scanner stops, so that its error can be
+					// read.
+					if scanCtx.Err() != nil {
+						continue
+					}
 
-				if len(fields) == 0 {
-					continue
-				}
+					// support inline comments
+					line = strings.Split(line, " #")[0]
 
-				if fields[0] == "run" {
-					err := fmt.Errorf("%q command (line: %v) is not permitted in run-mode", "run", lineno)
-					printError(givenCommand(c), c.Command.Name, err)
-					continue
-				}
+					line = strings.TrimSpace(line)
+					if line == "" {
+						continue
+					}
+
+					if strings.HasPrefix(line, "#") {
+						continue
+					}
 
-				fn := func() error {
-					subcmd := fields[0]
+					fields, err := shellquote.Split(line)
+					if err != nil {
+						removeQueue()
+						reader.Close()
+						err := fmt.Errorf("%v (line: %v)", err, lineno)
+						printError(givenCommand(c), c.Command.Name, err)
+						return err
+					}
 
-					cmd := AppCommand(subcmd)
-					if cmd == nil {
-						err := fmt.Errorf("%q command (line: %v) not found", subcmd, lineno)
+					workers, fields, err := parseLineWorkers(fields)
+					if err != nil {
+						err := fmt.Errorf("%v (line: %v)", err, lineno)
 						printError(givenCommand(c), c.Command.Name, err)
-						return nil
+						merrorLines = multierror.Append(merrorLines, err)
+						abort()
+						continue
+					}
+
+					if len(fields) == 0 {
+						continue
 					}
 
-					flagset := flag.NewFlagSet(subcmd, flag.ExitOnError)
-					if err := flagset.Parse(fields); err != nil {
+					if fields[0] == "run" {
+						err := fmt.Errorf("%q command (line: %v) is not permitted in run-mode", "run", lineno)
 						printError(givenCommand(c), c.Command.Name, err)
-						return nil
+						merrorLines = multierror.Append(merrorLines, err)
+						abort()
+						continue
 					}
 
-					ctx := cli.NewContext(app, flagset, c)
-					return cmd.Run(ctx)
+					fn := func() error {
+						subcmd := fields[0]
+
+						cmd := AppCommand(subcmd)
+						if cmd == nil {
+							err := fmt.Errorf("%q command (line: %v) not found", subcmd, lineno)
+							printError(givenCommand(c), c.Command.Name, err)
+							return err
+						}
+
+						flagset := flag.NewFlagSet(subcmd, flag.ExitOnError)
+						if err := flagset.Parse(fields); err != nil {
+							printError(givenCommand(c), c.Command.Name, err)
+							return err
+						}
+
+						ctx := cli.NewContext(app, flagset, c)
+						if workers > 0 {
+							ctx.Context = parallel.WithWorkers(ctx.Context, workers)
+						}
+						return cmd.Run(ctx)
+					}
+
+					pm.Run(fn, waiter)
 				}
 
-				pm.Run(fn, waiter)
+				removeQueue()
+				reader.Close()
+				merrorLines = multierror.Append(merrorLines, scanner.Err())
 			}
 
 			waiter.Wait()
 			<-errDoneCh
 
-			return scanner.Err()
+			if atomic.LoadInt32(&aborted) == 1 {
+				err := fmt.Errorf("run is aborted after a failure, %d commands are skipped", skipped)
+				printError(givenCommand(c), c.Command.Name, err)
+				merrorLines = multierror.Append(merrorLines, err)
+			}
+
+			return multierror.Append(merrorWaiter, merrorLines).ErrorOrNil()
 		},
 	}
 }
 
+// parseLineWorkers parses the optional "--numworkers N" hint at the beginning
+// of a command line, which limits the number of workers running the
+// operations of the command. It returns 0 if the line has no hint.
+func parseLineWorkers(fields []string) (int, []string, error) {
+	if len(fields) == 0 {
+		return 0, fields, nil
+	}
+
+	var value string
+	switch {
+	case fields[0] == "--numworkers":
+		if len(fields) < 2 {
+			return 0, nil, fmt.Errorf("numworkers expects a value")
+		}
+		value, fields = fields[1], fields[2:]
+	case strings.HasPrefix(fields[0], "--numworkers="):
+		value, fields = strings.TrimPrefix(fields[0], "--numworkers="), fields[1:]
+	default:
+		return 0, fields, nil
+	}
+
+	workers, err := strconv.Atoi(value)
+	if err != nil || workers <= 0 {
+		return 0, nil, fmt.Errorf("numworkers must be a positive number, got %q", value)
+	}
+	return workers, fields, nil
+}
+
+// openRunSource opens the given command file. "-" stands for the standard
+// input.
+func openRunSource(source string) (io.ReadCloser, error) {
+	if source == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(source)
+}
+
 // Scanner is a cancelable scanner.
 type Scanner struct {
 	*bufio.Scanner
@@ -145,10 +316,17 @@ type Scanner struct {
 
 // NewScanner creates a new scanner with cancellation.
 func NewScanner(ctx context.Context, r io.Reader) *Scanner {
+	return NewQueuedScanner(ctx, r, 0)
+}
+
+// NewQueuedScanner creates a new scanner with cancellation, which reads up to
+// size lines ahead of the consumer. The reader is read one line ahead if size
+// is zero.
+func NewQueuedScanner(ctx context.Context, r io.Reader, size int) *Scanner {
 	scanner := &Scanner{
 		ctx:     ctx,
 		Scanner: bufio.NewScanner(r),
-		linech:  make(chan string),
+		linech:  make(chan string, size),
 	}
 
 	go scanner.scan()
@@ -169,7 +347,14 @@ func (s *Scanner) scan() {
 				return
 			}
 
-			s.linech <- s.Scanner.Text()
+			// the consumer might stop reading the lines of a full queue
+			// once it is canceled.
+			select {
+			case s.linech <- s.Scanner.Text():
+			case <-s.ctx.Done():
+				s.err = s.ctx.Err()
+				return
+			}
 		}
 	}
 }
@@ -179,6 +364,11 @@ func (s *Scanner) Scan() <-chan string {
 	return s.linech
 }
 
+// Len returns the number of the lines which are read but not consumed yet.
+func (s *Scanner) Len() int {
+	return len(s.linech)
+}
+
 // Err returns encountered errors, if any.
 func (s *Scanner) Err() error {
 	if s.err != nil {
@@ -189,8 +379,19 @@ func (s *Scanner) Err() error {
 }
 
 func validateRunCommand(c *cli.Context) error {
-	if c.Args().Len() > 1 {
-		return fmt.Errorf("expected only 1 file")
+	var stdinCount int
+	for _, source := range c.Args().Slice() {
+		if source == "-" {
+			stdinCount++
+		}
+	}
+
+	if stdinCount > 1 {
+		return fmt.Errorf("standard input can be given only once")
+	}
+
+	if size := c.Int("queue-size"); size < 0 {
+		return fmt.Errorf("queue-size must be a non-negative number, got %d", size)
 	}
 	return nil
 }
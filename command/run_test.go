@@ -0,0 +1,110 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueuedScannerReadsAhead(t *testing.T) {
+	t.Parallel()
+
+	const size = 4
+
+	input := strings.Repeat("ls s3://bucket\n", 10)
+	scanner := NewQueuedScanner(context.Background(), strings.NewReader(input), size)
+
+	// the lines are queued while nothing is consumed.
+	deadline := time.Now().Add(5 * time.Second)
+	for scanner.Len() < size {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d queued lines, got %d", size, scanner.Len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var lines int
+	for range scanner.Scan() {
+		lines++
+	}
+	assert.Equal(t, 10, lines)
+	assert.NoError(t, scanner.Err())
+}
+
+func TestQueuedScannerCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the reader never ends, so the scanner blocks on the full queue until
+	// it is canceled.
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	go func() {
+		for {
+			if _, err := fmt.Fprintln(writer, "ls s3://bucket"); err != nil {
+				return
+			}
+		}
+	}()
+
+	scanner := NewQueuedScanner(ctx, reader, 2)
+	<-scanner.Scan()
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range scanner.Scan() {
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the scanner to stop after cancelation")
+	}
+	assert.Equal(t, context.Canceled, scanner.Err())
+	reader.Close()
+}
+
+// burstyReader returns a line per read, and stalls after each burst of lines
+// like a generator of commands.
+type burstyReader struct {
+	lines int
+	burst int
+	stall time.Duration
+}
+
+func (r *burstyReader) Read(p []byte) (int, error) {
+	if r.lines == 0 {
+		return 0, io.EOF
+	}
+	if r.lines%r.burst == 0 {
+		time.Sleep(r.stall)
+	}
+	r.lines--
+	return copy(p, "ls s3://bucket\n"), nil
+}
+
+// BenchmarkQueuedScanner consumes the lines of a bursty reader as slowly as
+// busy workers. The queue keeps the consumer busy while the reader stalls.
+func BenchmarkQueuedScanner(b *testing.B) {
+	for _, size := range []int{0, 16, 64} {
+		size := size
+		b.Run(fmt.Sprintf("queue-%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				reader := &burstyReader{lines: 100, burst: 20, stall: 20 * time.Millisecond}
+				scanner := NewQueuedScanner(context.Background(), reader, size)
+				for range scanner.Scan() {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		})
+	}
+}
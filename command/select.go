@@ -56,6 +56,10 @@ func NewSelectCommand() *cli.Command {
 				Name:  "exclude",
 				Usage: "exclude objects with given pattern",
 			},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern even if they match an exclude pattern",
+			},
 		},
 		CustomHelpTemplate: selectHelpTemplate,
 		Before: func(c *cli.Context) error {
@@ -76,6 +80,7 @@ func NewSelectCommand() *cli.Command {
 				query:           c.String("query"),
 				compressionType: c.String("compression"),
 				exclude:         c.StringSlice("exclude"),
+				include:         c.StringSlice("include"),
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -92,6 +97,7 @@ type Select struct {
 	query           string
 	compressionType string
 	exclude         []string
+	include         []string
 
 	// s3 options
 	storageOpts storage.Options
@@ -114,7 +120,10 @@ func (s Select) Run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	objch, err := expandSource(ctx, client, false, srcurl)
+	listCtx, cancelList := parallel.WithDrain(ctx)
+	defer cancelList()
+
+	objch, err := expandSource(listCtx, client, false, srcurl)
 	if err != nil {
 		printError(s.fullCommand, s.op, err)
 		return err
@@ -128,7 +137,7 @@ func (s Select) Run(ctx context.Context) error {
 		merrorObjects error
 	)
 
-	waiter := parallel.NewWaiter()
+	waiter := parallel.NewWaiter(ctx)
 	errDoneCh := make(chan bool)
 	writeDoneCh := make(chan bool)
 	resultCh := make(chan json.RawMessage, 128)
@@ -162,7 +171,13 @@ func (s Select) Run(ctx context.Context) error {
 		}
 	}()
 
-	excludePatterns, err := createExcludesFromWildcard(s.exclude)
+	excludePatterns, err := createRegexFromWildcard(s.exclude)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	includePatterns, err := createRegexFromWildcard(s.include)
 	if err != nil {
 		printError(s.fullCommand, s.op, err)
 		return err
@@ -186,7 +201,7 @@ func (s Select) Run(ctx context.Context) error {
 			continue
 		}
 
-		if isURLExcluded(excludePatterns, object.URL.Path, srcurl.Prefix) {
+		if isURLExcluded(excludePatterns, includePatterns, object.URL.Path, srcurl.Prefix) {
 			continue
 		}
 
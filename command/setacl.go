@@ -0,0 +1,226 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/error"
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/parallel"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+)
+
+var setACLHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] argument
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Make an S3 object publicly readable
+		 > s5cmd {{.HelpName}} --acl public-read s3://bucket/prefix/object
+
+	2. Grant full control of all objects with a prefix to the bucket owner
+		 > s5cmd {{.HelpName}} --acl bucket-owner-full-control s3://bucket/prefix/*
+
+	3. Make all matching objects private but exclude the ones with .txt extension
+		 > s5cmd {{.HelpName}} --acl private --exclude "*.txt" s3://bucket/prefix/*
+`
+
+func NewSetACLCommand() *cli.Command {
+	return &cli.Command{
+		Name:     "set-acl",
+		HelpName: "set-acl",
+		Usage:    "set canned acl of objects",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "acl",
+				Usage: "canned acl for objects ('private','public-read','public-read-write','authenticated-read','aws-exec-read','bucket-owner-read','bucket-owner-full-control')",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "exclude objects with given pattern",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern even if they match an exclude pattern",
+			},
+			&cli.BoolFlag{
+				Name:  "raw",
+				Usage: "disable the wildcard operations, useful with filenames that contains glob characters.",
+			},
+		},
+		CustomHelpTemplate: setACLHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateSetACLCommand(c)
+			if err != nil {
+				printError(givenCommand(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			return SetACL{
+				src:         c.Args().Get(0),
+				op:          c.Command.Name,
+				fullCommand: givenCommand(c),
+
+				// flags
+				acl:     c.String("acl"),
+				exclude: c.StringSlice("exclude"),
+				include: c.StringSlice("include"),
+				raw:     c.Bool("raw"),
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+}
+
+// SetACL holds set-acl operation flags and states.
+type SetACL struct {
+	src         string
+	op          string
+	fullCommand string
+
+	// flag options
+	acl     string
+	exclude []string
+	include []string
+	raw     bool
+
+	// s3 options
+	storageOpts storage.Options
+}
+
+// Run sets the canned acl of the given source objects.
+func (s SetACL) Run(ctx context.Context) error {
+	srcurl, err := url.New(s.src, url.WithRaw(s.raw))
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	client, err := storage.NewRemoteClient(ctx, srcurl, s.storageOpts)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	excludePatterns, err := createRegexFromWildcard(s.exclude)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	includePatterns, err := createRegexFromWildcard(s.include)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	listCtx, cancelList := parallel.WithDrain(ctx)
+	defer cancelList()
+
+	objch, err := expandSource(listCtx, client, false, srcurl)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	// create two different error objects instead of single object to avoid the
+	// data race for merror object, since there is a goroutine running,
+	// there might be a data race for a single error object.
+	var (
+		merrorWaiter  error
+		merrorObjects error
+	)
+
+	waiter := parallel.NewWaiter(ctx)
+	errDoneCh := make(chan bool)
+
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			printError(s.fullCommand, s.op, err)
+			merrorWaiter = multierror.Append(merrorWaiter, err)
+		}
+	}()
+
+	for object := range objch {
+		if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
+			continue
+		}
+
+		if err := object.Err; err != nil {
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(s.fullCommand, s.op, err)
+			continue
+		}
+
+		if isURLExcluded(excludePatterns, includePatterns, object.URL.Path, srcurl.Prefix) {
+			continue
+		}
+
+		task := s.prepareTask(ctx, client, object.URL)
+		parallel.Run(task, waiter)
+	}
+
+	waiter.Wait()
+	<-errDoneCh
+
+	return multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
+}
+
+func (s SetACL) prepareTask(ctx context.Context, client *storage.S3, url *url.URL) func() error {
+	return func() error {
+		if err := client.PutACL(ctx, url, s.acl); err != nil {
+			return &errorpkg.Error{
+				Op:  s.op,
+				Src: url,
+				Err: err,
+			}
+		}
+
+		msg := log.InfoMessage{
+			Operation: s.op,
+			Source:    url,
+		}
+		log.Info(msg)
+		return nil
+	}
+}
+
+func validateSetACLCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected source argument")
+	}
+
+	srcurl, err := url.New(c.Args().Get(0), url.WithRaw(c.Bool("raw")))
+	if err != nil {
+		return err
+	}
+
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("source must be remote")
+	}
+
+	if srcurl.IsBucket() || srcurl.IsPrefix() {
+		return fmt.Errorf("source argument must contain wildcard character")
+	}
+
+	if c.String("acl") == "" {
+		return fmt.Errorf("expected an acl")
+	}
+
+	return validateACL(c.String("acl"))
+}
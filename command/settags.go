@@ -0,0 +1,270 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/error"
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/parallel"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+)
+
+const (
+	maxTagCount       = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+var setTagsHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] argument
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Set tags of an S3 object
+		 > s5cmd {{.HelpName}} --tag env=prod --tag team=data s3://bucket/prefix/object
+
+	2. Set tags of all objects with a prefix
+		 > s5cmd {{.HelpName}} --tag env=prod s3://bucket/prefix/*
+
+	3. Set tags of all matching objects but exclude the ones with .txt extension
+		 > s5cmd {{.HelpName}} --tag env=prod --exclude "*.txt" s3://bucket/prefix/*
+`
+
+func NewSetTagsCommand() *cli.Command {
+	return &cli.Command{
+		Name:     "set-tags",
+		HelpName: "set-tags",
+		Usage:    "replace tags of objects",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "tag",
+				Usage: "set tag for objects in key=value format, can be specified multiple times, e.g. set-tags --tag 'env=prod'",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "exclude objects with given pattern",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern even if they match an exclude pattern",
+			},
+			&cli.BoolFlag{
+				Name:  "raw",
+				Usage: "disable the wildcard operations, useful with filenames that contains glob characters.",
+			},
+		},
+		CustomHelpTemplate: setTagsHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateSetTagsCommand(c)
+			if err != nil {
+				printError(givenCommand(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			// tags are already validated.
+			tags, _ := parseTags(c.StringSlice("tag"))
+
+			return SetTags{
+				src:         c.Args().Get(0),
+				op:          c.Command.Name,
+				fullCommand: givenCommand(c),
+
+				// flags
+				tags:    tags,
+				exclude: c.StringSlice("exclude"),
+				include: c.StringSlice("include"),
+				raw:     c.Bool("raw"),
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+}
+
+// SetTags holds set-tags operation flags and states.
+type SetTags struct {
+	src         string
+	op          string
+	fullCommand string
+
+	// flag options
+	tags    map[string]string
+	exclude []string
+	include []string
+	raw     bool
+
+	// s3 options
+	storageOpts storage.Options
+}
+
+// Run replaces tags of the given source objects.
+func (s SetTags) Run(ctx context.Context) error {
+	srcurl, err := url.New(s.src, url.WithRaw(s.raw))
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	client, err := storage.NewRemoteClient(ctx, srcurl, s.storageOpts)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	excludePatterns, err := createRegexFromWildcard(s.exclude)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	includePatterns, err := createRegexFromWildcard(s.include)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	listCtx, cancelList := parallel.WithDrain(ctx)
+	defer cancelList()
+
+	objch, err := expandSource(listCtx, client, false, srcurl)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	// create two different error objects instead of single object to avoid the
+	// data race for merror object, since there is a goroutine running,
+	// there might be a data race for a single error object.
+	var (
+		merrorWaiter  error
+		merrorObjects error
+	)
+
+	waiter := parallel.NewWaiter(ctx)
+	errDoneCh := make(chan bool)
+
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			printError(s.fullCommand, s.op, err)
+			merrorWaiter = multierror.Append(merrorWaiter, err)
+		}
+	}()
+
+	for object := range objch {
+		if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
+			continue
+		}
+
+		if err := object.Err; err != nil {
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(s.fullCommand, s.op, err)
+			continue
+		}
+
+		if isURLExcluded(excludePatterns, includePatterns, object.URL.Path, srcurl.Prefix) {
+			continue
+		}
+
+		task := s.prepareTask(ctx, client, object.URL)
+		parallel.Run(task, waiter)
+	}
+
+	waiter.Wait()
+	<-errDoneCh
+
+	return multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
+}
+
+func (s SetTags) prepareTask(ctx context.Context, client *storage.S3, url *url.URL) func() error {
+	return func() error {
+		if err := client.PutTagging(ctx, url, s.tags); err != nil {
+			return &errorpkg.Error{
+				Op:  s.op,
+				Src: url,
+				Err: err,
+			}
+		}
+
+		msg := log.InfoMessage{
+			Operation: s.op,
+			Source:    url,
+		}
+		log.Info(msg)
+		return nil
+	}
+}
+
+// parseTags parses the object tags given in key=value format and validates
+// them against the limits of S3.
+func parseTags(values []string) (map[string]string, error) {
+	if len(values) > maxTagCount {
+		return nil, fmt.Errorf("an object can have at most %d tags", maxTagCount)
+	}
+
+	tags := map[string]string{}
+	for _, value := range values {
+		kv := strings.SplitN(value, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("tag %q must be in key=value format", value)
+		}
+
+		key, val := kv[0], kv[1]
+		if key == "" {
+			return nil, fmt.Errorf("tag %q must have a non-empty key", value)
+		}
+		if utf8.RuneCountInString(key) > maxTagKeyLength {
+			return nil, fmt.Errorf("tag key %q can be at most %d characters long", key, maxTagKeyLength)
+		}
+		if utf8.RuneCountInString(val) > maxTagValueLength {
+			return nil, fmt.Errorf("tag value of %q can be at most %d characters long", key, maxTagValueLength)
+		}
+		if _, ok := tags[key]; ok {
+			return nil, fmt.Errorf("tag key %q is given more than once", key)
+		}
+		tags[key] = val
+	}
+	return tags, nil
+}
+
+func validateSetTagsCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected source argument")
+	}
+
+	srcurl, err := url.New(c.Args().Get(0), url.WithRaw(c.Bool("raw")))
+	if err != nil {
+		return err
+	}
+
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("source must be remote")
+	}
+
+	if srcurl.IsBucket() || srcurl.IsPrefix() {
+		return fmt.Errorf("source argument must contain wildcard character")
+	}
+
+	if len(c.StringSlice("tag")) == 0 {
+		return fmt.Errorf("expected at least 1 tag")
+	}
+
+	_, err = parseTags(c.StringSlice("tag"))
+	return err
+}
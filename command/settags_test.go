@@ -0,0 +1,90 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	t.Parallel()
+
+	tooMany := make([]string, maxTagCount+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("key%d=value", i)
+	}
+
+	testcases := []struct {
+		name     string
+		values   []string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "no tags",
+			expected: map[string]string{},
+		},
+		{
+			name:     "single tag",
+			values:   []string{"env=prod"},
+			expected: map[string]string{"env": "prod"},
+		},
+		{
+			name:     "empty value",
+			values:   []string{"env="},
+			expected: map[string]string{"env": ""},
+		},
+		{
+			name:     "value with equal sign",
+			values:   []string{"query=a=b"},
+			expected: map[string]string{"query": "a=b"},
+		},
+		{
+			name:    "missing equal sign",
+			values:  []string{"env"},
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			values:  []string{"=prod"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate key",
+			values:  []string{"env=prod", "env=dev"},
+			wantErr: true,
+		},
+		{
+			name:    "too long key",
+			values:  []string{strings.Repeat("k", maxTagKeyLength+1) + "=value"},
+			wantErr: true,
+		},
+		{
+			name:    "too long value",
+			values:  []string{"key=" + strings.Repeat("v", maxTagValueLength+1)},
+			wantErr: true,
+		},
+		{
+			name:    "too many tags",
+			values:  tooMany,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseTags(tc.values)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
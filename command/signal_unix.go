@@ -0,0 +1,14 @@
+// +build !windows
+
+package command
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyStatsSignal relays SIGUSR1 to ch to print the progress on demand.
+func notifyStatsSignal(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1)
+}
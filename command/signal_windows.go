@@ -0,0 +1,8 @@
+// +build windows
+
+package command
+
+import "os"
+
+// notifyStatsSignal does nothing since there is no SIGUSR1 on Windows.
+func notifyStatsSignal(chan<- os.Signal) {}
@@ -0,0 +1,678 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/error"
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/parallel"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+	"github.com/peak/s5cmd/strutil"
+)
+
+var syncHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] source destination
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	01. Sync local folder to S3 prefix
+		 > s5cmd {{.HelpName}} folder/ s3://bucket/prefix/
+
+	02. Sync S3 prefix to local folder
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/* folder/
+
+	03. Sync S3 prefix to another S3 prefix
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/* s3://target-bucket/prefix/
+
+	04. Sync S3 prefix to local folder and delete local files that are not in the source
+		 > s5cmd {{.HelpName}} --delete s3://bucket/prefix/* folder/
+
+	05. Sync local folder to S3 prefix by only comparing file sizes
+		 > s5cmd {{.HelpName}} --size-only folder/ s3://bucket/prefix/
+
+	06. Sync local folder to S3 prefix but exclude the files with txt extension
+		 > s5cmd {{.HelpName}} --exclude "*.txt" folder/ s3://bucket/prefix/
+
+	07. Preview the objects to be copied, updated and deleted by a sync without changing anything
+		 > s5cmd --dry-run {{.HelpName}} --delete s3://bucket/prefix/* folder/
+`
+
+// syncExcludedCopyFlags are the copy flags that either conflict with or are
+// superseded by the sync comparison logic.
+var syncExcludedCopyFlags = map[string]bool{
+	"no-clobber":      true,
+	"overwrite":       true,
+	"if-size-differ":  true,
+	"if-source-newer": true,
+	"flatten":         true,
+	"raw":             true,
+	// sync always walks the whole source.
+	"recursive": true,
+	// sizes of compressed objects differ from the files.
+	"compress":   true,
+	"decompress": true,
+	// sync compares the objects with the files instead.
+	"if-match":      true,
+	"if-none-match": true,
+	// a part of an object never matches the object.
+	"range": true,
+	// the filtered source objects would be deleted from the destination
+	// with --delete.
+	"min-size":   true,
+	"max-size":   true,
+	"newer-than": true,
+	"older-than": true,
+}
+
+func NewSyncCommandFlags() []cli.Flag {
+	flags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "delete",
+			Usage: "delete objects in destination but not in source",
+		},
+		&cli.BoolFlag{
+			Name:  "size-only",
+			Usage: "make size of object only criteria to decide whether an object should be synced",
+		},
+	}
+
+	for _, flag := range NewCopyCommandFlags() {
+		if syncExcludedCopyFlags[flag.Names()[0]] {
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+func NewSyncCommand() *cli.Command {
+	return &cli.Command{
+		Name:               "sync",
+		HelpName:           "sync",
+		Usage:              "sync objects",
+		Flags:              NewSyncCommandFlags(),
+		CustomHelpTemplate: syncHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateSyncCommand(c)
+			if err != nil {
+				printError(givenCommand(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			return NewSync(c).Run(c.Context)
+		},
+	}
+}
+
+// Sync holds sync operation flags and states.
+type Sync struct {
+	src         string
+	dst         string
+	op          string
+	fullCommand string
+
+	// flags
+	delete         bool
+	sizeOnly       bool
+	followSymlinks bool
+	dryRun         bool
+	exclude        []string
+	include        []string
+
+	// copy holds the copy settings used for transferring objects.
+	copy Copy
+}
+
+// NewSync creates Sync from cli.Context.
+func NewSync(c *cli.Context) Sync {
+	cp := NewCopy(c, false)
+	// objects are transferred and logged as regular copy operations.
+	cp.op = "cp"
+
+	// filter files are already validated.
+	exclude, include, _ := parseFilters(c)
+
+	return Sync{
+		src:         c.Args().Get(0),
+		dst:         c.Args().Get(1),
+		op:          c.Command.Name,
+		fullCommand: givenCommand(c),
+
+		// flags
+		delete:         c.Bool("delete"),
+		sizeOnly:       c.Bool("size-only"),
+		followSymlinks: !c.Bool("no-follow-symlinks"),
+		dryRun:         cp.storageOpts.DryRun,
+		exclude:        exclude,
+		include:        include,
+
+		copy: cp,
+	}
+}
+
+// Run compares the source and the destination, copies the objects that are
+// missing or changed on the destination and optionally deletes the objects
+// which don't exist on the source. In dry-run mode, the changes are reported
+// instead of being made.
+func (s Sync) Run(ctx context.Context) error {
+	srcurl, err := newSyncSourceURL(s.src)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	dsturl, err := url.New(s.dst)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	excludePatterns, err := createRegexFromWildcard(s.exclude)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	includePatterns, err := createRegexFromWildcard(s.include)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	dstObjects, dsturl, err := s.listDestination(ctx, dsturl, excludePatterns, includePatterns)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	srcClient, err := storage.NewClient(ctx, srcurl, s.copy.srcStorageOpts())
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	// the source is not listed any further once the manager is drained, the
+	// running transfers keep ctx.
+	listCtx, cancelList := parallel.WithDrain(ctx)
+	defer cancelList()
+
+	objch, err := expandSource(listCtx, srcClient, s.followSymlinks, srcurl)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	waiter := parallel.NewWaiter(ctx)
+
+	// create two different error objects instead of single object to avoid the
+	// data race for merror object, since there is a goroutine running,
+	// there might be a data race for a single error object.
+	var (
+		merrorWaiter  error // for the errors from waiter
+		merrorObjects error // for the errors from object channel
+		errDoneCh     = make(chan bool)
+	)
+
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			printError(s.fullCommand, s.op, err)
+			merrorWaiter = multierror.Append(merrorWaiter, err)
+		}
+	}()
+
+	s.copy.progressbar.Start()
+
+	var summary SyncSummaryMessage
+
+	for object := range objch {
+		if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
+			continue
+		}
+
+		if err := object.Err; err != nil {
+			// an empty source is synced like any other source. objects
+			// on the destination are subject to deletion.
+			if err == storage.ErrNoObjectFound {
+				continue
+			}
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(s.fullCommand, s.op, err)
+			continue
+		}
+
+		// the destination of a source object is never deleted, even if
+		// the object is not synced.
+		key := syncKey(object.URL)
+		dstObject, ok := dstObjects[key]
+		delete(dstObjects, key)
+
+		if object.StorageClass.IsGlacier() && !s.copy.forceGlacierTransfer {
+			err := fmt.Errorf("object '%v' is on Glacier storage", object)
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(s.fullCommand, s.op, err)
+			continue
+		}
+
+		if isURLExcluded(excludePatterns, includePatterns, object.URL.Path, srcurl.Prefix) {
+			continue
+		}
+
+		change, reason := syncChangeNew, ""
+		if ok {
+			reason = syncReason(object, dstObject, s.sizeOnly)
+			if reason == "" {
+				continue
+			}
+			change = syncChangeChanged
+		}
+
+		srcurl := object.URL
+
+		if s.dryRun {
+			summary.add(change)
+			log.Info(SyncMessage{
+				Change:      change,
+				Reason:      reason,
+				Source:      srcurl,
+				Destination: dsturl.Join(srcurl.Relative()),
+			})
+			continue
+		}
+
+		var task parallel.Task
+
+		switch {
+		case srcurl.Type == dsturl.Type: // remote->remote
+			task = s.copy.prepareCopyTask(ctx, srcurl, dsturl, object.Size, true)
+		case srcurl.IsRemote(): // remote->local
+			task = s.copy.prepareDownloadTask(ctx, srcurl, dsturl, true)
+		case dsturl.IsRemote(): // local->remote
+			task = s.copy.prepareUploadTask(ctx, srcurl, dsturl, true)
+		default:
+			panic("unexpected src-dst pair")
+		}
+
+		parallel.Run(s.copy.trackProgress(task, object.Size), waiter)
+	}
+
+	waiter.Wait()
+	<-errDoneCh
+	s.copy.progressbar.Finish()
+
+	// the source may not be listed completely if there are errors or if the
+	// listing is stopped by an interrupt, so the destination objects which
+	// are not seen in the source are kept.
+	shouldDelete := s.delete && merrorObjects == nil && listCtx.Err() == nil
+
+	if s.dryRun {
+		if shouldDelete {
+			s.reportDeletes(dstObjects, &summary)
+		}
+		log.Info(summary)
+		return multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
+	}
+
+	var merrorDelete error
+	if shouldDelete && len(dstObjects) > 0 {
+		merrorDelete = s.deleteObjects(ctx, dsturl, dstObjects)
+	}
+
+	return multierror.Append(merrorWaiter, merrorObjects, merrorDelete).ErrorOrNil()
+}
+
+// listDestination returns the objects residing in the destination, keyed by
+// their paths relative to the destination. The returned URL is the
+// destination that objects are synced to.
+func (s Sync) listDestination(
+	ctx context.Context,
+	dsturl *url.URL,
+	excludePatterns []*regexp.Regexp,
+	includePatterns []*regexp.Regexp,
+) (map[string]*storage.Object, *url.URL, error) {
+	client, err := storage.NewClient(ctx, dsturl, s.copy.dstStorageOpts())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var listurl *url.URL
+	if dsturl.IsRemote() {
+		listurl, err = url.New(withWildcard(dsturl))
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		// make sure the relative paths of the local objects are calculated
+		// against the destination directory itself.
+		dir := filepath.ToSlash(dsturl.Absolute())
+		if !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+
+		dsturl, err = url.New(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		obj, err := client.Stat(ctx, dsturl)
+		if err == storage.ErrGivenObjectNotFound {
+			return map[string]*storage.Object{}, dsturl, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if !obj.Type.IsDir() {
+			return nil, nil, fmt.Errorf("destination %q must be a directory", s.dst)
+		}
+		listurl = dsturl
+	}
+
+	objects := map[string]*storage.Object{}
+	for object := range client.List(ctx, listurl, s.followSymlinks) {
+		if object.Err == storage.ErrNoObjectFound {
+			continue
+		}
+
+		if err := object.Err; err != nil {
+			return nil, nil, err
+		}
+
+		if object.Type.IsDir() {
+			continue
+		}
+
+		if isURLExcluded(excludePatterns, includePatterns, object.URL.Path, listurl.Prefix) {
+			continue
+		}
+
+		objects[syncKey(object.URL)] = object
+	}
+
+	return objects, dsturl, nil
+}
+
+// reportDeletes reports the given destination objects to be deleted, sorted
+// by their keys.
+func (s Sync) reportDeletes(objects map[string]*storage.Object, summary *SyncSummaryMessage) {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		summary.add(syncChangeDelete)
+		log.Info(SyncMessage{
+			Change:      syncChangeDelete,
+			Destination: objects[key].URL,
+		})
+	}
+}
+
+// deleteObjects deletes the given destination objects.
+func (s Sync) deleteObjects(ctx context.Context, dsturl *url.URL, objects map[string]*storage.Object) error {
+	client, err := storage.NewClient(ctx, dsturl, s.copy.dstStorageOpts())
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	urlch := make(chan *url.URL)
+	go func() {
+		defer close(urlch)
+		for _, object := range objects {
+			urlch <- object.URL
+		}
+	}()
+
+	var merror error
+	for obj := range client.MultiDelete(ctx, urlch) {
+		if err := obj.Err; err != nil {
+			if errorpkg.IsCancelation(obj.Err) {
+				continue
+			}
+
+			merror = multierror.Append(merror, obj.Err)
+			printError(s.fullCommand, s.op, obj.Err)
+			continue
+		}
+
+		msg := log.InfoMessage{
+			Operation: "rm",
+			Source:    obj.URL,
+		}
+		log.Info(msg)
+		manifest.record(msg)
+	}
+
+	return merror
+}
+
+// syncReason returns the reason why the source object should be transferred
+// to the destination, e.g. "size 100->120", or an empty string if the
+// destination is up to date. If sizeOnly is set, objects are compared by their
+// sizes only. Otherwise an object is synced if sizes differ or the source is
+// newer than the destination.
+func syncReason(src, dst *storage.Object, sizeOnly bool) string {
+	if src.Size != dst.Size {
+		return fmt.Sprintf("size %d->%d", dst.Size, src.Size)
+	}
+
+	if sizeOnly {
+		return ""
+	}
+
+	if src.ModTime == nil || dst.ModTime == nil {
+		return "mtime unknown"
+	}
+
+	if !src.ModTime.After(*dst.ModTime) {
+		return ""
+	}
+	return fmt.Sprintf(
+		"mtime %v->%v",
+		dst.ModTime.UTC().Format(time.RFC3339),
+		src.ModTime.UTC().Format(time.RFC3339),
+	)
+}
+
+// syncKey returns the key that is used to match source and destination
+// objects.
+func syncKey(u *url.URL) string {
+	return filepath.ToSlash(u.Relative())
+}
+
+// newSyncSourceURL creates the source URL. S3 buckets and prefixes are
+// expanded to all objects under them.
+func newSyncSourceURL(src string) (*url.URL, error) {
+	srcurl, err := url.New(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if srcurl.IsBucket() || srcurl.IsPrefix() {
+		return url.New(withWildcard(srcurl))
+	}
+	return srcurl, nil
+}
+
+// withWildcard returns the string representation of given S3 bucket or
+// prefix which matches all objects under it.
+func withWildcard(u *url.URL) string {
+	s := u.Absolute()
+	if !strings.HasSuffix(s, "/") {
+		s += "/"
+	}
+	return s + "*"
+}
+
+const (
+	syncChangeNew     = "NEW"
+	syncChangeChanged = "CHANGED"
+	syncChangeDelete  = "DELETE"
+)
+
+// SyncMessage is the structure for logging a change that sync would make in
+// dry-run mode.
+type SyncMessage struct {
+	Change      string   `json:"change"`
+	Reason      string   `json:"reason,omitempty"`
+	Source      *url.URL `json:"source,omitempty"`
+	Destination *url.URL `json:"destination"`
+}
+
+// String returns the string representation of SyncMessage.
+func (s SyncMessage) String() string {
+	fields := []string{s.Change}
+	if s.Reason != "" {
+		fields = append(fields, s.Reason)
+	}
+	if s.Source != nil {
+		fields = append(fields, s.Source.String())
+	}
+	fields = append(fields, s.Destination.String())
+	return strings.Join(fields, " ")
+}
+
+// JSON returns the JSON representation of SyncMessage.
+func (s SyncMessage) JSON() string {
+	return strutil.JSON(s)
+}
+
+// SyncSummaryMessage is the structure for logging the number of changes that
+// sync would make in dry-run mode.
+type SyncSummaryMessage struct {
+	New     int `json:"new"`
+	Changed int `json:"changed"`
+	Delete  int `json:"delete"`
+}
+
+func (s *SyncSummaryMessage) add(change string) {
+	switch change {
+	case syncChangeNew:
+		s.New++
+	case syncChangeChanged:
+		s.Changed++
+	case syncChangeDelete:
+		s.Delete++
+	}
+}
+
+// String returns the string representation of SyncSummaryMessage.
+func (s SyncSummaryMessage) String() string {
+	return fmt.Sprintf("SUMMARY new %d, changed %d, delete %d", s.New, s.Changed, s.Delete)
+}
+
+// JSON returns the JSON representation of SyncSummaryMessage.
+func (s SyncSummaryMessage) JSON() string {
+	return strutil.JSON(s)
+}
+
+func validateSyncCommand(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return fmt.Errorf("expected source and destination arguments")
+	}
+
+	ctx := c.Context
+	src := c.Args().Get(0)
+	dst := c.Args().Get(1)
+
+	srcurl, err := newSyncSourceURL(src)
+	if err != nil {
+		return err
+	}
+
+	dsturl, err := url.New(dst)
+	if err != nil {
+		return err
+	}
+
+	// wildcard destination doesn't mean anything
+	if dsturl.IsWildcard() {
+		return fmt.Errorf("target %q can not contain glob characters", dst)
+	}
+
+	// the destination objects are compared by their relative paths to the
+	// source, which a template would rename.
+	if isDestinationTemplate(dsturl) {
+		return fmt.Errorf("destination templates can not be used with sync")
+	}
+
+	if err := validateStorageClass(c.String("storage-class")); err != nil {
+		return err
+	}
+
+	if err := validateEncryption(c.String("sse"), c.String("sse-kms-key-id")); err != nil {
+		return err
+	}
+
+	if _, err := parseMetadata(c.StringSlice("metadata")); err != nil {
+		return err
+	}
+
+	if err := validateMetadataRules(c, srcurl, dsturl); err != nil {
+		return err
+	}
+
+	if _, err := parseTags(c.StringSlice("tag")); err != nil {
+		return err
+	}
+
+	if err := validateMultipartThreshold(c.Int64("multipart-threshold")); err != nil {
+		return err
+	}
+
+	if err := validateObjectLock(c, dsturl); err != nil {
+		return err
+	}
+
+	if _, _, err := parseFilters(c); err != nil {
+		return err
+	}
+
+	if err := validateACL(c.String("acl")); err != nil {
+		return err
+	}
+
+	if dsturl.IsRemote() && !dsturl.IsPrefix() && !dsturl.IsBucket() {
+		return fmt.Errorf("target %q must be a bucket or a prefix", dsturl)
+	}
+
+	if !srcurl.IsRemote() && !dsturl.IsRemote() {
+		return fmt.Errorf("local->local sync operations are not permitted")
+	}
+
+	if srcurl.IsRemote() || srcurl.IsWildcard() {
+		return nil
+	}
+
+	obj, err := storage.NewLocalClient(NewStorageOpts(c)).Stat(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+
+	if !obj.Type.IsDir() {
+		return fmt.Errorf("source %q must be a directory or contain wildcard character", src)
+	}
+
+	return nil
+}
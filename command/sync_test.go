@@ -0,0 +1,79 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/storage"
+)
+
+func TestSyncReason(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	past := now.Add(-time.Minute)
+
+	testcases := []struct {
+		name     string
+		src      *storage.Object
+		dst      *storage.Object
+		sizeOnly bool
+		expected string
+	}{
+		{
+			name:     "size differs",
+			src:      &storage.Object{Size: 10, ModTime: &past},
+			dst:      &storage.Object{Size: 5, ModTime: &now},
+			expected: "size 5->10",
+		},
+		{
+			name:     "source is newer",
+			src:      &storage.Object{Size: 10, ModTime: &now},
+			dst:      &storage.Object{Size: 10, ModTime: &past},
+			expected: "mtime 2023-01-02T03:03:05Z->2023-01-02T03:04:05Z",
+		},
+		{
+			name:     "source is older",
+			src:      &storage.Object{Size: 10, ModTime: &past},
+			dst:      &storage.Object{Size: 10, ModTime: &now},
+			expected: "",
+		},
+		{
+			name:     "same modification time",
+			src:      &storage.Object{Size: 10, ModTime: &now},
+			dst:      &storage.Object{Size: 10, ModTime: &now},
+			expected: "",
+		},
+		{
+			name:     "source is newer with size only",
+			src:      &storage.Object{Size: 10, ModTime: &now},
+			dst:      &storage.Object{Size: 10, ModTime: &past},
+			sizeOnly: true,
+			expected: "",
+		},
+		{
+			name:     "size differs with size only",
+			src:      &storage.Object{Size: 10, ModTime: &past},
+			dst:      &storage.Object{Size: 5, ModTime: &now},
+			sizeOnly: true,
+			expected: "size 5->10",
+		},
+		{
+			name:     "missing modification time",
+			src:      &storage.Object{Size: 10},
+			dst:      &storage.Object{Size: 10, ModTime: &now},
+			expected: "mtime unknown",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := syncReason(tc.src, tc.dst, tc.sizeOnly); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
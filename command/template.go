@@ -0,0 +1,56 @@
+package command
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/peak/s5cmd/storage/url"
+)
+
+// The tokens of a destination template, which are replaced with the parts of
+// the key of each source object, e.g. for "s3://bucket/2023/01/file.txt":
+//
+//	{key}      2023/01/file.txt
+//	{dir}      2023/01
+//	{basename} file.txt
+const (
+	templateKey      = "{key}"
+	templateDir      = "{dir}"
+	templateBasename = "{basename}"
+)
+
+// isDestinationTemplate reports whether the destination has any token of a
+// template. The other characters in braces are kept as they are.
+func isDestinationTemplate(dsturl *url.URL) bool {
+	for _, token := range []string{templateKey, templateDir, templateBasename} {
+		if strings.Contains(dsturl.Path, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandDestinationTemplate returns the destination of the source object by
+// replacing the tokens of the destination template with its key. The keys of
+// the local files are their paths with forward slashes. An object without a
+// directory has an empty {dir}, and the separator after it is removed, so
+// that "{dir}/{basename}" is the basename only.
+func expandDestinationTemplate(dsturl, srcurl *url.URL) *url.URL {
+	key := strings.TrimPrefix(filepath.ToSlash(srcurl.Path), "/")
+
+	template := dsturl.Path
+	dir := path.Dir(key)
+	if dir == "." {
+		dir = ""
+		template = strings.Replace(template, templateDir+"/", "", -1)
+	}
+
+	expanded := dsturl.Clone()
+	expanded.Path = strings.NewReplacer(
+		templateKey, key,
+		templateDir, dir,
+		templateBasename, path.Base(key),
+	).Replace(template)
+	return expanded
+}
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peak/s5cmd/storage/url"
+)
+
+func TestExpandDestinationTemplate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		src      string
+		dst      string
+		expected string
+	}{
+		{
+			name:     "basename",
+			src:      "s3://src/2023/01/file.txt",
+			dst:      "s3://dst/archive/{basename}",
+			expected: "s3://dst/archive/file.txt",
+		},
+		{
+			name:     "dir",
+			src:      "s3://src/2023/01/file.txt",
+			dst:      "s3://dst/{dir}/latest.txt",
+			expected: "s3://dst/2023/01/latest.txt",
+		},
+		{
+			name:     "key",
+			src:      "s3://src/2023/01/file.txt",
+			dst:      "s3://dst/backup/{key}",
+			expected: "s3://dst/backup/2023/01/file.txt",
+		},
+		{
+			name:     "dir and basename",
+			src:      "s3://src/2023/01/file.txt",
+			dst:      "s3://dst/{dir}/2023-10-14/{basename}",
+			expected: "s3://dst/2023/01/2023-10-14/file.txt",
+		},
+		{
+			name:     "key without a directory",
+			src:      "s3://src/file.txt",
+			dst:      "s3://dst/{dir}/{basename}",
+			expected: "s3://dst/file.txt",
+		},
+		{
+			name:     "tokens in a name",
+			src:      "s3://src/logs/app.log",
+			dst:      "s3://dst/{dir}-{basename}.bak",
+			expected: "s3://dst/logs-app.log.bak",
+		},
+		{
+			name:     "other braces",
+			src:      "s3://src/a/b.txt",
+			dst:      "s3://dst/{date}/{basename}",
+			expected: "s3://dst/{date}/b.txt",
+		},
+		{
+			name:     "local source",
+			src:      "dir/nested/file.txt",
+			dst:      "s3://dst/{key}",
+			expected: "s3://dst/dir/nested/file.txt",
+		},
+		{
+			name:     "local destination",
+			src:      "s3://src/2023/01/file.txt",
+			dst:      "out/{dir}/{basename}",
+			expected: "out/2023/01/file.txt",
+		},
+	}
+
+	for _, tc := range testcases {
+		srcurl, err := url.New(tc.src)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		dsturl, err := url.New(tc.dst)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		assert.True(t, isDestinationTemplate(dsturl), tc.name)
+		assert.Equal(t, tc.expected, expandDestinationTemplate(dsturl, srcurl).String(), tc.name)
+	}
+}
+
+func TestIsDestinationTemplate(t *testing.T) {
+	t.Parallel()
+
+	for _, dst := range []string{"s3://bucket/prefix/", "s3://bucket/{a,b}/key", "dir/{name}"} {
+		dsturl, err := url.New(dst)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.False(t, isDestinationTemplate(dsturl), dst)
+	}
+}
+
+func TestPrepareRemoteDestinationTemplate(t *testing.T) {
+	t.Parallel()
+
+	dsturl, _ := url.New("s3://dst/archive/{basename}")
+
+	objurl, _ := url.New("s3://src/2023/01/file.txt")
+	objurl.SetRelative("s3://src/2023/*")
+
+	// the relative path of a batch is not joined to the templated destination.
+	for _, flatten := range []bool{false, true} {
+		got := prepareRemoteDestination(objurl, dsturl, flatten, true)
+		assert.Equal(t, "s3://dst/archive/file.txt", got.String())
+	}
+}
@@ -0,0 +1,183 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/error"
+	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/url"
+	"github.com/peak/s5cmd/strutil"
+)
+
+var versionListHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] argument
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. List the versions of an object
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/object
+
+	2. List the versions of all objects in a bucket
+		 > s5cmd {{.HelpName}} s3://bucket/*
+
+	3. Download a version of an object listed by {{.HelpName}}
+		 > s5cmd cp 's3://bucket/prefix/object?versionId=VERSION_ID' .
+
+	4. Delete a version of an object listed by {{.HelpName}}
+		 > s5cmd rm 's3://bucket/prefix/object?versionId=VERSION_ID'
+`
+
+func NewVersionListCommand() *cli.Command {
+	return &cli.Command{
+		Name:               "version-ls",
+		HelpName:           "version-ls",
+		Usage:              "list versions and delete markers of objects",
+		CustomHelpTemplate: versionListHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "humanize",
+				Aliases: []string{"H"},
+				Usage:   "human-readable output for object sizes",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			err := validateVersionListCommand(c)
+			if err != nil {
+				printError(givenCommand(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			return VersionList{
+				src:         c.Args().First(),
+				op:          c.Command.Name,
+				fullCommand: givenCommand(c),
+				// flags
+				humanize: c.Bool("humanize"),
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+}
+
+// VersionList holds version-ls operation flags and states.
+type VersionList struct {
+	src         string
+	op          string
+	fullCommand string
+
+	// flags
+	humanize bool
+
+	storageOpts storage.Options
+}
+
+// Run prints the versions of the objects at given source.
+func (v VersionList) Run(ctx context.Context) error {
+	srcurl, err := url.New(v.src)
+	if err != nil {
+		printError(v.fullCommand, v.op, err)
+		return err
+	}
+
+	client, err := storage.NewRemoteClient(ctx, srcurl, v.storageOpts)
+	if err != nil {
+		printError(v.fullCommand, v.op, err)
+		return err
+	}
+
+	var merror error
+
+	for object := range client.ListVersions(ctx, srcurl) {
+		if errorpkg.IsCancelation(object.Err) {
+			continue
+		}
+
+		if err := object.Err; err != nil {
+			merror = multierror.Append(merror, err)
+			printError(v.fullCommand, v.op, err)
+			continue
+		}
+
+		log.Info(VersionListMessage{
+			Object:        object,
+			showHumanized: v.humanize,
+		})
+	}
+
+	return merror
+}
+
+// VersionListMessage is a structure for logging version-ls results.
+type VersionListMessage struct {
+	Object *storage.Object `json:"object"`
+
+	showHumanized bool
+}
+
+// String returns the string representation of VersionListMessage.
+func (v VersionListMessage) String() string {
+	const versionListFormat = "%19s %12s %-32s %s"
+
+	if v.Object.Type.IsDir() {
+		return fmt.Sprintf(versionListFormat, "", "DIR", "", v.Object.URL.Relative())
+	}
+
+	size := "DELETED"
+	if !v.Object.IsDeleteMarker {
+		size = ListMessage{Object: v.Object, showHumanized: v.showHumanized}.humanize()
+	}
+
+	return fmt.Sprintf(
+		versionListFormat,
+		v.Object.ModTime.Format(dateFormat),
+		size,
+		v.Object.URL.VersionID,
+		v.Object.URL.Relative(),
+	)
+}
+
+// JSON returns the JSON representation of VersionListMessage.
+func (v VersionListMessage) JSON() string {
+	return strutil.JSON(struct {
+		*storage.Object
+		VersionID string `json:"version_id,omitempty"`
+	}{
+		Object:    v.Object,
+		VersionID: v.Object.URL.VersionID,
+	})
+}
+
+func validateVersionListCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only 1 argument")
+	}
+
+	srcurl, err := url.New(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("source must be a remote object, prefix or bucket")
+	}
+
+	if srcurl.VersionID != "" {
+		return fmt.Errorf("source can not have a version id")
+	}
+	return nil
+}
@@ -2,11 +2,21 @@ package e2e
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
 )
 
@@ -69,6 +79,489 @@ func TestAppRetryCount(t *testing.T) {
 	}
 }
 
+func TestAppRetryLogging(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		json     bool
+		expected []compareFunc
+	}{
+		{
+			name: "text",
+			expected: []compareFunc{
+				match(`^DEBUG retry 1/2 of ListObjectsV2 after (\S+) \((\S+) in total\): RequestError: send request failed .* \[RequestError\]$`),
+				match(`^DEBUG retry 2/2 of ListObjectsV2 after (\S+) \((\S+) in total\): RequestError: send request failed .* \[RequestError\]$`),
+			},
+		},
+		{
+			name: "json",
+			json: true,
+			expected: []compareFunc{
+				match(`^{"event":"retry","operation":"ListObjectsV2","attempt":1,"max_attempts":2,"delay":[0-9.e-]+,"total_delay":[0-9.e-]+,"code":"RequestError","error":"RequestError: send request failed .*"}$`),
+				match(`^{"event":"retry","operation":"ListObjectsV2","attempt":2,"max_attempts":2,"delay":[0-9.e-]+,"total_delay":[0-9.e-]+,"code":"RequestError","error":"RequestError: send request failed .*"}$`),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// nothing listens on the port, so the requests are retried.
+			_, s5cmd, cleanup := setup(t, withEndpointURL("http://127.0.0.1:1"))
+			defer cleanup()
+
+			args := []string{"--log", "debug", "--retry-count", "2"}
+			if tc.json {
+				args = append(args, "--json")
+			}
+			args = append(args, "--region", "us-east-1", "ls", "s3://bucket/")
+
+			cmd := s5cmd(args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			var retries []string
+			for _, line := range strings.Split(result.Stdout(), "\n") {
+				if strings.Contains(line, "retry") && strings.Contains(line, "ListObjectsV2") {
+					retries = append(retries, line)
+				}
+			}
+
+			assert.Equal(t, len(retries), len(tc.expected), result.Stdout())
+			for i, compare := range tc.expected {
+				assert.NilError(t, compare(retries[i]))
+			}
+		})
+	}
+}
+
+// --log-file manifest.log run commands.txt
+func TestAppLogFile(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	filesToContent := map[string]string{
+		"file1.txt": "content",
+		"file2.txt": "longer content",
+		"file3.txt": "the longest content",
+		"other.log": "other content",
+	}
+	for filename, content := range filesToContent {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	commands := fmt.Sprintf(`cp s3://%v/file1.txt s3://%v/copy/file1.txt
+cp "s3://%v/file*.txt" dir/
+rm s3://%v/other.log
+`, bucket, bucket, bucket, bucket)
+
+	workdir := fs.NewDir(t, "logfile", fs.WithFile("commands.txt", commands))
+	defer workdir.Remove()
+
+	manifest := workdir.Join("manifest.log")
+
+	cmd := s5cmd("--log-file", manifest, "run", workdir.Join("commands.txt"))
+	cmd.Dir = workdir.Path()
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	content, err := ioutil.ReadFile(manifest)
+	assert.NilError(t, err)
+
+	// the time of the operations is not known.
+	removeTime := regexp.MustCompile(`,"time":"[^"]+"`)
+	lines := removeTime.ReplaceAllString(string(content), "")
+
+	assertLines(t, lines, map[int]compareFunc{
+		0: equals(`{"operation":"cp","source":"s3://%v/file1.txt","destination":"dir/file1.txt","size":7}`, bucket),
+		1: equals(`{"operation":"cp","source":"s3://%v/file1.txt","destination":"s3://%v/copy/file1.txt","size":0}`, bucket, bucket),
+		2: equals(`{"operation":"cp","source":"s3://%v/file2.txt","destination":"dir/file2.txt","size":14}`, bucket),
+		3: equals(`{"operation":"cp","source":"s3://%v/file3.txt","destination":"dir/file3.txt","size":19}`, bucket),
+		4: equals(`{"operation":"rm","source":"s3://%v/other.log","size":0}`, bucket),
+	}, sortInput(true))
+
+	// the manifest is not printed.
+	assert.Assert(t, !strings.Contains(result.Stdout(), `"size"`))
+}
+
+// --resume manifest.log --log-file manifest.log cp s3://bucket/* dir/
+func TestAppResume(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	for _, filename := range []string{"file1.txt", "file2.txt", "file3.txt", "file4.txt"} {
+		putFile(t, s3client, bucket, filename, "content of "+filename)
+	}
+
+	workdir := fs.NewDir(t, "resume")
+	defer workdir.Remove()
+
+	manifest := workdir.Join("manifest.log")
+
+	// the interrupted run completes the first two objects.
+	cmd := s5cmd("--log-file", manifest, "cp", "s3://"+bucket+"/file[12].txt", "dir/")
+	cmd.Dir = workdir.Path()
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	// the completed operations are not repeated even if their
+	// destinations are gone.
+	assert.NilError(t, os.Remove(workdir.Join("dir", "file2.txt")))
+
+	cmd = s5cmd("--resume", manifest, "--log-file", manifest, "--stat", "cp", "s3://"+bucket+"/*", "dir/")
+	cmd.Dir = workdir.Path()
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	stdout := result.Stdout()
+	assert.Assert(t, !strings.Contains(stdout, "file1.txt"), stdout)
+	assert.Assert(t, !strings.Contains(stdout, "file2.txt"), stdout)
+	assert.Assert(t, strings.Contains(stdout, fmt.Sprintf("cp s3://%v/file3.txt dir/file3.txt", bucket)), stdout)
+	assert.Assert(t, strings.Contains(stdout, fmt.Sprintf("cp s3://%v/file4.txt dir/file4.txt", bucket)), stdout)
+	assert.Assert(t, strings.Contains(stdout, "Skipped: 2, "), stdout)
+
+	expected := fs.Expected(t,
+		fs.WithFile("manifest.log", "", fs.MatchAnyFileContent),
+		fs.WithDir("dir",
+			fs.WithFile("file1.txt", "content of file1.txt"),
+			fs.WithFile("file3.txt", "content of file3.txt"),
+			fs.WithFile("file4.txt", "content of file4.txt"),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	// the manifest has the operations of both runs.
+	content, err := ioutil.ReadFile(manifest)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(string(content), "\n"), 4)
+}
+
+// --log-file manifest.log --dry-run cp s3://bucket/file.txt dir/
+func TestAppLogFileDryRun(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, "logfile")
+	defer workdir.Remove()
+
+	manifest := workdir.Join("manifest.log")
+
+	cmd := s5cmd("--log-file", manifest, "--dry-run", "cp", "s3://"+bucket+"/file.txt", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	_, err := os.Stat(manifest)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+// --no-sign-request ls|cp|cat|head s3://bucket/object
+func TestAppNoSignRequest(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.txt"
+		content  = "this is a file content"
+	)
+
+	src := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected compareFunc
+	}{
+		{
+			name:     "ls",
+			args:     []string{"ls", "s3://" + bucket + "/"},
+			expected: suffix(filename),
+		},
+		{
+			name:     "cp",
+			args:     []string{"cp", src, "."},
+			expected: equals("cp %v %v", src, filename),
+		},
+		{
+			name:     "cat",
+			args:     []string{"cat", src},
+			expected: equals(content),
+		},
+		{
+			name:     "head",
+			args:     []string{"head", src},
+			expected: contains(filename),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+			putFile(t, s3client, bucket, filename, content)
+
+			cmd := s5cmd(append([]string{"--no-sign-request"}, tc.args...)...)
+
+			// requests can not be signed without credentials, so the command
+			// fails unless they are sent anonymously.
+			cmd.Env = []string{
+				"HOME=" + cmd.Dir,
+				"AWS_SHARED_CREDENTIALS_FILE=" + filepath.Join(cmd.Dir, "credentials"),
+				"AWS_CONFIG_FILE=" + filepath.Join(cmd.Dir, "config"),
+				"AWS_EC2_METADATA_DISABLED=true",
+			}
+
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: tc.expected,
+			}, strictLineCheck(false))
+		})
+	}
+}
+
+func TestAppNegativeOperationTimeout(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("--op-timeout", "-1s")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR operation timeout cannot be a negative value`),
+	})
+}
+
+func TestAppNegativeRequestRate(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("--request-rate", "-1")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR request rate cannot be a negative value`),
+	})
+}
+
+func TestAppNegativeMaxMemory(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("--max-memory", "-1")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR max memory cannot be a negative value`),
+	})
+}
+
+func TestAppInvalidNumWorkers(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("--numworkers", "many")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR numworkers must be a number or "auto", got "many"`),
+	})
+}
+
+func TestAppAutoNumWorkers(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	const bucket = "bucket"
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	cmd := s5cmd("--log", "debug", "--numworkers", "auto", "ls", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(`^DEBUG numworkers is set to \d+ for \d+ CPUs$`),
+		1: suffix("7 file.txt"),
+	})
+}
+
+func TestAppNegativeConnectionOptions(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		expected string
+	}{
+		{
+			name:     "max idle conns",
+			flags:    []string{"--max-idle-conns", "-1"},
+			expected: `ERROR idle connection count cannot be a negative value`,
+		},
+		{
+			name:     "max idle conns per host",
+			flags:    []string{"--max-idle-conns-per-host", "-1"},
+			expected: `ERROR idle connection count cannot be a negative value`,
+		},
+		{
+			name:     "dial timeout",
+			flags:    []string{"--dial-timeout", "-1s"},
+			expected: `ERROR connection timeout cannot be a negative value`,
+		},
+		{
+			name:     "tls handshake timeout",
+			flags:    []string{"--tls-handshake-timeout", "-1s"},
+			expected: `ERROR connection timeout cannot be a negative value`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.flags...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestAppOperationTimeoutExceeded(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, bucket)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/file.txt", bucket)
+
+	cmd := s5cmd("--op-timeout", "1ns", "cp", src, ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`context deadline exceeded`),
+	})
+}
+
+// --op-timeout 1s cp s3://bucket/object s3://bucket/copy with a hung copy
+func TestAppOperationTimeoutExceededServerSideCopy(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, _, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	backend, err := neturl.Parse(s3client.Endpoint)
+	assert.NilError(t, err)
+
+	// the copy requests never complete, unless they are canceled.
+	proxy := httputil.NewSingleHostReverseProxy(backend)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Minute):
+			}
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	_, s5cmd, cleanupProxy := setup(t, withEndpointURL(server.URL))
+	defer cleanupProxy()
+
+	src := fmt.Sprintf("s3://%v/file.txt", bucket)
+	dst := fmt.Sprintf("s3://%v/copy.txt", bucket)
+
+	cmd := s5cmd("--op-timeout", "1s", "cp", src, dst)
+	start := time.Now()
+	result := icmd.RunCmd(cmd, icmd.WithTimeout(30*time.Second))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`context deadline exceeded`),
+	})
+
+	if elapsed := time.Since(start); elapsed > 20*time.Second {
+		t.Errorf("expected the copy to time out after a second, took %v", elapsed)
+	}
+}
+
 func TestAppDashStat(t *testing.T) {
 	t.Parallel()
 
@@ -86,6 +579,195 @@ func TestAppDashStat(t *testing.T) {
 	assert.Assert(t, strings.Contains(out, tsv))
 }
 
+func TestAppDashStatTransferredBytes(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile("file.txt", content))
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--stat", "cp", "file.txt", dst)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	out := result.Stdout()
+
+	expected := fmt.Sprintf("Transferred %d in ", len(content))
+	assert.Assert(t, strings.Contains(out, expected), out)
+
+	expected = fmt.Sprintf("  upload: %d in ", len(content))
+	assert.Assert(t, strings.Contains(out, expected), out)
+	assert.Assert(t, !strings.Contains(out, "  download: "), out)
+
+	expected = "Operations: 1, Succeeded: 1, Failed: 0, Retried: 0, Skipped: 0, Elapsed: "
+	assert.Assert(t, strings.Contains(out, expected), out)
+}
+
+func TestAppDashStatTransferredBytesJSON(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+	putFile(t, s3client, bucket, "file.txt", content)
+
+	workdir := fs.NewDir(t, bucket)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/file.txt", bucket)
+
+	cmd := s5cmd("--json", "--stat", "cp", src, ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: prefix(`{"operation":"cp","success":true,"source":"%v"`, src),
+		1: equals(`{"operation":"cp","success":1,"error":0}`),
+		2: match(fmt.Sprintf(`^{"bytes":%d,"elapsed_seconds":[0-9.e-]+,"throughput":[0-9]+,"operations":1,"succeeded":1,"failed":0,"retried":0,"skipped":0,"transfers":\[{"type":"download","bytes":%d,"elapsed_seconds":[0-9.e-]+,"throughput":[0-9]+}\]}$`, len(content), len(content))),
+	})
+}
+
+func TestAppDashStatErrorCodes(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	input := strings.NewReader(
+		strings.Join([]string{
+			fmt.Sprintf("cp s3://%v/file.txt s3://%v/copy.txt", bucket, bucket),
+			fmt.Sprintf("cp s3://%v/missing1.txt s3://%v/copy1.txt", bucket, bucket),
+			fmt.Sprintf("cp s3://%v/missing2.txt s3://%v/copy2.txt", bucket, bucket),
+		}, "\n"),
+	)
+	cmd := s5cmd("--stat", "run")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	out := result.Stdout()
+
+	expected := "Operations: 3, Succeeded: 1, Failed: 2, "
+	assert.Assert(t, strings.Contains(out, expected), out)
+
+	expected = "Errors: NoSuchKey: 2\n"
+	assert.Assert(t, strings.Contains(out, expected), out)
+}
+
+func TestAppQuiet(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, bucket)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/file.txt", bucket)
+	missing := fmt.Sprintf("s3://%v/missing.txt", bucket)
+
+	cmd := s5cmd("--quiet", "--stat", "cp", src, ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	// only the statistics are printed.
+	out := result.Stdout()
+	assert.Assert(t, !strings.Contains(out, "cp "+src), out)
+	assert.Assert(t, strings.Contains(out, "Transferred 7 in "), out)
+
+	cmd = s5cmd("-q", "cp", missing, ".")
+	result = icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`ERROR "cp %v missing.txt"`, missing),
+	})
+}
+
+func TestAppOnlyShowErrorsJSON(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	workdir := fs.NewDir(t, bucket)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/file.txt", bucket)
+	missing := fmt.Sprintf("s3://%v/missing.txt", bucket)
+
+	commands := fmt.Sprintf("cp %v .\ncp %v .", src, missing)
+	cmd := s5cmd("--only-show-errors", "--json", "--stat", "run", "-c", commands)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	// the successful copy is not printed, only the statistics.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`{"operation":"cp","success":1,"error":1}`),
+		1: match(`^{"bytes":7,.*"succeeded":1,"failed":1,.*}$`),
+	})
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`{"operation":"cp","command":"cp %v missing.txt","error":"NoSuchKey:`, missing),
+	})
+}
+
+func TestAppTraceLogLevel(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	cmd := s5cmd("--log", "trace", "ls", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	out := result.Stdout()
+	assert.Assert(t, strings.Contains(out, "TRACE DEBUG: Request s3/ListObjectsV2"), out)
+	assert.Assert(t, strings.Contains(out, "file.txt"), out)
+}
+
 func TestAppUnknownCommand(t *testing.T) {
 	t.Parallel()
 
@@ -101,3 +783,109 @@ func TestAppUnknownCommand(t *testing.T) {
 		0: equals(`ERROR "unknown-command": command not found`),
 	})
 }
+
+func TestAppNegativeStatsInterval(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("--stats-interval", "-1s")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR stats interval cannot be a negative value`),
+	})
+}
+
+func TestAppStatsInterval(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	for _, filename := range []string{"a.txt", "b.txt", "c.txt"} {
+		putFile(t, s3client, bucket, filename, "content")
+	}
+
+	cmd := s5cmd(
+		"--json",
+		"--request-rate", "1",
+		"--stats-interval", "100ms",
+		"cp",
+		"s3://"+bucket+"/*",
+		".",
+	)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the progress reports must not be mixed with the output of the command.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains(`"operation":"cp"`),
+		1: contains(`"operation":"cp"`),
+		2: contains(`"operation":"cp"`),
+	}, jsonCheck(true))
+
+	stderr := strings.Split(strings.TrimSpace(result.Stderr()), "\n")
+	if len(stderr) == 0 || stderr[0] == "" {
+		t.Fatalf("expected progress reports in stderr, got none")
+	}
+	for _, line := range stderr {
+		assert.Assert(t, regexp.MustCompile(`^{"progress":{"workers":\d+,"active":\d+,"queued":\d+,"completed":\d+,"failed":0}}$`).MatchString(line), line)
+	}
+}
+
+func TestAppNegativeRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("--retry-budget", "-0.1")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR retry budget cannot be a negative value`),
+	})
+}
+
+func TestAppInvalidRequestPayer(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("--request-payer", "owner")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR request payer must be "requester", got "owner"`),
+	})
+}
+
+func TestAppAccelerateEndpointWithEndpointURL(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	// the endpoint of the test server is given with --endpoint-url.
+	cmd := s5cmd("--use-accelerate-endpoint")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR --use-accelerate-endpoint can not be used with --endpoint-url`),
+	})
+}
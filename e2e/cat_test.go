@@ -93,7 +93,7 @@ func TestCatS3ObjectFail(t *testing.T) {
 				src,
 			},
 			expected: map[int]compareFunc{
-				0: contains(`ERROR "cat s3://bucket/prefix/file.txt": NoSuchKey: status code: 404`),
+				0: match(`^ERROR "cat s3://bucket/prefix/file.txt": NoSuchKey: status code: 404, .* \[NoSuchKey\]$`),
 			},
 		},
 		{
@@ -104,7 +104,7 @@ func TestCatS3ObjectFail(t *testing.T) {
 				src,
 			},
 			expected: map[int]compareFunc{
-				0: contains(`{"operation":"cat","command":"cat s3://bucket/prefix/file.txt","error":"NoSuchKey: status code: 404,`),
+				0: match(`^{"operation":"cat","command":"cat s3://bucket/prefix/file.txt","error":"NoSuchKey: status code: 404, .*","code":"NoSuchKey"}$`),
 			},
 			assertOps: []assertOp{
 				jsonCheck(true),
@@ -221,3 +221,26 @@ func getSequentialFileContent() (string, map[int]compareFunc) {
 
 	return sb.String(), expectedLines
 }
+
+func TestCatS3ObjectOperationTimeoutExceeded(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	src := fmt.Sprintf("s3://%v/file.txt", bucket)
+
+	cmd := s5cmd("--op-timeout", "1ns", "cat", src)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`context deadline exceeded`),
+	})
+}
@@ -0,0 +1,121 @@
+package e2e
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/icmd"
+)
+
+func TestCheckBucket(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("check", "s3://"+bucket+"/prefix/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`OK head-bucket s3://%v`, bucket),
+		1: match(fmt.Sprintf(`^OK put s3://%v/prefix/\.s5cmd-check-[0-9]+$`, bucket)),
+		2: match(fmt.Sprintf(`^OK get s3://%v/prefix/\.s5cmd-check-[0-9]+$`, bucket)),
+		3: match(fmt.Sprintf(`^OK delete s3://%v/prefix/\.s5cmd-check-[0-9]+$`, bucket)),
+	})
+
+	// the object of the check is deleted.
+	out, err := s3client.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(bucket)})
+	assert.NilError(t, err)
+	assert.Equal(t, len(out.Contents), 0)
+}
+
+func TestCheckBucketDryRun(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("--dry-run", "check", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`OK head-bucket s3://%v`, bucket),
+		1: match(fmt.Sprintf(`^SKIPPED put s3://%v/\.s5cmd-check-[0-9]+$`, bucket)),
+		2: match(fmt.Sprintf(`^SKIPPED get s3://%v/\.s5cmd-check-[0-9]+$`, bucket)),
+		3: match(fmt.Sprintf(`^SKIPPED delete s3://%v/\.s5cmd-check-[0-9]+$`, bucket)),
+	})
+}
+
+// the region of a bucket is looked up before the checks, so a missing bucket
+// fails like the other commands.
+func TestCheckNonExistingBucket(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("check", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`ERROR "check s3://%v": NotFound: Not Found`, bucket),
+	})
+}
+
+func TestCheckBucketJSON(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("--json", "check", "s3://"+bucket)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`{"check":"head-bucket","url":"s3://%v","status":"ok"}`, bucket),
+		1: match(fmt.Sprintf(`^{"check":"put","url":"s3://%v/\.s5cmd-check-[0-9]+","status":"ok"}$`, bucket)),
+		2: match(fmt.Sprintf(`^{"check":"get","url":"s3://%v/\.s5cmd-check-[0-9]+","status":"ok"}$`, bucket)),
+		3: match(fmt.Sprintf(`^{"check":"delete","url":"s3://%v/\.s5cmd-check-[0-9]+","status":"ok"}$`, bucket)),
+	})
+}
+
+func TestCheckInvalidTarget(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("check", "s3://bucket/object")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "check s3://bucket/object": target "s3://bucket/object" must be a bucket or a prefix`),
+	})
+}
@@ -23,13 +23,26 @@
 package e2e
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
 	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	neturl "net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
@@ -233,6 +246,238 @@ func TestCopyS3PrefixToLocalMustReturnError(t *testing.T) {
 	assert.Assert(t, ensureS3Object(s3client, bucket, objectpath, content))
 }
 
+// cp --recursive s3://bucket/prefix dir/
+func TestCopyS3ObjectsToS3WithDestinationTemplate(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	contents := map[string]string{
+		"2023/01/a.txt": "content a",
+		"2023/02/b.txt": "content b",
+		"2023/c.txt":    "content c",
+	}
+	for key, content := range contents {
+		putFile(t, s3client, bucket, key, content)
+	}
+
+	src := fmt.Sprintf("s3://%v/2023/*", bucket)
+
+	testcases := []struct {
+		name     string
+		dst      string
+		expected map[string]string
+	}{
+		{
+			name: "basename",
+			dst:  "archive/{basename}",
+			expected: map[string]string{
+				"2023/01/a.txt": "archive/a.txt",
+				"2023/02/b.txt": "archive/b.txt",
+				"2023/c.txt":    "archive/c.txt",
+			},
+		},
+		{
+			name: "key",
+			dst:  "backup/{key}",
+			expected: map[string]string{
+				"2023/01/a.txt": "backup/2023/01/a.txt",
+				"2023/02/b.txt": "backup/2023/02/b.txt",
+				"2023/c.txt":    "backup/2023/c.txt",
+			},
+		},
+		{
+			name: "dir",
+			dst:  "renamed/{dir}/2023-10-14-{basename}",
+			expected: map[string]string{
+				"2023/01/a.txt": "renamed/2023/01/2023-10-14-a.txt",
+				"2023/02/b.txt": "renamed/2023/02/2023-10-14-b.txt",
+				"2023/c.txt":    "renamed/2023/2023-10-14-c.txt",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dst := fmt.Sprintf("s3://%v/%v", bucket, tc.dst)
+
+			cmd := s5cmd("cp", src, dst)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals(`cp s3://%v/2023/01/a.txt s3://%v/%v`, bucket, bucket, tc.expected["2023/01/a.txt"]),
+				1: equals(`cp s3://%v/2023/02/b.txt s3://%v/%v`, bucket, bucket, tc.expected["2023/02/b.txt"]),
+				2: equals(`cp s3://%v/2023/c.txt s3://%v/%v`, bucket, bucket, tc.expected["2023/c.txt"]),
+			}, sortInput(true))
+
+			for key, dst := range tc.expected {
+				assert.Assert(t, ensureS3Object(s3client, bucket, dst, contents[key]))
+			}
+		})
+	}
+}
+
+func TestCopyS3ObjectsToLocalWithDestinationTemplate(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "logs/web/app.log", "content 1")
+	putFile(t, s3client, bucket, "logs/db/app.log", "content 2")
+
+	workdir := fs.NewDir(t, "template")
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/logs/*", bucket)
+
+	cmd := s5cmd("cp", src, "out/{basename}.{dir}")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/logs/db/app.log out/app.log.logs/db`, bucket),
+		1: equals(`cp s3://%v/logs/web/app.log out/app.log.logs/web`, bucket),
+	}, sortInput(true))
+
+	expected := fs.Expected(t, fs.WithDir(
+		"out",
+		fs.WithDir("app.log.logs", fs.WithFile("db", "content 2"), fs.WithFile("web", "content 1")),
+	))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+func TestCopyS3ObjectsToS3WithDestinationTemplateSameDestination(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "src/a/file.txt", "content a")
+	putFile(t, s3client, bucket, "src/b/file.txt", "content b")
+
+	src := fmt.Sprintf("s3://%v/src/*", bucket)
+	dst := fmt.Sprintf("s3://%v/dst/{basename}", bucket)
+
+	cmd := s5cmd("cp", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp %v %v": "s3://%v/src/a/file.txt" and "s3://%v/src/b/file.txt" have the same destination "s3://%v/dst/file.txt"`, src, dst, bucket, bucket, bucket),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "dst/file.txt", "content a"))
+}
+
+func TestCopyWithDestinationTemplateInvalidFlags(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "flatten",
+			args:     []string{"cp", "--flatten", "s3://bucket/*", "s3://bucket/dst/{basename}"},
+			expected: `ERROR "cp s3://bucket/* s3://bucket/dst/{basename}": --flatten can not be used with a destination template`,
+		},
+		{
+			name:     "sync",
+			args:     []string{"sync", "s3://bucket/*", "s3://bucket/dst/{dir}/"},
+			expected: `ERROR "sync s3://bucket/* s3://bucket/dst/{dir}/": destination templates can not be used with sync`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result := icmd.RunCmd(s5cmd(tc.args...))
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestCopyS3PrefixToLocalRecursive(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "prefix/file1.txt", "content 1")
+	putFile(t, s3client, bucket, "prefix/dir/file2.txt", "content 2")
+	putFile(t, s3client, bucket, "prefix-sibling/file3.txt", "content 3")
+	putFile(t, s3client, bucket, "prefixfile.txt", "content 4")
+
+	testcases := []struct {
+		name string
+		src  string
+	}{
+		{name: "prefix", src: "prefix"},
+		{name: "prefix with separator", src: "prefix/"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			workdir := fs.NewDir(t, "recursive")
+			defer workdir.Remove()
+
+			src := fmt.Sprintf("s3://%v/%v", bucket, tc.src)
+
+			cmd := s5cmd("cp", "--recursive", src, "dir/")
+			result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals(`cp s3://%v/prefix/dir/file2.txt dir/dir/file2.txt`, bucket),
+				1: equals(`cp s3://%v/prefix/file1.txt dir/file1.txt`, bucket),
+			}, sortInput(true))
+
+			// the objects of prefix-sibling/ and prefixfile.txt are not
+			// under prefix/.
+			expected := fs.Expected(t, fs.WithDir(
+				"dir",
+				fs.WithFile("file1.txt", "content 1"),
+				fs.WithDir("dir", fs.WithFile("file2.txt", "content 2")),
+			))
+			assert.Assert(t, fs.Equal(workdir.Path(), expected))
+		})
+	}
+}
+
 // cp --flatten s3://bucket/* dir/ (flat source hiearchy)
 func TestCopyMultipleFlatS3ObjectsToLocal(t *testing.T) {
 	t.Parallel()
@@ -284,6 +529,52 @@ func TestCopyMultipleFlatS3ObjectsToLocal(t *testing.T) {
 	}
 }
 
+// cp --flatten s3://bucket/* dir/ (objects with the same name)
+func TestCopyMultipleFlatS3ObjectsWithSameNameToLocal(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	filesToContent := map[string]string{
+		"a/file.txt":   "this is the file in a",
+		"b/file.txt":   "this is the file in b",
+		"b/readme.md":  "this is a readme file",
+		"c/d/file.txt": "this is the file in c/d",
+	}
+
+	for filename, content := range filesToContent {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("cp", "--flatten", "s3://"+bucket+"/*", ".")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/a/file.txt file.txt`, bucket),
+		1: equals(`cp s3://%v/b/readme.md readme.md`, bucket),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp s3://%v/* .": "s3://%v/a/file.txt" and "s3://%v/b/file.txt" have the same name "file.txt" in the flattened destination`, bucket, bucket, bucket),
+		1: equals(`ERROR "cp s3://%v/* .": "s3://%v/a/file.txt" and "s3://%v/c/d/file.txt" have the same name "file.txt" in the flattened destination`, bucket, bucket, bucket),
+	}, sortInput(true))
+
+	// assert local filesystem
+	// the first object is not overwritten by the others with the same name.
+	expected := fs.Expected(t,
+		fs.WithFile("file.txt", "this is the file in a"),
+		fs.WithFile("readme.md", "this is a readme file"),
+	)
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
 // cp --flatten s3://bucket/*.txt dir/
 func TestCopyMultipleFlatS3ObjectsToLocalWithPartialMatching(t *testing.T) {
 	t.Parallel()
@@ -653,6 +944,95 @@ func TestCopyMultipleS3ObjectsToGivenLocalDirectory(t *testing.T) {
 	}
 }
 
+// cp s3://bucket/logs/{2021,2022,2022*}/*.{log,json} dir/
+func TestCopyS3ObjectsWithBracesToLocal(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	filesToContent := map[string]string{
+		"logs/2020/a.log":     "2020",
+		"logs/2021/a.log":     "2021",
+		"logs/2021/x/b.log":   "2021 nested",
+		"logs/2022/c.log":     "2022",
+		"logs/2022/c.txt":     "2022 text",
+		"logs/2022-01/d.log":  "2022-01",
+		"logs/{2021}/e.log":   "literal braces",
+		"logs/2022-02/f.json": "2022-02",
+	}
+
+	for filename, content := range filesToContent {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	// the 2022 logs are matched by two of the expansions, but they are copied
+	// once.
+	const dst = "dir"
+	cmd := s5cmd("cp", "s3://"+bucket+"/logs/{2021,2022,2022*}/*.{log,json}", dst+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/logs/2021/a.log %v/2021/a.log`, bucket, dst),
+		1: equals(`cp s3://%v/logs/2021/x/b.log %v/2021/x/b.log`, bucket, dst),
+		2: equals(`cp s3://%v/logs/2022-01/d.log %v/2022-01/d.log`, bucket, dst),
+		3: equals(`cp s3://%v/logs/2022-02/f.json %v/2022-02/f.json`, bucket, dst),
+		4: equals(`cp s3://%v/logs/2022/c.log %v/2022/c.log`, bucket, dst),
+	}, sortInput(true))
+
+	expected := fs.Expected(t, fs.WithDir(dst,
+		fs.WithDir("2021",
+			fs.WithFile("a.log", "2021"),
+			fs.WithDir("x", fs.WithFile("b.log", "2021 nested")),
+		),
+		fs.WithDir("2022", fs.WithFile("c.log", "2022")),
+		fs.WithDir("2022-01", fs.WithFile("d.log", "2022-01")),
+		fs.WithDir("2022-02", fs.WithFile("f.json", "2022-02")),
+	))
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+// cp dir/{a,b}/*.txt s3://bucket/
+func TestCopyLocalFilesWithBracesToS3(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithDir("a", fs.WithFile("1.txt", "a1")),
+		fs.WithDir("b", fs.WithFile("2.txt", "b2"), fs.WithFile("3.log", "b3")),
+		fs.WithDir("c", fs.WithFile("4.txt", "c4")),
+	)
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+	cmd := s5cmd("cp", srcpath+"/{a,b}/*.txt", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a/1.txt s3://%v/a/1.txt`, srcpath, bucket),
+		1: equals(`cp %v/b/2.txt s3://%v/b/2.txt`, srcpath, bucket),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a/1.txt", "a1"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "b/2.txt", "b2"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "b/3.log", "b3") != nil)
+	assert.Assert(t, ensureS3Object(s3client, bucket, "c/4.txt", "c4") != nil)
+}
+
 // cp dir/file s3://bucket/
 func TestCopySingleFileToS3(t *testing.T) {
 	t.Parallel()
@@ -808,11 +1188,8 @@ func TestCopyDirToS3(t *testing.T) {
 	assert.Assert(t, ensureS3Object(s3client, bucket, "c/file2.txt", "this is the second test file"))
 }
 
-// cp dir/{file, folderWithBackslash} s3://bucket
-func TestCopyDirBackslashedToS3(t *testing.T) {
-	if runtime.GOOS == "windows" {
-		t.Skip()
-	}
+// cp --metadata-from rules.txt --metadata key=value dir/ s3://bucket/
+func TestCopyDirToS3WithMetadataFrom(t *testing.T) {
 	t.Parallel()
 
 	bucket := s3BucketFromTestName(t)
@@ -823,39 +1200,56 @@ func TestCopyDirBackslashedToS3(t *testing.T) {
 	createBucket(t, s3client, bucket)
 
 	folderLayout := []fs.PathOp{
-		fs.WithFile("readme.md", `¯\_(ツ)_/¯`),
+		fs.WithFile("index.html", "<html></html>"),
 		fs.WithDir(
-			"t\\est",
-			fs.WithFile("filetest.txt", "try reaching me on windows :-)"),
+			"assets",
+			fs.WithFile("app.js", "console.log()"),
 		),
 	}
+
 	workdir := fs.NewDir(t, t.Name(), folderLayout...)
 	defer workdir.Remove()
-	srcpath := workdir.Path()
+
+	rules := fs.NewFile(t, "rules", fs.WithContent(`assets/* => x-amz-meta-cache: long
+* => x-amz-meta-cache: short
+* => x-amz-meta-owner: web
+`))
+	defer rules.Remove()
+
 	dstpath := fmt.Sprintf("s3://%v/", bucket)
 
-	cmd := s5cmd("cp", workdir.Path()+"/", dstpath)
+	cmd := s5cmd("cp", "--metadata-from", rules.Path(), "--metadata", "owner=ops", "--metadata", "team=site", workdir.Path()+"/", dstpath)
 	result := icmd.RunCmd(cmd)
 
 	result.Assert(t, icmd.Success)
 
-	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`cp %v/readme.md %vreadme.md`, srcpath, dstpath),
-		1: equals(`cp %v/t\est/filetest.txt %vt\est/filetest.txt`, srcpath, dstpath),
-	}, sortInput(true))
+	// the first matching rule wins, and replaces the metadata of the flags.
+	assert.Assert(t, ensureS3Object(s3client, bucket, "assets/app.js", "console.log()", ensureMetadata(map[string]string{"Cache": "long", "Owner": "ops", "Team": "site"})))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "index.html", "<html></html>", ensureMetadata(map[string]string{"Cache": "short", "Owner": "web", "Team": "site"})))
+}
 
-	// assert local filesystem
-	expected := fs.Expected(t, folderLayout...)
-	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+// cp --metadata-from rules.txt s3://bucket/object .
+func TestCopyS3ObjectToLocalWithMetadataFrom(t *testing.T) {
+	t.Parallel()
 
-	// assert s3
-	assert.Assert(t, ensureS3Object(s3client, bucket, "readme.md", `¯\_(ツ)_/¯`))
-	assert.Assert(t, ensureS3Object(s3client, bucket, "t\\est/filetest.txt", "try reaching me on windows :-)"))
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	rules := fs.NewFile(t, "rules", fs.WithContent("* => Cache-Control: no-cache\n"))
+	defer rules.Remove()
+
+	cmd := s5cmd("cp", "--metadata-from", rules.Path(), "s3://bucket/object", ".")
+	result := icmd.RunCmd(cmd)
 
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp s3://bucket/object .": --metadata-from can only be used for uploads`),
+	})
 }
 
-// cp --storage-class=GLACIER file s3://bucket/
-func TestCopySingleFileToS3WithStorageClassGlacier(t *testing.T) {
+// cp dir s3://bucket/prefix/ (deeply nested source hierarchy with empty dirs)
+func TestCopyNestedDirWithoutTrailingSlashToS3Prefix(t *testing.T) {
 	t.Parallel()
 
 	bucket := s3BucketFromTestName(t)
@@ -865,35 +1259,1276 @@ func TestCopySingleFileToS3WithStorageClassGlacier(t *testing.T) {
 
 	createBucket(t, s3client, bucket)
 
-	const (
-		// make sure that Put reads the file header, not the extension
+	folderLayout := []fs.PathOp{
+		fs.WithFile("file1.txt", "file1"),
+		fs.WithDir("empty"),
+		fs.WithDir(
+			"a",
+			fs.WithFile("file2.txt", "file2"),
+			fs.WithDir(
+				"b",
+				fs.WithFile("file3.txt", "file3"),
+				fs.WithDir("empty"),
+			),
+		),
+	}
+
+	workdir := fs.NewDir(t, t.Name(), folderLayout...)
+	defer workdir.Remove()
+	srcpath := filepath.ToSlash(workdir.Path())
+	dstpath := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("cp", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the source directory itself is copied under the prefix since it
+	// doesn't end with a slash. empty directories are ignored.
+	dirname := filepath.Base(srcpath)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a/b/file3.txt %v%v/a/b/file3.txt`, srcpath, dstpath, dirname),
+		1: equals(`cp %v/a/file2.txt %v%v/a/file2.txt`, srcpath, dstpath, dirname),
+		2: equals(`cp %v/file1.txt %v%v/file1.txt`, srcpath, dstpath, dirname),
+	}, sortInput(true))
+
+	// assert s3
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/"+dirname+"/file1.txt", "file1"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/"+dirname+"/a/file2.txt", "file2"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/"+dirname+"/a/b/file3.txt", "file3"))
+}
+
+// cp dir/{file, folderWithBackslash} s3://bucket
+func TestCopyDirBackslashedToS3(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	folderLayout := []fs.PathOp{
+		fs.WithFile("readme.md", `¯\_(ツ)_/¯`),
+		fs.WithDir(
+			"t\\est",
+			fs.WithFile("filetest.txt", "try reaching me on windows :-)"),
+		),
+	}
+	workdir := fs.NewDir(t, t.Name(), folderLayout...)
+	defer workdir.Remove()
+	srcpath := workdir.Path()
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", workdir.Path()+"/", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/readme.md %vreadme.md`, srcpath, dstpath),
+		1: equals(`cp %v/t\est/filetest.txt %vt\est/filetest.txt`, srcpath, dstpath),
+	}, sortInput(true))
+
+	// assert local filesystem
+	expected := fs.Expected(t, folderLayout...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	// assert s3
+	assert.Assert(t, ensureS3Object(s3client, bucket, "readme.md", `¯\_(ツ)_/¯`))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "t\\est/filetest.txt", "try reaching me on windows :-)"))
+
+}
+
+// cp --storage-class=GLACIER file s3://bucket/
+func TestCopySingleFileToS3WithStorageClassGlacier(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		// make sure that Put reads the file header, not the extension
 		filename             = "index.txt"
 		content              = "content"
 		expectedStorageClass = "GLACIER"
 	)
 
-	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := workdir.Join(filename)
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	srcpath = filepath.ToSlash(srcpath)
+	cmd := s5cmd("cp", "--storage-class=GLACIER", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`cp %v %v%v`, srcpath, dstpath, filename),
+	})
+
+	// assert local filesystem
+	expected := fs.Expected(t, fs.WithFile(filename, content))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureStorageClass(expectedStorageClass)))
+}
+
+// cp --storage-class=STANDARD_IA s3://bucket/object s3://bucket/object2
+func TestCopySingleS3ObjectToS3WithStorageClass(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename             = "testfile.txt"
+		dstfilename          = "copy_" + filename
+		content              = "content"
+		expectedStorageClass = "STANDARD_IA"
+	)
+
+	putFile(t, s3client, bucket, filename, content)
+
+	src := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	dst := fmt.Sprintf("s3://%v/%v", bucket, dstfilename)
+
+	cmd := s5cmd("cp", "--storage-class=STANDARD_IA", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v`, src, dst),
+	})
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+	assert.Assert(t, ensureS3Object(s3client, bucket, dstfilename, content, ensureStorageClass(expectedStorageClass)))
+}
+
+// cp --storage-class=GLACIER_IR file s3://bucket/
+func TestCopySingleFileToS3WithStorageClassUnknownToSDK(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename             = "testfile.txt"
+		content              = "content"
+		expectedStorageClass = "GLACIER_IR"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--storage-class=GLACIER_IR", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`cp %v %v%v`, srcpath, dstpath, filename),
+	})
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureStorageClass(expectedStorageClass)))
+}
+
+// cp "--storage-class=STANDARD IA" file s3://bucket/
+func TestCopySingleFileToS3WithInvalidStorageClass(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--storage-class=STANDARD IA", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp %v %v": invalid storage class "STANDARD IA"`, srcpath, dstpath),
+	})
+
+	// assert S3 object is not uploaded
+	err := ensureS3Object(s3client, bucket, filename, content)
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// cp --sse AES256 --sse-kms-key-id key file s3://bucket/
+func TestCopySingleFileToS3WithAES256AndKMSKeyMustReturnError(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--sse", "AES256", "--sse-kms-key-id", "key", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp %v %v": --sse-kms-key-id can not be used with AES256 encryption`, srcpath, dstpath),
+	})
+}
+
+// cp --content-type text/html --metadata owner=john --metadata env=prod file s3://bucket/
+func TestCopySingleFileToS3WithContentTypeAndMetadata(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "index.txt"
+		content  = "content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd(
+		"cp",
+		"--content-type", "text/html",
+		"--metadata", "owner=john",
+		"--metadata", "env=prod",
+		srcpath,
+		dstpath,
+	)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`cp %v %v%v`, srcpath, dstpath, filename),
+	})
+
+	// assert S3. content type of the object is not checked since s3 backend
+	// does not store it.
+	assert.Assert(t, ensureS3Object(
+		s3client,
+		bucket,
+		filename,
+		content,
+		ensureMetadata(map[string]string{
+			"Owner": "john",
+			"Env":   "prod",
+		}),
+	))
+}
+
+// cp --metadata key=value s3://bucket/object s3://bucket/
+// cp --compress file s3://bucket/
+func TestCopyFileToS3WithCompress(t *testing.T) {
+	t.Parallel()
+
+	random := make([]byte, 6*1024*1024)
+	rand.New(rand.NewSource(0)).Read(random)
+
+	testcases := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "single part",
+			content: strings.Repeat("this is a log line\n", 10000),
+		},
+		{
+			name:    "multipart",
+			content: string(random),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			const (
+				bucket   = "bucket"
+				filename = "file.log"
+			)
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+
+			workdir := fs.NewDir(t, bucket, fs.WithFile(filename, tc.content), fs.WithDir("download"))
+			defer workdir.Remove()
+
+			srcpath := filepath.ToSlash(workdir.Join(filename))
+			dstpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+			cmd := s5cmd("cp", "--compress", "--part-size", "5", srcpath, dstpath)
+			result := icmd.RunCmd(cmd)
+			result.Assert(t, icmd.Success)
+
+			// gofakes3 does not store Content-Encoding, so the object is
+			// downloaded as it is and decompressed here.
+			downloadpath := filepath.ToSlash(workdir.Join("download", filename))
+			cmd = s5cmd("cp", "--decompress", dstpath, downloadpath)
+			result = icmd.RunCmd(cmd)
+			result.Assert(t, icmd.Success)
+
+			compressed, err := ioutil.ReadFile(downloadpath)
+			assert.NilError(t, err)
+
+			gr, err := gzip.NewReader(bytes.NewReader(compressed))
+			assert.NilError(t, err)
+			got, err := ioutil.ReadAll(gr)
+			assert.NilError(t, err)
+			assert.Assert(t, string(got) == tc.content, "decompressed object differs from the uploaded file")
+		})
+	}
+}
+
+// --max-memory 10 cp --compress --part-size 5 dir/ s3://bucket/
+func TestCopyDirectoryToS3WithMaxMemory(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	// each compressed upload buffers up to two parts, so the memory of a
+	// single upload is available at a time.
+	content := strings.Repeat("0123456789", 1024)
+	files := []string{"file1.txt", "file2.txt", "file3.txt", "file4.txt"}
+
+	var ops []fs.PathOp
+	for _, filename := range files {
+		ops = append(ops, fs.WithFile(filename, content))
+	}
+	workdir := fs.NewDir(t, bucket, ops...)
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+
+	cmd := s5cmd("--max-memory", "10", "cp", "--compress", "--part-size", "5", srcpath+"/", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp %v/file1.txt s3://%v/file1.txt", srcpath, bucket),
+		1: equals("cp %v/file2.txt s3://%v/file2.txt", srcpath, bucket),
+		2: equals("cp %v/file3.txt s3://%v/file3.txt", srcpath, bucket),
+		3: equals("cp %v/file4.txt s3://%v/file4.txt", srcpath, bucket),
+	}, sortInput(true))
+
+	for _, filename := range files {
+		_, err := s3client.HeadObject(&s3.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(filename),
+		})
+		assert.NilError(t, err)
+	}
+}
+
+// cp --newer-than 7d --min-size 1KB dir/ s3://bucket/
+func TestCopyDirectoryToS3WithSizeAndDateFilters(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	large := strings.Repeat("0123456789", 200)
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	yesterday := time.Now().AddDate(0, 0, -1)
+
+	workdir := fs.NewDir(t, bucket,
+		fs.WithFile("old-large.txt", large, fs.WithTimestamps(lastMonth, lastMonth)),
+		fs.WithFile("recent-large.txt", large, fs.WithTimestamps(yesterday, yesterday)),
+		fs.WithFile("recent-small.txt", "small", fs.WithTimestamps(yesterday, yesterday)),
+	)
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+
+	cmd := s5cmd("cp", "--newer-than", "7d", "--min-size", "1KB", srcpath+"/", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp %v/recent-large.txt s3://%v/recent-large.txt", srcpath, bucket),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "recent-large.txt", large))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "old-large.txt", large) != nil)
+	assert.Assert(t, ensureS3Object(s3client, bucket, "recent-small.txt", "small") != nil)
+}
+
+// cp --older-than 30d s3://bucket/object dir/
+func TestCopySingleS3ObjectWithDateFilterFail(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("cp", "--older-than", "30d", "s3://bucket/object", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp s3://bucket/object dir/": size and date filters can only be used with wildcard or directory sources`),
+	})
+}
+
+func TestCopyCompressFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "compress download",
+			args:     []string{"cp", "--compress", "s3://bucket/file.log", "."},
+			expected: `ERROR "cp s3://bucket/file.log .": --compress can only be used for uploads`,
+		},
+		{
+			name:     "decompress upload",
+			args:     []string{"cp", "--decompress", "file.log", "s3://bucket/"},
+			expected: `ERROR "cp file.log s3://bucket/": --decompress can only be used for downloads`,
+		},
+		{
+			name:     "compress with check-md5",
+			args:     []string{"cp", "--compress", "--check-md5", "file.log", "s3://bucket/"},
+			expected: `ERROR "cp file.log s3://bucket/": --check-md5 can not be used with --compress`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestCopyETagPreconditionsFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "if match upload",
+			args:     []string{"cp", "--if-match", "etag", "file.log", "s3://bucket/"},
+			expected: `ERROR "cp file.log s3://bucket/": --if-match can only be used for downloads`,
+		},
+		{
+			name:     "if none match remote copy",
+			args:     []string{"cp", "--if-none-match", "etag", "s3://bucket/file.log", "s3://bucket/backup/"},
+			expected: `ERROR "cp s3://bucket/file.log s3://bucket/backup/": --if-none-match can only be used for downloads`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// --op-timeout 2s --rate-limit 1048576 cp s3://bucket/object .
+func TestCopyS3ObjectToLocalWritesPartialFile(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.bin"
+	)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, filename, strings.Repeat("0123456789", 512*1024))
+
+	workdir := fs.NewDir(t, bucket)
+	defer workdir.Remove()
+
+	// the download takes 5 seconds, so it is canceled while the file is
+	// being written.
+	cmd := s5cmd("--op-timeout", "2s", "--rate-limit", "1048576", "cp", "s3://"+bucket+"/"+filename, ".")
+	withWorkingDir(workdir)(&cmd)
+	result := icmd.StartCmd(cmd)
+
+	var files []os.FileInfo
+	for deadline := time.Now().Add(5 * time.Second); len(files) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		files, _ = ioutil.ReadDir(workdir.Path())
+	}
+
+	// only the partial file is written while downloading.
+	assert.Equal(t, len(files), 1)
+	assert.Assert(t, strings.HasPrefix(files[0].Name(), filename+"."), files[0].Name())
+	assert.Assert(t, strings.HasSuffix(files[0].Name(), ".partial"), files[0].Name())
+
+	result = icmd.WaitOnCmd(10*time.Second, result)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`context deadline exceeded`),
+	})
+
+	// the partial file is removed once the download is canceled.
+	assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t)))
+}
+
+func TestCopyStdinToS3(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name    string
+		flags   []string
+		key     string
+		content string
+	}{
+		{
+			name:    "single part",
+			key:     "file.txt",
+			content: "content",
+		},
+		{
+			name:    "content type",
+			flags:   []string{"--content-type", "application/sql"},
+			key:     "dump",
+			content: "select 1;",
+		},
+		{
+			// larger than a part, so it is uploaded in multiple parts.
+			name:    "multipart",
+			flags:   []string{"--part-size", "5"},
+			key:     "file.bin",
+			content: strings.Repeat("0123456789", 600*1024),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			const bucket = "bucket"
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+
+			dstpath := fmt.Sprintf("s3://%v/%v", bucket, tc.key)
+
+			args := append([]string{"cp"}, tc.flags...)
+			args = append(args, "-", dstpath)
+
+			cmd := s5cmd(args...)
+			result := icmd.RunCmd(cmd, icmd.WithStdin(strings.NewReader(tc.content)))
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals("cp - %v", dstpath),
+			})
+
+			// content type of the object is not checked since gofakes3 does
+			// not store it.
+			assert.Assert(t, ensureS3Object(s3client, bucket, tc.key, tc.content))
+		})
+	}
+}
+
+func TestCopyStdinFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "prefix destination",
+			args:     []string{"cp", "-", "s3://bucket/prefix/"},
+			expected: `ERROR "cp - s3://bucket/prefix/": target "s3://bucket/prefix/" must be an object when uploading standard input`,
+		},
+		{
+			name:     "local destination",
+			args:     []string{"cp", "-", "file.log"},
+			expected: `ERROR "cp - file.log": standard input can only be uploaded to a remote object`,
+		},
+		{
+			name:     "no clobber",
+			args:     []string{"cp", "-n", "-", "s3://bucket/file.log"},
+			expected: `ERROR "cp - s3://bucket/file.log": --no-clobber can not be used when uploading standard input`,
+		},
+		{
+			name:     "move",
+			args:     []string{"mv", "-", "s3://bucket/file.log"},
+			expected: `ERROR "mv - s3://bucket/file.log": standard input can not be moved`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestCopyContentHeadersToLocalFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "content disposition download",
+			args:     []string{"cp", "--content-disposition", "attachment", "s3://bucket/file.pdf", "."},
+			expected: `ERROR "cp s3://bucket/file.pdf .": --content-disposition can only be used for uploads and remote copies`,
+		},
+		{
+			name:     "content language download",
+			args:     []string{"cp", "--content-language", "en-US", "s3://bucket/file.pdf", "."},
+			expected: `ERROR "cp s3://bucket/file.pdf .": --content-language can only be used for uploads and remote copies`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestCopyS3ObjectToS3WithMetadata(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+
+	putFile(t, s3client, bucket, "src/file.txt", content)
+
+	src := fmt.Sprintf("s3://%v/src/file.txt", bucket)
+	dst := fmt.Sprintf("s3://%v/dst/", bucket)
+
+	cmd := s5cmd("cp", "--metadata", "owner=john", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %vfile.txt`, src, dst),
+	})
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(
+		s3client,
+		bucket,
+		"dst/file.txt",
+		content,
+		ensureMetadata(map[string]string{
+			"Owner": "john",
+		}),
+	))
+}
+
+// cp --metadata =value file s3://bucket/
+func TestCopySingleFileToS3WithInvalidMetadata(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name          string
+		metadata      string
+		expectedError string
+	}{
+		{
+			name:          "empty key",
+			metadata:      "=value",
+			expectedError: `metadata "=value" must have a non-empty key`,
+		},
+		{
+			name:          "no value",
+			metadata:      "key",
+			expectedError: `metadata "key" must be in key=value format`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			dstpath := "s3://bucket/"
+
+			cmd := s5cmd("cp", "--metadata", tc.metadata, "file.txt", dstpath)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(`ERROR "cp file.txt %v": %v`, dstpath, tc.expectedError),
+			})
+		})
+	}
+}
+
+// cp --expires tomorrow file.txt s3://bucket/
+func TestCopySingleFileToS3WithInvalidExpires(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	dstpath := "s3://bucket/"
+
+	cmd := s5cmd("cp", "--expires", "tomorrow", "file.txt", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp file.txt %v": invalid --expires value "tomorrow": must be a date, e.g. 2024-10-01T20:30:00Z or "Tue, 01 Oct 2024 20:30:00 GMT", or a duration, e.g. 7d`, dstpath),
+	})
+}
+
+// cp --multipart-threshold 5 --part-size 50 file s3://bucket/
+func TestCopySingleFileToS3WithMultipartThreshold(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const filename = "file.bin"
+
+	// larger than the threshold, smaller than the part size.
+	content := strings.Repeat("s5cmd", 6*1024*1024/5)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--multipart-threshold", "5", "--part-size", "50", filename, dstpath)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v%v`, filename, dstpath, filename),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+func TestCopySingleFileToS3WithInvalidMultipartThreshold(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	dstpath := "s3://bucket/"
+
+	cmd := s5cmd("cp", "--multipart-threshold", "1", "file.txt", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp file.txt %v": --multipart-threshold must be between 5 and 5120 MiB`, dstpath),
+	})
+}
+
+func TestCopySingleFileToS3WithObjectLock(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is a file content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--object-lock-mode", "GOVERNANCE", "--object-lock-retain-until", "30d", "--legal-hold", filename, dstpath)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v%v`, filename, dstpath, filename),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+func TestCopySingleFileToS3WithInvalidObjectLock(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "invalid mode",
+			args:     []string{"--object-lock-mode", "LEGAL", "--object-lock-retain-until", "30d", "file.txt", "s3://bucket/"},
+			expected: `ERROR "cp file.txt s3://bucket/": invalid --object-lock-mode value "LEGAL": must be GOVERNANCE or COMPLIANCE`,
+		},
+		{
+			name:     "mode without date",
+			args:     []string{"--object-lock-mode", "GOVERNANCE", "file.txt", "s3://bucket/"},
+			expected: `ERROR "cp file.txt s3://bucket/": --object-lock-mode can only be used with --object-lock-retain-until`,
+		},
+		{
+			name:     "date without mode",
+			args:     []string{"--object-lock-retain-until", "30d", "file.txt", "s3://bucket/"},
+			expected: `ERROR "cp file.txt s3://bucket/": --object-lock-retain-until can only be used with --object-lock-mode`,
+		},
+		{
+			name:     "date in the past",
+			args:     []string{"--object-lock-mode", "COMPLIANCE", "--object-lock-retain-until", "2020-01-01T00:00:00Z", "file.txt", "s3://bucket/"},
+			expected: `ERROR "cp file.txt s3://bucket/": invalid --object-lock-retain-until value "2020-01-01T00:00:00Z": must be in the future`,
+		},
+		{
+			name:     "legal hold on download",
+			args:     []string{"--legal-hold", "s3://bucket/file.txt", "."},
+			expected: `ERROR "cp s3://bucket/file.txt .": --legal-hold can only be used for uploads and remote copies`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := s5cmd(append([]string{"cp"}, tc.args...)...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestCopySingleFileToS3WithInvalidACL(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	dstpath := "s3://bucket/"
+
+	cmd := s5cmd("cp", "--acl", "public", "file.txt", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp file.txt %v": invalid acl "public"`, dstpath),
+	})
+}
+
+func TestCopySingleFileToS3WithInvalidTag(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	dstpath := "s3://bucket/"
+
+	cmd := s5cmd("cp", "--tag", "=prod", "file.txt", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp file.txt %v": %v`, dstpath, `tag "=prod" must have a non-empty key`),
+	})
+}
+
+// --rate-limit 1024 cp dir/* s3://bucket/
+func TestCopyMultipleFilesToS3WithRateLimit(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	content := strings.Repeat("0", 1024)
+
+	workdir := fs.NewDir(t, bucket,
+		fs.WithFile("file1.txt", content),
+		fs.WithFile("file2.txt", content),
+	)
 	defer workdir.Remove()
 
-	srcpath := workdir.Join(filename)
+	srcpath := filepath.ToSlash(workdir.Path())
 	dstpath := fmt.Sprintf("s3://%v/", bucket)
 
-	srcpath = filepath.ToSlash(srcpath)
-	cmd := s5cmd("cp", "--storage-class=GLACIER", srcpath, dstpath)
+	start := time.Now()
+
+	cmd := s5cmd("--rate-limit", "1024", "cp", srcpath+"/*", dstpath)
 	result := icmd.RunCmd(cmd)
 
 	result.Assert(t, icmd.Success)
 
+	// the first second worth of bytes is allowed at once, the rest is limited.
+	elapsed := time.Since(start)
+	assert.Assert(t, elapsed >= 900*time.Millisecond, "expected copy to be limited, took %v", elapsed)
+
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: suffix(`cp %v %v%v`, srcpath, dstpath, filename),
+		0: equals(`cp %v/file1.txt %vfile1.txt`, srcpath, dstpath),
+		1: equals(`cp %v/file2.txt %vfile2.txt`, srcpath, dstpath),
+	}, sortInput(true))
+
+	// assert s3 objects
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file1.txt", content))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file2.txt", content))
+}
+
+// cp --preserve-mtime s3://bucket/object .
+func TestCopySingleS3ObjectToLocalWithPreserveMtime(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	head, err := s3client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String("file.txt"),
+	})
+	assert.NilError(t, err)
+
+	cmd := s5cmd("cp", "--preserve-mtime", fmt.Sprintf("s3://%v/file.txt", bucket), ".")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	st, err := os.Stat(filepath.Join(cmd.Dir, "file.txt"))
+	assert.NilError(t, err)
+
+	lastModified := aws.TimeValue(head.LastModified)
+	assert.Assert(t, st.ModTime().Equal(lastModified), "expected %v, got %v", lastModified, st.ModTime())
+}
+
+// cp --preserve-mtime file s3://bucket/ && cp --preserve-mtime s3://bucket/file dir/
+func TestCopySingleFileRoundTripWithPreserveMtime(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	mtime := time.Date(2020, time.March, 19, 10, 30, 15, 250000000, time.UTC)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile("file.txt", "content", fs.WithTimestamps(mtime, mtime)))
+	defer workdir.Remove()
+
+	localpath := filepath.ToSlash(workdir.Join("file.txt"))
+	cmd := s5cmd("cp", "--preserve-mtime", localpath, fmt.Sprintf("s3://%v/", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	cmd = s5cmd("cp", "--preserve-mtime", fmt.Sprintf("s3://%v/file.txt", bucket), "dir/")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	st, err := os.Stat(filepath.Join(cmd.Dir, "dir", "file.txt"))
+	assert.NilError(t, err)
+	assert.Assert(t, st.ModTime().Equal(mtime), "expected %v, got %v", mtime, st.ModTime())
+
+	// files downloaded without the flag have the current time.
+	cmd = s5cmd("cp", fmt.Sprintf("s3://%v/file.txt", bucket), "other/")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	st, err = os.Stat(filepath.Join(cmd.Dir, "other", "file.txt"))
+	assert.NilError(t, err)
+	assert.Assert(t, !st.ModTime().Equal(mtime))
+}
+
+// cp --check-md5 file s3://bucket/ && cp --check-md5 s3://bucket/file dir/
+func TestCopySingleFileRoundTripWithCheckMD5(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const content = "this is a file content"
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile("file.txt", content))
+	defer workdir.Remove()
+
+	localpath := filepath.ToSlash(workdir.Join("file.txt"))
+	cmd := s5cmd("--log", "debug", "cp", "--check-md5", localpath, fmt.Sprintf("s3://%v/", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the checksum is verified, so there is no debug message about skipping it.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v s3://%v/file.txt`, localpath, bucket),
+	})
+
+	cmd = s5cmd("cp", "--check-md5", fmt.Sprintf("s3://%v/file.txt", bucket), "dir/")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/file.txt dir/file.txt`, bucket),
 	})
 
 	// assert local filesystem
-	expected := fs.Expected(t, fs.WithFile(filename, content))
-	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+	expected := fs.Expected(t, fs.WithDir("dir", fs.WithFile("file.txt", content, fs.WithMode(0644))))
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
 
-	// assert S3
-	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureStorageClass(expectedStorageClass)))
+// cp --show-progress dir/* s3://bucket/
+func TestCopyMultipleFilesToS3WithProgress(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, bucket,
+		fs.WithFile("file1.txt", "content"),
+		fs.WithFile("file2.txt", "content"),
+	)
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--show-progress", srcpath+"/*", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`cp %v/file1.txt %vfile1.txt`, srcpath, dstpath),
+		1: suffix(`cp %v/file2.txt %vfile2.txt`, srcpath, dstpath),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: match(`100% \[=+\] 14 / 14 .* 2 / 2 objects$`),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file1.txt", "content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file2.txt", "content"))
+}
+
+// --json cp --show-progress s3://bucket/object dir/
+func TestCopySingleS3ObjectToLocalWithProgressJSON(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "content"
+	)
+
+	putFile(t, s3client, bucket, filename, content)
+
+	workdir := fs.NewDir(t, bucket)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	cmd := s5cmd("--json", "cp", "--show-progress", src, ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: prefix(`{"operation":"cp","success":true,"source":"%v"`, src),
+	})
+
+	// progress bar must not be printed in JSON mode
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
 }
 
 // cp --flatten dir/ s3://bucket/
@@ -1047,21 +2682,101 @@ func TestFlattenCopyMultipleFilesToS3Bucket(t *testing.T) {
 		3: equals(`cp %v/testfile1.txt %vtestfile1.txt`, srcpath, dstpath),
 	}, sortInput(true))
 
-	// assert local filesystem
-	expected := fs.Expected(t, folderLayout...)
-	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+	// assert local filesystem
+	expected := fs.Expected(t, folderLayout...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	expectedS3Content := map[string]string{
+		"testfile1.txt":          "this is a test file 1",
+		"readme.md":              "this is a readme file",
+		"filename-with-hypen.gz": "file has hypen in its name",
+		"another_test_file.txt":  "yet another txt file. yatf.",
+	}
+
+	// assert s3
+	for filename, content := range expectedS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+	}
+}
+
+// cp dir/*.gz s3://bucket/prefix/
+func TestCopyLocalFilesWithPartialGlobToS3Prefix(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	folderLayout := []fs.PathOp{
+		fs.WithFile("a.gz", "content of a"),
+		fs.WithFile("b.gz", "content of b"),
+		fs.WithFile("readme.md", "this is a readme file"),
+		// directories matching the glob are uploaded with their files.
+		fs.WithDir(
+			"archive.gz",
+			fs.WithFile("c.txt", "content of c"),
+		),
+	}
+
+	workdir := fs.NewDir(t, "logs", folderLayout...)
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+	dstpath := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("cp", srcpath+"/*.gz", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a.gz %va.gz`, srcpath, dstpath),
+		1: equals(`cp %v/archive.gz/c.txt %varchive.gz/c.txt`, srcpath, dstpath),
+		2: equals(`cp %v/b.gz %vb.gz`, srcpath, dstpath),
+	}, sortInput(true))
 
 	expectedS3Content := map[string]string{
-		"testfile1.txt":          "this is a test file 1",
-		"readme.md":              "this is a readme file",
-		"filename-with-hypen.gz": "file has hypen in its name",
-		"another_test_file.txt":  "yet another txt file. yatf.",
+		"prefix/a.gz":             "content of a",
+		"prefix/b.gz":             "content of b",
+		"prefix/archive.gz/c.txt": "content of c",
 	}
 
-	// assert s3
 	for filename, content := range expectedS3Content {
 		assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
 	}
+
+	err := ensureS3Object(s3client, bucket, "prefix/readme.md", "this is a readme file")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// cp dir/*.gz s3://bucket/prefix/ (error)
+func TestCopyLocalGlobWithNoMatchToS3(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "logs", fs.WithFile("readme.md", "this is a readme file"))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path())
+	dstpath := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("cp", srcpath+"/*.gz", dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp %v/*.gz %v": no match found for "%v/*.gz"`, srcpath, dstpath, srcpath),
+	})
 }
 
 // cp dir/* s3://bucket/prefix (error)
@@ -1495,6 +3210,183 @@ func TestCopySingleS3ObjectToS3(t *testing.T) {
 	assert.Assert(t, ensureS3Object(s3client, bucket, dstfilename, content))
 }
 
+// cp --source-profile source --destination-profile destination s3://bucket/object s3://bucket2/object
+func TestCopySingleS3ObjectToS3WithDifferentProfiles(t *testing.T) {
+	t.Parallel()
+
+	const (
+		srcbucket = "source"
+		dstbucket = "destination"
+		filename  = "testfile1.txt"
+		content   = "this is a file content"
+	)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	_, err := s3client.PutObject(&s3.PutObjectInput{
+		Bucket:   aws.String(srcbucket),
+		Key:      aws.String(filename),
+		Body:     strings.NewReader(content),
+		Metadata: aws.StringMap(map[string]string{"owner": "john"}),
+	})
+	assert.NilError(t, err)
+
+	credentials := fs.NewFile(t, "credentials", fs.WithContent(`[source]
+aws_access_key_id = source-access-key
+aws_secret_access_key = source-secret-key
+
+[destination]
+aws_access_key_id = destination-access-key
+aws_secret_access_key = destination-secret-key
+`))
+	defer credentials.Remove()
+
+	src := fmt.Sprintf("s3://%v/%v", srcbucket, filename)
+	dst := fmt.Sprintf("s3://%v/%v", dstbucket, filename)
+
+	cmd := s5cmd("cp", "--source-profile", "source", "--destination-profile", "destination", src, dst)
+	cmd.Env = append(cmd.Env, "AWS_SHARED_CREDENTIALS_FILE="+credentials.Path())
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v`, src, dst),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, srcbucket, filename, content))
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, filename, content))
+
+	// the object is uploaded by s5cmd, which keeps the metadata of the
+	// source like S3 does for copies.
+	output, err := s3client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(dstbucket),
+		Key:    aws.String(filename),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, aws.StringValue(output.Metadata["Owner"]), "john")
+}
+
+// cp --source-profile source --destination-profile destination --preserve-tags s3://bucket/object s3://bucket2/object
+func TestCopySingleS3ObjectToS3WithDifferentProfilesPreserveTags(t *testing.T) {
+	t.Parallel()
+
+	const (
+		srcbucket = "source"
+		dstbucket = "destination"
+		filename  = "testfile1.txt"
+		content   = "this is a file content"
+	)
+
+	s3client, _, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	_, err := s3client.PutObject(&s3.PutObjectInput{
+		Bucket:   aws.String(srcbucket),
+		Key:      aws.String(filename),
+		Body:     strings.NewReader(content),
+		Metadata: aws.StringMap(map[string]string{"owner": "john"}),
+	})
+	assert.NilError(t, err)
+
+	// the test server does not keep tags, so the tags of the source are
+	// served and the tags of the upload are recorded in front of it.
+	backend, err := neturl.Parse(s3client.Endpoint)
+	assert.NilError(t, err)
+	proxy := httputil.NewSingleHostReverseProxy(backend)
+
+	var (
+		mu         sync.Mutex
+		gotTagging string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["tagging"]; ok && r.Method == http.MethodGet {
+			fmt.Fprint(w, `<Tagging><TagSet><Tag><Key>env</Key><Value>prod</Value></Tag></TagSet></Tagging>`)
+			return
+		}
+		if r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/"+dstbucket+"/") {
+			mu.Lock()
+			gotTagging = r.Header.Get("X-Amz-Tagging")
+			mu.Unlock()
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	_, s5cmd, cleanupProxy := setup(t, withEndpointURL(server.URL))
+	defer cleanupProxy()
+
+	credentials := fs.NewFile(t, "credentials", fs.WithContent(`[source]
+aws_access_key_id = source-access-key
+aws_secret_access_key = source-secret-key
+
+[destination]
+aws_access_key_id = destination-access-key
+aws_secret_access_key = destination-secret-key
+`))
+	defer credentials.Remove()
+
+	src := fmt.Sprintf("s3://%v/%v", srcbucket, filename)
+	dst := fmt.Sprintf("s3://%v/%v", dstbucket, filename)
+
+	cmd := s5cmd("cp", "--source-profile", "source", "--destination-profile", "destination", "--preserve-tags", src, dst)
+	cmd.Env = append(cmd.Env, "AWS_SHARED_CREDENTIALS_FILE="+credentials.Path())
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v`, src, dst),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, filename, content, ensureMetadata(map[string]string{"Owner": "john"})))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, gotTagging, "env=prod")
+}
+
+// cp --preserve-tags --tag key=value s3://bucket/object s3://bucket2/object
+func TestCopyS3ObjectToS3WithPreserveTagsAndTag(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("cp", "--preserve-tags", "--tag", "env=prod", "s3://bucket/object", "s3://bucket2/object")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp s3://bucket/object s3://bucket2/object": --preserve-tags can not be used with --tag`),
+	})
+}
+
+// cp --source-profile source dir/file s3://bucket/file
+func TestCopyLocalFileWithSourceProfileFail(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("cp", "--source-profile", "source", "file.txt", "s3://bucket/file.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp file.txt s3://bucket/file.txt": --source-profile can only be used with remote sources`),
+	})
+}
+
 // --json cp s3://bucket/object s3://bucket2/object
 func TestCopySingleS3ObjectToS3JSON(t *testing.T) {
 	t.Parallel()
@@ -2067,6 +3959,105 @@ func TestCopyS3ToLocalWithSameFilenameWithNoClobber(t *testing.T) {
 	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 }
 
+// cp -n: existing destinations are skipped and counted, missing ones are
+// copied.
+func TestCopyWithNoClobberSkipsExistingDestination(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.txt"
+		content  = "this is the content"
+		existing = "this is the existing content"
+	)
+
+	testcases := []struct {
+		name      string
+		dstExists bool
+		src       string
+		dst       string
+	}{
+		{name: "download existing", dstExists: true, src: "s3://bucket/src/file.txt", dst: "."},
+		{name: "download missing", src: "s3://bucket/src/file.txt", dst: "."},
+		{name: "upload existing", dstExists: true, src: filename, dst: "s3://bucket/dst/"},
+		{name: "upload missing", src: filename, dst: "s3://bucket/dst/"},
+		{name: "copy existing", dstExists: true, src: "s3://bucket/src/file.txt", dst: "s3://bucket/dst/"},
+		{name: "copy missing", src: "s3://bucket/src/file.txt", dst: "s3://bucket/dst/"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+			putFile(t, s3client, bucket, "src/"+filename, content)
+
+			var ops []fs.PathOp
+			if strings.HasPrefix(tc.src, "s3://") {
+				if tc.dstExists && tc.dst == "." {
+					ops = append(ops, fs.WithFile(filename, existing))
+				}
+			} else {
+				ops = append(ops, fs.WithFile(filename, content))
+			}
+			if tc.dstExists && tc.dst != "." {
+				putFile(t, s3client, bucket, "dst/"+filename, existing)
+			}
+
+			workdir := fs.NewDir(t, bucket, ops...)
+			defer workdir.Remove()
+
+			cmd := s5cmd("--stat", "cp", "-n", tc.src, tc.dst)
+			result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+			result.Assert(t, icmd.Success)
+
+			expectedContent, skipped := content, 0
+			if tc.dstExists {
+				expectedContent, skipped = existing, 1
+			}
+
+			out := result.Stdout()
+			expected := fmt.Sprintf("Skipped: %d, ", skipped)
+			assert.Assert(t, strings.Contains(out, expected), out)
+
+			if tc.dst == "." {
+				expected := fs.Expected(t, fs.WithFile(filename, expectedContent))
+				assert.Assert(t, fs.Equal(workdir.Path(), expected))
+			} else {
+				assert.Assert(t, ensureS3Object(s3client, bucket, "dst/"+filename, expectedContent))
+			}
+		})
+	}
+}
+
+func TestCopyOverwriteWithConditionalFlagsFail(t *testing.T) {
+	t.Parallel()
+
+	for _, flag := range []string{"--no-clobber", "--if-size-differ", "--if-source-newer"} {
+		flag := flag
+		t.Run(flag, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd("cp", "--overwrite", flag, "s3://bucket/file.txt", ".")
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(`ERROR "cp s3://bucket/file.txt .": --overwrite can not be used with %v`, flag),
+			})
+		})
+	}
+}
+
 // cp -n -s s3://bucket/object dir/
 func TestCopyS3ToLocalWithSameFilenameOverrideIfSizeDiffers(t *testing.T) {
 	t.Parallel()
@@ -3253,45 +5244,165 @@ func TestCopyMultipleS3ObjectsToS3WithRawMode(t *testing.T) {
 	for filename, content := range expectedFiles {
 		assert.Assert(t, ensureS3Object(s3client, destBucket, filename, content))
 	}
-}
+}
+
+// cp --raw s3://bucket/file* s3://destbucket
+func TestCopyMultipleS3ObjectsWithPrefixToS3WithRawMode(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+	const destBucket = "destbucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, destBucket)
+
+	filesToContent := map[string]string{
+		"file*/file.txt":   "this is a test file 1 in file*",
+		"file*/file1.txt":  "this is a test file 2 in file*",
+		"file*a/file.txt":  "this is a test file 1 in file*b",
+		"file*a/file1.txt": "this is a test file 2 in file*b",
+	}
+
+	for filename, content := range filesToContent {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	src := fmt.Sprintf("s3://%v/file*", bucket)
+	dst := fmt.Sprintf("s3://%v", destBucket)
+
+	cmd := s5cmd("cp", "--raw", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	expected := fmt.Sprintf(`ERROR "cp %v %v/file*": NoSuchKey:`, src, dst)
+
+	assertLines(t, result.Stderr()[:len(expected)], map[int]compareFunc{
+		0: equals(expected),
+	})
+}
+
+// cp --raw s3://bucket/file?.txt s3://destbucket/
+func TestCopyS3ObjectsWithGlobCharactersToS3WithRawMode(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name   string
+		key    string
+		others []string
+	}{
+		{name: "star", key: "file*.txt", others: []string{"file1.txt", "file*1.txt"}},
+		{name: "question mark", key: "file?.txt", others: []string{"file1.txt", "file??.txt"}},
+		{name: "bracket", key: "file[1].txt", others: []string{"file1.txt", "file[2].txt"}},
+		{name: "brace", key: "file{1,2}.txt", others: []string{"file1.txt", "file2.txt"}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			const (
+				bucket     = "bucket"
+				destBucket = "destbucket"
+				content    = "this is a file content"
+			)
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+			createBucket(t, s3client, destBucket)
+
+			putFile(t, s3client, bucket, tc.key, content)
+			for _, key := range tc.others {
+				putFile(t, s3client, bucket, key, content)
+			}
+
+			src := fmt.Sprintf("s3://%v/%v", bucket, tc.key)
+			dst := fmt.Sprintf("s3://%v/", destBucket)
+
+			cmd := s5cmd("cp", "--raw", src, dst)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals("cp %v %v%v", src, dst, tc.key),
+			})
+
+			assert.Assert(t, ensureS3Object(s3client, destBucket, tc.key, content))
+			for _, key := range tc.others {
+				err := ensureS3Object(s3client, destBucket, key, content)
+				assertError(t, err, errS3NoSuchKey)
+			}
+		})
+	}
+}
+
+// cp --raw dir/file?.txt s3://bucket/
+func TestCopyLocalFilesWithGlobCharactersToS3WithRawMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	testcases := []struct {
+		name   string
+		file   string
+		others []string
+	}{
+		{name: "star", file: "file*.txt", others: []string{"file1.txt", "file*1.txt"}},
+		{name: "question mark", file: "file?.txt", others: []string{"file1.txt", "file??.txt"}},
+		{name: "bracket", file: "file[1].txt", others: []string{"file1.txt", "file[2].txt"}},
+	}
 
-// cp --raw s3://bucket/file* s3://destbucket
-func TestCopyMultipleS3ObjectsWithPrefixToS3WithRawMode(t *testing.T) {
-	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
 
-	const bucket = "bucket"
-	const destBucket = "destbucket"
+			const (
+				bucket  = "bucket"
+				content = "this is a file content"
+			)
 
-	s3client, s5cmd, cleanup := setup(t)
-	defer cleanup()
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
 
-	createBucket(t, s3client, bucket)
-	createBucket(t, s3client, destBucket)
+			createBucket(t, s3client, bucket)
 
-	filesToContent := map[string]string{
-		"file*/file.txt":   "this is a test file 1 in file*",
-		"file*/file1.txt":  "this is a test file 2 in file*",
-		"file*a/file.txt":  "this is a test file 1 in file*b",
-		"file*a/file1.txt": "this is a test file 2 in file*b",
-	}
+			folderLayout := []fs.PathOp{fs.WithFile(tc.file, content)}
+			for _, file := range tc.others {
+				folderLayout = append(folderLayout, fs.WithFile(file, content))
+			}
 
-	for filename, content := range filesToContent {
-		putFile(t, s3client, bucket, filename, content)
-	}
+			workdir := fs.NewDir(t, "rawmode", folderLayout...)
+			defer workdir.Remove()
 
-	src := fmt.Sprintf("s3://%v/file*", bucket)
-	dst := fmt.Sprintf("s3://%v", destBucket)
+			src := filepath.ToSlash(workdir.Join(tc.file))
+			dst := fmt.Sprintf("s3://%v/", bucket)
 
-	cmd := s5cmd("cp", "--raw", src, dst)
-	result := icmd.RunCmd(cmd)
+			cmd := s5cmd("cp", "--raw", src, dst)
+			result := icmd.RunCmd(cmd)
 
-	result.Assert(t, icmd.Expected{ExitCode: 1})
+			result.Assert(t, icmd.Success)
 
-	expected := fmt.Sprintf(`ERROR "cp %v %v/file*": NoSuchKey:`, src, dst)
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals("cp %v %v%v", src, dst, tc.file),
+			})
 
-	assertLines(t, result.Stderr()[:len(expected)], map[int]compareFunc{
-		0: equals(expected),
-	})
+			assert.Assert(t, ensureS3Object(s3client, bucket, tc.file, content))
+			for _, file := range tc.others {
+				err := ensureS3Object(s3client, bucket, file, content)
+				assertError(t, err, errS3NoSuchKey)
+			}
+		})
+	}
 }
 
 // cp --raw s3://bucket/file* s3://destbucket
@@ -3456,6 +5567,56 @@ func TestCopyS3ObjectsWithExcludeFilters(t *testing.T) {
 	assert.Assert(t, fs.Equal(cmd.Dir, expected))
 }
 
+// cp --exclude "*" --include "*.txt" s3://bucket/* .
+func TestCopyS3ObjectsWithExcludeAndIncludeFilters(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const fileContent = "content"
+
+	files := [...]string{
+		"file1.txt",
+		"file.py",
+		"src/file.txt",
+		"src/main.c",
+	}
+
+	for _, filename := range files {
+		putFile(t, s3client, bucket, filename, fileContent)
+	}
+
+	srcpath := fmt.Sprintf("s3://%s", bucket)
+
+	cmd := s5cmd("cp", "--exclude", "*", "--include", "*.txt", srcpath+"/*", ".")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp %v/file1.txt file1.txt", srcpath),
+		1: equals("cp %v/src/file.txt src/file.txt", srcpath),
+	}, sortInput(true))
+
+	// assert s3
+	for _, f := range files {
+		assert.Assert(t, ensureS3Object(s3client, bucket, f, fileContent))
+	}
+
+	expectedFileSystem := []fs.PathOp{
+		fs.WithFile("file1.txt", fileContent),
+		fs.WithDir("src", fs.WithFile("file.txt", fileContent)),
+	}
+	// assert local filesystem
+	expected := fs.Expected(t, expectedFileSystem...)
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
 // cp --exclude ".txt" s3://bucket/abc* .
 func TestCopyS3ObjectsWithPrefixWithExcludeFilters(t *testing.T) {
 	t.Parallel()
@@ -3858,3 +6019,309 @@ func TestCopyExpectExitCode1OnUnreachableHost(t *testing.T) {
 
 	result.Assert(t, icmd.Expected{ExitCode: 1})
 }
+
+// cp file dir/
+func TestCopyLocalFileToLocalDirectory(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	const content = "this is a file content"
+
+	workdir := fs.NewDir(t, "",
+		fs.WithFile("script.sh", content, fs.WithMode(0755)),
+		fs.WithDir("dir"),
+	)
+	defer workdir.Remove()
+
+	cmd := s5cmd("cp", "script.sh", "dir/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp script.sh dir/script.sh"),
+	})
+
+	// the source is kept and the permissions are copied.
+	expected := fs.Expected(t,
+		fs.WithFile("script.sh", content, fs.WithMode(0755)),
+		fs.WithDir("dir",
+			fs.WithFile("script.sh", content, fs.WithMode(0755)),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp 'src/*' dst/
+func TestCopyLocalDirectoryToLocal(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	workdir := fs.NewDir(t, "",
+		fs.WithDir("src",
+			fs.WithFile("a.txt", "content of a"),
+			fs.WithDir("nested",
+				fs.WithFile("b.txt", "content of b"),
+			),
+		),
+	)
+	defer workdir.Remove()
+
+	cmd := s5cmd("cp", "src/*", "dst/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp src/a.txt dst/a.txt"),
+		1: equals("cp src/nested/b.txt dst/nested/b.txt"),
+	}, sortInput(true))
+
+	src := fs.WithDir("src",
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithDir("nested",
+			fs.WithFile("b.txt", "content of b"),
+		),
+	)
+	dst := fs.WithDir("dst",
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithDir("nested",
+			fs.WithFile("b.txt", "content of b"),
+		),
+	)
+	expected := fs.Expected(t, src, dst)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp -n file existing-file
+func TestCopyLocalFileToLocalWithNoClobber(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	workdir := fs.NewDir(t, "",
+		fs.WithFile("a.txt", "new content"),
+		fs.WithFile("b.txt", "old content"),
+	)
+	defer workdir.Remove()
+
+	cmd := s5cmd("cp", "-n", "a.txt", "b.txt")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+
+	expected := fs.Expected(t,
+		fs.WithFile("a.txt", "new content"),
+		fs.WithFile("b.txt", "old content"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp --range bytes=0-3 s3://bucket/object .
+func TestCopyS3ObjectToLocalWithRange(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.txt"
+		content  = "0123456789"
+	)
+
+	testcases := []struct {
+		name     string
+		rng      string
+		expected string
+	}{
+		{name: "first and last bytes", rng: "bytes=2-5", expected: "2345"},
+		{name: "from first byte", rng: "bytes=7-", expected: "789"},
+		{name: "last bytes", rng: "bytes=-3", expected: "789"},
+		{name: "beyond the end", rng: "bytes=8-100", expected: "89"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+			putFile(t, s3client, bucket, filename, content)
+
+			// the existing file is replaced by the range.
+			workdir := fs.NewDir(t, bucket, fs.WithFile(filename, "an existing content"))
+			defer workdir.Remove()
+
+			cmd := s5cmd("cp", "--range", tc.rng, "s3://"+bucket+"/"+filename, ".")
+			result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals("cp s3://%v/%v %v", bucket, filename, filename),
+			})
+
+			expected := fs.Expected(t, fs.WithFile(filename, tc.expected))
+			assert.Assert(t, fs.Equal(workdir.Path(), expected))
+		})
+	}
+}
+
+func TestCopyRangeFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "invalid range",
+			args:     []string{"cp", "--range", "0-1023", "s3://bucket/file.log", "."},
+			expected: `ERROR "cp s3://bucket/file.log .": invalid --range value "0-1023": must be bytes=first-last, bytes=first- or bytes=-length`,
+		},
+		{
+			name:     "last byte before first byte",
+			args:     []string{"cp", "--range", "bytes=10-5", "s3://bucket/file.log", "."},
+			expected: `ERROR "cp s3://bucket/file.log .": invalid --range value "bytes=10-5": last byte can not be before the first byte`,
+		},
+		{
+			name:     "range upload",
+			args:     []string{"cp", "--range", "bytes=0-1023", "file.log", "s3://bucket/"},
+			expected: `ERROR "cp file.log s3://bucket/": --range can only be used for downloads`,
+		},
+		{
+			name:     "range with check-md5",
+			args:     []string{"cp", "--range", "bytes=0-1023", "--check-md5", "s3://bucket/file.log", "."},
+			expected: `ERROR "cp s3://bucket/file.log .": --check-md5 can not be used with --range`,
+		},
+		{
+			name:     "range move",
+			args:     []string{"mv", "--range", "bytes=0-1023", "s3://bucket/file.log", "."},
+			expected: `ERROR "mv s3://bucket/file.log .": --range can not be used with mv`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// cp --if-match etag s3://bucket/object . && cp --if-none-match etag s3://bucket/object .
+func TestCopyS3ObjectToLocalWithETagPreconditions(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.txt"
+		content  = "new content"
+		existing = "old content"
+	)
+
+	etag := fmt.Sprintf("%x", md5.Sum([]byte(content)))
+
+	testcases := []struct {
+		name            string
+		globalFlags     []string
+		flags           []string
+		expectedStdout  map[int]compareFunc
+		expectedStderr  map[int]compareFunc
+		expectedExit    int
+		expectedContent string
+	}{
+		{
+			name:  "if match matches",
+			flags: []string{"--if-match", etag},
+			expectedStdout: map[int]compareFunc{
+				0: equals("cp s3://%v/%v %v", bucket, filename, filename),
+			},
+			expectedContent: content,
+		},
+		{
+			name:  "if match does not match",
+			flags: []string{"--if-match", "0123456789abcdef0123456789abcdef"},
+			expectedStderr: map[int]compareFunc{
+				0: equals(`ERROR "cp s3://%v/%v %v": object etag does not match`, bucket, filename, filename),
+			},
+			expectedExit:    1,
+			expectedContent: existing,
+		},
+		{
+			name:        "if none match matches",
+			globalFlags: []string{"--log", "debug"},
+			flags:       []string{"--if-none-match", `"` + etag + `"`},
+			expectedStdout: map[int]compareFunc{
+				0: equals(`DEBUG "cp s3://%v/%v %v": object is not modified`, bucket, filename, filename),
+			},
+			expectedContent: existing,
+		},
+		{
+			name:  "if none match does not match",
+			flags: []string{"--if-none-match", "0123456789abcdef0123456789abcdef"},
+			expectedStdout: map[int]compareFunc{
+				0: equals("cp s3://%v/%v %v", bucket, filename, filename),
+			},
+			expectedContent: content,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+			putFile(t, s3client, bucket, filename, content)
+
+			workdir := fs.NewDir(t, bucket, fs.WithFile(filename, existing))
+			defer workdir.Remove()
+
+			args := append(tc.globalFlags, "cp")
+			args = append(args, tc.flags...)
+			args = append(args, "s3://"+bucket+"/"+filename, ".")
+
+			cmd := s5cmd(args...)
+			result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+			result.Assert(t, icmd.Expected{ExitCode: tc.expectedExit})
+
+			if tc.expectedStdout == nil {
+				tc.expectedStdout = map[int]compareFunc{}
+			}
+			if tc.expectedStderr == nil {
+				tc.expectedStderr = map[int]compareFunc{}
+			}
+			assertLines(t, result.Stdout(), tc.expectedStdout)
+			assertLines(t, result.Stderr(), tc.expectedStderr)
+
+			expected := fs.Expected(t, fs.WithFile(filename, tc.expectedContent))
+			assert.Assert(t, fs.Equal(workdir.Path(), expected))
+		})
+	}
+}
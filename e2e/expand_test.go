@@ -0,0 +1,217 @@
+package e2e
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+// expand --exclude pattern s3://bucket/prefix/*
+func TestExpandMatchesCopySources(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		src      string
+		expected []string
+	}{
+		{
+			name: "wildcard",
+			src:  "s3://bucket/logs/*.log",
+			expected: []string{
+				"s3://bucket/logs/2020/app.log",
+				"s3://bucket/logs/app.log",
+				"s3://bucket/logs/keep/app.log",
+			},
+		},
+		{
+			name:  "exclude and include",
+			flags: []string{"--exclude", "*.log", "--include", "keep/*"},
+			src:   "s3://bucket/logs/*",
+			expected: []string{
+				"s3://bucket/logs/keep/app.log",
+				"s3://bucket/logs/keep/notes.txt",
+				"s3://bucket/logs/notes.txt",
+			},
+		},
+		{
+			name:  "size filter",
+			flags: []string{"--min-size", "1KB"},
+			src:   "s3://bucket/logs/*",
+			expected: []string{
+				"s3://bucket/logs/2020/app.log",
+			},
+		},
+		{
+			name:  "recursive",
+			flags: []string{"--recursive"},
+			src:   "s3://bucket/logs",
+			expected: []string{
+				"s3://bucket/logs/2020/app.log",
+				"s3://bucket/logs/app.log",
+				"s3://bucket/logs/keep/app.log",
+				"s3://bucket/logs/keep/notes.txt",
+				"s3://bucket/logs/notes.txt",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+			putFile(t, s3client, bucket, "logs/app.log", "log")
+			putFile(t, s3client, bucket, "logs/notes.txt", "notes")
+			putFile(t, s3client, bucket, "logs/2020/app.log", strings.Repeat("log", 1024))
+			putFile(t, s3client, bucket, "logs/keep/app.log", "log")
+			putFile(t, s3client, bucket, "logs/keep/notes.txt", "notes")
+			putFile(t, s3client, bucket, "logs-other/app.log", "log")
+
+			cmd := s5cmd(append(append([]string{"expand"}, tc.flags...), tc.src)...)
+			result := icmd.RunCmd(cmd)
+			result.Assert(t, icmd.Success)
+
+			expanded := strings.Fields(result.Stdout())
+			sort.Strings(expanded)
+			assert.DeepEqual(t, expanded, tc.expected)
+
+			// the sources of cp are the expanded objects.
+			cmd = s5cmd(append(append([]string{"--dry-run", "cp"}, tc.flags...), tc.src, "dir/")...)
+			result = icmd.RunCmd(cmd)
+			result.Assert(t, icmd.Success)
+
+			var copied []string
+			for _, line := range strings.Split(strings.TrimSpace(result.Stdout()), "\n") {
+				copied = append(copied, strings.Fields(line)[1])
+			}
+			sort.Strings(copied)
+			assert.DeepEqual(t, copied, expanded)
+		})
+	}
+}
+
+// expand dir/
+func TestExpandLocalDirectory(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	workdir := fs.NewDir(t, "expand",
+		fs.WithFile("file1.txt", "content"),
+		fs.WithFile("file2.log", "content"),
+		fs.WithDir("a", fs.WithFile("file3.txt", "content")),
+	)
+	defer workdir.Remove()
+
+	cmd := s5cmd("expand", "--exclude", "*.log", ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("a/file3.txt"),
+		1: equals("file1.txt"),
+	}, sortInput(true))
+}
+
+// --json expand s3://bucket/*
+func TestExpandJSON(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	cmd := s5cmd("--json", "expand", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`{"source":"s3://%v/file.txt"}`, bucket),
+	}, jsonCheck(true))
+}
+
+// expand s3://bucket/*.log
+func TestExpandNoMatch(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	cmd := s5cmd("expand", "s3://"+bucket+"/*.log")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "expand s3://%v/*.log": no object found`, bucket),
+	})
+}
+
+func TestExpandFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "no arguments",
+			args:     []string{"expand"},
+			expected: `ERROR "expand": expected only 1 argument`,
+		},
+		{
+			name:     "prefix without wildcard",
+			args:     []string{"expand", "s3://bucket/prefix/"},
+			expected: `ERROR "expand s3://bucket/prefix/": source argument must contain wildcard character`,
+		},
+		{
+			name:     "recursive with raw",
+			args:     []string{"expand", "--recursive", "--raw", "s3://bucket/prefix"},
+			expected: `ERROR "expand s3://bucket/prefix": --recursive can not be used with --raw`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
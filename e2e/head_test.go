@@ -0,0 +1,151 @@
+package e2e
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/icmd"
+)
+
+func TestHeadS3Object(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.txt"
+		content  = "this is a file content"
+	)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	_, err := s3client.PutObject(&s3.PutObjectInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(filename),
+		Body:     strings.NewReader(content),
+		Metadata: aws.StringMap(map[string]string{"owner": "john", "env": "prod"}),
+	})
+	assert.NilError(t, err)
+
+	cmd := s5cmd("head", fmt.Sprintf("s3://%v/%v", bucket, filename))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`Key: s3://bucket/file.txt`),
+		1: equals(`Size: %d`, len(content)),
+		2: match(`^Last Modified: \d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}$`),
+		3: match(`^ETag: [0-9a-f]{32}$`),
+		4: equals(`Storage Class: STANDARD`),
+		5: equals(`Metadata: Env=prod`),
+		6: equals(`Metadata: Owner=john`),
+	})
+}
+
+func TestHeadS3ObjectJSON(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.txt"
+		content  = "this is a file content"
+	)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	_, err := s3client.PutObject(&s3.PutObjectInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(filename),
+		Body:     strings.NewReader(content),
+		Metadata: aws.StringMap(map[string]string{"owner": "john"}),
+	})
+	assert.NilError(t, err)
+
+	cmd := s5cmd("--json", "head", fmt.Sprintf("s3://%v/%v", bucket, filename))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(fmt.Sprintf(`^{"key":"s3://bucket/file.txt","size":%d,"last_modified":".+","etag":"[0-9a-f]{32}","storage_class":"STANDARD",.*"metadata":{"Owner":"john"}}$`, len(content))),
+	}, jsonCheck(true))
+}
+
+func TestHeadS3ObjectFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		cmd      []string
+		expected map[int]compareFunc
+	}{
+		{
+			name: "head non existent remote object",
+			cmd: []string{
+				"head",
+				"s3://bucket/nonexistent.txt",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "head s3://bucket/nonexistent.txt": given object not found`),
+			},
+		},
+		{
+			name: "head bucket",
+			cmd: []string{
+				"head",
+				"s3://bucket",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "head s3://bucket": remote source must be an object`),
+			},
+		},
+		{
+			name: "head remote object with glob",
+			cmd: []string{
+				"head",
+				"s3://bucket/*.txt",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "head s3://bucket/*.txt": remote source "s3://bucket/*.txt" can not contain glob characters`),
+			},
+		},
+		{
+			name: "head local file",
+			cmd: []string{
+				"head",
+				"file.txt",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "head file.txt": source must be a remote object`),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, "bucket")
+
+			cmd := s5cmd(tc.cmd...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+			assertLines(t, result.Stderr(), tc.expected)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
 )
@@ -233,6 +234,432 @@ func TestListMultipleWildcardS3ObjectWithPrefix(t *testing.T) {
 	}, alignment(true))
 }
 
+// ls --min-size 1KB --max-size 4KB bucket/*
+func TestListS3ObjectsWithSizeFilters(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	// bolt backend reports wrong object sizes while listing, hence use
+	// in-memory storage for size comparisons.
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "small.txt", "small")
+	putFile(t, s3client, bucket, "medium.txt", strings.Repeat("x", 2048))
+	putFile(t, s3client, bucket, "large.txt", strings.Repeat("x", 8192))
+	putFile(t, s3client, bucket, "dir/medium.txt", strings.Repeat("x", 1024))
+
+	cmd := s5cmd("ls", "--min-size", "1KB", "--max-size", "4KB", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("1024 dir/medium.txt"),
+		1: suffix("2048 medium.txt"),
+	}, alignment(true))
+}
+
+// ls --newer-than 2023-01-02 --older-than 1h bucket/
+func TestListS3ObjectsWithDateFilters(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+	putFile(t, s3client, bucket, "dir/file.txt", "content")
+
+	// the objects are just created, so only the listing of the recent ones
+	// finds them. the directory is skipped by the filters.
+	cmd := s5cmd("ls", "--newer-than", "1h", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains("file.txt"),
+	})
+
+	cmd = s5cmd("ls", "--newer-than", "2023-01-02", "--older-than", "1h", "s3://"+bucket+"/*")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), "")
+}
+
+// ls --min-size 10MB --max-size 1MB bucket/
+func TestListS3ObjectsWithInvalidSizeFilters(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("ls", "--min-size", "10MB", "--max-size", "1MB", "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "ls s3://bucket/": --min-size can not be greater than --max-size`),
+	})
+}
+
+// ls bucket/{a,b{c,d}}/*.txt
+func TestListS3ObjectsWithBraces(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "a/file1.txt", "content")
+	putFile(t, s3client, bucket, "b/file2.txt", "content")
+	putFile(t, s3client, bucket, "bc/file3.txt", "content")
+	putFile(t, s3client, bucket, "bd/file4.txt", "content")
+	putFile(t, s3client, bucket, "bd/file5.log", "content")
+
+	cmd := s5cmd("ls", "s3://"+bucket+"/{a,b{c,d}}/*.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("300 a/file1.txt"),
+		1: suffix("301 bc/file3.txt"),
+		2: suffix("301 bd/file4.txt"),
+	}, alignment(true))
+}
+
+// ls bucket/{a,b}/*.txt
+func TestListS3ObjectsWithBracesNoMatch(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "c/file.txt", "content")
+
+	cmd := s5cmd("ls", "s3://"+bucket+"/{a,b}/*.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "ls s3://%v/{a,b}/*.txt": no object found`, bucket),
+	})
+}
+
+// ls bucket/prefix/log-2023-0?-*.gz bucket/prefix/data[0-9].csv
+func TestListS3ObjectsSorted(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		expected map[int]compareFunc
+	}{
+		{
+			name:  "size",
+			flags: []string{"--sort", "size"},
+			expected: map[int]compareFunc{
+				0: suffix(" 1 b.txt"),
+				1: suffix(" 3 a.txt"),
+				2: suffix(" 3 c.txt"),
+			},
+		},
+		{
+			name:  "size reverse",
+			flags: []string{"--sort", "size", "--reverse"},
+			expected: map[int]compareFunc{
+				0: suffix(" 3 c.txt"),
+				1: suffix(" 3 a.txt"),
+				2: suffix(" 1 b.txt"),
+			},
+		},
+		{
+			name:  "name reverse",
+			flags: []string{"--sort", "name", "--reverse"},
+			expected: map[int]compareFunc{
+				0: suffix(" c.txt"),
+				1: suffix(" b.txt"),
+				2: suffix(" a.txt"),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the sizes of the objects are listed correctly by the mem backend.
+			s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+			putFile(t, s3client, bucket, "a.txt", "foo")
+			putFile(t, s3client, bucket, "b.txt", "f")
+			putFile(t, s3client, bucket, "c.txt", "bar")
+
+			args := append([]string{"ls"}, tc.flags...)
+			args = append(args, "s3://"+bucket+"/*")
+
+			cmd := s5cmd(args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), tc.expected)
+		})
+	}
+}
+
+func TestListS3ObjectsSortFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		expected string
+	}{
+		{
+			name:     "invalid sort key",
+			flags:    []string{"--sort", "owner"},
+			expected: `ERROR "ls s3://bucket/*": invalid sort key "owner"`,
+		},
+		{
+			name:     "reverse without sort",
+			flags:    []string{"--reverse"},
+			expected: `ERROR "ls s3://bucket/*": --reverse can only be used with --sort`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			args := append([]string{"ls"}, tc.flags...)
+			args = append(args, "s3://bucket/*")
+
+			cmd := s5cmd(args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals("%v", tc.expected),
+			})
+		})
+	}
+}
+
+func TestListS3ObjectsWithMaxKeysAndStartAfter(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	testcases := []struct {
+		name     string
+		flags    []string
+		expected map[int]compareFunc
+	}{
+		{
+			name:  "max keys",
+			flags: []string{"--max-keys", "2"},
+			expected: map[int]compareFunc{
+				0: suffix(" a.txt"),
+				1: suffix(" b.txt"),
+			},
+		},
+		{
+			name:  "start after",
+			flags: []string{"--start-after", "b.txt"},
+			expected: map[int]compareFunc{
+				0: suffix(" c.txt"),
+				1: suffix(" d.txt"),
+			},
+		},
+		{
+			name:  "max keys and start after",
+			flags: []string{"--max-keys", "1", "--start-after", "a.txt"},
+			expected: map[int]compareFunc{
+				0: suffix(" b.txt"),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the bolt backend does not support listing after a key.
+			s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+			for _, filename := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+				putFile(t, s3client, bucket, filename, "content")
+			}
+
+			args := append([]string{"ls"}, tc.flags...)
+			args = append(args, "s3://"+bucket+"/*")
+
+			cmd := s5cmd(args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), tc.expected)
+		})
+	}
+}
+
+func TestListS3ObjectsWithDelimiter(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "2020-01-report.txt", "content")
+	putFile(t, s3client, bucket, "2020-02-report.txt", "content")
+	putFile(t, s3client, bucket, "2021-01-report.txt", "content")
+
+	cmd := s5cmd("ls", "--delimiter", "-", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("DIR 2020-"),
+		1: suffix("DIR 2021-"),
+	})
+}
+
+func TestListWithListingFlagsFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "negative max keys",
+			args:     []string{"--max-keys", "-1", "s3://bucket/*"},
+			expected: `ERROR "ls s3://bucket/*": max keys cannot be a negative value`,
+		},
+		{
+			name:     "start after for buckets",
+			args:     []string{"--start-after", "a"},
+			expected: `ERROR "ls": --start-after can not be used to list buckets`,
+		},
+		{
+			name:     "delimiter for local directory",
+			args:     []string{"--delimiter", "-", "dir/"},
+			expected: `ERROR "ls dir/": --delimiter can only be used for remote listings`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(append([]string{"ls"}, tc.args...)...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals("%v", tc.expected),
+			})
+		})
+	}
+}
+
+func TestListS3ObjectsWithCharacterClassAndQuestionMark(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "prefix/log-2023-01-01.gz", "content")
+	putFile(t, s3client, bucket, "prefix/log-2023-09-30.gz", "content")
+	putFile(t, s3client, bucket, "prefix/log-2023-10-01.gz", "content")
+	putFile(t, s3client, bucket, "prefix/data1.csv", "content")
+	putFile(t, s3client, bucket, "prefix/data9.csv", "content")
+	putFile(t, s3client, bucket, "prefix/data10.csv", "content")
+	putFile(t, s3client, bucket, "prefix/datax.csv", "content")
+	putFile(t, s3client, bucket, "prefix/[old]/data.csv", "content")
+
+	testcases := []struct {
+		pattern  string
+		expected map[int]compareFunc
+	}{
+		{
+			pattern: "prefix/log-2023-0?-*.gz",
+			expected: map[int]compareFunc{
+				0: suffix(" log-2023-01-01.gz"),
+				1: suffix(" log-2023-09-30.gz"),
+			},
+		},
+		{
+			pattern: "prefix/data[0-9].csv",
+			expected: map[int]compareFunc{
+				0: suffix(" data1.csv"),
+				1: suffix(" data9.csv"),
+			},
+		},
+		{
+			pattern: "prefix/data[^0-9].csv",
+			expected: map[int]compareFunc{
+				0: suffix(" datax.csv"),
+			},
+		},
+		{
+			pattern: "prefix/[[]old]/*",
+			expected: map[int]compareFunc{
+				0: suffix(" [old]/data.csv"),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		cmd := s5cmd("ls", fmt.Sprintf("s3://%v/%v", bucket, tc.pattern))
+		result := icmd.RunCmd(cmd)
+
+		result.Assert(t, icmd.Success)
+
+		assertLines(t, result.Stdout(), tc.expected)
+	}
+}
+
 // ls bucket
 func TestListS3ObjectsAndFolders(t *testing.T) {
 	t.Parallel()
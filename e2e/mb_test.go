@@ -102,3 +102,28 @@ func TestMakeBucket_failure_json(t *testing.T) {
 		0: equals(`{"operation":"mb","command":"mb %v","error":"invalid s3 bucket"}`, src),
 	}, jsonCheck(true))
 }
+
+// --dry-run mb s3://bucket
+func TestMakeBucketDryRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	bucketName := "test-bucket"
+	src := fmt.Sprintf("s3://%s", bucketName)
+
+	cmd := s5cmd("--dry-run", "mb", src)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`mb %v`, src),
+	})
+
+	_, err := s3client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucketName)})
+	if err == nil {
+		t.Errorf("bucket is created in dry-run mode")
+	}
+}
@@ -313,6 +313,42 @@ func TestMoveMultipleS3ObjectsToS3(t *testing.T) {
 	}
 }
 
+// mv -r s3://bucket/prefix s3://bucket/dst/
+func TestMoveS3PrefixToS3Recursive(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "prefix/file1.txt", "content 1")
+	putFile(t, s3client, bucket, "prefix/dir/file2.txt", "content 2")
+	putFile(t, s3client, bucket, "prefix-sibling/file3.txt", "content 3")
+
+	src := fmt.Sprintf("s3://%v/prefix", bucket)
+	dst := fmt.Sprintf("s3://%v/dst/", bucket)
+
+	cmd := s5cmd("mv", "-r", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("mv %v/dir/file2.txt %vdir/file2.txt", src, dst),
+		1: equals("mv %v/file1.txt %vfile1.txt", src, dst),
+	}, sortInput(true))
+
+	assertError(t, ensureS3Object(s3client, bucket, "prefix/file1.txt", "content 1"), errS3NoSuchKey)
+	assertError(t, ensureS3Object(s3client, bucket, "prefix/dir/file2.txt", "content 2"), errS3NoSuchKey)
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "dst/file1.txt", "content 1"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "dst/dir/file2.txt", "content 2"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix-sibling/file3.txt", "content 3"))
+}
+
 // --dry-run mv s3://bucket/* s3://bucket2/prefix/
 func TestMoveMultipleS3ObjectsToS3DryRun(t *testing.T) {
 	t.Parallel()
@@ -362,6 +398,64 @@ func TestMoveMultipleS3ObjectsToS3DryRun(t *testing.T) {
 	}
 }
 
+// mv --raw s3://bucket/file?.txt s3://bucket/dst/
+func TestMoveS3ObjectWithGlobCharactersRawFlag(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name   string
+		key    string
+		others []string
+	}{
+		{name: "star", key: "file*.txt", others: []string{"file1.txt", "file*1.txt"}},
+		{name: "question mark", key: "file?.txt", others: []string{"file1.txt", "file??.txt"}},
+		{name: "bracket", key: "file[1].txt", others: []string{"file1.txt", "file[2].txt"}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			const (
+				bucket  = "bucket"
+				content = "this is a file content"
+			)
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+
+			putFile(t, s3client, bucket, tc.key, content)
+			for _, key := range tc.others {
+				putFile(t, s3client, bucket, key, content)
+			}
+
+			src := fmt.Sprintf("s3://%v/%v", bucket, tc.key)
+			dst := fmt.Sprintf("s3://%v/dst/", bucket)
+
+			cmd := s5cmd("mv", "--raw", src, dst)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals("mv %v %v%v", src, dst, tc.key),
+			})
+
+			assert.Assert(t, ensureS3Object(s3client, bucket, "dst/"+tc.key, content))
+
+			err := ensureS3Object(s3client, bucket, tc.key, content)
+			assertError(t, err, errS3NoSuchKey)
+
+			for _, key := range tc.others {
+				assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+			}
+		})
+	}
+}
+
 // mv --raw file s3://bucket/
 func TestMoveLocalObjectToS3WithRawFlag(t *testing.T) {
 	if runtime.GOOS == "windows" {
@@ -425,3 +519,209 @@ func TestMoveLocalObjectToS3WithRawFlag(t *testing.T) {
 	expected := fs.Expected(t, otherObjects...)
 	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 }
+
+// mv file s3://nonexistentbucket/
+func TestMoveSingleFileToS3Fail(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is a test file"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/", bucket)
+	cmd := s5cmd("mv", filename, dst)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`ERROR "mv %v %v%v"`, filename, dst, filename),
+	})
+
+	// assert the source file is not removed since the upload failed
+	expected := fs.Expected(t, fs.WithFile(filename, content, fs.WithMode(0644)))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// mv s3://bucket/object s3://nonexistentbucket/object
+func TestMoveSingleS3ObjectToS3Fail(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := "nonexistent-" + bucket
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is a file content"
+	)
+
+	putFile(t, s3client, bucket, filename, content)
+
+	src := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	dst := fmt.Sprintf("s3://%v/%v", dstbucket, filename)
+
+	cmd := s5cmd("mv", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`ERROR "mv %v %v"`, src, dst),
+	})
+
+	// assert the source object is not removed since the copy failed
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+// mv s3://bucket/object file/object (file is not a directory)
+func TestMoveSingleS3ObjectToLocalFail(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is a file content"
+	)
+
+	putFile(t, s3client, bucket, filename, content)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile("file", "not a directory"))
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	cmd := s5cmd("mv", src, "file/"+filename)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`ERROR "mv %v file/%v"`, src, filename),
+	})
+
+	// assert the source object is not removed since the download failed
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+// mv -n s3://bucket/object dir/ (object exists in dir)
+func TestMoveSingleS3ObjectToLocalNoClobber(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename     = "testfile.txt"
+		content      = "this is a file content"
+		localContent = "this is the local content"
+	)
+
+	putFile(t, s3client, bucket, filename, content)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, localContent))
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	cmd := s5cmd("mv", "-n", src, ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+
+	// assert neither the local file is overridden nor the source object is
+	// removed since the move is skipped
+	expected := fs.Expected(t, fs.WithFile(filename, localContent, fs.WithMode(0644)))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+// mv file other-file
+func TestMoveLocalFileToLocal(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	const content = "this is a file content"
+
+	workdir := fs.NewDir(t, "", fs.WithFile("a.txt", content, fs.WithMode(0600)))
+	defer workdir.Remove()
+
+	cmd := s5cmd("mv", "a.txt", "b.txt")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("mv a.txt b.txt"),
+	})
+
+	expected := fs.Expected(t, fs.WithFile("b.txt", content, fs.WithMode(0600)))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// mv 'src/*' dst/
+func TestMoveLocalDirectoryToLocal(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	workdir := fs.NewDir(t, "",
+		fs.WithDir("src",
+			fs.WithFile("a.txt", "content of a"),
+			fs.WithDir("nested",
+				fs.WithFile("b.txt", "content of b"),
+			),
+		),
+	)
+	defer workdir.Remove()
+
+	cmd := s5cmd("mv", "src/*", "dst/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("mv src/a.txt dst/a.txt"),
+		1: equals("mv src/nested/b.txt dst/nested/b.txt"),
+	}, sortInput(true))
+
+	// the directories of the source are not deleted.
+	expected := fs.Expected(t,
+		fs.WithDir("src", fs.WithDir("nested")),
+		fs.WithDir("dst",
+			fs.WithFile("a.txt", "content of a"),
+			fs.WithDir("nested",
+				fs.WithFile("b.txt", "content of b"),
+			),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
@@ -0,0 +1,172 @@
+package e2e
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/icmd"
+)
+
+func TestPresignS3ObjectForDownload(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.txt"
+		content  = "this is a file content"
+	)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, filename, content)
+
+	cmd := s5cmd("presign", "--expire", "5m", fmt.Sprintf("s3://%v/%v", bucket, filename))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(`/bucket/file.txt\?.*X-Amz-Expires=300`),
+	})
+
+	resp, err := http.Get(strings.TrimSpace(result.Stdout()))
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NilError(t, err)
+
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, string(body), content)
+}
+
+func TestPresignS3ObjectForUpload(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bucket   = "bucket"
+		filename = "file.txt"
+		content  = "this is a file content"
+	)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("presign", "--method", "put", fmt.Sprintf("s3://%v/%v", bucket, filename))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(`/bucket/file.txt\?.*X-Amz-Expires=3600`),
+	})
+
+	req, err := http.NewRequest(http.MethodPut, strings.TrimSpace(result.Stdout()), strings.NewReader(content))
+	assert.NilError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NilError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	// assert s3 object
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+}
+
+func TestPresignS3ObjectJSON(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, "bucket")
+
+	cmd := s5cmd("--json", "presign", "s3://bucket/file.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(`^{"source":"s3://bucket/file.txt","method":"GET","url":".*/bucket/file.txt\?.*X-Amz-Expires=3600.*"}$`),
+	}, jsonCheck(true))
+}
+
+func TestPresignFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		cmd      []string
+		expected map[int]compareFunc
+	}{
+		{
+			name: "presign local file",
+			cmd: []string{
+				"presign",
+				"file.txt",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "presign file.txt": source must be a remote object`),
+			},
+		},
+		{
+			name: "presign bucket",
+			cmd: []string{
+				"presign",
+				"s3://bucket",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "presign s3://bucket": remote source must be an object`),
+			},
+		},
+		{
+			name: "presign with unsupported method",
+			cmd: []string{
+				"presign",
+				"--method",
+				"DELETE",
+				"s3://bucket/file.txt",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "presign s3://bucket/file.txt": unsupported method "DELETE", expected GET or PUT`),
+			},
+		},
+		{
+			name: "presign with too long expiration time",
+			cmd: []string{
+				"presign",
+				"--expire",
+				"200h",
+				"s3://bucket/file.txt",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "presign s3://bucket/file.txt": expire must be a positive duration of at most 168h0m0s`),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.cmd...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+			assertLines(t, result.Stderr(), tc.expected)
+		})
+	}
+}
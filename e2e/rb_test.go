@@ -134,3 +134,83 @@ func TestRemoveBucketWithObject(t *testing.T) {
 		0: match(expected),
 	})
 }
+
+// --dry-run rb s3://bucket
+func TestRemoveBucketDryRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	bucketName := "bucket"
+	src := fmt.Sprintf("s3://%v", bucketName)
+
+	createBucket(t, s3client, bucketName)
+
+	cmd := s5cmd("--dry-run", "rb", src)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rb %v`, src),
+	})
+
+	_, err := s3client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		t.Errorf("bucket is removed in dry-run mode: %v", err)
+	}
+}
+
+// rb --force s3://bucket
+func TestRemoveBucketForce(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "test-bucket"
+
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "prefix/file2.txt", "content")
+
+	bucketName := fmt.Sprintf("s3://%v", bucket)
+	cmd := s5cmd("rb", "--force", bucketName)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rb %v`, bucketName),
+		1: match(fmt.Sprintf(`^rm %v/file1.txt(\?versionId=.+)?$`, bucketName)),
+		2: match(fmt.Sprintf(`^rm %v/prefix/file2.txt(\?versionId=.+)?$`, bucketName)),
+	}, sortInput(true))
+
+	_, err := s3client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if err == nil {
+		t.Errorf("bucket still exists after remove bucket operation\n")
+	}
+}
+
+// rb --force s3://bucket
+func TestRemoveBucketForceEmptyBucket(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "test-bucket"
+
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	bucketName := fmt.Sprintf("s3://%v", bucket)
+	cmd := s5cmd("rb", "--force", bucketName)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rb %v`, bucketName),
+	})
+}
@@ -1,9 +1,18 @@
 package e2e
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	neturl "net/url"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -128,6 +137,71 @@ func TestRemoveMultipleS3Objects(t *testing.T) {
 	}
 }
 
+// rm --recursive s3://bucket/prefix
+func TestRemoveS3PrefixRecursive(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	removed := map[string]string{
+		"prefix/file1.txt":     "content 1",
+		"prefix/dir/file2.txt": "content 2",
+	}
+	kept := map[string]string{
+		"prefix-sibling/file3.txt": "content 3",
+		"prefixfile.txt":           "content 4",
+	}
+
+	for filename, content := range removed {
+		putFile(t, s3client, bucket, filename, content)
+	}
+	for filename, content := range kept {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("rm", "--recursive", "s3://"+bucket+"/prefix")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/prefix/dir/file2.txt`, bucket),
+		1: equals(`rm s3://%v/prefix/file1.txt`, bucket),
+	}, sortInput(true))
+
+	for filename, content := range removed {
+		err := ensureS3Object(s3client, bucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+	for filename, content := range kept {
+		assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+	}
+}
+
+// rm --recursive --raw s3://bucket/prefix
+func TestRemoveS3PrefixRecursiveWithRawFlag(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("rm", "--recursive", "--raw", "s3://bucket/prefix")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "rm s3://bucket/prefix": --recursive can not be used with --raw`),
+	})
+}
+
 // --json rm s3://bucket/*
 func TestRemoveMultipleS3ObjectsJSON(t *testing.T) {
 	t.Parallel()
@@ -716,6 +790,60 @@ func TestRemoveS3ObjectRawFlag(t *testing.T) {
 	}
 }
 
+// rm --raw s3://bucket/file?.txt
+func TestRemoveS3ObjectsWithGlobCharactersRawFlag(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name   string
+		key    string
+		others []string
+	}{
+		{name: "star", key: "file*.txt", others: []string{"file1.txt", "file*1.txt"}},
+		{name: "question mark", key: "file?.txt", others: []string{"file1.txt", "file??.txt"}},
+		{name: "bracket", key: "file[1].txt", others: []string{"file1.txt", "file[2].txt"}},
+		{name: "brace", key: "file{1,2}.txt", others: []string{"file1.txt", "file2.txt"}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			const (
+				bucket  = "bucket"
+				content = "this is a file content"
+			)
+
+			s3client, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, bucket)
+
+			putFile(t, s3client, bucket, tc.key, content)
+			for _, key := range tc.others {
+				putFile(t, s3client, bucket, key, content)
+			}
+
+			cmd := s5cmd("rm", "--raw", "s3://"+bucket+"/"+tc.key)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals(`rm s3://%v/%v`, bucket, tc.key),
+			})
+
+			err := ensureS3Object(s3client, bucket, tc.key, content)
+			assertError(t, err, errS3NoSuchKey)
+
+			for _, key := range tc.others {
+				assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+			}
+		})
+	}
+}
+
 func TestRemoveS3ObjectsPrefixRawFlag(t *testing.T) {
 	t.Parallel()
 
@@ -865,6 +993,112 @@ func TestRemoveMultipleS3ObjectsWithExcludeFilter(t *testing.T) {
 	}
 }
 
+// rm --exclude "*.md" --exclude-from exclude.txt --include-from include.txt s3://bucket/*
+func TestRemoveMultipleS3ObjectsWithFilterFiles(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	const (
+		bucket  = "bucket"
+		content = "content"
+	)
+	createBucket(t, s3client, bucket)
+
+	const (
+		excludeFile = `# text files are kept
+*.txt
+
+a/*
+`
+		includeFile = `# python files are removed everywhere
+*.py
+`
+	)
+
+	workdir := fs.NewDir(t, "filters", fs.WithFile("exclude.txt", excludeFile), fs.WithFile("include.txt", includeFile))
+	defer workdir.Remove()
+
+	for _, filename := range []string{"readme.md", "file.txt", "file.gz", "a/file.gz", "a/file.py"} {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd(
+		"rm",
+		"--exclude", "*.md",
+		"--exclude-from", workdir.Join("exclude.txt"),
+		"--include-from", workdir.Join("include.txt"),
+		"s3://"+bucket+"/*",
+	)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/a/file.py`, bucket),
+		1: equals(`rm s3://%v/file.gz`, bucket),
+	}, sortInput(true))
+
+	for _, filename := range []string{"readme.md", "file.txt", "a/file.gz"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+	}
+	for _, filename := range []string{"file.gz", "a/file.py"} {
+		err := ensureS3Object(s3client, bucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+func TestRemoveWithMissingFilterFile(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("rm", "--exclude-from", "missing.txt", "s3://bucket/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: match(`^ERROR "rm s3://bucket/\*": open missing.txt: `),
+	})
+}
+
+// rm --older-than 30d s3://bucket/*
+func TestRemoveS3ObjectsWithDateFilter(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+
+	// the objects are just created, so none of them is deleted.
+	cmd := s5cmd("rm", "--older-than", "30d", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), "")
+
+	cmd = s5cmd("rm", "--newer-than", "1h", "s3://"+bucket+"/*")
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/file1.txt`, bucket),
+		1: equals(`rm s3://%v/file2.txt`, bucket),
+	}, sortInput(true))
+
+	err := ensureS3Object(s3client, bucket, "file1.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+}
+
 // rm --exclude "*.txt" "*.gz" s3://bucket/*
 func TestRemoveMultipleS3ObjectsWithExcludeFilters(t *testing.T) {
 	t.Parallel()
@@ -932,6 +1166,60 @@ func TestRemoveMultipleS3ObjectsWithExcludeFilters(t *testing.T) {
 	}
 }
 
+// rm --exclude "a/*" --include "a/*.py" s3://bucket/*
+func TestRemoveMultipleS3ObjectsWithExcludeAndIncludeFilters(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	expectedFiles := map[string]string{
+		"a/file.txt":   "this is a txt file",
+		"a/b/file.txt": "this is a nested txt file",
+	}
+
+	nonExpectedFiles := map[string]string{
+		"readme.md":   "this is a readme file",
+		"a/file.py":   "this is a python file with prefix a",
+		"a/b/file.py": "this is a nested python file",
+	}
+
+	for filename, content := range expectedFiles {
+		putFile(t, s3client, bucket, filename, content)
+	}
+	for filename, content := range nonExpectedFiles {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("rm", "--exclude", "a/*", "--include", "a/*.py", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/a/b/file.py`, bucket),
+		1: equals(`rm s3://%v/a/file.py`, bucket),
+		2: equals(`rm s3://%v/readme.md`, bucket),
+	}, sortInput(true))
+
+	// assert s3 objects were not removed
+	for filename, content := range expectedFiles {
+		assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+	}
+
+	// assert s3 objects should be removed
+	for filename, content := range nonExpectedFiles {
+		err := ensureS3Object(s3client, bucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
 // rm --exclude "" s3://bucket/*
 func TestRemoveS3ObjectsWithEmptyExcludeFilter(t *testing.T) {
 	t.Parallel()
@@ -1170,3 +1458,64 @@ func TestRemoveLocalFilesWithPrefixandExcludeFilters(t *testing.T) {
 	expected := fs.Expected(t, expectedFileSystem...)
 	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 }
+
+// --stat rm s3://bucket/* with a key which is not deleted
+func TestRemoveMultipleS3ObjectsPartialFailureStat(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, _, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+	putFile(t, s3client, bucket, "locked.txt", "content")
+
+	// the test server deletes all the keys, so one of them is reported as
+	// failed by the proxy.
+	deleted := regexp.MustCompile(`<Deleted>\s*<Key>locked.txt</Key>\s*</Deleted>`)
+	failed := []byte(`<Error><Key>locked.txt</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+
+	backend, err := neturl.Parse(s3client.Endpoint)
+	assert.NilError(t, err)
+
+	proxy := httputil.NewSingleHostReverseProxy(backend)
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if _, isDelete := resp.Request.URL.Query()["delete"]; !isDelete || resp.Request.Method != http.MethodPost {
+			return nil
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		body = deleted.ReplaceAll(body, failed)
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return nil
+	}
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	_, s5cmd, cleanupProxy := setup(t, withEndpointURL(server.URL))
+	defer cleanupProxy()
+
+	cmd := s5cmd("--stat", "rm", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "rm s3://%v/locked.txt": AccessDenied: Access Denied [AccessDenied]`, bucket),
+	})
+
+	// the keys are counted separately.
+	out := result.Stdout()
+	assert.Assert(t, strings.Contains(out, "Operations: 3, Succeeded: 2, Failed: 1, "), out)
+	assert.Assert(t, strings.Contains(out, "Errors: AccessDenied: 1\n"), out)
+}
@@ -62,7 +62,7 @@ func TestRunFromStdinWithErrors(t *testing.T) {
 	cmd := s5cmd("run")
 	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
 
-	result.Assert(t, icmd.Success)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{})
 
@@ -72,6 +72,111 @@ func TestRunFromStdinWithErrors(t *testing.T) {
 	}, sortInput(true))
 }
 
+func TestRunFromStdinWithFailFast(t *testing.T) {
+	t.Parallel()
+
+	const commandCount = 20
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+
+	lines := []string{fmt.Sprintf("ls s3://%v/nonexistentobject", bucket)}
+	for i := 0; i < commandCount; i++ {
+		lines = append(lines, fmt.Sprintf("ls s3://%v/file1.txt", bucket))
+	}
+	input := strings.NewReader(strings.Join(lines, "\n"))
+
+	cmd := s5cmd("--numworkers", "1", "run", "--fail-fast")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "ls s3://%v/nonexistentobject": no object found`, bucket),
+		1: match(`^ERROR "run": run is aborted after a failure, [0-9]+ commands are skipped$`),
+	})
+
+	// the commands read before the failure is noticed may still run.
+	if n := strings.Count(result.Stdout(), "file1.txt"); n >= commandCount {
+		t.Errorf("expected remaining commands to be skipped, %d of them run", n)
+	}
+}
+
+func TestRunFromStdinWithUnknownCommand(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	input := strings.NewReader(
+		strings.Join([]string{
+			fmt.Sprintf("ls s3://%v/file.txt", bucket),
+			"unknowncommand",
+		}, "\n"),
+	)
+	cmd := s5cmd("run")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	// the failing line doesn't stop the others but is reflected in the exit
+	// code.
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("file.txt"),
+	})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "run": "unknowncommand" command (line: 1) not found`),
+	})
+}
+
+func TestRunFromStdinWithNumworkersHint(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+
+	input := strings.NewReader(
+		strings.Join([]string{
+			fmt.Sprintf("--numworkers 1 cp s3://%v/* s3://%v/copy/", bucket, bucket),
+			fmt.Sprintf("--numworkers=2 ls s3://%v/file1.txt", bucket),
+			fmt.Sprintf("--numworkers 0 ls s3://%v/file2.txt", bucket),
+			"--numworkers",
+		}, "\n"),
+	)
+	cmd := s5cmd("run")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(" file1.txt"),
+		1: equals(`cp s3://%v/file1.txt s3://%v/copy/file1.txt`, bucket, bucket),
+		2: equals(`cp s3://%v/file2.txt s3://%v/copy/file2.txt`, bucket, bucket),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "run": numworkers expects a value (line: 3)`),
+		1: equals(`ERROR "run": numworkers must be a positive number, got "0" (line: 2)`),
+	}, sortInput(true))
+}
+
 func TestRunFromStdinJSON(t *testing.T) {
 	t.Parallel()
 
@@ -103,6 +208,51 @@ func TestRunFromStdinJSON(t *testing.T) {
 	assertLines(t, result.Stderr(), map[int]compareFunc{})
 }
 
+func TestRunFromStdinWithUnterminatedQuoteJSON(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	input := strings.NewReader(
+		strings.Join([]string{
+			fmt.Sprintf(`ls "s3://%v/file1.txt`, bucket),
+		}, "\n"),
+	)
+	cmd := s5cmd("--json", "run")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`{"operation":"run","command":"run","error":"Unterminated double-quoted string (line: 0)"}`),
+	}, jsonCheck(true))
+}
+
+func TestRunFromNonexistentFile(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("run", "nonexistentfile")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: prefix(`ERROR "run nonexistentfile": open nonexistentfile:`),
+	})
+}
+
 func TestRunFromFile(t *testing.T) {
 	t.Parallel()
 
@@ -136,6 +286,198 @@ func TestRunFromFile(t *testing.T) {
 	assertLines(t, result.Stderr(), map[int]compareFunc{})
 }
 
+func TestRunFromMultipleFilesAndStdin(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+	putFile(t, s3client, bucket, "file3.txt", "content")
+
+	file1 := fs.NewFile(t, "prefix", fs.WithContent(fmt.Sprintf("ls s3://%v/file1.txt", bucket)))
+	defer file1.Remove()
+
+	file3 := fs.NewFile(t, "prefix", fs.WithContent(fmt.Sprintf("ls s3://%v/file3.txt", bucket)))
+	defer file3.Remove()
+
+	input := strings.NewReader(fmt.Sprintf("ls s3://%v/file2.txt", bucket))
+
+	cmd := s5cmd("run", file1.Path(), "-", file3.Path())
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("file1.txt"),
+		1: suffix("file2.txt"),
+		2: suffix("file3.txt"),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+}
+
+func TestRunInlineCommands(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+	putFile(t, s3client, bucket, "file3.txt", "content")
+
+	commands := strings.Join([]string{
+		fmt.Sprintf("ls s3://%v/file1.txt", bucket),
+		"# this is a comment",
+		fmt.Sprintf("ls s3://%v/file2.txt", bucket),
+	}, "\n")
+
+	// standard input is not read if the commands are given inline.
+	input := strings.NewReader(fmt.Sprintf("ls s3://%v/file3.txt", bucket))
+
+	cmd := s5cmd("run", "-c", commands)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("file1.txt"),
+		1: suffix("file2.txt"),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+}
+
+func TestRunInlineCommandsAndFile(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+
+	file := fs.NewFile(t, "prefix", fs.WithContent(fmt.Sprintf("ls s3://%v/file2.txt", bucket)))
+	defer file.Remove()
+
+	cmd := s5cmd("run", "--commands", fmt.Sprintf("ls s3://%v/file1.txt", bucket), file.Path())
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("file1.txt"),
+		1: suffix("file2.txt"),
+	}, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+}
+
+func TestRunFromMultipleFilesWithNonexistentFile(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+
+	file := fs.NewFile(t, "prefix", fs.WithContent(fmt.Sprintf("ls s3://%v/file1.txt", bucket)))
+	defer file.Remove()
+
+	cmd := s5cmd("run", "nonexistentfile", file.Path())
+	result := icmd.RunCmd(cmd)
+
+	// the remaining files are run but the failure is reflected in the exit
+	// code.
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("file1.txt"),
+	})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: prefix(`ERROR "run nonexistentfile %v": open nonexistentfile:`, file.Path()),
+	})
+}
+
+func TestRunFromStdinMoreThanOnce(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("run", "-", "-")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "run - -": standard input can be given only once`),
+	})
+}
+
+func TestRunFromStdinWithQueueSize(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	var lines []string
+	for i := 0; i < 10; i++ {
+		filename := fmt.Sprintf("file%d.txt", i)
+		putFile(t, s3client, bucket, filename, "content")
+		lines = append(lines, fmt.Sprintf("ls s3://%v/%v", bucket, filename))
+	}
+
+	input := strings.NewReader(strings.Join(lines, "\n"))
+	cmd := s5cmd("run", "--queue-size", "4")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(input))
+
+	result.Assert(t, icmd.Success)
+
+	expected := map[int]compareFunc{}
+	for i := 0; i < 10; i++ {
+		expected[i] = suffix("file%d.txt", i)
+	}
+	assertLines(t, result.Stdout(), expected, sortInput(true))
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+}
+
+func TestRunWithNegativeQueueSize(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("run", "--queue-size", "-1")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "run": queue-size must be a non-negative number, got -1`),
+	})
+}
+
 func TestRunFromFileJSON(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,85 @@
+package e2e
+
+import (
+	"testing"
+
+	"gotest.tools/v3/icmd"
+)
+
+// --dry-run set-acl --acl public-read s3://bucket/*
+func TestSetACLMultipleS3ObjectsDryRun(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	filesToContent := map[string]string{
+		"testfile1.txt": "this is a test file 1",
+		"a/another.txt": "yet another txt file",
+	}
+
+	for filename, content := range filesToContent {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("--dry-run", "set-acl", "--acl", "public-read", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`set-acl s3://%v/a/another.txt`, bucket),
+		1: equals(`set-acl s3://%v/testfile1.txt`, bucket),
+	}, sortInput(true))
+}
+
+func TestSetACLFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name:          "no acl",
+			args:          []string{"s3://bucket/key"},
+			expectedError: `ERROR "set-acl s3://bucket/key": expected an acl`,
+		},
+		{
+			name:          "invalid acl",
+			args:          []string{"--acl", "public", "s3://bucket/key"},
+			expectedError: `ERROR "set-acl s3://bucket/key": invalid acl "public"`,
+		},
+		{
+			name:          "local source",
+			args:          []string{"--acl", "private", "file.txt"},
+			expectedError: `ERROR "set-acl file.txt": source must be remote`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(append([]string{"set-acl"}, tc.args...)...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expectedError),
+			})
+		})
+	}
+}
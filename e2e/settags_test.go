@@ -0,0 +1,97 @@
+package e2e
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/icmd"
+)
+
+// --dry-run set-tags --tag env=prod s3://bucket/*
+func TestSetTagsMultipleS3ObjectsDryRun(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	filesToContent := map[string]string{
+		"testfile1.txt": "this is a test file 1",
+		"readme.md":     "this is a readme file",
+		"a/another.txt": "yet another txt file",
+	}
+
+	for filename, content := range filesToContent {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("--dry-run", "set-tags", "--tag", "env=prod", "--exclude", "*.md", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`set-tags s3://%v/a/another.txt`, bucket),
+		1: equals(`set-tags s3://%v/testfile1.txt`, bucket),
+	}, sortInput(true))
+
+	// assert s3 objects were not modified
+	for filename, content := range filesToContent {
+		assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+	}
+}
+
+func TestSetTagsFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name:          "no tags",
+			args:          []string{"s3://bucket/key"},
+			expectedError: `ERROR "set-tags s3://bucket/key": expected at least 1 tag`,
+		},
+		{
+			name:          "invalid tag",
+			args:          []string{"--tag", "env", "s3://bucket/key"},
+			expectedError: `ERROR "set-tags s3://bucket/key": tag "env" must be in key=value format`,
+		},
+		{
+			name:          "local source",
+			args:          []string{"--tag", "env=prod", "file.txt"},
+			expectedError: `ERROR "set-tags file.txt": source must be remote`,
+		},
+		{
+			name:          "prefix source",
+			args:          []string{"--tag", "env=prod", "s3://bucket/prefix/"},
+			expectedError: `ERROR "set-tags s3://bucket/prefix/": source argument must contain wildcard character`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(append([]string{"set-tags"}, tc.args...)...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expectedError),
+			})
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// +build !windows
+
+package e2e
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAppStatsSignal(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	icmd := s5cmd("--json", "--stat", "run")
+	cmd := exec.Command(icmd.Command[0], icmd.Command[1:]...)
+	cmd.Env = icmd.Env
+	cmd.Dir = icmd.Dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	// the signal handler is registered once the first command has run.
+	fmt.Fprintf(stdin, "ls s3://%v/file.txt\n", bucket)
+	if !bufio.NewScanner(stdout).Scan() {
+		t.Fatalf("expected output of the command, got none")
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	linech := make(chan string)
+	go func() {
+		defer close(linech)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			linech <- scanner.Text()
+		}
+	}()
+
+	var progress, summary bool
+	timeout := time.After(10 * time.Second)
+	for !progress || !summary {
+		select {
+		case line, ok := <-linech:
+			if !ok {
+				t.Fatalf("expected the stats in stderr, progress: %v, summary: %v", progress, summary)
+			}
+			progress = progress || strings.HasPrefix(line, `{"progress":`)
+			summary = summary || strings.Contains(line, `"operations":`)
+		case <-timeout:
+			t.Fatalf("timed out waiting for the stats, progress: %v, summary: %v", progress, summary)
+		}
+	}
+
+	stdin.Close()
+	go func() {
+		for range linech {
+		}
+	}()
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("expected s5cmd to exit successfully: %v", err)
+	}
+}
+
+func TestRunInterruptStopsReadingCommands(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	icmd := s5cmd("run")
+	cmd := exec.Command(icmd.Command[0], icmd.Command[1:]...)
+	cmd.Env = icmd.Env
+	cmd.Dir = icmd.Dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	// the signal handler is registered once the first command has run.
+	fmt.Fprintf(stdin, "ls s3://%v/file.txt\n", bucket)
+	outscanner := bufio.NewScanner(stdout)
+	if !outscanner.Scan() {
+		t.Fatalf("expected output of the command, got none")
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for the interrupt to be handled.
+	if !bufio.NewScanner(stderr).Scan() {
+		t.Fatalf("expected the drain warning in stderr, got none")
+	}
+
+	// the commands read after the interrupt are not run, and run exits
+	// without waiting for the end of the input.
+	fmt.Fprintf(stdin, "ls s3://%v/file.txt\n", bucket)
+
+	exitch := make(chan error, 1)
+	go func() {
+		var lines []string
+		for outscanner.Scan() {
+			lines = append(lines, outscanner.Text())
+		}
+		if len(lines) > 0 {
+			exitch <- fmt.Errorf("expected no output after the interrupt, got %q", lines)
+			return
+		}
+		exitch <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-exitch:
+		if err != nil {
+			if _, ok := err.(*exec.ExitError); !ok {
+				t.Fatal(err)
+			}
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for s5cmd to exit after the interrupt")
+	}
+}
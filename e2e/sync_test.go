@@ -0,0 +1,509 @@
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+// sync dir/ s3://bucket/prefix/
+func TestSyncLocalFolderToS3PrefixEmptyDestination(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	folderLayout := []fs.PathOp{
+		fs.WithFile("file1.txt", "content1"),
+		fs.WithDir(
+			"a",
+			fs.WithFile("file2.txt", "content2"),
+		),
+	}
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithDir("dir", folderLayout...))
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("sync", "dir/", dst)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp dir/a/file2.txt %va/file2.txt`, dst),
+		1: equals(`cp dir/file1.txt %vfile1.txt`, dst),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/file1.txt", "content1"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/a/file2.txt", "content2"))
+}
+
+// sync dir/ s3://bucket/
+func TestSyncLocalFolderToS3BucketOnlyChangedFiles(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	// bolt backend reports wrong object sizes while listing, hence use
+	// in-memory storage for size comparisons.
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "unchanged.txt", "content")
+	putFile(t, s3client, bucket, "size-differs.txt", "content")
+
+	// local files are older than their remote counterparts.
+	past := time.Now().Add(-time.Hour)
+	timestamp := fs.WithTimestamps(past, past)
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithDir("dir",
+			fs.WithFile("unchanged.txt", "content", timestamp),
+			fs.WithFile("size-differs.txt", "changed content", timestamp),
+			fs.WithFile("new.txt", "new content", timestamp),
+		),
+	)
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "dir/", dst)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp dir/new.txt %vnew.txt`, dst),
+		1: equals(`cp dir/size-differs.txt %vsize-differs.txt`, dst),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "unchanged.txt", "content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "size-differs.txt", "changed content"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "new.txt", "new content"))
+}
+
+// sync s3://bucket/prefix/* dir/
+func TestSyncS3PrefixToLocalFolderSourceIsNewer(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	// bolt backend reports wrong object sizes while listing, hence use
+	// in-memory storage for size comparisons.
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "prefix/older.txt", "remote")
+	putFile(t, s3client, bucket, "prefix/newer.txt", "remote")
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithDir("dir",
+			fs.WithFile("older.txt", "locals", fs.WithTimestamps(past, past)),
+			fs.WithFile("newer.txt", "locals", fs.WithTimestamps(future, future)),
+		),
+	)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/prefix/*", bucket)
+
+	cmd := s5cmd("sync", src, "dir/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/prefix/older.txt dir/older.txt`, bucket),
+	})
+
+	expected := fs.Expected(t,
+		fs.WithDir("dir",
+			fs.WithFile("older.txt", "remote"),
+			fs.WithFile("newer.txt", "locals"),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// sync --size-only s3://bucket/prefix/ dir/
+func TestSyncS3PrefixToLocalFolderSizeOnly(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	// bolt backend reports wrong object sizes while listing, hence use
+	// in-memory storage for size comparisons.
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "prefix/samesize.txt", "remote")
+	putFile(t, s3client, bucket, "prefix/sizediffers.txt", "remote")
+
+	past := time.Now().Add(-time.Hour)
+	timestamp := fs.WithTimestamps(past, past)
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithDir("dir",
+			fs.WithFile("samesize.txt", "locals", timestamp),
+			fs.WithFile("sizediffers.txt", "local content", timestamp),
+		),
+	)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("sync", "--size-only", src, "dir/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/prefix/sizediffers.txt dir/sizediffers.txt`, bucket),
+	})
+
+	expected := fs.Expected(t,
+		fs.WithDir("dir",
+			fs.WithFile("samesize.txt", "locals"),
+			fs.WithFile("sizediffers.txt", "remote"),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// sync --delete s3://bucket/* s3://destbucket/prefix/
+func TestSyncS3BucketToS3PrefixWithDelete(t *testing.T) {
+	t.Parallel()
+
+	const (
+		srcbucket = "bucket"
+		dstbucket = "destbucket"
+	)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	putFile(t, s3client, srcbucket, "file1.txt", "content")
+	putFile(t, s3client, dstbucket, "prefix/stale.txt", "content")
+	putFile(t, s3client, dstbucket, "outside.txt", "content")
+
+	src := fmt.Sprintf("s3://%v/*", srcbucket)
+	dst := fmt.Sprintf("s3://%v/prefix/", dstbucket)
+
+	cmd := s5cmd("sync", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/file1.txt %vfile1.txt`, srcbucket, dst),
+		1: equals(`rm %vstale.txt`, dst),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "prefix/file1.txt", "content"))
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "outside.txt", "content"))
+
+	err := ensureS3Object(s3client, dstbucket, "prefix/stale.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// sourceListingProxy returns the endpoint of a proxy in front of the test
+// server, which modifies the responses of the listings of the given bucket.
+func sourceListingProxy(t *testing.T, endpoint, bucket string, modify func(*http.Response) error) (string, func()) {
+	t.Helper()
+
+	backend, err := neturl.Parse(endpoint)
+	assert.NilError(t, err)
+
+	proxy := httputil.NewSingleHostReverseProxy(backend)
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		r := resp.Request
+		_, isList := r.URL.Query()["list-type"]
+		if r.Method == http.MethodGet && isList && (r.URL.Path == "/"+bucket || r.URL.Path == "/"+bucket+"/") {
+			return modify(resp)
+		}
+		return nil
+	}
+
+	server := httptest.NewServer(proxy)
+	return server.URL, server.Close
+}
+
+// sync --delete s3://bucket/* s3://destbucket/ with objects on Glacier
+func TestSyncS3BucketToS3BucketWithDeleteKeepsGlacierObjects(t *testing.T) {
+	t.Parallel()
+
+	const (
+		srcbucket = "bucket"
+		dstbucket = "destbucket"
+	)
+
+	s3client, _, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	putFile(t, s3client, srcbucket, "archived.txt", "content")
+	putFile(t, s3client, dstbucket, "archived.txt", "content")
+	putFile(t, s3client, dstbucket, "stale.txt", "content")
+
+	// the test server does not keep storage classes, so the source objects
+	// are listed on Glacier by the proxy.
+	storageClass := regexp.MustCompile(`<StorageClass>[^<]*</StorageClass>`)
+	endpoint, closeProxy := sourceListingProxy(t, s3client.Endpoint, srcbucket, func(resp *http.Response) error {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		body = storageClass.ReplaceAll(body, nil)
+		body = bytes.ReplaceAll(body, []byte("</Size>"), []byte("</Size><StorageClass>GLACIER</StorageClass>"))
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return nil
+	})
+	defer closeProxy()
+
+	_, s5cmd, cleanupProxy := setup(t, withEndpointURL(endpoint))
+	defer cleanupProxy()
+
+	src := fmt.Sprintf("s3://%v/*", srcbucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`is on Glacier storage`),
+	})
+
+	// the destination of the object on Glacier is kept, and so are the
+	// other objects since the source is not synced without errors.
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "archived.txt", "content"))
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "stale.txt", "content"))
+}
+
+// sync --delete s3://bucket/* s3://destbucket/ when the source listing fails
+func TestSyncS3BucketToS3BucketWithDeleteSourceListingFails(t *testing.T) {
+	t.Parallel()
+
+	const (
+		srcbucket = "bucket"
+		dstbucket = "destbucket"
+	)
+
+	testcases := []struct {
+		name           string
+		globalFlags    []string
+		expectedStdout map[int]compareFunc
+	}{
+		{
+			name:           "sync",
+			expectedStdout: map[int]compareFunc{},
+		},
+		{
+			name:        "dry run",
+			globalFlags: []string{"--dry-run"},
+			expectedStdout: map[int]compareFunc{
+				0: equals(`SUMMARY new 0, changed 0, delete 0`),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, _, cleanup := setup(t)
+			defer cleanup()
+
+			createBucket(t, s3client, srcbucket)
+			createBucket(t, s3client, dstbucket)
+
+			putFile(t, s3client, srcbucket, "file1.txt", "content")
+			putFile(t, s3client, dstbucket, "file1.txt", "content")
+			putFile(t, s3client, dstbucket, "file2.txt", "content")
+
+			endpoint, closeProxy := sourceListingProxy(t, s3client.Endpoint, srcbucket, func(resp *http.Response) error {
+				body := []byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+				resp.Body.Close()
+				resp.StatusCode = http.StatusForbidden
+				resp.Status = http.StatusText(http.StatusForbidden)
+				resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+				resp.ContentLength = int64(len(body))
+				resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+				return nil
+			})
+			defer closeProxy()
+
+			_, s5cmd, cleanupProxy := setup(t, withEndpointURL(endpoint))
+			defer cleanupProxy()
+
+			src := fmt.Sprintf("s3://%v/*", srcbucket)
+			dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+			args := append(tc.globalFlags, "sync", "--delete", src, dst)
+			cmd := s5cmd(args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stdout(), tc.expectedStdout)
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: contains(`AccessDenied`),
+			})
+
+			assert.Assert(t, ensureS3Object(s3client, dstbucket, "file1.txt", "content"))
+			assert.Assert(t, ensureS3Object(s3client, dstbucket, "file2.txt", "content"))
+		})
+	}
+}
+
+// --dry-run sync --delete s3://bucket/* dir/
+func TestSyncS3BucketToLocalFolderWithDeleteDryRun(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file1.txt", "content")
+
+	folderLayout := []fs.PathOp{
+		fs.WithDir("dir",
+			fs.WithFile("stale.txt", "content"),
+		),
+	}
+
+	workdir := fs.NewDir(t, t.Name(), folderLayout...)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+
+	cmd := s5cmd("--dry-run", "sync", "--delete", src, "dir/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`NEW s3://%v/file1.txt dir/file1.txt`, bucket),
+		1: equals(`DELETE dir/stale.txt`),
+		2: equals(`SUMMARY new 1, changed 0, delete 1`),
+	})
+
+	// assert no change in local filesystem
+	expected := fs.Expected(t, folderLayout...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// --dry-run sync --delete dir/ s3://bucket/prefix/
+func TestSyncLocalFolderToS3PrefixDryRunReport(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	// bolt backend reports wrong object sizes while listing, hence use
+	// in-memory storage for size comparisons.
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "prefix/bigger.txt", "content")
+	putFile(t, s3client, bucket, "prefix/newer.txt", "content")
+	putFile(t, s3client, bucket, "prefix/same.txt", "content")
+	putFile(t, s3client, bucket, "prefix/stale.txt", "content")
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithDir("dir",
+			fs.WithFile("bigger.txt", "longer content", fs.WithTimestamps(past, past)),
+			fs.WithFile("newer.txt", "updated", fs.WithTimestamps(future, future)),
+			fs.WithFile("same.txt", "content", fs.WithTimestamps(past, past)),
+			fs.WithDir("a", fs.WithFile("new.txt", "content")),
+		),
+	)
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("--dry-run", "sync", "--delete", "dir/", dst)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(fmt.Sprintf(`^CHANGED mtime \S+->\S+ dir/newer.txt %vnewer.txt$`, dst)),
+		1: equals(`CHANGED size 7->14 dir/bigger.txt %vbigger.txt`, dst),
+		2: equals(`DELETE %vstale.txt`, dst),
+		3: equals(`NEW dir/a/new.txt %va/new.txt`, dst),
+		4: equals(`SUMMARY new 1, changed 2, delete 1`),
+	}, sortInput(true))
+
+	// assert no change in s3
+	for _, key := range []string{"bigger.txt", "newer.txt", "same.txt", "stale.txt"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, "prefix/"+key, "content"))
+	}
+	err := ensureS3Object(s3client, bucket, "prefix/a/new.txt", "content")
+	assertError(t, err, errS3NoSuchKey)
+}
+
+// sync dir/ s3://bucket/object
+func TestSyncLocalFolderToS3ObjectMustReturnError(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithDir("dir"))
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/object", bucket)
+
+	cmd := s5cmd("sync", "dir/", dst)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync dir/ %v": target %q must be a bucket or a prefix`, dst, dst),
+	})
+}
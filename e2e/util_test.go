@@ -245,6 +245,7 @@ var errS3NoSuchKey = fmt.Errorf("s3: no such key")
 type ensureOpts struct {
 	contentType  *string
 	storageClass *string
+	metadata     map[string]*string
 }
 
 type ensureOption func(*ensureOpts)
@@ -261,6 +262,12 @@ func ensureStorageClass(expected string) ensureOption {
 	}
 }
 
+func ensureMetadata(expected map[string]string) ensureOption {
+	return func(opts *ensureOpts) {
+		opts.metadata = aws.StringMap(expected)
+	}
+}
+
 func ensureS3Object(
 	client *s3.S3,
 	bucket string,
@@ -311,6 +318,12 @@ func ensureS3Object(
 		}
 	}
 
+	if opts.metadata != nil {
+		if diff := cmp.Diff(opts.metadata, output.Metadata); diff != "" {
+			return fmt.Errorf("metadata of %v/%v: (-want +got):\n%v", bucket, key, diff)
+		}
+	}
+
 	return nil
 }
 
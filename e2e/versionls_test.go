@@ -0,0 +1,184 @@
+package e2e
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+// enableVersioning enables versioning of the given bucket and puts the given
+// contents as the versions of the given key, the first one being the oldest.
+// It returns the version ids.
+func enableVersioning(t *testing.T, s3client *s3.S3, bucket, key string, contents ...string) []string {
+	t.Helper()
+
+	_, err := s3client.PutBucketVersioning(&s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucket),
+		VersioningConfiguration: &s3.VersioningConfiguration{
+			Status: aws.String(s3.BucketVersioningStatusEnabled),
+		},
+	})
+	assert.NilError(t, err)
+
+	var versions []string
+	for _, content := range contents {
+		output, err := s3client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   strings.NewReader(content),
+		})
+		assert.NilError(t, err)
+		versions = append(versions, aws.StringValue(output.VersionId))
+	}
+	return versions
+}
+
+func TestVersionListS3Object(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	versions := enableVersioning(t, s3client, bucket, "file.txt", "first", "second version")
+
+	// deleting the object in a versioned bucket creates a delete marker.
+	_, err := s3client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String("file.txt"),
+	})
+	assert.NilError(t, err)
+
+	cmd := s5cmd("version-ls", "s3://bucket/file.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the order of the versions is not checked since it differs between S3
+	// and the test backend.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(fmt.Sprintf(`^ +5 %v file.txt$`, versions[0])),
+		1: match(fmt.Sprintf(`^ +14 %v file.txt$`, versions[1])),
+		2: match(`^ +DELETED \S+ file.txt$`),
+	}, trimMatch(dateRe), sortInput(true), alignment(true))
+}
+
+func TestVersionListS3ObjectJSON(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	versions := enableVersioning(t, s3client, bucket, "file.txt", "content")
+
+	cmd := s5cmd("--json", "version-ls", "s3://bucket/file.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(fmt.Sprintf(`^{"key":"s3://bucket/file.txt\?versionId=%v",.*"size":7,.*"version_id":"%v"}$`, versions[0], versions[0])),
+	}, jsonCheck(true))
+}
+
+func TestVersionListFail(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		cmd      []string
+		expected map[int]compareFunc
+	}{
+		{
+			name: "version-ls local file",
+			cmd: []string{
+				"version-ls",
+				"file.txt",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "version-ls file.txt": source must be a remote object, prefix or bucket`),
+			},
+		},
+		{
+			name: "version-ls a version",
+			cmd: []string{
+				"version-ls",
+				"s3://bucket/file.txt?versionId=abc",
+			},
+			expected: map[int]compareFunc{
+				0: equals(`ERROR "version-ls s3://bucket/file.txt?versionId=abc": source can not have a version id`),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd, cleanup := setup(t)
+			defer cleanup()
+
+			cmd := s5cmd(tc.cmd...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+			assertLines(t, result.Stderr(), tc.expected)
+		})
+	}
+}
+
+// cp s3://bucket/object?versionId=id .
+func TestCopyS3ObjectVersionToLocal(t *testing.T) {
+	t.Parallel()
+
+	const bucket = "bucket"
+
+	s3client, s5cmd, cleanup := setup(t, withS3Backend("mem"))
+	defer cleanup()
+
+	createBucket(t, s3client, bucket)
+	versions := enableVersioning(t, s3client, bucket, "file.txt", "first", "second")
+
+	src := fmt.Sprintf("s3://%v/file.txt?versionId=%v", bucket, versions[0])
+
+	cmd := s5cmd("cp", src, ".")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v file.txt`, src),
+	})
+
+	expected := fs.Expected(t, fs.WithFile("file.txt", "first"))
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+// cp file s3://bucket/object?versionId=id
+func TestCopyFileToS3ObjectVersion(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd, cleanup := setup(t)
+	defer cleanup()
+
+	cmd := s5cmd("cp", "file.txt", "s3://bucket/file.txt?versionId=abc")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp file.txt s3://bucket/file.txt?versionId=abc": target "s3://bucket/file.txt?versionId=abc" can not have a version id`),
+	})
+}
@@ -24,8 +24,12 @@ type Error struct {
 	Err error
 }
 
-// FullCommand returns the command string that occurred at.
+// FullCommand returns the command string that occurred at. The destination is
+// omitted for the operations without one, e.g. rm.
 func (e *Error) FullCommand() string {
+	if e.Dst == nil {
+		return fmt.Sprintf("%v %v", e.Op, e.Src)
+	}
 	return fmt.Sprintf("%v %v %v", e.Op, e.Src, e.Dst)
 }
 
@@ -76,13 +80,18 @@ var (
 
 	// ErrObjectSizesMatch indicates the sizes of objects match.
 	ErrObjectSizesMatch = fmt.Errorf("object size matches")
+
+	// ErrOperationCompleted indicates an operation is completed by a previous
+	// run which is resumed.
+	ErrOperationCompleted = fmt.Errorf("operation is already completed")
 )
 
 // IsWarning checks if given error is either ErrObjectExists,
-// ErrObjectIsNewer or ErrObjectSizesMatch.
+// ErrObjectIsNewer, ErrObjectSizesMatch, ErrOperationCompleted or
+// storage.ErrObjectNotModified.
 func IsWarning(err error) bool {
 	switch err {
-	case ErrObjectExists, ErrObjectIsNewer, ErrObjectSizesMatch:
+	case ErrObjectExists, ErrObjectIsNewer, ErrObjectSizesMatch, ErrOperationCompleted, storage.ErrObjectNotModified:
 		return true
 	}
 
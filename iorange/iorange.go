@@ -0,0 +1,69 @@
+// Package iorange keeps track of the byte ranges read from a reader.
+package iorange
+
+import (
+	"sort"
+	"sync"
+)
+
+// Set is a set of byte ranges. It is safe for concurrent use.
+type Set struct {
+	mu sync.Mutex
+
+	// spans are sorted, disjoint and not adjacent.
+	spans []span
+}
+
+type span struct {
+	start, end int64
+}
+
+// Add adds the n bytes at offset off to the set and returns the number of
+// them which were not in the set.
+func (s *Set) Add(off int64, n int) int64 {
+	if n <= 0 {
+		return 0
+	}
+
+	start, end := off, off+int64(n)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := end - start
+	merged := span{start: start, end: end}
+
+	// the spans from i to j overlap or touch the added one, and are merged
+	// into it.
+	i := sort.Search(len(s.spans), func(i int) bool { return s.spans[i].end >= start })
+	j := i
+	for ; j < len(s.spans) && s.spans[j].start <= end; j++ {
+		sp := s.spans[j]
+		added -= overlap(sp, start, end)
+		if sp.start < merged.start {
+			merged.start = sp.start
+		}
+		if sp.end > merged.end {
+			merged.end = sp.end
+		}
+	}
+
+	spans := append([]span{}, s.spans[:i]...)
+	spans = append(spans, merged)
+	s.spans = append(spans, s.spans[j:]...)
+	return added
+}
+
+// overlap returns the number of the bytes of sp in [start, end).
+func overlap(sp span, start, end int64) int64 {
+	if sp.start > start {
+		start = sp.start
+	}
+	if sp.end < end {
+		end = sp.end
+	}
+	if end < start {
+		return 0
+	}
+	return end - start
+}
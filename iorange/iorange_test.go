@@ -0,0 +1,57 @@
+package iorange
+
+import "testing"
+
+func TestSetAdd(t *testing.T) {
+	t.Parallel()
+
+	type add struct {
+		off      int64
+		n        int
+		expected int64
+	}
+
+	testcases := []struct {
+		name string
+		adds []add
+	}{
+		{
+			name: "read twice",
+			adds: []add{{0, 10, 10}, {0, 10, 0}},
+		},
+		{
+			name: "disjoint",
+			adds: []add{{0, 10, 10}, {20, 10, 10}, {20, 5, 0}, {0, 30, 10}},
+		},
+		{
+			name: "adjacent",
+			adds: []add{{10, 10, 10}, {0, 10, 10}, {20, 10, 10}, {0, 30, 0}},
+		},
+		{
+			name: "overlapping",
+			adds: []add{{5, 10, 10}, {0, 10, 5}, {10, 10, 5}, {0, 25, 5}},
+		},
+		{
+			name: "in different chunks",
+			adds: []add{{0, 3, 3}, {3, 3, 3}, {6, 4, 4}, {0, 4, 0}, {4, 6, 0}},
+		},
+		{
+			name: "nothing",
+			adds: []add{{0, 0, 0}, {0, -1, 0}},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var set Set
+			for i, a := range tc.adds {
+				if got := set.Add(a.off, a.n); got != a.expected {
+					t.Fatalf("add %d: expected %d new bytes, got %d", i, a.expected, got)
+				}
+			}
+		})
+	}
+}
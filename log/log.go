@@ -1,100 +1,167 @@
 package log
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 )
 
 // output is an internal container for messages to be logged.
 type output struct {
-	std     *os.File
+	std     io.Writer
 	message string
 }
 
-// outputCh is used to synchronize writes to standard output. Multi-line
-// logging is not possible if all workers print logs at the same time.
-var outputCh = make(chan output, 10000)
-
 var global *Logger
 
 // Init inits global logger.
-func Init(level string, json bool) {
-	global = New(level, json)
+func Init(level string, json bool, opts ...Option) {
+	global = New(level, json, opts...)
+}
+
+// Trace prints message in trace mode.
+func Trace(msg Message) {
+	global.printf(levelTrace, msg, global.stdout)
 }
 
 // Debug prints message in debug mode.
 func Debug(msg Message) {
-	global.printf(levelDebug, msg, os.Stdout)
+	global.printf(levelDebug, msg, global.stdout)
 }
 
 // Info prints message in info mode.
 func Info(msg Message) {
-	global.printf(levelInfo, msg, os.Stdout)
+	global.printf(levelInfo, msg, global.stdout)
 }
 
 // Error prints message in error mode.
 func Error(msg Message) {
-	global.printf(levelError, msg, os.Stderr)
+	global.printf(levelError, msg, global.stderr)
+}
+
+// Summary prints message regardless of the log level. It is used for the
+// messages which are explicitly requested, such as statistics.
+func Summary(msg Message) {
+	global.print(levelInfo, msg, global.stdout)
+}
+
+// Progress prints message to standard error regardless of the log level, so
+// that the periodic progress reports do not mix with the output of commands.
+func Progress(msg Message) {
+	global.print(levelInfo, msg, global.stderr)
 }
 
 // Close closes logger and its channel.
 func Close() {
-	close(outputCh)
+	close(global.outputCh)
 	<-global.donech
 }
 
 // Logger is a structure for logging messages.
 type Logger struct {
-	donech chan struct{}
-	json   bool
-	level  logLevel
+	// outputCh is used to synchronize writes to standard output. Multi-line
+	// logging is not possible if all workers print logs at the same time.
+	// The messages are written by a single goroutine, so that the lines of
+	// the workers are never interleaved.
+	outputCh chan output
+	donech   chan struct{}
+	json     bool
+	level    logLevel
+
+	// stdout and stderr are the writers of the messages of the standard
+	// output and the standard error.
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Option is a functional option of Logger.
+type Option func(l *Logger)
+
+// WithOutput sets the writers of the messages printed to the standard output
+// and the standard error, e.g. to capture the messages of the commands run by
+// a program embedding s5cmd. The writers are not written concurrently.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(l *Logger) {
+		l.stdout = stdout
+		l.stderr = stderr
+	}
 }
 
-// New creates new logger.
-func New(level string, json bool) *Logger {
+// New creates new logger. The messages are printed to os.Stdout and os.Stderr
+// unless WithOutput option is given.
+func New(level string, json bool, opts ...Option) *Logger {
 	logLevel := levelFromString(level)
 	logger := &Logger{
-		donech: make(chan struct{}),
-		json:   json,
-		level:  logLevel,
+		outputCh: make(chan output, 10000),
+		donech:   make(chan struct{}),
+		json:     json,
+		level:    logLevel,
+		stdout:   os.Stdout,
+		stderr:   os.Stderr,
+	}
+	for _, opt := range opts {
+		opt(logger)
 	}
 	go logger.out()
 	return logger
 }
 
 // printf prints message according to the given level, message and std mode.
-func (l *Logger) printf(level logLevel, message Message, std *os.File) {
+func (l *Logger) printf(level logLevel, message Message, std io.Writer) {
 	if level < l.level {
 		return
 	}
+	l.print(level, message, std)
+}
 
+// print prints message according to the given level and std mode without
+// checking the log level of the logger.
+func (l *Logger) print(level logLevel, message Message, std io.Writer) {
 	if l.json {
-		outputCh <- output{
+		l.outputCh <- output{
 			message: message.JSON(),
 			std:     std,
 		}
 	} else {
-		outputCh <- output{
+		l.outputCh <- output{
 			message: fmt.Sprintf("%v%v", level, message.String()),
 			std:     std,
 		}
 	}
 }
 
-// out listens for outputCh and logs messages.
+// out listens for outputCh and logs messages. The messages are buffered
+// while more of them are waiting in the channel, and written once it is
+// drained, so that the messages of many workers are written with a few writes.
 func (l *Logger) out() {
 	defer close(l.donech)
 
-	for output := range outputCh {
-		_, _ = fmt.Fprintln(output.std, output.message)
+	var std io.Writer
+	w := bufio.NewWriter(nil)
+	for output := range l.outputCh {
+		// the buffered messages of the other writer are written first to
+		// keep the order of the messages.
+		if output.std != std {
+			_ = w.Flush()
+			std = output.std
+			w.Reset(std)
+		}
+
+		_, _ = fmt.Fprintln(w, output.message)
+		if len(l.outputCh) == 0 {
+			_ = w.Flush()
+		}
 	}
+	_ = w.Flush()
 }
 
 // logLevel is the level of Logger.
 type logLevel int
 
 const (
-	levelDebug logLevel = iota
+	levelTrace logLevel = iota
+	levelDebug
 	levelInfo
 	levelError
 )
@@ -108,6 +175,8 @@ func (l logLevel) String() string {
 		return "ERROR "
 	case levelDebug:
 		return "DEBUG "
+	case levelTrace:
+		return "TRACE "
 	default:
 		return "UNKNOWN "
 	}
@@ -117,6 +186,8 @@ func (l logLevel) String() string {
 // return `levelInfo` as a default.
 func levelFromString(s string) logLevel {
 	switch s {
+	case "trace":
+		return levelTrace
 	case "debug":
 		return levelDebug
 	case "info":
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/peak/s5cmd/storage/url"
+)
+
+// terminal is the screen which both the standard output and the standard
+// error of a command are written to.
+type terminal struct {
+	buf     bytes.Buffer
+	writing int32
+	// concurrent is set if a write starts while another one is in progress.
+	concurrent int32
+}
+
+// stream is the standard output or the standard error written to terminal.
+type stream struct {
+	t *terminal
+}
+
+// Write writes p to the terminal byte by byte, so that the concurrent writes
+// would garble the lines.
+func (s stream) Write(p []byte) (int, error) {
+	if !atomic.CompareAndSwapInt32(&s.t.writing, 0, 1) {
+		atomic.StoreInt32(&s.t.concurrent, 1)
+		return 0, fmt.Errorf("concurrent write")
+	}
+	defer atomic.StoreInt32(&s.t.writing, 0)
+
+	for _, b := range p {
+		s.t.buf.WriteByte(b)
+		runtime.Gosched()
+	}
+	return len(p), nil
+}
+
+func TestLoggerSerializesConcurrentMessages(t *testing.T) {
+	const (
+		workers  = 32
+		messages = 200
+	)
+
+	term := &terminal{}
+	Init("info", false, WithOutput(stream{term}, stream{term}))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				name := fmt.Sprintf("worker-%d-%d", w, i)
+				if i%3 == 0 {
+					Error(ErrorMessage{Command: "cp", Err: name})
+					continue
+				}
+				src, _ := url.New("s3://bucket/" + name)
+				Info(InfoMessage{Operation: "cp", Source: src})
+			}
+		}(w)
+	}
+	wg.Wait()
+	Close()
+
+	if atomic.LoadInt32(&term.concurrent) == 1 {
+		t.Fatal("expected the messages to be written by a single writer at a time")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(term.buf.String(), "\n"), "\n")
+	if len(lines) != workers*messages {
+		t.Fatalf("expected %d lines, got %d", workers*messages, len(lines))
+	}
+
+	// the messages of each worker are printed whole and in the order they
+	// are logged.
+	next := make([]int, workers)
+	for _, line := range lines {
+		format := "cp s3://bucket/worker-%d-%d"
+		if strings.HasPrefix(line, "ERROR ") {
+			format = `ERROR "cp": worker-%d-%d`
+		}
+
+		var w, i int
+		if _, err := fmt.Sscanf(line, format, &w, &i); err != nil || w < 0 || w >= workers {
+			t.Fatalf("garbled line %q", line)
+		}
+		if i != next[w] {
+			t.Fatalf("expected message %d of worker %d, got %d", next[w], w, i)
+		}
+		next[w]++
+	}
+}
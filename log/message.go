@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/peak/s5cmd/storage/url"
 	"github.com/peak/s5cmd/strutil"
@@ -41,14 +42,22 @@ type ErrorMessage struct {
 	Operation string `json:"operation,omitempty"`
 	Command   string `json:"command,omitempty"`
 	Err       string `json:"error"`
+
+	// Code is the error code returned by the remote storage, e.g.
+	// AccessDenied. It is empty for the other errors.
+	Code string `json:"code,omitempty"`
 }
 
 // String is the string representation of ErrorMessage.
 func (e ErrorMessage) String() string {
-	if e.Command == "" {
-		return fmt.Sprint(e.Err)
+	s := e.Err
+	if e.Command != "" {
+		s = fmt.Sprintf("%q: %v", e.Command, e.Err)
+	}
+	if e.Code != "" {
+		s = fmt.Sprintf("%v [%v]", s, e.Code)
 	}
-	return fmt.Sprintf("%q: %v", e.Command, e.Err)
+	return s
 }
 
 // JSON is the JSON representation of ErrorMessage.
@@ -75,3 +84,75 @@ func (d DebugMessage) String() string {
 func (d DebugMessage) JSON() string {
 	return strutil.JSON(d)
 }
+
+// RetryMessage is the structure for logging a request which is going to be
+// retried.
+type RetryMessage struct {
+	// Operation is the name of the API operation, e.g. PutObject.
+	Operation string
+	// Attempt is the number of the retry, starting from 1, and MaxAttempts
+	// is the maximum number of retries.
+	Attempt     int
+	MaxAttempts int
+	// Delay is the delay before this retry, and TotalDelay is the sum of the
+	// delays of the request so far, including this one.
+	Delay      time.Duration
+	TotalDelay time.Duration
+	// Code is the error code returned by the remote storage, e.g. SlowDown.
+	// It is empty for the other errors.
+	Code string
+	Err  string
+}
+
+// String is the string representation of RetryMessage.
+func (r RetryMessage) String() string {
+	s := fmt.Sprintf("retry %d/%d", r.Attempt, r.MaxAttempts)
+	if r.Operation != "" {
+		s = fmt.Sprintf("%v of %v", s, r.Operation)
+	}
+	s = fmt.Sprintf("%v after %v (%v in total): %v", s, r.Delay, r.TotalDelay, r.Err)
+	if r.Code != "" {
+		s = fmt.Sprintf("%v [%v]", s, r.Code)
+	}
+	return s
+}
+
+// JSON is the JSON representation of RetryMessage. The delays are in
+// seconds.
+func (r RetryMessage) JSON() string {
+	return strutil.JSON(struct {
+		Event       string  `json:"event"`
+		Operation   string  `json:"operation,omitempty"`
+		Attempt     int     `json:"attempt"`
+		MaxAttempts int     `json:"max_attempts"`
+		Delay       float64 `json:"delay"`
+		TotalDelay  float64 `json:"total_delay"`
+		Code        string  `json:"code,omitempty"`
+		Err         string  `json:"error"`
+	}{
+		Event:       "retry",
+		Operation:   r.Operation,
+		Attempt:     r.Attempt,
+		MaxAttempts: r.MaxAttempts,
+		Delay:       r.Delay.Seconds(),
+		TotalDelay:  r.TotalDelay.Seconds(),
+		Code:        r.Code,
+		Err:         r.Err,
+	})
+}
+
+// TraceMessage is a generic message structure for the messages of the
+// underlying SDK.
+type TraceMessage struct {
+	Message string `json:"message"`
+}
+
+// String is the string representation of TraceMessage.
+func (t TraceMessage) String() string {
+	return t.Message
+}
+
+// JSON is the JSON representation of TraceMessage.
+func (t TraceMessage) JSON() string {
+	return strutil.JSON(t)
+}
@@ -3,9 +3,12 @@ package stat
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"text/tabwriter"
+	"time"
 
 	"github.com/peak/s5cmd/strutil"
 )
@@ -16,21 +19,58 @@ const (
 )
 
 var (
-	enabled bool
-	stats   statistics
+	enabled   bool
+	stats     statistics
+	startedAt time.Time
+
+	// transferredBytes is the total number of bytes transferred between the
+	// local filesystem and the remote storage.
+	transferredBytes int64
+
+	// skippedOperations is the number of operations which are not run due to
+	// an interruption or since the destination exists, e.g. with
+	// --no-clobber.
+	skippedOperations int64
+
+	// retriedRequests is the number of requests which are retried.
+	retriedRequests int64
+
+	// errorCodes is the number of the printed errors by their codes.
+	errorCodes syncMapStrInt64
+
+	// transfers is the bytes and the elapsed time of the transfers by their
+	// types.
+	transfers transferStats
+
+	// now is the clock of the transfers. It is replaced by the tests.
+	now = time.Now
 )
 
+// The types of the transfers.
+const (
+	Upload   = "upload"
+	Download = "download"
+	Copy     = "copy"
+)
+
+// unknownErrorCode groups the errors without a code, e.g. the errors of the
+// local filesystem.
+const unknownErrorCode = "Unknown"
+
 type statistics [2]syncMapStrInt64
 
 // InitStat initializes collecting program statistics.
 func InitStat() {
 	enabled = true
+	startedAt = time.Now()
 	for i := range stats {
 		stats[i] = syncMapStrInt64{
 			Mutex:       sync.Mutex{},
 			mapStrInt64: map[string]int64{},
 		}
 	}
+	errorCodes = syncMapStrInt64{mapStrInt64: map[string]int64{}}
+	transfers = transferStats{byType: map[string]*transferStat{}}
 }
 
 // syncMapStrInt64 is a statically typed and synchronized map.
@@ -46,6 +86,19 @@ func (s *syncMapStrInt64) add(key string, val int64) {
 	s.mapStrInt64[key] += val
 }
 
+// snapshot returns a copy of the map, which can be read while the operations
+// are still being collected.
+func (s *syncMapStrInt64) snapshot() map[string]int64 {
+	s.Lock()
+	defer s.Unlock()
+
+	m := make(map[string]int64, len(s.mapStrInt64))
+	for key, val := range s.mapStrInt64 {
+		m[key] = val
+	}
+	return m
+}
+
 // Stat is for storing a particular statistics.
 type Stat struct {
 	Operation string `json:"operation"`
@@ -56,18 +109,202 @@ type Stat struct {
 // Collect collects function execution data.
 func Collect(op string, err *error) func() {
 	return func() {
-		if !enabled {
-			return
+		var opErr error
+		if err != nil {
+			opErr = *err
+		}
+		AddOperation(op, opErr)
+	}
+}
+
+// AddOperation counts an operation of op, which failed if err is not nil. It
+// is used by the commands which count their operations by objects instead of
+// by the command, e.g. the keys deleted by rm.
+func AddOperation(op string, err error) {
+	if !enabled {
+		return
+	}
+	if err == nil {
+		stats[succCount].add(op, 1)
+	}
+	stats[totalCount].add(op, 1)
+}
+
+// transferStats is the statistics of the transfers by their types.
+type transferStats struct {
+	sync.Mutex
+	byType map[string]*transferStat
+}
+
+// transferStat is the statistics of the transfers of a type. The transfers
+// run concurrently, so the elapsed time is the time while at least one of
+// them is in progress, instead of the sum of their durations.
+type transferStat struct {
+	bytes   int64
+	active  int
+	since   time.Time
+	elapsed time.Duration
+}
+
+// Transfer is a transfer in progress, which is timed from StartTransfer to
+// End. A nil Transfer does nothing.
+type Transfer struct {
+	typ string
+}
+
+// StartTransfer starts timing a transfer of the given type, e.g. Upload. It
+// returns nil if the statistics are not collected.
+func StartTransfer(typ string) *Transfer {
+	if !enabled {
+		return nil
+	}
+
+	transfers.Lock()
+	defer transfers.Unlock()
+
+	st := transfers.byType[typ]
+	if st == nil {
+		st = &transferStat{}
+		transfers.byType[typ] = st
+	}
+	if st.active == 0 {
+		st.since = now()
+	}
+	st.active++
+	return &Transfer{typ: typ}
+}
+
+// End stops timing the transfer. It must be called once for each transfer,
+// whether it succeeds or not.
+func (t *Transfer) End() {
+	if t == nil {
+		return
+	}
+
+	transfers.Lock()
+	defer transfers.Unlock()
+
+	st := transfers.byType[t.typ]
+	st.active--
+	if st.active == 0 {
+		st.elapsed += now().Sub(st.since)
+	}
+}
+
+// AddBytes adds the number of bytes transferred between the local filesystem
+// and the remote storage by the transfer.
+func (t *Transfer) AddBytes(n int64) {
+	if t == nil {
+		return
+	}
+
+	atomic.AddInt64(&transferredBytes, n)
+
+	transfers.Lock()
+	defer transfers.Unlock()
+
+	transfers.byType[t.typ].bytes += n
+}
+
+// summaries returns the summaries of the transfers in the order of their
+// types. The transfers in progress are timed until now.
+func (s *transferStats) summaries() []TransferSummary {
+	s.Lock()
+	defer s.Unlock()
+
+	var result []TransferSummary
+	for typ, st := range s.byType {
+		elapsed := st.elapsed
+		if st.active > 0 {
+			elapsed += now().Sub(st.since)
+		}
+
+		summary := TransferSummary{
+			Type:    typ,
+			Bytes:   st.bytes,
+			Elapsed: elapsed.Seconds(),
 		}
-		if err == nil || *err == nil {
-			stats[succCount].add(op, 1)
+		if elapsed > 0 {
+			summary.Throughput = int64(float64(st.bytes) / elapsed.Seconds())
 		}
-		stats[totalCount].add(op, 1)
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Type < result[j].Type
+	})
+	return result
+}
+
+// AddSkipped increments the number of operations which are not run due to an
+// interruption or since the destination exists.
+func AddSkipped() {
+	if !enabled {
+		return
+	}
+	atomic.AddInt64(&skippedOperations, 1)
+}
+
+// AddRetried increments the number of retried requests.
+func AddRetried() {
+	if !enabled {
+		return
 	}
+	atomic.AddInt64(&retriedRequests, 1)
+}
+
+// AddError increments the number of the errors with the given code, e.g.
+// AccessDenied. The errors without a code are counted as Unknown.
+func AddError(code string) {
+	if !enabled {
+		return
+	}
+	if code == "" {
+		code = unknownErrorCode
+	}
+	errorCodes.add(code, 1)
+}
+
+// ErrorCount is the number of the errors with a code.
+type ErrorCount struct {
+	Code  string `json:"code"`
+	Count int64  `json:"count"`
+}
+
+// TransferSummary is the number of bytes transferred by the transfers of a
+// type, the time while they are in progress and their throughput.
+type TransferSummary struct {
+	Type       string  `json:"type"`
+	Bytes      int64   `json:"bytes"`
+	Elapsed    float64 `json:"elapsed_seconds"`
+	Throughput int64   `json:"throughput"`
+}
+
+// Summary is the totals of the program execution: the number of bytes
+// transferred, the throughput and the number of operations.
+type Summary struct {
+	Bytes      int64   `json:"bytes"`
+	Elapsed    float64 `json:"elapsed_seconds"`
+	Throughput int64   `json:"throughput"`
+	Operations int64   `json:"operations"`
+	Succeeded  int64   `json:"succeeded"`
+	Failed     int64   `json:"failed"`
+	Retried    int64   `json:"retried"`
+	Skipped    int64   `json:"skipped"`
+
+	// Errors is the number of the errors grouped by their codes, in the
+	// descending order of their counts.
+	Errors []ErrorCount `json:"errors,omitempty"`
+
+	// Transfers is the summary of the transfers by their types, e.g. upload,
+	// download and copy.
+	Transfers []TransferSummary `json:"transfers,omitempty"`
 }
 
 // Stats implements log.Message interface.
-type Stats []Stat
+type Stats struct {
+	Stats   []Stat
+	Summary Summary
+}
 
 func (s Stats) String() string {
 	var buf bytes.Buffer
@@ -75,20 +312,59 @@ func (s Stats) String() string {
 	w := tabwriter.NewWriter(&buf, 0, 8, 1, '\t', tabwriter.AlignRight)
 
 	fmt.Fprintf(w, "\n%s\t%s\t%s\t%s\t\n", "Operation", "Total", "Error", "Success")
-	for _, stat := range s {
+	for _, stat := range s.Stats {
 		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t\n", stat.Operation, stat.Error+stat.Success, stat.Error, stat.Success)
 	}
 
 	w.Flush()
+
+	fmt.Fprintf(
+		&buf,
+		"\nTransferred %s in %.1fs (%s/s)\n",
+		strutil.HumanizeBytes(s.Summary.Bytes),
+		s.Summary.Elapsed,
+		strutil.HumanizeBytes(s.Summary.Throughput),
+	)
+
+	for _, t := range s.Summary.Transfers {
+		fmt.Fprintf(
+			&buf,
+			"  %s: %s in %.1fs (%s/s)\n",
+			t.Type,
+			strutil.HumanizeBytes(t.Bytes),
+			t.Elapsed,
+			strutil.HumanizeBytes(t.Throughput),
+		)
+	}
+
+	fmt.Fprintf(
+		&buf,
+		"Operations: %d, Succeeded: %d, Failed: %d, Retried: %d, Skipped: %d, Elapsed: %v\n",
+		s.Summary.Operations,
+		s.Summary.Succeeded,
+		s.Summary.Failed,
+		s.Summary.Retried,
+		s.Summary.Skipped,
+		time.Duration(s.Summary.Elapsed*float64(time.Second)).Round(time.Millisecond),
+	)
+
+	if len(s.Summary.Errors) > 0 {
+		counts := make([]string, 0, len(s.Summary.Errors))
+		for _, e := range s.Summary.Errors {
+			counts = append(counts, fmt.Sprintf("%s: %d", e.Code, e.Count))
+		}
+		fmt.Fprintf(&buf, "Errors: %s\n", strings.Join(counts, ", "))
+	}
 	return buf.String()
 }
 
 func (s Stats) JSON() string {
 	var builder strings.Builder
 
-	for _, stat := range s {
+	for _, stat := range s.Stats {
 		builder.WriteString(strutil.JSON(stat) + "\n")
 	}
+	builder.WriteString(strutil.JSON(s.Summary) + "\n")
 	return builder.String()
 }
 
@@ -98,15 +374,52 @@ func Statistics() Stats {
 		return Stats{}
 	}
 
+	// the successes are counted before the totals by Collect, so they are
+	// read first to not report more successes than operations.
+	succeeded := stats[succCount].snapshot()
+	totals := stats[totalCount].snapshot()
+
 	var result Stats
-	for op, total := range stats[totalCount].mapStrInt64 {
-		success := stats[succCount].mapStrInt64[op]
+	for op, total := range totals {
+		success := succeeded[op]
 
-		result = append(result, Stat{
+		result.Stats = append(result.Stats, Stat{
 			Operation: op,
 			Success:   success,
 			Error:     total - success,
 		})
+
+		result.Summary.Operations += total
+		result.Summary.Succeeded += success
+		result.Summary.Failed += total - success
+	}
+
+	result.Summary.Bytes = atomic.LoadInt64(&transferredBytes)
+	result.Summary.Skipped = atomic.LoadInt64(&skippedOperations)
+	result.Summary.Retried = atomic.LoadInt64(&retriedRequests)
+	result.Summary.Errors = errorCounts(errorCodes.snapshot())
+	result.Summary.Transfers = transfers.summaries()
+
+	elapsed := time.Since(startedAt).Seconds()
+	result.Summary.Elapsed = elapsed
+	if elapsed > 0 {
+		result.Summary.Throughput = int64(float64(result.Summary.Bytes) / elapsed)
 	}
 	return result
 }
+
+// errorCounts sorts the counts of the error codes in the descending order of
+// the counts, and the codes with the same count by their names.
+func errorCounts(codes map[string]int64) []ErrorCount {
+	counts := make([]ErrorCount, 0, len(codes))
+	for code, count := range codes {
+		counts = append(counts, ErrorCount{Code: code, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Code < counts[j].Code
+	})
+	return counts
+}
@@ -0,0 +1,128 @@
+package stat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStatisticsGroupsErrorsByCode(t *testing.T) {
+	InitStat()
+
+	for i := 0; i < 42; i++ {
+		AddError("AccessDenied")
+	}
+	for i := 0; i < 7; i++ {
+		AddError("NoSuchKey")
+	}
+	AddError("")
+	AddError("")
+	AddError("SlowDown")
+	AddError("InternalError")
+
+	stats := Statistics()
+
+	expected := []ErrorCount{
+		{Code: "AccessDenied", Count: 42},
+		{Code: "NoSuchKey", Count: 7},
+		{Code: "Unknown", Count: 2},
+		{Code: "InternalError", Count: 1},
+		{Code: "SlowDown", Count: 1},
+	}
+	if diff := cmp.Diff(expected, stats.Summary.Errors); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+
+	const line = "Errors: AccessDenied: 42, NoSuchKey: 7, Unknown: 2, InternalError: 1, SlowDown: 1\n"
+	if out := stats.String(); !strings.Contains(out, line) {
+		t.Errorf("expected %q in:\n%v", line, out)
+	}
+
+	const json = `"errors":[{"code":"AccessDenied","count":42},{"code":"NoSuchKey","count":7},{"code":"Unknown","count":2},{"code":"InternalError","count":1},{"code":"SlowDown","count":1}]`
+	if out := stats.JSON(); !strings.Contains(out, json) {
+		t.Errorf("expected %q in:\n%v", json, out)
+	}
+
+	// the errors of a previous run are not counted.
+	InitStat()
+	if errors := Statistics().Summary.Errors; len(errors) != 0 {
+		t.Errorf("expected no errors, got %v", errors)
+	}
+	if out := Statistics().String(); strings.Contains(out, "Errors:") {
+		t.Errorf("expected no errors in:\n%v", out)
+	}
+}
+
+func TestStatisticsByTransferType(t *testing.T) {
+	clock := time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	InitStat()
+
+	// two concurrent uploads between 0s and 4s.
+	upload1 := StartTransfer(Upload)
+	clock = clock.Add(time.Second)
+	upload2 := StartTransfer(Upload)
+	download := StartTransfer(Download)
+	clock = clock.Add(2 * time.Second)
+	upload1.End()
+	upload1.AddBytes(100)
+	download.End()
+	download.AddBytes(4000)
+	clock = clock.Add(time.Second)
+	upload2.End()
+	upload2.AddBytes(300)
+
+	// a failed copy is timed without any bytes.
+	failed := StartTransfer(Copy)
+	clock = clock.Add(time.Second)
+	failed.End()
+
+	// a copy in progress is timed until now.
+	StartTransfer(Copy)
+	clock = clock.Add(time.Second)
+
+	stats := Statistics()
+
+	expected := []TransferSummary{
+		{Type: Copy, Bytes: 0, Elapsed: 2, Throughput: 0},
+		{Type: Download, Bytes: 4000, Elapsed: 2, Throughput: 2000},
+		{Type: Upload, Bytes: 400, Elapsed: 4, Throughput: 100},
+	}
+	if diff := cmp.Diff(expected, stats.Summary.Transfers); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+
+	if stats.Summary.Bytes != 4400 {
+		t.Errorf("expected 4400 bytes in total, got %v", stats.Summary.Bytes)
+	}
+
+	for _, line := range []string{
+		"  download: 3.9K in 2.0s (2.0K/s)\n",
+		"  upload: 400 in 4.0s (100/s)\n",
+	} {
+		if out := stats.String(); !strings.Contains(out, line) {
+			t.Errorf("expected %q in:\n%v", line, out)
+		}
+	}
+
+	const json = `"transfers":[{"type":"copy","bytes":0,"elapsed_seconds":2,"throughput":0},{"type":"download","bytes":4000,"elapsed_seconds":2,"throughput":2000},{"type":"upload","bytes":400,"elapsed_seconds":4,"throughput":100}]`
+	if out := stats.JSON(); !strings.Contains(out, json) {
+		t.Errorf("expected %q in:\n%v", json, out)
+	}
+}
+
+func TestTransferNotCollected(t *testing.T) {
+	defer func(collected bool) { enabled = collected }(enabled)
+	enabled = false
+
+	transfer := StartTransfer(Upload)
+	if transfer != nil {
+		t.Fatalf("expected no transfer, got %v", transfer)
+	}
+	transfer.AddBytes(100)
+	transfer.End()
+}
@@ -2,20 +2,33 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/peak/s5cmd/command"
+	"github.com/peak/s5cmd/parallel"
 )
 
+const drainWarning = `received an interrupt, waiting for the running operations to finish.
+interrupt again to cancel them.`
+
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	go func() {
 		ch := make(chan os.Signal, 1)
 		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
-		<-ch
+
+		// the first interrupt lets the running operations finish without
+		// starting new ones. SIGTERM or a second interrupt cancels them.
+		if sig := <-ch; sig == os.Interrupt {
+			fmt.Fprintln(os.Stderr, drainWarning)
+			parallel.Drain()
+			<-ch
+		}
+
 		cancel()
 		signal.Stop(ch)
 	}()
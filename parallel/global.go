@@ -1,19 +1,91 @@
 package parallel
 
-import "github.com/peak/s5cmd/parallel/fdlimit"
+import (
+	"context"
+
+	"github.com/peak/s5cmd/parallel/fdlimit"
+	"github.com/peak/s5cmd/ratelimit"
+)
 
 var global *Manager
 
 // Init tries to increase the soft limit of open files and
-// creates new global ParallelManager.
-func Init(workercount int) {
+// creates new global ParallelManager. The aggregate throughput of the
+// transfers is limited to bytesPerSecond if it is positive. The number of
+// tasks started per second is limited to tasksPerSecond if it is positive.
+// The memory of the transfer buffers is limited to maxMemory bytes if it is
+// positive.
+func Init(workercount int, bytesPerSecond, tasksPerSecond, maxMemory int64) {
 	_ = fdlimit.Raise()
 	global = New(workercount)
+	if bytesPerSecond > 0 {
+		global.limiter = ratelimit.New(bytesPerSecond)
+	}
+	if tasksPerSecond > 0 {
+		global.taskLimiter = ratelimit.New(tasksPerSecond)
+	}
+	if maxMemory > 0 {
+		global.memoryLimiter = NewMemoryLimiter(maxMemory)
+	}
 }
 
 // Close waits all jobs to finish and
 // closes the semaphore of global ParallelManager.
 func Close() { global.Close() }
 
+// Drain stops global ParallelManager running new tasks.
+func Drain() {
+	// an interrupt might be received before the manager is initialized.
+	if global != nil {
+		global.Drain()
+	}
+}
+
+// WithDrain returns a copy of ctx which is canceled once global
+// ParallelManager is drained, to stop producing the tasks on the first
+// interrupt.
+func WithDrain(ctx context.Context) (context.Context, context.CancelFunc) {
+	if global == nil {
+		return context.WithCancel(ctx)
+	}
+	return global.WithDrain(ctx)
+}
+
+// Limiter returns the rate limiter of global ParallelManager. It is nil if the
+// throughput is not limited.
+func Limiter() *ratelimit.Limiter {
+	if global == nil {
+		return nil
+	}
+	return global.limiter
+}
+
+// Memory returns the memory limiter of global ParallelManager. It is nil if
+// the memory is not limited.
+func Memory() *MemoryLimiter {
+	if global == nil {
+		return nil
+	}
+	return global.memoryLimiter
+}
+
+// Statistics returns the current state of the workers and the tasks of global
+// ParallelManager.
+func Statistics() Stats {
+	if global == nil {
+		return Stats{}
+	}
+	return global.Stats()
+}
+
+// AddQueue adds the queue of the given length to the queued tasks of global
+// ParallelManager. The returned function removes the queue.
+func AddQueue(length func() int) func() {
+	if global == nil {
+		return func() {}
+	}
+	return global.AddQueue(length)
+}
+
 // Run runs global ParallelManager.
 func Run(task Task, waiter *Waiter) { global.Run(task, waiter) }
@@ -0,0 +1,107 @@
+package parallel
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryLimiter is a semaphore of bytes shared by the transfers of all
+// workers to limit the memory used by their buffers. Waiting callers are
+// served in order, so that large requests are not starved by small ones. A
+// nil MemoryLimiter does not limit anything.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	limit   int64
+	used    int64
+	waiters list.List
+}
+
+type memoryWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// NewMemoryLimiter creates a new MemoryLimiter which allows limit bytes to be
+// acquired at once.
+func NewMemoryLimiter(limit int64) *MemoryLimiter {
+	return &MemoryLimiter{limit: limit}
+}
+
+// Limit returns the number of bytes which can be acquired at once. It is
+// zero if the memory is not limited.
+func (m *MemoryLimiter) Limit() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.limit
+}
+
+// Acquire blocks until n bytes are acquired or ctx is canceled. The acquired
+// bytes must be released with Release.
+func (m *MemoryLimiter) Acquire(ctx context.Context, n int64) error {
+	if m == nil || n <= 0 {
+		return nil
+	}
+
+	if n > m.limit {
+		return fmt.Errorf("%d bytes exceed the memory limit of %d bytes", n, m.limit)
+	}
+
+	m.mu.Lock()
+	if m.waiters.Len() == 0 && m.used+n <= m.limit {
+		m.used += n
+		m.mu.Unlock()
+		return nil
+	}
+
+	w := &memoryWaiter{n: n, ready: make(chan struct{})}
+	elem := m.waiters.PushBack(w)
+	m.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		select {
+		case <-w.ready:
+			// the bytes are acquired while canceling.
+			m.used -= n
+		default:
+			m.waiters.Remove(elem)
+		}
+		// the waiters behind might fit now.
+		m.notify()
+		return ctx.Err()
+	}
+}
+
+// Release releases n bytes acquired with Acquire.
+func (m *MemoryLimiter) Release(n int64) {
+	if m == nil || n <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.used -= n
+	m.notify()
+}
+
+// notify wakes up the waiters in order while their bytes fit in the limit.
+func (m *MemoryLimiter) notify() {
+	for elem := m.waiters.Front(); elem != nil; elem = m.waiters.Front() {
+		w := elem.Value.(*memoryWaiter)
+		if m.used+w.n > m.limit {
+			return
+		}
+		m.used += w.n
+		m.waiters.Remove(elem)
+		close(w.ready)
+	}
+}
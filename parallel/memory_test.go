@@ -0,0 +1,104 @@
+package parallel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiterPeak(t *testing.T) {
+	t.Parallel()
+
+	const limit = 100
+
+	limiter := NewMemoryLimiter(limit)
+
+	var (
+		wg         sync.WaitGroup
+		inuse      int64
+		peak       int64
+		peakUpdate sync.Mutex
+	)
+	for i := 0; i < 50; i++ {
+		n := int64(i%limit + 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := limiter.Acquire(context.Background(), n); err != nil {
+				t.Error(err)
+				return
+			}
+			defer limiter.Release(n)
+
+			current := atomic.AddInt64(&inuse, n)
+			peakUpdate.Lock()
+			if current > peak {
+				peak = current
+			}
+			peakUpdate.Unlock()
+
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&inuse, -n)
+		}()
+	}
+	wg.Wait()
+
+	if peak > limit {
+		t.Errorf("expected at most %v bytes in use, got %v", limit, peak)
+	}
+	if limiter.used != 0 {
+		t.Errorf("expected all bytes to be released, got %v in use", limiter.used)
+	}
+}
+
+func TestMemoryLimiterCancel(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewMemoryLimiter(100)
+	if err := limiter.Acquire(context.Background(), 80); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Acquire(ctx, 50); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// the canceled waiter does not block the ones which fit.
+	if err := limiter.Acquire(context.Background(), 20); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter.Release(100)
+	if limiter.used != 0 {
+		t.Errorf("expected all bytes to be released, got %v in use", limiter.used)
+	}
+}
+
+func TestMemoryLimiterExceedingRequest(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewMemoryLimiter(100)
+	if err := limiter.Acquire(context.Background(), 101); err == nil {
+		t.Errorf("expected an error for a request exceeding the limit")
+	}
+}
+
+func TestNilMemoryLimiter(t *testing.T) {
+	t.Parallel()
+
+	var limiter *MemoryLimiter
+	if err := limiter.Acquire(context.Background(), 1<<40); err != nil {
+		t.Fatal(err)
+	}
+	limiter.Release(1 << 40)
+
+	if limit := limiter.Limit(); limit != 0 {
+		t.Errorf("expected no limit, got %v", limit)
+	}
+}
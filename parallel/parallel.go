@@ -1,14 +1,33 @@
 package parallel
 
 import (
+	"context"
+	"fmt"
 	"runtime"
 	"sync"
+	syncatomic "sync/atomic"
+
+	"github.com/peak/s5cmd/atomic"
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/ratelimit"
+	"github.com/peak/s5cmd/strutil"
 )
 
 const (
 	minNumWorkers = 2
+
+	// autoWorkersPerCPU is the number of workers per CPU of AutoWorkerCount.
+	// The workers mostly wait for the network, so a worker per CPU would
+	// leave the CPUs idle.
+	autoWorkersPerCPU = 32
 )
 
+// AutoWorkerCount returns a worker count derived from the number of CPUs for
+// the transfer bound operations.
+func AutoWorkerCount() int {
+	return runtime.NumCPU() * autoWorkersPerCPU
+}
+
 // Task is a function type for parallel manager.
 type Task func() error
 
@@ -16,6 +35,38 @@ type Task func() error
 type Manager struct {
 	wg        *sync.WaitGroup
 	semaphore chan bool
+	draining  atomic.Bool
+
+	// drained is closed once the manager is drained, to stop the producers
+	// of the tasks.
+	drained   chan struct{}
+	drainOnce sync.Once
+
+	// limiter is shared by the transfers of all workers to limit their
+	// aggregate throughput. It is nil if the throughput is not limited.
+	limiter *ratelimit.Limiter
+
+	// taskLimiter limits the number of tasks started per second by all
+	// workers, a token per task. It is nil if the task rate is not limited.
+	taskLimiter *ratelimit.Limiter
+
+	// memoryLimiter limits the memory of the buffers of the transfers of all
+	// workers. It is nil if the memory is not limited.
+	memoryLimiter *MemoryLimiter
+
+	// counters of the tasks, updated atomically. queued is the number of
+	// tasks waiting for a worker or for the task rate limit. The tasks
+	// waiting in the queues of the producers are counted by queues.
+	queued    int64
+	active    int64
+	completed int64
+	failed    int64
+
+	// queues are the lengths of the queues of the tasks which are not run
+	// yet, e.g. the commands read ahead by run.
+	queuesMu  sync.Mutex
+	queues    map[int]func() int
+	nextQueue int
 }
 
 // New creates a new parallel.Manager.
@@ -31,6 +82,7 @@ func New(workercount int) *Manager {
 	return &Manager{
 		wg:        &sync.WaitGroup{},
 		semaphore: make(chan bool, workercount),
+		drained:   make(chan struct{}),
 	}
 }
 
@@ -46,38 +98,185 @@ func (p *Manager) release() {
 	<-p.semaphore
 }
 
-// Run runs the given task while limiting the concurrency.
+// Run runs the given task while limiting the concurrency and the task rate.
+// The task is skipped if the manager is draining. If the waiter has a worker
+// limit, the task also waits for a worker of the waiter, before acquiring a
+// worker of the manager so that the other waiters are not blocked.
 func (p *Manager) Run(fn Task, waiter *Waiter) {
 	waiter.wg.Add(1)
+	syncatomic.AddInt64(&p.queued, 1)
+	waiter.acquire()
 	p.acquire()
 	go func() {
 		defer waiter.wg.Done()
+		defer waiter.release()
 		defer p.release()
 
+		if p.draining.Get() {
+			syncatomic.AddInt64(&p.queued, -1)
+			stat.AddSkipped()
+			waiter.errch <- context.Canceled
+			return
+		}
+
+		// the task is not active until it is allowed by the task rate.
+		p.taskLimiter.Wait(1)
+		syncatomic.AddInt64(&p.queued, -1)
+		syncatomic.AddInt64(&p.active, 1)
+		defer syncatomic.AddInt64(&p.active, -1)
+
 		if err := fn(); err != nil {
+			syncatomic.AddInt64(&p.failed, 1)
 			waiter.errch <- err
+			return
 		}
+		syncatomic.AddInt64(&p.completed, 1)
 	}()
 }
 
+// Stats returns the current state of the workers and the tasks of the
+// manager.
+func (p *Manager) Stats() Stats {
+	return Stats{
+		Workers:   cap(p.semaphore),
+		Active:    syncatomic.LoadInt64(&p.active),
+		Queued:    syncatomic.LoadInt64(&p.queued) + int64(p.queueLengths()),
+		Completed: syncatomic.LoadInt64(&p.completed),
+		Failed:    syncatomic.LoadInt64(&p.failed),
+	}
+}
+
+// AddQueue adds the queue of the given length, e.g. the commands read ahead by
+// run, to the queued tasks of the manager. The returned function removes the
+// queue.
+func (p *Manager) AddQueue(length func() int) func() {
+	p.queuesMu.Lock()
+	defer p.queuesMu.Unlock()
+
+	if p.queues == nil {
+		p.queues = map[int]func() int{}
+	}
+	id := p.nextQueue
+	p.nextQueue++
+	p.queues[id] = length
+
+	return func() {
+		p.queuesMu.Lock()
+		defer p.queuesMu.Unlock()
+		delete(p.queues, id)
+	}
+}
+
+// queueLengths returns the total length of the queues of the manager.
+func (p *Manager) queueLengths() int {
+	p.queuesMu.Lock()
+	defer p.queuesMu.Unlock()
+
+	var n int
+	for _, length := range p.queues {
+		n += length()
+	}
+	return n
+}
+
+// Drain stops running new tasks. Tasks which are already running are not
+// affected.
+func (p *Manager) Drain() {
+	p.draining.Set(true)
+	p.drainOnce.Do(func() { close(p.drained) })
+}
+
+// WithDrain returns a copy of ctx which is canceled once the manager is
+// drained. It is meant for the producers of the tasks, such as the listings of
+// the sources, while the tasks keep using ctx.
+func (p *Manager) WithDrain(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-p.drained:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 // Close waits all tasks to finish.
 func (p *Manager) Close() {
 	p.wg.Wait()
 	close(p.semaphore)
 }
 
+// Stats is a snapshot of the workers and the tasks of a Manager. It implements
+// log.Message interface.
+type Stats struct {
+	Workers   int   `json:"workers"`
+	Active    int64 `json:"active"`
+	Queued    int64 `json:"queued"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf(
+		"Workers: %d/%d active, Queued: %d, Completed: %d, Failed: %d",
+		s.Active,
+		s.Workers,
+		s.Queued,
+		s.Completed,
+		s.Failed,
+	)
+}
+
+func (s Stats) JSON() string {
+	return strutil.JSON(struct {
+		Progress Stats `json:"progress"`
+	}{s})
+}
+
+// workersKey is the context key of the worker limit.
+type workersKey struct{}
+
+// WithWorkers returns a copy of ctx which limits the number of concurrent
+// tasks of the waiters created with it to n. It is used to override the
+// worker count for a single command.
+func WithWorkers(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, workersKey{}, n)
+}
+
 // Waiter is a structure for waiting and reading
 // error messages created by Manager.
 type Waiter struct {
 	wg    sync.WaitGroup
 	errch chan error
+
+	// semaphore limits the concurrent tasks of the waiter. It is nil if the
+	// tasks are only limited by the manager.
+	semaphore chan bool
 }
 
-// NewWaiter creates a new parallel.Waiter.
-func NewWaiter() *Waiter {
-	return &Waiter{
+// NewWaiter creates a new parallel.Waiter. The concurrent tasks of the waiter
+// are limited if ctx is created with WithWorkers.
+func NewWaiter(ctx context.Context) *Waiter {
+	w := &Waiter{
 		errch: make(chan error),
 	}
+	if n, ok := ctx.Value(workersKey{}).(int); ok {
+		w.semaphore = make(chan bool, n)
+	}
+	return w
+}
+
+func (w *Waiter) acquire() {
+	if w.semaphore != nil {
+		w.semaphore <- true
+	}
+}
+
+func (w *Waiter) release() {
+	if w.semaphore != nil {
+		<-w.semaphore
+	}
 }
 
 // Wait blocks until the WaitGroup counter is zero
@@ -0,0 +1,285 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/ratelimit"
+)
+
+func TestManagerDrain(t *testing.T) {
+	t.Parallel()
+
+	manager := New(2)
+	defer manager.Close()
+
+	waiter := NewWaiter(context.Background())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	manager.Run(func() error {
+		close(started)
+		<-release
+		return nil
+	}, waiter)
+
+	<-started
+	manager.Drain()
+
+	var ran bool
+	manager.Run(func() error {
+		ran = true
+		return nil
+	}, waiter)
+
+	var errs []error
+	errDoneCh := make(chan struct{})
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			errs = append(errs, err)
+		}
+	}()
+
+	// running task must not be affected by draining.
+	close(release)
+
+	waiter.Wait()
+	<-errDoneCh
+
+	if ran {
+		t.Errorf("expected task to be skipped while draining")
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected a single cancelation error, got %v", errs)
+	}
+}
+
+func TestManagerWithDrain(t *testing.T) {
+	t.Parallel()
+
+	manager := New(2)
+	defer manager.Close()
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+
+	ctx, cancel := manager.WithDrain(parent)
+	defer cancel()
+
+	if ctx.Err() != nil {
+		t.Fatalf("expected the context not to be canceled before draining")
+	}
+
+	manager.Drain()
+	// draining twice must not panic.
+	manager.Drain()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the context to be canceled once the manager is drained")
+	}
+
+	// the context given to the tasks must not be affected by draining.
+	if parent.Err() != nil {
+		t.Errorf("expected the parent context not to be canceled")
+	}
+}
+
+func TestWaiterWithWorkers(t *testing.T) {
+	t.Parallel()
+
+	const workers = 2
+
+	manager := New(8)
+	defer manager.Close()
+
+	waiter := NewWaiter(WithWorkers(context.Background(), workers))
+
+	var running, maxRunning int64
+	for i := 0; i < 10; i++ {
+		manager.Run(func() error {
+			n := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+
+			for {
+				max := atomic.LoadInt64(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+					break
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}, waiter)
+	}
+
+	go func() {
+		for range waiter.Err() {
+		}
+	}()
+	waiter.Wait()
+
+	if maxRunning != workers {
+		t.Errorf("expected at most %v concurrent tasks, got %v", workers, maxRunning)
+	}
+}
+
+func TestManagerTaskRate(t *testing.T) {
+	t.Parallel()
+
+	const (
+		rate  = 50
+		tasks = 100
+	)
+
+	manager := New(8)
+	manager.taskLimiter = ratelimit.New(rate)
+	defer manager.Close()
+
+	waiter := NewWaiter(context.Background())
+
+	start := time.Now()
+	for i := 0; i < tasks; i++ {
+		manager.Run(func() error { return nil }, waiter)
+	}
+	go func() {
+		for range waiter.Err() {
+		}
+	}()
+	waiter.Wait()
+	elapsed := time.Since(start)
+
+	// a second worth of tasks is allowed as a burst, the rest is started at
+	// the given rate.
+	expected := time.Duration(float64(tasks-rate) / rate * float64(time.Second))
+	if elapsed < expected*9/10 || elapsed > expected*3/2 {
+		t.Errorf("expected tasks to finish in about %v, took %v", expected, elapsed)
+	}
+}
+
+func TestManagerStats(t *testing.T) {
+	t.Parallel()
+
+	manager := New(2)
+	defer manager.Close()
+
+	waiter := NewWaiter(context.Background())
+	go func() {
+		for range waiter.Err() {
+		}
+	}()
+
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		manager.Run(func() error {
+			<-release
+			return nil
+		}, waiter)
+	}
+
+	// all workers are busy, so the next task waits in the queue.
+	queuedDone := make(chan struct{})
+	go func() {
+		defer close(queuedDone)
+		manager.Run(func() error { return errors.New("failed") }, waiter)
+	}()
+
+	for manager.Stats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	expected := Stats{Workers: 2, Active: 2, Queued: 1}
+	if got := manager.Stats(); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	close(release)
+	<-queuedDone
+	waiter.Wait()
+
+	expected = Stats{Workers: 2, Completed: 2, Failed: 1}
+	if got := manager.Stats(); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestManagerStatsTaskRate(t *testing.T) {
+	t.Parallel()
+
+	manager := New(2)
+	// the burst of a task is taken by the first task, so the second one
+	// waits for a second.
+	manager.taskLimiter = ratelimit.New(1)
+	defer manager.Close()
+
+	waiter := NewWaiter(context.Background())
+	go func() {
+		for range waiter.Err() {
+		}
+	}()
+
+	release := make(chan struct{})
+	running := make(chan struct{})
+	manager.Run(func() error {
+		close(running)
+		<-release
+		return nil
+	}, waiter)
+	<-running
+
+	manager.Run(func() error { return nil }, waiter)
+
+	// the task waiting for the task rate is not active, although it holds a
+	// worker.
+	expected := Stats{Workers: 2, Active: 1, Queued: 1}
+	if got := manager.Stats(); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	close(release)
+	waiter.Wait()
+}
+
+func TestManagerStatsQueues(t *testing.T) {
+	t.Parallel()
+
+	manager := New(2)
+	defer manager.Close()
+
+	removeFirst := manager.AddQueue(func() int { return 3 })
+	removeSecond := manager.AddQueue(func() int { return 2 })
+
+	if got := manager.Stats().Queued; got != 5 {
+		t.Errorf("expected the lengths of the queues to be queued, got %v", got)
+	}
+
+	removeFirst()
+	if got := manager.Stats().Queued; got != 2 {
+		t.Errorf("expected the removed queue not to be counted, got %v", got)
+	}
+
+	removeSecond()
+	if got := manager.Stats().Queued; got != 0 {
+		t.Errorf("expected no queued tasks, got %v", got)
+	}
+}
+
+func TestAutoWorkerCount(t *testing.T) {
+	t.Parallel()
+
+	got := AutoWorkerCount()
+	if expected := runtime.NumCPU() * autoWorkersPerCPU; got != expected {
+		t.Errorf("expected %v workers, got %v", expected, got)
+	}
+	if got < minNumWorkers {
+		t.Errorf("expected at least %v workers, got %v", minNumWorkers, got)
+	}
+}
@@ -0,0 +1,246 @@
+// Package progress implements a progress bar for the transfer operations.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/peak/s5cmd/iorange"
+	"github.com/peak/s5cmd/strutil"
+)
+
+const (
+	barWidth        = 40
+	refreshInterval = 200 * time.Millisecond
+)
+
+// ProgressBar is the interface for reporting the progress of an operation.
+type ProgressBar interface {
+	Start()
+	Finish()
+	AddTotalObject(size int64)
+	AddCompletedObject(size int64)
+	AddCompletedBytes(n int64)
+}
+
+// NoOp is a ProgressBar which does nothing. It is used when the progress bar
+// is not requested.
+type NoOp struct{}
+
+func (NoOp) Start()                   {}
+func (NoOp) Finish()                  {}
+func (NoOp) AddTotalObject(int64)     {}
+func (NoOp) AddCompletedObject(int64) {}
+func (NoOp) AddCompletedBytes(int64)  {}
+
+// Bar renders the aggregate progress of all transfers of a command on a
+// single line. Multiple workers can update Bar concurrently.
+type Bar struct {
+	w io.Writer
+
+	totalObjects     int64
+	completedObjects int64
+	totalBytes       int64
+	completedBytes   int64
+
+	startedAt time.Time
+	donech    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New creates a new progress bar which renders to w.
+func New(w io.Writer) *Bar {
+	return &Bar{
+		w:      w,
+		donech: make(chan struct{}),
+	}
+}
+
+// Start starts rendering the progress bar periodically.
+func (b *Bar) Start() {
+	b.startedAt = time.Now()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Fprint(b.w, "\r"+b.String())
+			case <-b.donech:
+				return
+			}
+		}
+	}()
+}
+
+// Finish stops rendering and prints the final state of the progress bar.
+func (b *Bar) Finish() {
+	close(b.donech)
+	b.wg.Wait()
+	fmt.Fprintln(b.w, "\r"+b.String())
+}
+
+// AddTotalObject adds an object with the given size to the progress bar.
+func (b *Bar) AddTotalObject(size int64) {
+	atomic.AddInt64(&b.totalObjects, 1)
+	atomic.AddInt64(&b.totalBytes, size)
+}
+
+// AddCompletedObject marks an object with the given size as completed.
+func (b *Bar) AddCompletedObject(size int64) {
+	atomic.AddInt64(&b.completedObjects, 1)
+	atomic.AddInt64(&b.completedBytes, size)
+}
+
+// AddCompletedBytes adds n bytes of an in-flight transfer to the completed
+// bytes.
+func (b *Bar) AddCompletedBytes(n int64) {
+	atomic.AddInt64(&b.completedBytes, n)
+}
+
+// String returns the string representation of the progress bar.
+func (b *Bar) String() string {
+	var (
+		totalObjects     = atomic.LoadInt64(&b.totalObjects)
+		completedObjects = atomic.LoadInt64(&b.completedObjects)
+		totalBytes       = atomic.LoadInt64(&b.totalBytes)
+		completedBytes   = atomic.LoadInt64(&b.completedBytes)
+	)
+
+	// completed bytes might exceed the total if a part is retried.
+	if completedBytes > totalBytes {
+		completedBytes = totalBytes
+	}
+
+	ratio := 1.0
+	if totalBytes > 0 {
+		ratio = float64(completedBytes) / float64(totalBytes)
+	}
+
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+	var throughput int64
+	if elapsed := time.Since(b.startedAt).Seconds(); elapsed > 0 {
+		throughput = int64(float64(completedBytes) / elapsed)
+	}
+
+	return fmt.Sprintf(
+		"%3d%% [%s] %s / %s  %s/s  %d / %d objects",
+		int(ratio*100),
+		bar,
+		strutil.HumanizeBytes(completedBytes),
+		strutil.HumanizeBytes(totalBytes),
+		strutil.HumanizeBytes(throughput),
+		completedObjects,
+		totalObjects,
+	)
+}
+
+// Reader counts the bytes read from the underlying reader as completed bytes
+// of an in-flight transfer.
+type Reader interface {
+	io.Reader
+
+	// Done removes the bytes counted by the reader from the progress bar.
+	// The transferred object is expected to be marked as completed
+	// afterwards.
+	Done()
+}
+
+// NewReader creates a new Reader which reports to bar. The returned reader
+// implements io.ReaderAt and io.Seeker if r implements both, so that the
+// uploader can still read the parts of a file without buffering them.
+func NewReader(r io.Reader, bar ProgressBar) Reader {
+	cr := &reader{r: r, bar: bar}
+	if ra, ok := r.(readerAtSeeker); ok {
+		return &readerAt{reader: cr, ra: ra}
+	}
+	return cr
+}
+
+type reader struct {
+	r   io.Reader
+	bar ProgressBar
+	n   int64
+}
+
+// Read implements io.Reader.
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.add(int64(n))
+	return n, err
+}
+
+func (r *reader) add(n int64) {
+	atomic.AddInt64(&r.n, n)
+	r.bar.AddCompletedBytes(n)
+}
+
+// Done implements Reader.
+func (r *reader) Done() {
+	r.bar.AddCompletedBytes(-atomic.LoadInt64(&r.n))
+}
+
+type readerAtSeeker interface {
+	io.ReaderAt
+	io.Seeker
+}
+
+type readerAt struct {
+	*reader
+	ra readerAtSeeker
+
+	// the uploader reads a part once to sign it and once more to send it,
+	// and again on each retry. only the first read of each byte is counted,
+	// otherwise the bar would pass the size of the object.
+	read iorange.Set
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.ra.ReadAt(p, off)
+	r.add(r.read.Add(off, n))
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *readerAt) Seek(offset int64, whence int) (int64, error) {
+	return r.ra.Seek(offset, whence)
+}
+
+// WriterAt counts the bytes written to the underlying writer as completed
+// bytes of an in-flight transfer.
+type WriterAt struct {
+	w   io.WriterAt
+	bar ProgressBar
+	n   int64
+}
+
+// NewWriterAt creates a new WriterAt which reports to bar.
+func NewWriterAt(w io.WriterAt, bar ProgressBar) *WriterAt {
+	return &WriterAt{w: w, bar: bar}
+}
+
+// WriteAt implements io.WriterAt.
+func (w *WriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.w.WriteAt(p, off)
+	atomic.AddInt64(&w.n, int64(n))
+	w.bar.AddCompletedBytes(int64(n))
+	return n, err
+}
+
+// Done removes the bytes counted by the writer from the progress bar. The
+// transferred object is expected to be marked as completed afterwards.
+func (w *WriterAt) Done() {
+	w.bar.AddCompletedBytes(-atomic.LoadInt64(&w.n))
+}
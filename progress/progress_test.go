@@ -0,0 +1,127 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestBarString(t *testing.T) {
+	t.Parallel()
+
+	bar := New(ioutil.Discard)
+	bar.AddTotalObject(1024)
+	bar.AddTotalObject(1024)
+	bar.AddCompletedObject(1024)
+
+	got := bar.String()
+	if !strings.HasPrefix(got, " 50% [") {
+		t.Errorf("expected progress to be 50%%, got %q", got)
+	}
+	if !strings.HasSuffix(got, "1 / 2 objects") {
+		t.Errorf("expected 1 of 2 objects to be completed, got %q", got)
+	}
+}
+
+func TestBarStringCompletedBytesExceedTotal(t *testing.T) {
+	t.Parallel()
+
+	bar := New(ioutil.Discard)
+	bar.AddTotalObject(10)
+	bar.AddCompletedBytes(20)
+
+	got := bar.String()
+	if !strings.HasPrefix(got, "100% [") {
+		t.Errorf("expected progress to be 100%%, got %q", got)
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	content := "0123456789"
+
+	bar := New(ioutil.Discard)
+	bar.AddTotalObject(int64(len(content)))
+
+	r := NewReader(strings.NewReader(content), bar)
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if bar.completedBytes != int64(len(content)) {
+		t.Errorf("expected %v completed bytes, got %v", len(content), bar.completedBytes)
+	}
+
+	r.Done()
+	if bar.completedBytes != 0 {
+		t.Errorf("expected completed bytes to be reset, got %v", bar.completedBytes)
+	}
+}
+
+func TestReaderAt(t *testing.T) {
+	t.Parallel()
+
+	content := "0123456789"
+
+	bar := New(ioutil.Discard)
+	bar.AddTotalObject(int64(len(content)))
+
+	r := NewReader(strings.NewReader(content), bar)
+	ra, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	})
+	if !ok {
+		t.Fatalf("expected the reader of a seekable reader to implement io.ReaderAt and io.Seeker")
+	}
+
+	if size, err := ra.Seek(0, io.SeekEnd); err != nil || size != int64(len(content)) {
+		t.Fatalf("expected to seek to %v, got %v: %v", len(content), size, err)
+	}
+
+	// the parts are read once to sign them and once more to send them.
+	for i := 0; i < 2; i++ {
+		for _, part := range []*io.SectionReader{
+			io.NewSectionReader(ra, 0, 6),
+			io.NewSectionReader(ra, 6, 4),
+		} {
+			if _, err := ioutil.ReadAll(part); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if bar.completedBytes != int64(len(content)) {
+		t.Errorf("expected %v completed bytes, got %v", len(content), bar.completedBytes)
+	}
+
+	r.Done()
+	if bar.completedBytes != 0 {
+		t.Errorf("expected completed bytes to be reset, got %v", bar.completedBytes)
+	}
+
+	if _, ok := NewReader(ioutil.NopCloser(strings.NewReader(content)), bar).(io.ReaderAt); ok {
+		t.Errorf("expected the reader of a non-seekable reader not to implement io.ReaderAt")
+	}
+}
+
+func TestFinish(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	bar := New(&buf)
+	bar.Start()
+	bar.AddTotalObject(5)
+	bar.AddCompletedObject(5)
+	bar.Finish()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\r")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "100% [") || !strings.HasSuffix(last, "1 / 1 objects") {
+		t.Errorf("unexpected final progress %q", last)
+	}
+}
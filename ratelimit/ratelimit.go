@@ -0,0 +1,144 @@
+// Package ratelimit implements a token bucket to limit the throughput of the
+// transfer operations.
+package ratelimit
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/iorange"
+)
+
+// Limiter is a token bucket which is refilled with a byte per token at a
+// constant rate. Multiple workers can share a Limiter to limit their
+// aggregate throughput. A nil Limiter does not limit anything.
+type Limiter struct {
+	mu sync.Mutex
+
+	// rate is the number of bytes allowed per second.
+	rate float64
+
+	// burst is the maximum number of bytes which are allowed at once after
+	// the limiter is idle for a while.
+	burst float64
+
+	tokens float64
+	last   time.Time
+}
+
+// New creates a new Limiter which allows bytesPerSecond bytes per second and
+// bursts up to a second worth of bytes.
+func New(bytesPerSecond int64) *Limiter {
+	return &Limiter{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until n bytes are allowed to be transferred. Waiting callers
+// reserve their tokens in advance, so they are served in order.
+func (l *Limiter) Wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	time.Sleep(l.reserve(n))
+}
+
+// reserve takes n tokens from the bucket, which might leave it in debt, and
+// returns the duration to wait until the debt is paid.
+func (l *Limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Reader returns r as is if l is nil. Otherwise it returns a reader which
+// waits for l after each read. The returned reader implements io.ReaderAt and
+// io.Seeker if r implements both, since the uploader reads the parts of such
+// readers without buffering them.
+func (l *Limiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	lr := &reader{r: r, l: l}
+	if ra, ok := r.(readerAtSeeker); ok {
+		return &readerAt{reader: lr, ra: ra}
+	}
+	return lr
+}
+
+// WriterAt returns w as is if l is nil. Otherwise it returns a writer which
+// waits for l after each write.
+func (l *Limiter) WriterAt(w io.WriterAt) io.WriterAt {
+	if l == nil {
+		return w
+	}
+	return &writerAt{w: w, l: l}
+}
+
+type reader struct {
+	r io.Reader
+	l *Limiter
+}
+
+// Read implements io.Reader.
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.l.Wait(n)
+	return n, err
+}
+
+type readerAtSeeker interface {
+	io.ReaderAt
+	io.Seeker
+}
+
+type readerAt struct {
+	*reader
+	ra readerAtSeeker
+
+	// a part is read more than once by the uploader, to sign and to send it
+	// and to retry a failed request. the tokens are taken only for the
+	// bytes which are not read before, so a part costs its size once.
+	read iorange.Set
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.ra.ReadAt(p, off)
+	r.l.Wait(int(r.read.Add(off, n)))
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *readerAt) Seek(offset int64, whence int) (int64, error) {
+	return r.ra.Seek(offset, whence)
+}
+
+type writerAt struct {
+	w io.WriterAt
+	l *Limiter
+}
+
+// WriteAt implements io.WriterAt.
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.w.WriteAt(p, off)
+	w.l.Wait(n)
+	return n, err
+}
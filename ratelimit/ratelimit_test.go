@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterAggregateRate(t *testing.T) {
+	t.Parallel()
+
+	const (
+		rate        = 100 * 1024
+		workers     = 4
+		bytesToRead = 50 * 1024
+	)
+
+	limiter := New(rate)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			r := limiter.Reader(bytes.NewReader(make([]byte, bytesToRead)))
+			if _, err := ioutil.ReadAll(r); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// a second worth of bytes is allowed as a burst, the rest is limited.
+	expected := time.Duration(float64(workers*bytesToRead-rate) / rate * float64(time.Second))
+	if elapsed < expected*9/10 || elapsed > expected*3/2 {
+		t.Errorf("expected workers to finish in about %v, took %v", expected, elapsed)
+	}
+}
+
+func TestLimiterWriterAt(t *testing.T) {
+	t.Parallel()
+
+	const rate = 1024
+
+	f, err := ioutil.TempFile("", "ratelimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := New(rate).WriterAt(f)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := w.WriteAt(make([]byte, rate/2), int64(i*rate/2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first 1024 bytes are the burst, the remaining 512 bytes take half
+	// a second.
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("expected writes to be limited, took %v", elapsed)
+	}
+}
+
+func TestLimiterReaderAt(t *testing.T) {
+	t.Parallel()
+
+	const rate = 1024
+
+	content := make([]byte, rate*3/2)
+	r := New(rate).Reader(bytes.NewReader(content))
+
+	ra, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	})
+	if !ok {
+		t.Fatalf("expected the reader of a seekable reader to implement io.ReaderAt and io.Seeker")
+	}
+
+	start := time.Now()
+	// the part is read once to sign it and once more to send it.
+	for i := 0; i < 2; i++ {
+		if _, err := ioutil.ReadAll(io.NewSectionReader(ra, 0, int64(len(content)))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first 1024 bytes are the burst, the remaining 512 bytes take half
+	// a second. the second read is not limited.
+	elapsed := time.Since(start)
+	if elapsed < 450*time.Millisecond || elapsed > 900*time.Millisecond {
+		t.Errorf("expected reads to take about half a second, took %v", elapsed)
+	}
+
+	if _, ok := New(rate).Reader(ioutil.NopCloser(bytes.NewReader(content))).(io.ReaderAt); ok {
+		t.Errorf("expected the reader of a non-seekable reader not to implement io.ReaderAt")
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	t.Parallel()
+
+	var limiter *Limiter
+
+	r := strings.NewReader("")
+	if limiter.Reader(r) != r {
+		t.Errorf("expected nil limiter to return the given reader")
+	}
+
+	var w *os.File
+	if limiter.WriterAt(w) != w {
+		t.Errorf("expected nil limiter to return the given writer")
+	}
+
+	// must not block.
+	limiter.Wait(1 << 30)
+}
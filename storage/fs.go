@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/karrick/godirwalk"
 	"github.com/termie/go-shutil"
@@ -169,6 +170,32 @@ func (f *Filesystem) Copy(ctx context.Context, src, dst *url.URL, _ Metadata) er
 	return err
 }
 
+// rename is os.Rename, replaced by the tests to simulate moves across
+// filesystems.
+var rename = os.Rename
+
+// Rename moves given source to destination. If the destination is on another
+// filesystem, the source is copied and deleted.
+func (f *Filesystem) Rename(ctx context.Context, src, dst *url.URL) error {
+	if f.dryRun {
+		return nil
+	}
+
+	if err := os.MkdirAll(dst.Dir(), os.ModePerm); err != nil {
+		return err
+	}
+
+	err := rename(src.Absolute(), dst.Absolute())
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+
+	if err := f.Copy(ctx, src, dst, NewMetadata()); err != nil {
+		return err
+	}
+	return f.Delete(ctx, src)
+}
+
 // Delete deletes given file.
 func (f *Filesystem) Delete(ctx context.Context, url *url.URL) error {
 	if f.dryRun {
@@ -213,6 +240,14 @@ func (f *Filesystem) Create(path string) (*os.File, error) {
 	return os.Create(path)
 }
 
+// Chtimes calls os.Chtimes.
+func (f *Filesystem) Chtimes(path string, atime, mtime time.Time) error {
+	if f.dryRun {
+		return nil
+	}
+	return os.Chtimes(path, atime, mtime)
+}
+
 // Open opens the given source.
 func (f *Filesystem) Open(path string) (*os.File, error) {
 	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
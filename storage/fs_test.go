@@ -1,6 +1,16 @@
 package storage
 
-import "testing"
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/peak/s5cmd/storage/url"
+)
 
 func TestFilesystemImplementsStorageInterface(t *testing.T) {
 	var i interface{} = new(Filesystem)
@@ -8,3 +18,120 @@ func TestFilesystemImplementsStorageInterface(t *testing.T) {
 		t.Errorf("expected %t to implement Storage interface", i)
 	}
 }
+
+func TestFilesystemRename(t *testing.T) {
+	testcases := []struct {
+		name        string
+		crossDevice bool
+	}{
+		{
+			name: "same device",
+		},
+		{
+			name:        "cross device",
+			crossDevice: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.crossDevice {
+				rename = func(oldpath, newpath string) error {
+					return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+				}
+				defer func() { rename = os.Rename }()
+			}
+
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			srcpath := filepath.Join(dir, "src.sh")
+			if err := ioutil.WriteFile(srcpath, []byte("content"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			dstpath := filepath.Join(dir, "nested", "dst.sh")
+
+			src, err := url.New(srcpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dst, err := url.New(dstpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := new(Filesystem).Rename(context.Background(), src, dst); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := os.Stat(srcpath); !os.IsNotExist(err) {
+				t.Errorf("expected the source to be deleted, got %v", err)
+			}
+
+			content, err := ioutil.ReadFile(dstpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "content" {
+				t.Errorf("content got = %q, want %q", content, "content")
+			}
+
+			// only the read-only bit of the permissions is kept on Windows.
+			if runtime.GOOS == "windows" {
+				return
+			}
+
+			st, err := os.Stat(dstpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.Mode().Perm() != 0755 {
+				t.Errorf("mode got = %v, want %v", st.Mode().Perm(), os.FileMode(0755))
+			}
+		})
+	}
+}
+
+func TestFilesystemRenameFails(t *testing.T) {
+	renameErr := &os.LinkError{Op: "rename", Err: syscall.EACCES}
+	rename = func(oldpath, newpath string) error {
+		return renameErr
+	}
+	defer func() { rename = os.Rename }()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srcpath := filepath.Join(dir, "src.txt")
+	if err := ioutil.WriteFile(srcpath, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dstpath := filepath.Join(dir, "dst.txt")
+
+	src, err := url.New(srcpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := url.New(dstpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the renames across filesystems fall back to copying.
+	if err := new(Filesystem).Rename(context.Background(), src, dst); err != renameErr {
+		t.Fatalf("expected the rename error, got %v", err)
+	}
+
+	if _, err := os.Stat(srcpath); err != nil {
+		t.Errorf("expected the source to be kept, got %v", err)
+	}
+	if _, err := os.Stat(dstpath); !os.IsNotExist(err) {
+		t.Errorf("expected the destination not to be created, got %v", err)
+	}
+}
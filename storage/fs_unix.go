@@ -0,0 +1,14 @@
+// +build !windows
+
+package storage
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDevice reports whether err is returned for a rename to another
+// filesystem.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
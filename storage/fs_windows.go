@@ -0,0 +1,18 @@
+// +build windows
+
+package storage
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, which is returned for a move to
+// another volume.
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDevice reports whether err is returned for a rename to another
+// volume.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice) || errors.Is(err, syscall.EXDEV)
+}
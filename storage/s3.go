@@ -7,18 +7,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	urlpkg "net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	syncatomic "sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -26,7 +30,9 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/peak/s5cmd/atomic"
 	"github.com/peak/s5cmd/log"
+	"github.com/peak/s5cmd/log/stat"
 	"github.com/peak/s5cmd/storage/url"
 )
 
@@ -37,16 +43,38 @@ const (
 	// request.
 	deleteObjectsMax = 1000
 
+	// maxKeysPerPage is the max allowed objects to be listed on single HTTP
+	// request.
+	maxKeysPerPage = 1000
+
 	// Amazon Accelerated Transfer endpoint
 	transferAccelEndpoint = "s3-accelerate.amazonaws.com"
 
 	// Google Cloud Storage endpoint
 	gcsEndpoint = "storage.googleapis.com"
+
+	// MaxCopyObjectSize is the size of the largest object which can be
+	// copied with a single CopyObject request. Larger objects are copied
+	// with MultipartCopy.
+	MaxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+	// maxCopyPartSize is the size of the largest part of a multipart copy.
+	maxCopyPartSize = 5 * 1024 * 1024 * 1024
+
+	// MaxPutObjectSize is the size of the largest object which can be
+	// uploaded with a single request.
+	MaxPutObjectSize = 5 * 1024 * 1024 * 1024
+
+	// MinPartSize is the size of the smallest part of a multipart upload,
+	// except its last part.
+	MinPartSize = s3manager.MinUploadPartSize
 )
 
 // Re-used AWS sessions dramatically improve performance.
 var globalSessionCache = &SessionCache{
-	sessions: map[Options]*session.Session{},
+	sessions:    map[Options]*session.Session{},
+	clients:     map[Options]*S3{},
+	credentials: map[assumedRole]*credentials.Credentials{},
 }
 
 // S3 is a storage type which interacts with S3API, DownloaderAPI and
@@ -57,6 +85,10 @@ type S3 struct {
 	uploader    s3manageriface.UploaderAPI
 	endpointURL urlpkg.URL
 	dryRun      bool
+
+	// requestPayer is sent with the requests to access requester pays
+	// buckets. It is empty if the bucket owner pays.
+	requestPayer string
 }
 
 func parseEndpoint(endpoint string) (urlpkg.URL, error) {
@@ -76,8 +108,14 @@ func parseEndpoint(endpoint string) (urlpkg.URL, error) {
 	return *u, nil
 }
 
-// NewS3Storage creates new S3 session.
+// NewS3Storage creates new S3 session. The clients are safe for concurrent
+// use, so a client is created once for the given options and shared by all
+// workers.
 func newS3Storage(ctx context.Context, opts Options) (*S3, error) {
+	return globalSessionCache.newClient(ctx, opts)
+}
+
+func newS3Client(ctx context.Context, opts Options) (*S3, error) {
 	endpointURL, err := parseEndpoint(opts.Endpoint)
 	if err != nil {
 		return nil, err
@@ -89,19 +127,22 @@ func newS3Storage(ctx context.Context, opts Options) (*S3, error) {
 	}
 
 	return &S3{
-		api:         s3.New(awsSession),
-		downloader:  s3manager.NewDownloader(awsSession),
-		uploader:    s3manager.NewUploader(awsSession),
-		endpointURL: endpointURL,
-		dryRun:      opts.DryRun,
+		api:          s3.New(awsSession),
+		downloader:   s3manager.NewDownloader(awsSession),
+		uploader:     s3manager.NewUploader(awsSession),
+		endpointURL:  endpointURL,
+		dryRun:       opts.DryRun,
+		requestPayer: opts.RequestPayer,
 	}, nil
 }
 
 // Stat retrieves metadata from S3 object without returning the object itself.
 func (s *S3) Stat(ctx context.Context, url *url.URL) (*Object, error) {
 	output, err := s.api.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(url.Bucket),
-		Key:    aws.String(url.Path),
+		Bucket:       aws.String(url.Bucket),
+		Key:          aws.String(url.Path),
+		VersionId:    versionID(url),
+		RequestPayer: s.payer(),
 	})
 	if err != nil {
 		if errHasCode(err, "NotFound") {
@@ -110,13 +151,40 @@ func (s *S3) Stat(ctx context.Context, url *url.URL) (*Object, error) {
 		return nil, err
 	}
 
+	metadata := NewMetadata()
+	for key, value := range map[string]*string{
+		"ContentType":        output.ContentType,
+		"ContentEncoding":    output.ContentEncoding,
+		"ContentDisposition": output.ContentDisposition,
+		"ContentLanguage":    output.ContentLanguage,
+		"CacheControl":       output.CacheControl,
+		"Expires":            output.Expires,
+		"EncryptionMethod":   output.ServerSideEncryption,
+		"EncryptionKeyID":    output.SSEKMSKeyId,
+	} {
+		if v := aws.StringValue(value); v != "" {
+			metadata[key] = v
+		}
+	}
+	for key, value := range output.Metadata {
+		metadata.SetUserDefined(key, aws.StringValue(value))
+	}
+
+	// storage class is not returned for the standard storage class.
+	storageClass := aws.StringValue(output.StorageClass)
+	if storageClass == "" {
+		storageClass = s3.StorageClassStandard
+	}
+
 	etag := aws.StringValue(output.ETag)
 	mod := aws.TimeValue(output.LastModified)
 	return &Object{
-		URL:     url,
-		Etag:    strings.Trim(etag, `"`),
-		ModTime: &mod,
-		Size:    aws.Int64Value(output.ContentLength),
+		URL:          url,
+		Etag:         strings.Trim(etag, `"`),
+		ModTime:      &mod,
+		Size:         aws.Int64Value(output.ContentLength),
+		StorageClass: StorageClass(storageClass),
+		Metadata:     metadata,
 	}, nil
 }
 
@@ -132,14 +200,24 @@ func (s *S3) List(ctx context.Context, url *url.URL, _ bool) <-chan *Object {
 
 func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 	listInput := s3.ListObjectsV2Input{
-		Bucket: aws.String(url.Bucket),
-		Prefix: aws.String(url.Prefix),
+		Bucket:       aws.String(url.Bucket),
+		Prefix:       aws.String(url.Prefix),
+		RequestPayer: s.payer(),
 	}
 
 	if url.Delimiter != "" {
 		listInput.SetDelimiter(url.Delimiter)
 	}
 
+	if url.StartAfter != "" {
+		listInput.SetStartAfter(url.StartAfter)
+	}
+
+	// the pages are not larger than needed for a few keys.
+	if url.MaxKeys > 0 && url.MaxKeys < maxKeysPerPage {
+		listInput.SetMaxKeys(url.MaxKeys)
+	}
+
 	objCh := make(chan *Object)
 
 	go func() {
@@ -147,6 +225,7 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 		objectFound := false
 
 		var now time.Time
+		var listed int64
 
 		err := s.api.ListObjectsV2PagesWithContext(ctx, &listInput, func(p *s3.ListObjectsV2Output, lastPage bool) bool {
 			for _, c := range p.CommonPrefixes {
@@ -163,6 +242,9 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 				}
 
 				objectFound = true
+				if listed++; listed == url.MaxKeys {
+					return false
+				}
 			}
 			// track the instant object iteration began,
 			// so it can be used to bypass objects created after this instant
@@ -201,6 +283,9 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 				}
 
 				objectFound = true
+				if listed++; listed == url.MaxKeys {
+					return false
+				}
 			}
 
 			return !lastPage
@@ -223,14 +308,26 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 // ListObjectsV2 API. I'm looking at you GCS.
 func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 	listInput := s3.ListObjectsInput{
-		Bucket: aws.String(url.Bucket),
-		Prefix: aws.String(url.Prefix),
+		Bucket:       aws.String(url.Bucket),
+		Prefix:       aws.String(url.Prefix),
+		RequestPayer: s.payer(),
 	}
 
 	if url.Delimiter != "" {
 		listInput.SetDelimiter(url.Delimiter)
 	}
 
+	// ListObjects API has no StartAfter parameter, but the marker is the key
+	// to start listing after as well.
+	if url.StartAfter != "" {
+		listInput.SetMarker(url.StartAfter)
+	}
+
+	// the pages are not larger than needed for a few keys.
+	if url.MaxKeys > 0 && url.MaxKeys < maxKeysPerPage {
+		listInput.SetMaxKeys(url.MaxKeys)
+	}
+
 	objCh := make(chan *Object)
 
 	go func() {
@@ -238,6 +335,7 @@ func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 		objectFound := false
 
 		var now time.Time
+		var listed int64
 
 		err := s.api.ListObjectsPagesWithContext(ctx, &listInput, func(p *s3.ListObjectsOutput, lastPage bool) bool {
 			for _, c := range p.CommonPrefixes {
@@ -254,6 +352,9 @@ func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 				}
 
 				objectFound = true
+				if listed++; listed == url.MaxKeys {
+					return false
+				}
 			}
 			// track the instant object iteration began,
 			// so it can be used to bypass objects created after this instant
@@ -291,6 +392,105 @@ func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 					StorageClass: StorageClass(aws.StringValue(c.StorageClass)),
 				}
 
+				objectFound = true
+				if listed++; listed == url.MaxKeys {
+					return false
+				}
+			}
+
+			return !lastPage
+		})
+
+		if err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+
+		if !objectFound {
+			objCh <- &Object{Err: ErrNoObjectFound}
+		}
+	}()
+
+	return objCh
+}
+
+// ListVersions is a non-blocking S3 list operation which paginates and filters
+// the versions and the delete markers of S3 keys. The URL of each version has
+// its version id. If no version is found or an error is encountered during
+// this period, it sends these errors to object channel.
+func (s *S3) ListVersions(ctx context.Context, url *url.URL) <-chan *Object {
+	listInput := s3.ListObjectVersionsInput{
+		Bucket: aws.String(url.Bucket),
+		Prefix: aws.String(url.Prefix),
+	}
+
+	if url.Delimiter != "" {
+		listInput.SetDelimiter(url.Delimiter)
+	}
+
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+		objectFound := false
+
+		err := s.api.ListObjectVersionsPagesWithContext(ctx, &listInput, func(p *s3.ListObjectVersionsOutput, lastPage bool) bool {
+			for _, c := range p.CommonPrefixes {
+				prefix := aws.StringValue(c.Prefix)
+				if !url.Match(prefix) {
+					continue
+				}
+
+				newurl := url.Clone()
+				newurl.Path = prefix
+				objCh <- &Object{
+					URL:  newurl,
+					Type: ObjectType{os.ModeDir},
+				}
+
+				objectFound = true
+			}
+
+			for _, v := range p.Versions {
+				key := aws.StringValue(v.Key)
+				if !url.Match(key) {
+					continue
+				}
+
+				newurl := url.Clone()
+				newurl.Path = key
+				newurl.VersionID = aws.StringValue(v.VersionId)
+				etag := aws.StringValue(v.ETag)
+				mod := aws.TimeValue(v.LastModified).UTC()
+
+				objCh <- &Object{
+					URL:          newurl,
+					Etag:         strings.Trim(etag, `"`),
+					ModTime:      &mod,
+					Size:         aws.Int64Value(v.Size),
+					StorageClass: StorageClass(aws.StringValue(v.StorageClass)),
+				}
+
+				objectFound = true
+			}
+
+			for _, m := range p.DeleteMarkers {
+				key := aws.StringValue(m.Key)
+				if !url.Match(key) {
+					continue
+				}
+
+				newurl := url.Clone()
+				newurl.Path = key
+				newurl.VersionID = aws.StringValue(m.VersionId)
+				mod := aws.TimeValue(m.LastModified).UTC()
+
+				objCh <- &Object{
+					URL:            newurl,
+					ModTime:        &mod,
+					IsDeleteMarker: true,
+				}
+
 				objectFound = true
 			}
 
@@ -317,13 +517,17 @@ func (s *S3) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) err
 		return nil
 	}
 
-	// SDK expects CopySource like "bucket[/key]"
+	// SDK expects CopySource like "bucket[/key][?versionId=id]"
 	copySource := from.EscapedPath()
+	if from.VersionID != "" {
+		copySource += "?versionId=" + from.VersionID
+	}
 
 	input := &s3.CopyObjectInput{
-		Bucket:     aws.String(to.Bucket),
-		Key:        aws.String(to.Path),
-		CopySource: aws.String(copySource),
+		Bucket:       aws.String(to.Bucket),
+		Key:          aws.String(to.Path),
+		CopySource:   aws.String(copySource),
+		RequestPayer: s.payer(),
 	}
 
 	storageClass := metadata.StorageClass()
@@ -345,9 +549,32 @@ func (s *S3) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) err
 		input.ACL = aws.String(acl)
 	}
 
+	// the metadata of the source object is copied unless any of it is
+	// given, which replaces all of it.
+	var replaceMetadata bool
+
+	contentType := metadata.ContentType()
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+		replaceMetadata = true
+	}
+
 	cacheControl := metadata.CacheControl()
 	if cacheControl != "" {
 		input.CacheControl = aws.String(cacheControl)
+		replaceMetadata = true
+	}
+
+	contentDisposition := metadata.ContentDisposition()
+	if contentDisposition != "" {
+		input.ContentDisposition = aws.String(contentDisposition)
+		replaceMetadata = true
+	}
+
+	contentLanguage := metadata.ContentLanguage()
+	if contentLanguage != "" {
+		input.ContentLanguage = aws.String(contentLanguage)
+		replaceMetadata = true
 	}
 
 	expires := metadata.Expires()
@@ -357,17 +584,297 @@ func (s *S3) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) err
 			return err
 		}
 		input.Expires = aws.Time(t)
+		replaceMetadata = true
 	}
 
-	_, err := s.api.CopyObject(input)
+	userMetadata := metadata.UserDefined()
+	if len(userMetadata) > 0 {
+		input.Metadata = aws.StringMap(userMetadata)
+		replaceMetadata = true
+	}
+
+	if replaceMetadata {
+		input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+	}
+
+	objectLockMode := metadata.ObjectLockMode()
+	if objectLockMode != "" {
+		t, err := time.Parse(time.RFC3339, metadata.ObjectLockRetainUntil())
+		if err != nil {
+			return err
+		}
+		input.ObjectLockMode = aws.String(objectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(t)
+	}
+
+	if metadata.ObjectLockLegalHold() {
+		input.ObjectLockLegalHoldStatus = aws.String(s3.ObjectLockLegalHoldStatusOn)
+	}
+
+	tagging := metadata.Tagging()
+	if tagging != "" {
+		input.Tagging = aws.String(tagging)
+		input.TaggingDirective = aws.String(s3.TaggingDirectiveReplace)
+	}
+
+	_, err := s.api.CopyObjectWithContext(ctx, input)
 	return err
 }
 
+// MultipartCopy copies the remote object of the given size on the server side
+// in parts, which is required for the objects larger than MaxCopyObjectSize.
+// The parts are copied with UploadPartCopy requests, concurrency of them at
+// once. Unlike Copy, the metadata and the tags of the source object are not
+// kept, so the metadata to set should include them.
+func (s *S3) MultipartCopy(
+	ctx context.Context,
+	from, to *url.URL,
+	metadata Metadata,
+	size int64,
+	concurrency int,
+	partSize int64,
+) error {
+	if s.dryRun {
+		return nil
+	}
+
+	// SDK expects CopySource like "bucket[/key][?versionId=id]"
+	copySource := from.EscapedPath()
+	if from.VersionID != "" {
+		copySource += "?versionId=" + from.VersionID
+	}
+
+	input, err := createMultipartUploadInput(to, metadata)
+	if err != nil {
+		return err
+	}
+	input.RequestPayer = s.payer()
+
+	output, err := s.api.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	uploadID := output.UploadId
+
+	parts, err := s.copyParts(ctx, copySource, to, uploadID, copyPartRanges(size, partSize), concurrency)
+	if err != nil {
+		// the parts copied so far are charged until the upload is aborted.
+		_, _ = s.api.AbortMultipartUploadWithContext(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:       aws.String(to.Bucket),
+			Key:          aws.String(to.Path),
+			UploadId:     uploadID,
+			RequestPayer: s.payer(),
+		})
+		return err
+	}
+
+	_, err = s.api.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(to.Bucket),
+		Key:             aws.String(to.Path),
+		UploadId:        uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+		RequestPayer:    s.payer(),
+	})
+	return err
+}
+
+// copyParts copies the given byte ranges of the source to the parts of the
+// multipart upload, concurrency of them at once. It stops at the first error.
+func (s *S3) copyParts(
+	ctx context.Context,
+	copySource string,
+	to *url.URL,
+	uploadID *string,
+	ranges []string,
+	concurrency int,
+) ([]*s3.CompletedPart, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		parts   = make([]*s3.CompletedPart, len(ranges))
+		partch  = make(chan int)
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		partErr error
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range partch {
+				partNumber := aws.Int64(int64(i + 1))
+				output, err := s.api.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+					Bucket:          aws.String(to.Bucket),
+					Key:             aws.String(to.Path),
+					CopySource:      aws.String(copySource),
+					CopySourceRange: aws.String(ranges[i]),
+					PartNumber:      partNumber,
+					UploadId:        uploadID,
+					RequestPayer:    s.payer(),
+				})
+				if err != nil {
+					errOnce.Do(func() {
+						partErr = err
+						cancel()
+					})
+					continue
+				}
+				parts[i] = &s3.CompletedPart{
+					ETag:       output.CopyPartResult.ETag,
+					PartNumber: partNumber,
+				}
+			}
+		}()
+	}
+
+loop:
+	for i := range ranges {
+		select {
+		case partch <- i:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(partch)
+	wg.Wait()
+
+	if partErr != nil {
+		return nil, partErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// copyPartRanges splits an object of the given size into the byte ranges of
+// the parts of a multipart copy, e.g. "bytes=0-5242879". The part size is
+// raised to the minimum part size, and to fit the object in the maximum
+// number of parts, and capped to the maximum part size.
+func copyPartRanges(size, partSize int64) []string {
+	if partSize < s3manager.MinUploadPartSize {
+		partSize = s3manager.MinUploadPartSize
+	}
+	if minSize := (size + s3manager.MaxUploadParts - 1) / s3manager.MaxUploadParts; partSize < minSize {
+		partSize = minSize
+	}
+	if partSize > maxCopyPartSize {
+		partSize = maxCopyPartSize
+	}
+
+	var ranges []string
+	for start := int64(0); start < size; start += partSize {
+		end := start + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		ranges = append(ranges, fmt.Sprintf("bytes=%d-%d", start, end))
+	}
+	return ranges
+}
+
+// createMultipartUploadInput returns the input of a multipart upload with the
+// given metadata.
+func createMultipartUploadInput(to *url.URL, metadata Metadata) (*s3.CreateMultipartUploadInput, error) {
+	contentType := metadata.ContentType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(to.Bucket),
+		Key:         aws.String(to.Path),
+		ContentType: aws.String(contentType),
+	}
+
+	storageClass := metadata.StorageClass()
+	if storageClass != "" {
+		input.StorageClass = aws.String(storageClass)
+	}
+
+	acl := metadata.ACL()
+	if acl != "" {
+		input.ACL = aws.String(acl)
+	}
+
+	cacheControl := metadata.CacheControl()
+	if cacheControl != "" {
+		input.CacheControl = aws.String(cacheControl)
+	}
+
+	contentEncoding := metadata.ContentEncoding()
+	if contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+
+	contentDisposition := metadata.ContentDisposition()
+	if contentDisposition != "" {
+		input.ContentDisposition = aws.String(contentDisposition)
+	}
+
+	contentLanguage := metadata.ContentLanguage()
+	if contentLanguage != "" {
+		input.ContentLanguage = aws.String(contentLanguage)
+	}
+
+	expires := metadata.Expires()
+	if expires != "" {
+		t, err := time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return nil, err
+		}
+		input.Expires = aws.Time(t)
+	}
+
+	sseEncryption := metadata.SSE()
+	if sseEncryption != "" {
+		input.ServerSideEncryption = aws.String(sseEncryption)
+		sseKmsKeyID := metadata.SSEKeyID()
+		if sseKmsKeyID != "" {
+			input.SSEKMSKeyId = aws.String(sseKmsKeyID)
+		}
+	}
+
+	userMetadata := metadata.UserDefined()
+	if len(userMetadata) > 0 {
+		input.Metadata = aws.StringMap(userMetadata)
+	}
+
+	tagging := metadata.Tagging()
+	if tagging != "" {
+		input.Tagging = aws.String(tagging)
+	}
+
+	objectLockMode := metadata.ObjectLockMode()
+	if objectLockMode != "" {
+		t, err := time.Parse(time.RFC3339, metadata.ObjectLockRetainUntil())
+		if err != nil {
+			return nil, err
+		}
+		input.ObjectLockMode = aws.String(objectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(t)
+	}
+
+	if metadata.ObjectLockLegalHold() {
+		input.ObjectLockLegalHoldStatus = aws.String(s3.ObjectLockLegalHoldStatusOn)
+	}
+
+	return input, nil
+}
+
 // Read fetches the remote object and returns its contents as an io.ReadCloser.
 func (s *S3) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
 	resp, err := s.api.GetObjectWithContext(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(src.Bucket),
-		Key:    aws.String(src.Path),
+		Bucket:       aws.String(src.Bucket),
+		Key:          aws.String(src.Path),
+		VersionId:    versionID(src),
+		RequestPayer: s.payer(),
 	})
 	if err != nil {
 		return nil, err
@@ -378,10 +885,15 @@ func (s *S3) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
 // Get is a multipart download operation which downloads S3 objects into any
 // destination that implements io.WriterAt interface.
 // Makes a single 'GetObject' call if 'concurrency' is 1 and ignores 'partSize'.
+// If the object does not meet the preconditions, ErrPreconditionFailed or
+// ErrObjectNotModified is returned. If byteRange is given, only the bytes in
+// the range are downloaded, in a single 'GetObject' call.
 func (s *S3) Get(
 	ctx context.Context,
 	from *url.URL,
 	to io.WriterAt,
+	preconditions Preconditions,
+	byteRange string,
 	concurrency int,
 	partSize int64,
 ) (int64, error) {
@@ -389,13 +901,42 @@ func (s *S3) Get(
 		return 0, nil
 	}
 
-	return s.downloader.DownloadWithContext(ctx, to, &s3.GetObjectInput{
-		Bucket: aws.String(from.Bucket),
-		Key:    aws.String(from.Path),
-	}, func(u *s3manager.Downloader) {
+	input := &s3.GetObjectInput{
+		Bucket:       aws.String(from.Bucket),
+		Key:          aws.String(from.Path),
+		VersionId:    versionID(from),
+		RequestPayer: s.payer(),
+	}
+
+	if preconditions.IfMatch != "" {
+		input.SetIfMatch(quoteETag(preconditions.IfMatch))
+	}
+	if preconditions.IfNoneMatch != "" {
+		input.SetIfNoneMatch(quoteETag(preconditions.IfNoneMatch))
+	}
+	// the range is downloaded in a single request.
+	if byteRange != "" {
+		input.SetRange(byteRange)
+	}
+
+	n, err := s.downloader.DownloadWithContext(ctx, to, input, func(u *s3manager.Downloader) {
 		u.PartSize = partSize
 		u.Concurrency = concurrency
 	})
+
+	switch {
+	case errHasStatusCode(err, http.StatusNotModified):
+		return n, ErrObjectNotModified
+	case errHasStatusCode(err, http.StatusPreconditionFailed):
+		return n, ErrPreconditionFailed
+	}
+	return n, err
+}
+
+// quoteETag returns the given ETag surrounded by quotes, as it is sent in the
+// conditional headers.
+func quoteETag(etag string) string {
+	return `"` + strings.Trim(etag, `"`) + `"`
 }
 
 type SelectQuery struct {
@@ -491,10 +1032,11 @@ func (s *S3) Put(
 	}
 
 	input := &s3manager.UploadInput{
-		Bucket:      aws.String(to.Bucket),
-		Key:         aws.String(to.Path),
-		Body:        reader,
-		ContentType: aws.String(contentType),
+		Bucket:       aws.String(to.Bucket),
+		Key:          aws.String(to.Path),
+		Body:         reader,
+		ContentType:  aws.String(contentType),
+		RequestPayer: s.payer(),
 	}
 
 	storageClass := metadata.StorageClass()
@@ -511,6 +1053,21 @@ func (s *S3) Put(
 		input.CacheControl = aws.String(cacheControl)
 	}
 
+	contentEncoding := metadata.ContentEncoding()
+	if contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+
+	contentDisposition := metadata.ContentDisposition()
+	if contentDisposition != "" {
+		input.ContentDisposition = aws.String(contentDisposition)
+	}
+
+	contentLanguage := metadata.ContentLanguage()
+	if contentLanguage != "" {
+		input.ContentLanguage = aws.String(contentLanguage)
+	}
+
 	expires := metadata.Expires()
 	if expires != "" {
 		t, err := time.Parse(time.RFC3339, expires)
@@ -529,6 +1086,30 @@ func (s *S3) Put(
 		}
 	}
 
+	userMetadata := metadata.UserDefined()
+	if len(userMetadata) > 0 {
+		input.Metadata = aws.StringMap(userMetadata)
+	}
+
+	tagging := metadata.Tagging()
+	if tagging != "" {
+		input.Tagging = aws.String(tagging)
+	}
+
+	objectLockMode := metadata.ObjectLockMode()
+	if objectLockMode != "" {
+		t, err := time.Parse(time.RFC3339, metadata.ObjectLockRetainUntil())
+		if err != nil {
+			return err
+		}
+		input.ObjectLockMode = aws.String(objectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(t)
+	}
+
+	if metadata.ObjectLockLegalHold() {
+		input.ObjectLockLegalHoldStatus = aws.String(s3.ObjectLockLegalHoldStatusOn)
+	}
+
 	_, err := s.uploader.UploadWithContext(ctx, input, func(u *s3manager.Uploader) {
 		u.PartSize = partSize
 		u.Concurrency = concurrency
@@ -537,6 +1118,95 @@ func (s *S3) Put(
 	return err
 }
 
+// PutACL replaces the access control list of the remote object with the given
+// canned ACL.
+func (s *S3) PutACL(ctx context.Context, to *url.URL, acl string) error {
+	if s.dryRun {
+		return nil
+	}
+
+	_, err := s.api.PutObjectAclWithContext(ctx, &s3.PutObjectAclInput{
+		Bucket:       aws.String(to.Bucket),
+		Key:          aws.String(to.Path),
+		ACL:          aws.String(acl),
+		RequestPayer: s.payer(),
+	})
+	return err
+}
+
+// Presign returns a URL which can be used to make a request of given HTTP
+// method to the remote object without credentials until it expires.
+// Presigning does not send any request to the remote storage.
+func (s *S3) Presign(ctx context.Context, url *url.URL, method string, expire time.Duration) (string, error) {
+	var req *request.Request
+	switch method {
+	case http.MethodGet:
+		req, _ = s.api.GetObjectRequest(&s3.GetObjectInput{
+			Bucket:       aws.String(url.Bucket),
+			Key:          aws.String(url.Path),
+			VersionId:    versionID(url),
+			RequestPayer: s.payer(),
+		})
+	case http.MethodPut:
+		req, _ = s.api.PutObjectRequest(&s3.PutObjectInput{
+			Bucket:       aws.String(url.Bucket),
+			Key:          aws.String(url.Path),
+			RequestPayer: s.payer(),
+		})
+	default:
+		return "", fmt.Errorf("unsupported method %q", method)
+	}
+
+	req.SetContext(ctx)
+	return req.Presign(expire)
+}
+
+// GetTagging returns the tags of the remote object.
+func (s *S3) GetTagging(ctx context.Context, url *url.URL) (map[string]string, error) {
+	output, err := s.api.GetObjectTaggingWithContext(ctx, &s3.GetObjectTaggingInput{
+		Bucket:    aws.String(url.Bucket),
+		Key:       aws.String(url.Path),
+		VersionId: versionID(url),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// PutTagging replaces the tags of the remote object with the given tags.
+func (s *S3) PutTagging(ctx context.Context, to *url.URL, tags map[string]string) error {
+	if s.dryRun {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tagSet := make([]*s3.Tag, 0, len(keys))
+	for _, key := range keys {
+		tagSet = append(tagSet, &s3.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+
+	_, err := s.api.PutObjectTaggingWithContext(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(to.Bucket),
+		Key:     aws.String(to.Path),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	})
+	return err
+}
+
 // chunk is an object identifier container which is used on MultiDelete
 // operations. Since DeleteObjects API allows deleting objects up to 1000,
 // splitting keys into multiple chunks is required.
@@ -562,7 +1232,10 @@ func (s *S3) calculateChunks(ch <-chan *url.URL) <-chan chunk {
 		for url := range ch {
 			bucket = url.Bucket
 
-			objid := &s3.ObjectIdentifier{Key: aws.String(url.Path)}
+			objid := &s3.ObjectIdentifier{
+				Key:       aws.String(url.Path),
+				VersionId: versionID(url),
+			}
 			keys = append(keys, objid)
 			if len(keys) == deleteObjectsMax {
 				chunkch <- chunk{
@@ -589,7 +1262,7 @@ func (s *S3) Delete(ctx context.Context, url *url.URL) error {
 	chunk := chunk{
 		Bucket: url.Bucket,
 		Keys: []*s3.ObjectIdentifier{
-			{Key: aws.String(url.Path)},
+			{Key: aws.String(url.Path), VersionId: versionID(url)},
 		},
 	}
 
@@ -608,6 +1281,7 @@ func (s *S3) doDelete(ctx context.Context, chunk chunk, resultch chan *Object) {
 		for _, k := range chunk.Keys {
 			key := fmt.Sprintf("s3://%v/%v", chunk.Bucket, aws.StringValue(k.Key))
 			url, _ := url.New(key)
+			url.VersionID = aws.StringValue(k.VersionId)
 			resultch <- &Object{URL: url}
 		}
 		return
@@ -615,8 +1289,9 @@ func (s *S3) doDelete(ctx context.Context, chunk chunk, resultch chan *Object) {
 
 	bucket := chunk.Bucket
 	o, err := s.api.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
-		Bucket: aws.String(bucket),
-		Delete: &s3.Delete{Objects: chunk.Keys},
+		Bucket:       aws.String(bucket),
+		Delete:       &s3.Delete{Objects: chunk.Keys},
+		RequestPayer: s.payer(),
 	})
 	if err != nil {
 		resultch <- &Object{Err: err}
@@ -626,15 +1301,19 @@ func (s *S3) doDelete(ctx context.Context, chunk chunk, resultch chan *Object) {
 	for _, d := range o.Deleted {
 		key := fmt.Sprintf("s3://%v/%v", bucket, aws.StringValue(d.Key))
 		url, _ := url.New(key)
+		url.VersionID = aws.StringValue(d.VersionId)
 		resultch <- &Object{URL: url}
 	}
 
+	// some of the keys may not be deleted while the others are. The error
+	// of each key keeps its code, e.g. AccessDenied.
 	for _, e := range o.Errors {
 		key := fmt.Sprintf("s3://%v/%v", bucket, aws.StringValue(e.Key))
 		url, _ := url.New(key)
+		url.VersionID = aws.StringValue(e.VersionId)
 		resultch <- &Object{
 			URL: url,
-			Err: fmt.Errorf(aws.StringValue(e.Message)),
+			Err: awserr.New(aws.StringValue(e.Code), aws.StringValue(e.Message), nil),
 		}
 	}
 }
@@ -707,6 +1386,15 @@ func (s *S3) MakeBucket(ctx context.Context, name string) error {
 	return err
 }
 
+// HeadBucket checks whether the S3 bucket with the given name exists and it
+// can be accessed with the credentials of the client.
+func (s *S3) HeadBucket(ctx context.Context, name string) error {
+	_, err := s.api.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(name),
+	})
+	return err
+}
+
 // RemoveBucket removes an S3 bucket with the given name.
 func (s *S3) RemoveBucket(ctx context.Context, name string) error {
 	if s.dryRun {
@@ -724,6 +1412,36 @@ func (s *S3) RemoveBucket(ctx context.Context, name string) error {
 type SessionCache struct {
 	sync.Mutex
 	sessions map[Options]*session.Session
+	clients  map[Options]*S3
+
+	// credentials are the credentials of the assumed roles, shared by the
+	// sessions of the different buckets and regions.
+	credentials map[assumedRole]*credentials.Credentials
+}
+
+// newClient returns the S3 client of the given options, creating it if
+// needed.
+func (sc *SessionCache) newClient(ctx context.Context, opts Options) (*S3, error) {
+	sc.Lock()
+	client, ok := sc.clients[opts]
+	sc.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	// the lock is not held while creating the client since newSession
+	// acquires it. A client created concurrently for the same options is
+	// replaced, which is harmless since both use the same session.
+	client, err := newS3Client(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.Lock()
+	sc.clients[opts] = client
+	sc.Unlock()
+
+	return client, nil
 }
 
 // newSession initializes a new AWS session with region fallback and custom
@@ -751,8 +1469,15 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 	// use virtual-host-style if the endpoint is known to support it,
 	// otherwise use the path-style approach.
 	isVirtualHostStyle := isVirtualHostStyle(endpointURL)
+	if value := os.Getenv("AWS_S3_FORCE_PATH_STYLE"); value != "" {
+		forcePathStyle, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AWS_S3_FORCE_PATH_STYLE value %q: must be true or false", value)
+		}
+		isVirtualHostStyle = !forcePathStyle
+	}
 
-	useAccelerate := supportsTransferAcceleration(endpointURL)
+	useAccelerate := opts.UseAccelerate || supportsTransferAcceleration(endpointURL)
 	// AWS SDK handles transfer acceleration automatically. Setting the
 	// Endpoint to a transfer acceleration endpoint would cause bucket
 	// operations fail.
@@ -760,18 +1485,23 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 		endpointURL = sentinelURL
 	}
 
-	var httpClient *http.Client
-	if opts.NoVerifySSL {
-		httpClient = insecureHTTPClient
-	}
-
 	awsCfg = awsCfg.
 		WithEndpoint(endpointURL.String()).
 		WithS3ForcePathStyle(!isVirtualHostStyle).
 		WithS3UseAccelerate(useAccelerate).
-		WithHTTPClient(httpClient)
-
-	awsCfg.Retryer = newCustomRetryer(opts.MaxRetries)
+		WithHTTPClient(newHTTPClient(opts))
+
+	retryer := newCustomRetryer(opts.MaxRetries, opts.MaxRetryDuration)
+	retryer.budget = opts.RetryBudget
+	awsCfg.Retryer = retryer
+
+	if opts.LogLevel == "trace" {
+		awsCfg = awsCfg.
+			WithLogLevel(aws.LogDebug).
+			WithLogger(aws.LoggerFunc(func(args ...interface{}) {
+				log.Trace(log.TraceMessage{Message: fmt.Sprint(args...)})
+			}))
+	}
 
 	useSharedConfig := session.SharedConfigEnable
 	{
@@ -789,12 +1519,20 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 		session.Options{
 			Config:            *awsCfg,
 			SharedConfigState: useSharedConfig,
+			Profile:           opts.Profile,
 		},
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	sess.Handlers.Build.PushBack(retryer.countRequest)
+	sess.Handlers.Complete.PushBack(retryer.forgetRequest)
+
+	if opts.AssumeRoleARN != "" {
+		sess.Config.Credentials = sc.assumeRoleCredentials(sess, opts)
+	}
+
 	// get region of the bucket and create session accordingly. if the region
 	// is not provided, it means we want region-independent session
 	// for operations such as listing buckets, making a new bucket etc.
@@ -812,10 +1550,62 @@ func (sc *SessionCache) newSession(ctx context.Context, opts Options) (*session.
 	return sess, nil
 }
 
+// assumedRole identifies the credentials of an assumed role.
+type assumedRole struct {
+	profile         string
+	endpoint        string
+	roleARN         string
+	externalID      string
+	roleSessionName string
+}
+
+// assumeRoleCredentials returns the credentials of the role of opts, assumed
+// with the credentials of sess. The credentials are created once for each
+// role, so that the sessions of all buckets and regions share the temporary
+// credentials, which are refreshed before they expire. It must be called
+// with the lock held.
+func (sc *SessionCache) assumeRoleCredentials(sess *session.Session, opts Options) *credentials.Credentials {
+	key := assumedRole{
+		profile:         opts.Profile,
+		endpoint:        opts.Endpoint,
+		roleARN:         opts.AssumeRoleARN,
+		externalID:      opts.ExternalID,
+		roleSessionName: opts.RoleSessionName,
+	}
+	if creds, ok := sc.credentials[key]; ok {
+		return creds
+	}
+
+	// STS requests are signed with the credentials of the profile and sent
+	// to the endpoint of the session, like the S3 compatible services which
+	// implement STS expect. They need a region even if the region of the
+	// bucket is not known yet.
+	stsSess := sess.Copy()
+	if aws.StringValue(stsSess.Config.Region) == "" {
+		stsSess.Config.Region = aws.String(endpoints.UsEast1RegionID)
+	}
+
+	creds := stscreds.NewCredentials(stsSess, opts.AssumeRoleARN, func(p *stscreds.AssumeRoleProvider) {
+		// refresh the credentials before they expire, so that the requests
+		// in flight are not signed with expired credentials.
+		p.ExpiryWindow = time.Minute
+		if opts.ExternalID != "" {
+			p.ExternalID = aws.String(opts.ExternalID)
+		}
+		if opts.RoleSessionName != "" {
+			p.RoleSessionName = opts.RoleSessionName
+		}
+	})
+	sc.credentials[key] = creds
+	return creds
+}
+
 func (sc *SessionCache) clear() {
 	sc.Lock()
 	defer sc.Unlock()
 	sc.sessions = map[Options]*session.Session{}
+	sc.clients = map[Options]*S3{}
+	sc.credentials = map[assumedRole]*credentials.Credentials{}
 }
 
 func setSessionRegion(ctx context.Context, sess *session.Session, bucket string) error {
@@ -849,20 +1639,84 @@ func setSessionRegion(ctx context.Context, sess *session.Session, bucket string)
 // error codes. Such as, retry for S3 InternalError code.
 type customRetryer struct {
 	client.DefaultRetryer
+
+	// maxRetryDuration is the maximum duration since the first attempt of a
+	// request, after which it is not retried anymore. There is no limit if
+	// it is zero.
+	maxRetryDuration time.Duration
+
+	// budget is the maximum ratio of the retries to the requests of all
+	// sessions. There is no limit if it is zero.
+	budget float64
+	// counter is shared by the retryers of all sessions.
+	counter *retryCounter
+
+	// delays is the sum of the retry delays of each request in flight.
+	delays sync.Map
 }
 
-func newCustomRetryer(maxRetries int) *customRetryer {
+func newCustomRetryer(maxRetries int, maxRetryDuration time.Duration) *customRetryer {
 	return &customRetryer{
 		DefaultRetryer: client.DefaultRetryer{
 			NumMaxRetries: maxRetries,
 		},
+		maxRetryDuration: maxRetryDuration,
+		counter:          &globalRetryCounter,
 	}
 }
 
+// minRetryBudget is the number of retries allowed regardless of the retry
+// budget, so that the first failures of a run can be retried before enough
+// requests are sent.
+const minRetryBudget = 10
+
+// retryCounter counts the requests and the retries to enforce the retry
+// budget.
+type retryCounter struct {
+	requests int64
+	retries  int64
+
+	// exhausted is set once the budget is exhausted to warn only once.
+	exhausted atomic.Bool
+}
+
+var globalRetryCounter retryCounter
+
+// countRequest is a request handler counting the requests sent. It is called
+// once for each request, regardless of its retries.
+func (c *customRetryer) countRequest(*request.Request) {
+	syncatomic.AddInt64(&c.counter.requests, 1)
+}
+
+// withinBudget reports whether a request can be retried without exceeding the
+// retry budget.
+func (c *customRetryer) withinBudget() bool {
+	if c.budget <= 0 {
+		return true
+	}
+
+	retries := syncatomic.LoadInt64(&c.counter.retries)
+	requests := syncatomic.LoadInt64(&c.counter.requests)
+	if retries < minRetryBudget || float64(retries) < c.budget*float64(requests) {
+		return true
+	}
+
+	if !c.counter.exhausted.Get() {
+		c.counter.exhausted.Set(true)
+		err := fmt.Errorf("retry budget is exhausted after %d retries of %d requests, failed requests are not retried", retries, requests)
+		log.Error(log.ErrorMessage{Err: err.Error()})
+	}
+	return false
+}
+
 // ShouldRetry overrides SDK's built in DefaultRetryer, adding custom retry
 // logics that are not included in the SDK.
 func (c *customRetryer) ShouldRetry(req *request.Request) bool {
-	shouldRetry := errHasCode(req.Error, "InternalError") || errHasCode(req.Error, "RequestTimeTooSkewed") || strings.Contains(req.Error.Error(), "connection reset")
+	shouldRetry := errHasCode(req.Error, "InternalError") ||
+		errHasCode(req.Error, "RequestTimeTooSkewed") ||
+		errHasCode(req.Error, "SlowDown") ||
+		isServerError(req.Error) ||
+		strings.Contains(req.Error.Error(), "connection reset")
 	if !shouldRetry {
 		shouldRetry = c.DefaultRetryer.ShouldRetry(req)
 	}
@@ -872,19 +1726,155 @@ func (c *customRetryer) ShouldRetry(req *request.Request) bool {
 		return false
 	}
 
-	if shouldRetry && req.Error != nil {
-		err := fmt.Errorf("retryable error: %v", req.Error)
+	if shouldRetry && c.maxRetryDuration > 0 && time.Since(req.Time) > c.maxRetryDuration {
+		err := fmt.Errorf("not retrying after %v: %v", c.maxRetryDuration, req.Error)
 		msg := log.DebugMessage{Err: err.Error()}
 		log.Debug(msg)
+		return false
+	}
+
+	if shouldRetry && !c.withinBudget() {
+		return false
 	}
 
 	return shouldRetry
 }
 
-var insecureHTTPClient = &http.Client{
-	Transport: &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	},
+// RetryRules overrides SDK's built in DefaultRetryer to count the retried
+// requests and to wait as long as the server asks for, up to the maximum
+// throttle delay. It is only called if the request is going to be retried.
+func (c *customRetryer) RetryRules(req *request.Request) time.Duration {
+	stat.AddRetried()
+	syncatomic.AddInt64(&c.counter.retries, 1)
+
+	delay, ok := retryAfter(req)
+	if ok {
+		delay = c.capRetryAfter(req, delay)
+	} else {
+		delay = c.DefaultRetryer.RetryRules(req)
+	}
+	c.logRetry(req, delay)
+	return delay
+}
+
+// capRetryAfter limits the delay asked by the server to the maximum throttle
+// delay, and to the time left of maxRetryDuration, so that a worker is not
+// parked for hours by a Retry-After header.
+func (c *customRetryer) capRetryAfter(req *request.Request, delay time.Duration) time.Duration {
+	maxDelay := c.MaxThrottleDelay
+	if maxDelay == 0 {
+		maxDelay = client.DefaultRetryerMaxThrottleDelay
+	}
+
+	if c.maxRetryDuration > 0 {
+		left := c.maxRetryDuration - time.Since(req.Time)
+		if left < 0 {
+			left = 0
+		}
+		if left < maxDelay {
+			maxDelay = left
+		}
+	}
+
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// logRetry logs the retry of the request after the given delay, along with
+// the sum of its delays so far.
+func (c *customRetryer) logRetry(req *request.Request, delay time.Duration) {
+	total := delay
+	if v, ok := c.delays.Load(req); ok {
+		total += v.(time.Duration)
+	}
+	c.delays.Store(req, total)
+
+	msg := log.RetryMessage{
+		Attempt:     req.RetryCount + 1,
+		MaxAttempts: req.MaxRetries(),
+		Delay:       delay,
+		TotalDelay:  total,
+		Code:        ErrorCode(req.Error),
+	}
+	if req.Operation != nil {
+		msg.Operation = req.Operation.Name
+	}
+	if req.Error != nil {
+		// the errors of the SDK span multiple lines.
+		msg.Err = strings.Join(strings.Fields(req.Error.Error()), " ")
+	}
+	log.Debug(msg)
+}
+
+// forgetRequest is a request handler removing the retry delays of the request
+// once it is complete.
+func (c *customRetryer) forgetRequest(req *request.Request) {
+	c.delays.Delete(req)
+}
+
+// retryAfter returns the delay given in the Retry-After header of a 503 Slow
+// Down or a 429 Too Many Requests response. The header is either the number
+// of seconds or the date to retry after.
+func retryAfter(req *request.Request) (time.Duration, bool) {
+	resp := req.HTTPResponse
+	if resp == nil {
+		return 0, false
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	delay := time.Until(date)
+	if delay < 0 {
+		return 0, true
+	}
+	return delay, true
+}
+
+// newHTTPClient creates the HTTP client of a session. The default transport
+// keeps only 2 idle connections per host, so concurrent workers would keep
+// opening new connections to the same endpoint instead of reusing them.
+func newHTTPClient(opts Options) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if opts.DialTimeout > 0 {
+		dialer.Timeout = opts.DialTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = opts.MaxIdleConns
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	}
+	if opts.NoVerifySSL {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &http.Client{Transport: transport}
 }
 
 func supportsTransferAcceleration(endpoint urlpkg.URL) bool {
@@ -902,6 +1892,43 @@ func isVirtualHostStyle(endpoint urlpkg.URL) bool {
 	return endpoint == sentinelURL || supportsTransferAcceleration(endpoint) || isGoogleEndpoint(endpoint)
 }
 
+// versionID returns the version id of the given url to be set in requests. It
+// is nil for the latest version.
+func versionID(u *url.URL) *string {
+	if u.VersionID == "" {
+		return nil
+	}
+	return aws.String(u.VersionID)
+}
+
+// payer returns the request payer of the requests, or nil if the bucket owner
+// pays for them.
+func (s *S3) payer() *string {
+	if s.requestPayer == "" {
+		return nil
+	}
+	return aws.String(s.requestPayer)
+}
+
+// ErrorCode returns the error code of the given error returned by the remote
+// storage, e.g. "NoSuchKey". It returns an empty string if the error does not
+// have a code.
+func ErrorCode(err error) string {
+	// the code of a failed multipart upload is the code of the failed part.
+	var multiUploadErr s3manager.MultiUploadFailure
+	if errors.As(err, &multiUploadErr) {
+		if code := ErrorCode(multiUploadErr.OrigErr()); code != "" {
+			return code
+		}
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code()
+	}
+	return ""
+}
+
 func errHasCode(err error, code string) bool {
 	if err == nil || code == "" {
 		return false
@@ -923,8 +1950,50 @@ func errHasCode(err error, code string) bool {
 
 }
 
+// isServerError reports whether the given error is a request failure with a
+// 5xx status code. 501 Not Implemented is excluded since retrying it would
+// fail the same way.
+func isServerError(err error) bool {
+	var reqErr awserr.RequestFailure
+	if !errors.As(err, &reqErr) {
+		return false
+	}
+
+	code := reqErr.StatusCode()
+	return code >= http.StatusInternalServerError && code != http.StatusNotImplemented
+}
+
+// errHasStatusCode reports whether the given error is a response with the
+// given HTTP status code.
+func errHasStatusCode(err error, code int) bool {
+	var reqErr awserr.RequestFailure
+	if !errors.As(err, &reqErr) {
+		return false
+	}
+	return reqErr.StatusCode() == code
+}
+
 // IsCancelationError reports whether given error is a storage related
-// cancelation error.
+// cancelation error. Requests which exceed their deadline are not considered
+// as canceled.
 func IsCancelationError(err error) bool {
-	return errHasCode(err, request.CanceledErrorCode)
+	if !errHasCode(err, request.CanceledErrorCode) {
+		return false
+	}
+
+	return !isDeadlineExceeded(err)
+}
+
+// isDeadlineExceeded reports whether the given error is caused by an exceeded
+// context deadline.
+func isDeadlineExceeded(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.OrigErr() != nil {
+		return isDeadlineExceeded(awsErr.OrigErr())
+	}
+	return false
 }
@@ -7,11 +7,16 @@ import (
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	urlpkg "net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,12 +24,14 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/awsutil"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/awstesting/unit"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/v3/assert"
 
 	"github.com/peak/s5cmd/log"
@@ -108,6 +115,191 @@ func TestNewSessionWithRegionSetViaEnv(t *testing.T) {
 	}
 }
 
+func TestNewSessionRegionFlagOverridesEnv(t *testing.T) {
+	globalSessionCache.clear()
+
+	const expectedRegion = "eu-west-1"
+
+	os.Setenv("AWS_REGION", "us-west-2")
+	defer os.Unsetenv("AWS_REGION")
+
+	opts := Options{}
+	opts.SetRegion(expectedRegion)
+
+	sess, err := globalSessionCache.newSession(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := aws.StringValue(sess.Config.Region)
+	if got != expectedRegion {
+		t.Fatalf("expected %v, got %v", expectedRegion, got)
+	}
+}
+
+func TestNewSessionPathStyleSetViaEnv(t *testing.T) {
+	testcases := []struct {
+		name            string
+		endpoint        string
+		env             string
+		expectPathStyle bool
+		expectErr       bool
+	}{
+		{
+			name:            "force_path_style_for_aws_endpoint",
+			endpoint:        "",
+			env:             "true",
+			expectPathStyle: true,
+		},
+		{
+			name:            "force_virtual_host_style_for_custom_endpoint",
+			endpoint:        "example.com",
+			env:             "false",
+			expectPathStyle: false,
+		},
+		{
+			name:      "invalid_value",
+			endpoint:  "example.com",
+			env:       "sometimes",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// sessions are cached by their options, not by the environment.
+			globalSessionCache.clear()
+
+			os.Setenv("AWS_S3_FORCE_PATH_STYLE", tc.env)
+			defer os.Unsetenv("AWS_S3_FORCE_PATH_STYLE")
+
+			sess, err := globalSessionCache.newSession(context.Background(), Options{Endpoint: tc.endpoint})
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := aws.BoolValue(sess.Config.S3ForcePathStyle)
+			if got != tc.expectPathStyle {
+				t.Fatalf("expected: %v, got: %v", tc.expectPathStyle, got)
+			}
+		})
+	}
+}
+
+func TestNewRemoteClientIsShared(t *testing.T) {
+	globalSessionCache.clear()
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := Options{NoSignRequest: true}
+	opts.SetRegion("us-east-1")
+
+	first, err := NewRemoteClient(context.Background(), u, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewRemoteClient(context.Background(), u, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Errorf("expected the client to be shared for the same options")
+	}
+
+	opts.DryRun = true
+	other, err := NewRemoteClient(context.Background(), u, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other == first {
+		t.Errorf("expected a different client for different options")
+	}
+	if !other.dryRun {
+		t.Errorf("expected the client to be created with the given options")
+	}
+}
+
+func TestNewSessionWithProfile(t *testing.T) {
+	globalSessionCache.clear()
+
+	const content = `[default]
+aws_access_key_id = default-access-key
+aws_secret_access_key = default-secret-key
+
+[myprofile]
+aws_access_key_id = myprofile-access-key
+aws_secret_access_key = myprofile-secret-key
+`
+
+	file, err := ioutil.TempFile("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", file.Name())
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+
+	opts := Options{Profile: "myprofile"}
+	opts.SetRegion("us-east-1")
+
+	sess, err := globalSessionCache.newSession(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := sess.Config.Credentials.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = "myprofile-access-key"
+	if value.AccessKeyID != expected {
+		t.Fatalf("expected %v, got %v", expected, value.AccessKeyID)
+	}
+}
+
+func TestNewSessionWithAccelerate(t *testing.T) {
+	globalSessionCache.clear()
+
+	opts := Options{UseAccelerate: true}
+	opts.SetRegion("us-east-1")
+
+	sess, err := globalSessionCache.newSession(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, aws.BoolValue(sess.Config.S3UseAccelerate), true)
+	// the SDK resolves the accelerate endpoint of the bucket itself.
+	assert.Equal(t, aws.StringValue(sess.Config.Endpoint), sentinelURL.String())
+	assert.Equal(t, aws.BoolValue(sess.Config.S3ForcePathStyle), false)
+
+	req, _ := s3.New(sess).HeadBucketRequest(&s3.HeadBucketInput{Bucket: aws.String("bucket")})
+	if err := req.Build(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, req.HTTPRequest.URL.Host, "bucket.s3-accelerate.amazonaws.com")
+}
+
 func TestNewSessionWithNoSignRequest(t *testing.T) {
 	globalSessionCache.clear()
 
@@ -126,6 +318,103 @@ func TestNewSessionWithNoSignRequest(t *testing.T) {
 	}
 }
 
+func TestNewSessionWithAssumeRole(t *testing.T) {
+	globalSessionCache.clear()
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "base-access-key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "base-secret-key")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	const roleARN = "arn:aws:iam::123456789012:role/s5cmd"
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		for key, expected := range map[string]string{
+			"Action":          "AssumeRole",
+			"RoleArn":         roleARN,
+			"ExternalId":      "external-id",
+			"RoleSessionName": "session-name",
+		} {
+			if got := r.PostForm.Get(key); got != expected {
+				t.Errorf("%v: expected %q, got %q", key, expected, got)
+			}
+		}
+
+		expiration := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>role-access-key</AccessKeyId>
+<SecretAccessKey>role-secret-key</SecretAccessKey>
+<SessionToken>role-session-token</SessionToken>
+<Expiration>%v</Expiration>
+</Credentials></AssumeRoleResult></AssumeRoleResponse>`, expiration)
+	}))
+	defer server.Close()
+
+	newOpts := func(region string) Options {
+		opts := Options{
+			Endpoint:        server.URL,
+			AssumeRoleARN:   roleARN,
+			ExternalID:      "external-id",
+			RoleSessionName: "session-name",
+		}
+		opts.SetRegion(region)
+		return opts
+	}
+
+	newSession := func(region string) *session.Session {
+		opts := newOpts(region)
+
+		sess, err := globalSessionCache.newSession(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+
+	sess := newSession("us-east-1")
+
+	value, err := sess.Config.Credentials.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, value.AccessKeyID, "role-access-key")
+	assert.Equal(t, value.SessionToken, "role-session-token")
+	assert.Equal(t, value.ProviderName, stscreds.ProviderName)
+
+	// the sessions of other regions share the assumed role credentials.
+	other := newSession("eu-west-1")
+	if other == sess {
+		t.Fatalf("expected a new session for another region")
+	}
+	if other.Config.Credentials != sess.Config.Credentials {
+		t.Fatalf("expected the credentials to be shared")
+	}
+
+	if _, err := other.Config.Credentials.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the clients of the buckets assume the role as well.
+	bucket, err := url.New("s3://bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewRemoteClient(context.Background(), bucket, newOpts("us-east-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.api.(*s3.S3).Config.Credentials != sess.Config.Credentials {
+		t.Fatalf("expected the credentials of the client to be shared")
+	}
+	assert.Equal(t, atomic.LoadInt32(&requests), int32(1))
+}
+
 func TestS3ListURL(t *testing.T) {
 	url, err := url.New("s3://bucket/key")
 	if err != nil {
@@ -202,6 +491,65 @@ func TestS3ListURL(t *testing.T) {
 	}
 }
 
+func TestS3ListMaxKeysAndStartAfter(t *testing.T) {
+	url, err := url.New("s3://bucket/*")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	url.MaxKeys = 3
+	url.StartAfter = "a"
+
+	mockApi := s3.New(unit.Session)
+	mockS3 := &S3{
+		api: mockApi,
+	}
+
+	var requests int
+	mockApi.Handlers.Send.Clear() // mock sending
+	mockApi.Handlers.Unmarshal.Clear()
+	mockApi.Handlers.UnmarshalMeta.Clear()
+	mockApi.Handlers.ValidateResponse.Clear()
+	mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		requests++
+
+		input := r.Params.(*s3.ListObjectsV2Input)
+		if diff := cmp.Diff("a", aws.StringValue(input.StartAfter)); diff != "" {
+			t.Errorf("(-want +got):\n%v", diff)
+		}
+		if diff := cmp.Diff(int64(3), aws.Int64Value(input.MaxKeys)); diff != "" {
+			t.Errorf("(-want +got):\n%v", diff)
+		}
+
+		// the page is larger than the max keys and there are more pages.
+		r.Data = &s3.ListObjectsV2Output{
+			Contents: []*s3.Object{
+				{Key: aws.String("b")},
+				{Key: aws.String("c")},
+				{Key: aws.String("d")},
+				{Key: aws.String("e")},
+			},
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("token"),
+		}
+	})
+
+	var got []string
+	for object := range mockS3.List(context.Background(), url, true) {
+		if object.Err != nil {
+			t.Errorf("unexpected error: %v", object.Err)
+			continue
+		}
+		got = append(got, object.URL.Path)
+	}
+
+	if diff := cmp.Diff([]string{"b", "c", "d"}, got); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+	if diff := cmp.Diff(1, requests); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+}
+
 func TestS3ListError(t *testing.T) {
 	url, err := url.New("s3://bucket/key")
 	if err != nil {
@@ -303,6 +651,50 @@ func TestS3ListContextCancelled(t *testing.T) {
 	}
 }
 
+func TestIsCancelationError(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "context canceled",
+			err:      awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled),
+			expected: true,
+		},
+		{
+			name:     "context deadline exceeded",
+			err:      awserr.New(request.CanceledErrorCode, "request context canceled", context.DeadlineExceeded),
+			expected: false,
+		},
+		{
+			name: "wrapped deadline exceeded",
+			err: fmt.Errorf("upload failed: %w",
+				awserr.New(request.CanceledErrorCode, "request context canceled", context.DeadlineExceeded),
+			),
+			expected: false,
+		},
+		{
+			name:     "other error",
+			err:      awserr.New("InternalError", "internal error", nil),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := IsCancelationError(tc.err); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
 func TestS3Retry(t *testing.T) {
 	log.Init("debug", false)
 
@@ -390,6 +782,51 @@ func TestS3Retry(t *testing.T) {
 			err:           awserr.New("RequestThrottledException", "request throttled exception", nil),
 			expectedRetry: 5,
 		},
+		{
+			name:          "SlowDown",
+			err:           awserr.New("SlowDown", "slow down", nil),
+			expectedRetry: 5,
+		},
+
+		// Server errors
+		{
+			name: "ServiceUnavailable",
+			err: awserr.NewRequestFailure(
+				awserr.New("ServiceUnavailable", "service unavailable", nil),
+				503,
+				"0",
+			),
+			expectedRetry: 5,
+		},
+		{
+			name: "InternalServerError",
+			err: awserr.NewRequestFailure(
+				awserr.New("InternalError", "we encountered an internal error", nil),
+				500,
+				"0",
+			),
+			expectedRetry: 5,
+		},
+
+		// Client errors
+		{
+			name: "NoSuchKey",
+			err: awserr.NewRequestFailure(
+				awserr.New("NoSuchKey", "the specified key does not exist", nil),
+				404,
+				"0",
+			),
+			expectedRetry: 0,
+		},
+		{
+			name: "AccessDenied",
+			err: awserr.NewRequestFailure(
+				awserr.New("AccessDenied", "access denied", nil),
+				403,
+				"0",
+			),
+			expectedRetry: 0,
+		},
 
 		// Expired credential errors
 		{
@@ -440,7 +877,7 @@ func TestS3Retry(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			sess := unit.Session
-			sess.Config.Retryer = newCustomRetryer(expectedRetry)
+			sess.Config.Retryer = newCustomRetryer(expectedRetry, 0)
 
 			mockApi := s3.New(sess)
 			mockS3 := &S3{
@@ -476,52 +913,70 @@ func TestS3Retry(t *testing.T) {
 	}
 }
 
-func TestS3CopyEncryptionRequest(t *testing.T) {
+func TestS3CopyMetadataDirective(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
 	testcases := []struct {
 		name     string
-		sse      string
-		sseKeyID string
-		acl      string
+		metadata Metadata
 
-		expectedSSE      string
-		expectedSSEKeyID string
-		expectedAcl      string
+		expectedMetadataDirective string
+		expectedTaggingDirective  string
 	}{
 		{
-			name: "no encryption/no acl, by default",
+			name:     "no metadata",
+			metadata: NewMetadata(),
 		},
 		{
-			name: "aws:kms encryption with server side generated keys",
-			sse:  "aws:kms",
-
-			expectedSSE: "aws:kms",
+			name:     "storage class, encryption and acl keep the metadata",
+			metadata: NewMetadata().SetStorageClass("STANDARD_IA").SetSSE("aws:kms").SetACL("private"),
 		},
 		{
-			name:     "aws:kms encryption with user provided key",
-			sse:      "aws:kms",
-			sseKeyID: "sdkjn12SDdci#@#EFRFERTqW/ke",
-
-			expectedSSE:      "aws:kms",
-			expectedSSEKeyID: "sdkjn12SDdci#@#EFRFERTqW/ke",
+			name:                      "content type",
+			metadata:                  NewMetadata().SetContentType("text/html"),
+			expectedMetadataDirective: s3.MetadataDirectiveReplace,
 		},
 		{
-			name:     "provide key without encryption flag, shall be ignored",
-			sseKeyID: "1234567890",
+			name:                      "cache control",
+			metadata:                  NewMetadata().SetCacheControl("no-cache"),
+			expectedMetadataDirective: s3.MetadataDirectiveReplace,
 		},
 		{
-			name:        "acl flag with a value",
-			acl:         "bucket-owner-full-control",
-			expectedAcl: "bucket-owner-full-control",
+			name:                      "expires",
+			metadata:                  NewMetadata().SetExpires("2024-10-01T20:30:00Z"),
+			expectedMetadataDirective: s3.MetadataDirectiveReplace,
+		},
+		{
+			name:                      "content disposition",
+			metadata:                  NewMetadata().SetContentDisposition("attachment"),
+			expectedMetadataDirective: s3.MetadataDirectiveReplace,
+		},
+		{
+			name:                      "content language",
+			metadata:                  NewMetadata().SetContentLanguage("en-US"),
+			expectedMetadataDirective: s3.MetadataDirectiveReplace,
+		},
+		{
+			name:                      "user defined metadata",
+			metadata:                  NewMetadata().SetUserDefined("owner", "john"),
+			expectedMetadataDirective: s3.MetadataDirectiveReplace,
+		},
+		{
+			name:                     "tags",
+			metadata:                 NewMetadata().SetTagging(map[string]string{"env": "prod"}),
+			expectedTaggingDirective: s3.TaggingDirectiveReplace,
 		},
 	}
 
-	u, err := url.New("s3://bucket/key")
-	if err != nil {
-		t.Errorf("unexpected error: %v", err)
-	}
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
 
 			mockApi := s3.New(unit.Session)
 
@@ -530,30 +985,13 @@ func TestS3CopyEncryptionRequest(t *testing.T) {
 			mockApi.Handlers.UnmarshalError.Clear()
 			mockApi.Handlers.Send.Clear()
 
+			var input *s3.CopyObjectInput
 			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
-
 				r.HTTPResponse = &http.Response{
 					StatusCode: http.StatusOK,
 					Body:       ioutil.NopCloser(strings.NewReader("")),
 				}
-
-				params := r.Params
-				sse := valueAtPath(params, "ServerSideEncryption")
-				key := valueAtPath(params, "SSEKMSKeyId")
-
-				if !(sse == nil && tc.expectedSSE == "") {
-					assert.Equal(t, sse, tc.expectedSSE)
-				}
-				if !(key == nil && tc.expectedSSEKeyID == "") {
-					assert.Equal(t, key, tc.expectedSSEKeyID)
-				}
-
-				aclVal := valueAtPath(r.Params, "ACL")
-
-				if aclVal == nil && tc.expectedAcl == "" {
-					return
-				}
-				assert.Equal(t, aclVal, tc.expectedAcl)
+				input = r.Params.(*s3.CopyObjectInput)
 			})
 			mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
 				if r.Error != nil {
@@ -565,65 +1003,80 @@ func TestS3CopyEncryptionRequest(t *testing.T) {
 				}
 			})
 
-			mockS3 := &S3{
-				api: mockApi,
-			}
+			mockS3 := &S3{api: mockApi}
 
-			metadata := NewMetadata().SetSSE(tc.sse).SetSSEKeyID(tc.sseKeyID).SetACL(tc.acl)
+			if err := mockS3.Copy(context.Background(), u, u, tc.metadata); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 
-			err = mockS3.Copy(context.Background(), u, u, metadata)
+			assert.Equal(t, aws.StringValue(input.MetadataDirective), tc.expectedMetadataDirective)
+			assert.Equal(t, aws.StringValue(input.TaggingDirective), tc.expectedTaggingDirective)
 
-			if err != nil {
-				t.Errorf("Expected %v, but received %q", nil, err)
+			if tc.expectedMetadataDirective != "" {
+				assert.Equal(t, aws.StringValue(input.ContentType), tc.metadata.ContentType())
+				assert.Equal(t, aws.StringValue(input.ContentDisposition), tc.metadata.ContentDisposition())
+				assert.Equal(t, aws.StringValue(input.ContentLanguage), tc.metadata.ContentLanguage())
+				if expires := tc.metadata.Expires(); expires != "" {
+					assert.Equal(t, aws.TimeValue(input.Expires).Format(time.RFC3339), expires)
+				}
+				assert.DeepEqual(t, aws.StringValueMap(input.Metadata), tc.metadata.UserDefined(), cmpopts.EquateEmpty())
 			}
 		})
 	}
 }
 
-func TestS3PutEncryptionRequest(t *testing.T) {
-	testcases := []struct {
-		name     string
-		sse      string
-		sseKeyID string
-		acl      string
+func TestS3VersionRequest(t *testing.T) {
+	t.Parallel()
 
-		expectedSSE      string
-		expectedSSEKeyID string
-		expectedAcl      string
+	u, err := url.New("s3://bucket/key?versionId=abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name  string
+		run   func(s *S3) error
+		check func(t *testing.T, params interface{})
 	}{
 		{
-			name: "no encryption, no acl flag",
-		},
-		{
-			name:        "aws:kms encryption with server side generated keys",
-			sse:         "aws:kms",
-			expectedSSE: "aws:kms",
-		},
-		{
-			name:     "aws:kms encryption with user provided key",
-			sse:      "aws:kms",
-			sseKeyID: "sdkjn12SDdci#@#EFRFERTqW/ke",
-
-			expectedSSE:      "aws:kms",
-			expectedSSEKeyID: "sdkjn12SDdci#@#EFRFERTqW/ke",
+			name: "stat",
+			run: func(s *S3) error {
+				_, err := s.Stat(context.Background(), u)
+				return err
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.HeadObjectInput)
+				assert.Equal(t, aws.StringValue(input.VersionId), "abc")
+			},
 		},
 		{
-			name:     "provide key without encryption flag, shall be ignored",
-			sseKeyID: "1234567890",
+			name: "read",
+			run: func(s *S3) error {
+				_, err := s.Read(context.Background(), u)
+				return err
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.GetObjectInput)
+				assert.Equal(t, aws.StringValue(input.VersionId), "abc")
+			},
 		},
 		{
-			name:        "acl flag with a value",
-			acl:         "bucket-owner-full-control",
-			expectedAcl: "bucket-owner-full-control",
+			name: "copy",
+			run: func(s *S3) error {
+				dst, _ := url.New("s3://bucket/dst")
+				return s.Copy(context.Background(), u, dst, NewMetadata())
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.CopyObjectInput)
+				assert.Equal(t, aws.StringValue(input.CopySource), "bucket/key?versionId=abc")
+			},
 		},
 	}
-	u, err := url.New("s3://bucket/key")
-	if err != nil {
-		t.Errorf("unexpected error: %v", err)
-	}
+
 	for _, tc := range testcases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
 
 			mockApi := s3.New(unit.Session)
 
@@ -633,146 +1086,1239 @@ func TestS3PutEncryptionRequest(t *testing.T) {
 			mockApi.Handlers.Send.Clear()
 
 			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
-
 				r.HTTPResponse = &http.Response{
 					StatusCode: http.StatusOK,
 					Body:       ioutil.NopCloser(strings.NewReader("")),
 				}
 
-				params := r.Params
-				sse := valueAtPath(params, "ServerSideEncryption")
-				key := valueAtPath(params, "SSEKMSKeyId")
-
-				if !(sse == nil && tc.expectedSSE == "") {
-					assert.Equal(t, sse, tc.expectedSSE)
-				}
-				if !(key == nil && tc.expectedSSEKeyID == "") {
-					assert.Equal(t, key, tc.expectedSSEKeyID)
-				}
-
-				aclVal := valueAtPath(r.Params, "ACL")
-
-				if aclVal == nil && tc.expectedAcl == "" {
-					return
+				tc.check(t, r.Params)
+			})
+			mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				if r.Error != nil {
+					if awsErr, ok := r.Error.(awserr.Error); ok {
+						if awsErr.Code() == request.ErrCodeSerialization {
+							r.Error = nil
+						}
+					}
 				}
-				assert.Equal(t, aclVal, tc.expectedAcl)
 			})
 
-			mockS3 := &S3{
-				uploader: s3manager.NewUploaderWithClient(mockApi),
+			mockS3 := &S3{api: mockApi}
+
+			if err := tc.run(mockS3); err != nil {
+				t.Errorf("unexpected error: %v", err)
 			}
+		})
+	}
 
-			metadata := NewMetadata().SetSSE(tc.sse).SetSSEKeyID(tc.sseKeyID).SetACL(tc.acl)
+	t.Run("delete", func(t *testing.T) {
+		t.Parallel()
 
-			err = mockS3.Put(context.Background(), bytes.NewReader([]byte("")), u, metadata, 1, 5242880)
+		urlch := make(chan *url.URL, 1)
+		urlch <- u
+		close(urlch)
 
-			if err != nil {
-				t.Errorf("Expected %v, but received %q", nil, err)
+		chunk := <-(&S3{}).calculateChunks(urlch)
+		assert.Equal(t, len(chunk.Keys), 1)
+		assert.Equal(t, aws.StringValue(chunk.Keys[0].VersionId), "abc")
+	})
+}
+
+func TestErrorCode(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: "",
+		},
+		{
+			name:     "error without code",
+			err:      fmt.Errorf("some error"),
+			expected: "",
+		},
+		{
+			name:     "aws error",
+			err:      awserr.New("AccessDenied", "Access Denied", nil),
+			expected: "AccessDenied",
+		},
+		{
+			name:     "wrapped aws error",
+			err:      fmt.Errorf("wrapped: %w", awserr.New("NoSuchBucket", "", nil)),
+			expected: "NoSuchBucket",
+		},
+		{
+			name: "multipart upload error",
+			err: testMultiUploadFailure{
+				awserr.New("MultipartUpload", "upload multipart failed", awserr.New("AccessDenied", "", nil)),
+			},
+			expected: "AccessDenied",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ErrorCode(tc.err); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
 			}
 		})
 	}
 }
 
-func TestS3listObjectsV2(t *testing.T) {
-	const (
-		numObjectsToReturn = 10100
-		numObjectsToIgnore = 1127
+type testMultiUploadFailure struct {
+	err awserr.Error
+}
 
-		pre = "s3://bucket/key"
+func (f testMultiUploadFailure) Error() string   { return f.err.Error() }
+func (f testMultiUploadFailure) Code() string    { return f.err.Code() }
+func (f testMultiUploadFailure) Message() string { return f.err.Message() }
+func (f testMultiUploadFailure) OrigErr() error  { return f.err.OrigErr() }
+func (testMultiUploadFailure) UploadID() string  { return "" }
+
+var _ s3manager.MultiUploadFailure = testMultiUploadFailure{}
+
+func TestS3RetryMaxDuration(t *testing.T) {
+	log.Init("debug", false)
+
+	const (
+		maxRetries       = 100
+		maxRetryDuration = 100 * time.Millisecond
 	)
 
-	u, err := url.New(pre)
+	url, err := url.New("s3://bucket/key")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	mapReturnObjNameToModtime := map[string]time.Time{}
-	mapIgnoreObjNameToModtime := map[string]time.Time{}
-
-	s3objs := make([]*s3.Object, 0, numObjectsToIgnore+numObjectsToReturn)
-
-	for i := 0; i < numObjectsToReturn; i++ {
-		fname := fmt.Sprintf("%s/%d", pre, i)
-		now := time.Now()
-
-		mapReturnObjNameToModtime[pre+"/"+fname] = now
-		s3objs = append(s3objs, &s3.Object{
-			Key:          aws.String("key/" + fname),
-			LastModified: aws.Time(now),
-		})
-	}
+	retryer := newCustomRetryer(maxRetries, maxRetryDuration)
+	retryer.MinRetryDelay = 10 * time.Millisecond
+	retryer.MaxRetryDelay = 10 * time.Millisecond
 
-	for i := 0; i < numObjectsToIgnore; i++ {
-		fname := fmt.Sprintf("%s/%d", pre, numObjectsToReturn+i)
-		later := time.Now().Add(time.Second * 10)
+	// the unit session does not sleep between retries.
+	sess := unit.Session.Copy(&aws.Config{Retryer: retryer, SleepDelay: time.Sleep})
 
-		mapIgnoreObjNameToModtime[pre+"/"+fname] = later
-		s3objs = append(s3objs, &s3.Object{
-			Key:          aws.String("key/" + fname),
-			LastModified: aws.Time(later),
-		})
+	mockApi := s3.New(sess)
+	mockS3 := &S3{
+		api: mockApi,
 	}
 
-	// shuffle the objects array to remove possible assumptions about how objects
-	// are stored.
-	rand.Shuffle(len(s3objs), func(i, j int) {
-		s3objs[i], s3objs[j] = s3objs[j], s3objs[i]
-	})
-
-	mockApi := s3.New(unit.Session)
-
+	mockApi.Handlers.Send.Clear() // mock sending
 	mockApi.Handlers.Unmarshal.Clear()
 	mockApi.Handlers.UnmarshalMeta.Clear()
-	mockApi.Handlers.UnmarshalError.Clear()
-	mockApi.Handlers.Send.Clear()
-
-	mockApi.Handlers.Send.PushBack(func(r *request.Request) {
-		r.HTTPResponse = &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       ioutil.NopCloser(strings.NewReader("")),
-		}
+	mockApi.Handlers.ValidateResponse.Clear()
+	mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		r.Error = awserr.New("InternalError", "internal error", nil)
+		r.HTTPResponse = &http.Response{}
+	})
 
-		r.Data = &s3.ListObjectsV2Output{
-			Contents: s3objs,
-		}
+	retried := -1
+	mockApi.Handlers.AfterRetry.PushBack(func(_ *request.Request) {
+		retried++
 	})
 
-	mockS3 := &S3{
-		api: mockApi,
+	start := time.Now()
+	for range mockS3.List(context.Background(), url, true) {
 	}
+	elapsed := time.Since(start)
 
-	ouputCh := mockS3.listObjectsV2(context.Background(), u)
+	if retried <= 0 || retried >= maxRetries {
+		t.Errorf("expected retries to stop early, got %v retries", retried)
+	}
 
-	for obj := range ouputCh {
-		if _, ok := mapReturnObjNameToModtime[obj.String()]; ok {
-			delete(mapReturnObjNameToModtime, obj.String())
-			continue
-		}
-		t.Errorf("%v should not have been returned\n", obj)
+	// a single retry delay might be waited after the cap is reached.
+	if elapsed > 2*maxRetryDuration {
+		t.Errorf("expected retries to stop after %v, took %v", maxRetryDuration, elapsed)
 	}
-	assert.Equal(t, len(mapReturnObjNameToModtime), 0)
 }
 
-func TestSessionCreateAndCachingWithDifferentBuckets(t *testing.T) {
+func TestS3RetryAfter(t *testing.T) {
 	log.Init("error", false)
+
+	const maxRetries = 3
+
 	testcases := []struct {
-		bucket         string
-		alreadyCreated bool // sessions should not be created again if they already have been created before
+		name             string
+		statusCode       int
+		retryAfter       string
+		maxRetryDuration time.Duration
+		// hinted is true if the delay of every retry is expected to be the
+		// given delay instead of the default backoff.
+		hinted   bool
+		expected time.Duration
 	}{
-		{bucket: "bucket"},
-		{bucket: "bucket", alreadyCreated: true},
-		{bucket: "test-bucket"},
+		{
+			name:       "seconds",
+			statusCode: http.StatusServiceUnavailable,
+			retryAfter: "7",
+			hinted:     true,
+			expected:   7 * time.Second,
+		},
+		{
+			name:       "date in the past",
+			statusCode: http.StatusServiceUnavailable,
+			retryAfter: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+			hinted:     true,
+			expected:   0,
+		},
+		{
+			name:       "seconds beyond the maximum throttle delay",
+			statusCode: http.StatusServiceUnavailable,
+			retryAfter: "86400",
+			hinted:     true,
+			expected:   time.Minute,
+		},
+		{
+			name:       "date beyond the maximum throttle delay",
+			statusCode: http.StatusTooManyRequests,
+			retryAfter: time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat),
+			hinted:     true,
+			expected:   time.Minute,
+		},
+		{
+			name:             "seconds beyond the max retry duration",
+			statusCode:       http.StatusServiceUnavailable,
+			retryAfter:       "30",
+			maxRetryDuration: 10 * time.Second,
+			hinted:           true,
+			expected:         10 * time.Second,
+		},
+		{
+			name:       "no header",
+			statusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "invalid header",
+			statusCode: http.StatusServiceUnavailable,
+			retryAfter: "soon",
+		},
+		{
+			name:       "not a throttling response",
+			statusCode: http.StatusInternalServerError,
+			retryAfter: "7",
+		},
 	}
 
-	sess := map[string]*session.Session{}
-
 	for _, tc := range testcases {
-		awsSess, err := globalSessionCache.newSession(context.Background(), Options{
-			bucket: tc.bucket,
-		})
-		if err != nil {
-			t.Error(err)
-		}
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			url, err := url.New("s3://bucket/key")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			retryer := newCustomRetryer(maxRetries, tc.maxRetryDuration)
+			retryer.counter = &retryCounter{}
+			retryer.MinRetryDelay = time.Millisecond
+			retryer.MaxRetryDelay = time.Millisecond
+			retryer.MinThrottleDelay = time.Millisecond
+			// the delays are not slept, the maximum caps the Retry-After
+			// header.
+			retryer.MaxThrottleDelay = time.Minute
+
+			var delays []time.Duration
+			sess := unit.Session.Copy(&aws.Config{
+				Retryer: retryer,
+				SleepDelay: func(d time.Duration) {
+					delays = append(delays, d)
+				},
+			})
+
+			mockApi := s3.New(sess)
+			mockS3 := &S3{
+				api: mockApi,
+			}
+
+			mockApi.Handlers.Send.Clear() // mock sending
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.ValidateResponse.Clear()
+			mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				header := http.Header{}
+				if tc.retryAfter != "" {
+					header.Set("Retry-After", tc.retryAfter)
+				}
+				r.HTTPResponse = &http.Response{StatusCode: tc.statusCode, Header: header}
+				r.Error = awserr.NewRequestFailure(
+					awserr.New("SlowDown", "please reduce your request rate", nil),
+					tc.statusCode,
+					"0",
+				)
+			})
+
+			for range mockS3.List(context.Background(), url, true) {
+			}
+
+			if len(delays) != maxRetries {
+				t.Fatalf("expected %v retries, got %v", maxRetries, len(delays))
+			}
+
+			for _, delay := range delays {
+				// the time left of the max retry duration decreases while
+				// the test runs.
+				if tc.maxRetryDuration > 0 {
+					assert.Assert(t, delay <= tc.expected && delay > tc.expected-time.Second, delay)
+					continue
+				}
+				if tc.hinted {
+					assert.Equal(t, delay, tc.expected)
+					continue
+				}
+				// the default backoff is only a few milliseconds.
+				if delay <= 0 || delay > time.Second {
+					t.Errorf("expected the default backoff, got %v", delay)
+				}
+			}
+		})
+	}
+}
+
+func TestS3RetryBudget(t *testing.T) {
+	log.Init("error", false)
+
+	const (
+		maxRetries = 5
+		requests   = 20
+	)
+
+	testcases := []struct {
+		name              string
+		budget            float64
+		expectedRetries   int
+		expectedExhausted bool
+	}{
+		{
+			name:            "no budget",
+			budget:          0,
+			expectedRetries: requests * maxRetries,
+		},
+		{
+			name:   "budget",
+			budget: 0.1,
+			// the budget allows 2 retries for 20 requests, but the first
+			// retries are allowed regardless of the budget.
+			expectedRetries:   minRetryBudget,
+			expectedExhausted: true,
+		},
+		{
+			name:            "budget larger than max retries",
+			budget:          10,
+			expectedRetries: requests * maxRetries,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			url, err := url.New("s3://bucket/key")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			retryer := newCustomRetryer(maxRetries, 0)
+			retryer.budget = tc.budget
+			retryer.counter = &retryCounter{}
+
+			sess := unit.Session.Copy(&aws.Config{Retryer: retryer})
+
+			mockApi := s3.New(sess)
+			mockS3 := &S3{
+				api: mockApi,
+			}
+
+			mockApi.Handlers.Build.PushBack(retryer.countRequest)
+			mockApi.Handlers.Send.Clear() // mock sending
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.ValidateResponse.Clear()
+			// the endpoint is throttling all requests.
+			mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				r.Error = awserr.New("SlowDown", "slow down", nil)
+				r.HTTPResponse = &http.Response{}
+			})
+
+			retried := -requests
+			// AfterRetry handlers are run after every attempt of a request.
+			mockApi.Handlers.AfterRetry.PushBack(func(_ *request.Request) {
+				retried++
+			})
+
+			for i := 0; i < requests; i++ {
+				for range mockS3.List(context.Background(), url, true) {
+				}
+			}
+
+			if retried != tc.expectedRetries {
+				t.Errorf("expected %v retries, got %v", tc.expectedRetries, retried)
+			}
+
+			if exhausted := retryer.counter.exhausted.Get(); exhausted != tc.expectedExhausted {
+				t.Errorf("expected exhausted budget %v, got %v", tc.expectedExhausted, exhausted)
+			}
+		})
+	}
+}
+
+func TestS3GetPreconditions(t *testing.T) {
+	testcases := []struct {
+		name                string
+		preconditions       Preconditions
+		statusCode          int
+		expectedIfMatch     string
+		expectedIfNoneMatch string
+		expectedErr         error
+	}{
+		{
+			name:                "if none match",
+			preconditions:       Preconditions{IfNoneMatch: "etag"},
+			statusCode:          http.StatusNotModified,
+			expectedIfNoneMatch: `"etag"`,
+			expectedErr:         ErrObjectNotModified,
+		},
+		{
+			name:            "if match",
+			preconditions:   Preconditions{IfMatch: `"etag"`},
+			statusCode:      http.StatusPreconditionFailed,
+			expectedIfMatch: `"etag"`,
+			expectedErr:     ErrPreconditionFailed,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.New("s3://bucket/key")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			mockApi := s3.New(unit.Session)
+			mockS3 := &S3{
+				api:        mockApi,
+				downloader: s3manager.NewDownloaderWithClient(mockApi),
+			}
+
+			mockApi.Handlers.Send.Clear() // mock sending
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.ValidateResponse.Clear()
+			mockApi.Handlers.UnmarshalError.Clear()
+			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+				// the ETags are always sent with quotes.
+				header := r.HTTPRequest.Header
+				assert.Equal(t, header.Get("If-Match"), tc.expectedIfMatch)
+				assert.Equal(t, header.Get("If-None-Match"), tc.expectedIfNoneMatch)
+
+				r.HTTPResponse = &http.Response{StatusCode: tc.statusCode, Header: http.Header{}}
+				r.Error = awserr.NewRequestFailure(awserr.New("", "", nil), tc.statusCode, "")
+			})
+
+			_, err = mockS3.Get(context.Background(), u, aws.NewWriteAtBuffer(nil), tc.preconditions, "", 1, 5*1024*1024)
+			if err != tc.expectedErr {
+				t.Errorf("error got = %v, want %v", err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestS3GetRange(t *testing.T) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mockApi := s3.New(unit.Session)
+	mockS3 := &S3{
+		api:        mockApi,
+		downloader: s3manager.NewDownloaderWithClient(mockApi),
+	}
+
+	var ranges []string
+	mockApi.Handlers.Send.Clear() // mock sending
+	mockApi.Handlers.Unmarshal.Clear()
+	mockApi.Handlers.UnmarshalMeta.Clear()
+	mockApi.Handlers.ValidateResponse.Clear()
+	mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+		ranges = append(ranges, r.HTTPRequest.Header.Get("Range"))
+
+		// the response is not unmarshaled.
+		r.HTTPResponse = &http.Response{StatusCode: http.StatusPartialContent, Header: http.Header{}}
+		r.Data.(*s3.GetObjectOutput).Body = ioutil.NopCloser(strings.NewReader("0123"))
+	})
+
+	buf := aws.NewWriteAtBuffer(nil)
+	// the range is not split into parts of partSize.
+	n, err := mockS3.Get(context.Background(), u, buf, Preconditions{}, "bytes=0-3", 5, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, n, int64(4))
+	assert.Equal(t, string(buf.Bytes()), "0123")
+	assert.DeepEqual(t, ranges, []string{"bytes=0-3"})
+}
+
+func TestPreconditionsCheck(t *testing.T) {
+	testcases := []struct {
+		name          string
+		preconditions Preconditions
+		etag          string
+		expected      error
+	}{
+		{name: "none", etag: "etag"},
+		{name: "if match", preconditions: Preconditions{IfMatch: "etag"}, etag: "etag"},
+		{name: "if match with quotes", preconditions: Preconditions{IfMatch: `"etag"`}, etag: "etag"},
+		{name: "if match fails", preconditions: Preconditions{IfMatch: "other"}, etag: "etag", expected: ErrPreconditionFailed},
+		{name: "if none match", preconditions: Preconditions{IfNoneMatch: "other"}, etag: "etag"},
+		{name: "if none match fails", preconditions: Preconditions{IfNoneMatch: "etag"}, etag: `"etag"`, expected: ErrObjectNotModified},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.preconditions.Check(tc.etag); err != tc.expected {
+				t.Errorf("error got = %v, want %v", err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestS3RequestPayer(t *testing.T) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		operation string
+		fn        func(s *S3)
+	}{
+		{
+			name:      "stat",
+			operation: "HeadObject",
+			fn:        func(s *S3) { _, _ = s.Stat(context.Background(), u) },
+		},
+		{
+			name:      "list",
+			operation: "ListObjectsV2",
+			fn: func(s *S3) {
+				for range s.List(context.Background(), u, false) {
+				}
+			},
+		},
+		{
+			name:      "copy",
+			operation: "CopyObject",
+			fn:        func(s *S3) { _ = s.Copy(context.Background(), u, u, NewMetadata()) },
+		},
+		{
+			name:      "read",
+			operation: "GetObject",
+			fn:        func(s *S3) { _, _ = s.Read(context.Background(), u) },
+		},
+		{
+			name:      "put acl",
+			operation: "PutObjectAcl",
+			fn:        func(s *S3) { _ = s.PutACL(context.Background(), u, "public-read") },
+		},
+		{
+			name:      "delete",
+			operation: "DeleteObjects",
+			fn:        func(s *S3) { _ = s.Delete(context.Background(), u) },
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		for _, requestPayer := range []string{"", "requester"} {
+			requestPayer := requestPayer
+			t.Run(fmt.Sprintf("%v/%q", tc.name, requestPayer), func(t *testing.T) {
+				mockApi := s3.New(unit.Session)
+
+				mockApi.Handlers.Unmarshal.Clear()
+				mockApi.Handlers.UnmarshalMeta.Clear()
+				mockApi.Handlers.UnmarshalError.Clear()
+				mockApi.Handlers.Send.Clear()
+
+				var sent bool
+				mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+					sent = true
+
+					assert.Equal(t, r.Operation.Name, tc.operation)
+
+					got := valueAtPath(r.Params, "RequestPayer")
+					if requestPayer == "" {
+						assert.Equal(t, got, nil)
+					} else {
+						assert.Equal(t, got, requestPayer)
+					}
+
+					r.HTTPResponse = &http.Response{
+						StatusCode: http.StatusForbidden,
+						Body:       ioutil.NopCloser(strings.NewReader("")),
+					}
+					r.Error = awserr.New("AccessDenied", "access denied", nil)
+				})
+
+				mockS3 := &S3{
+					api:          mockApi,
+					requestPayer: requestPayer,
+				}
+
+				tc.fn(mockS3)
+
+				if !sent {
+					t.Errorf("expected a %v request", tc.operation)
+				}
+			})
+		}
+	}
+}
+
+func TestS3CopyEncryptionRequest(t *testing.T) {
+	testcases := []struct {
+		name     string
+		sse      string
+		sseKeyID string
+		acl      string
+
+		expectedSSE      string
+		expectedSSEKeyID string
+		expectedAcl      string
+	}{
+		{
+			name: "no encryption/no acl, by default",
+		},
+		{
+			name: "aws:kms encryption with server side generated keys",
+			sse:  "aws:kms",
+
+			expectedSSE: "aws:kms",
+		},
+		{
+			name:     "aws:kms encryption with user provided key",
+			sse:      "aws:kms",
+			sseKeyID: "sdkjn12SDdci#@#EFRFERTqW/ke",
+
+			expectedSSE:      "aws:kms",
+			expectedSSEKeyID: "sdkjn12SDdci#@#EFRFERTqW/ke",
+		},
+		{
+			name:     "provide key without encryption flag, shall be ignored",
+			sseKeyID: "1234567890",
+		},
+		{
+			name:        "acl flag with a value",
+			acl:         "bucket-owner-full-control",
+			expectedAcl: "bucket-owner-full-control",
+		},
+	}
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+
+			mockApi := s3.New(unit.Session)
+
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.UnmarshalError.Clear()
+			mockApi.Handlers.Send.Clear()
+
+			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}
+
+				params := r.Params
+				sse := valueAtPath(params, "ServerSideEncryption")
+				key := valueAtPath(params, "SSEKMSKeyId")
+
+				if !(sse == nil && tc.expectedSSE == "") {
+					assert.Equal(t, sse, tc.expectedSSE)
+				}
+				if !(key == nil && tc.expectedSSEKeyID == "") {
+					assert.Equal(t, key, tc.expectedSSEKeyID)
+				}
+
+				aclVal := valueAtPath(r.Params, "ACL")
+
+				if aclVal == nil && tc.expectedAcl == "" {
+					return
+				}
+				assert.Equal(t, aclVal, tc.expectedAcl)
+			})
+			mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				if r.Error != nil {
+					if awsErr, ok := r.Error.(awserr.Error); ok {
+						if awsErr.Code() == request.ErrCodeSerialization {
+							r.Error = nil
+						}
+					}
+				}
+			})
+
+			mockS3 := &S3{
+				api: mockApi,
+			}
+
+			metadata := NewMetadata().SetSSE(tc.sse).SetSSEKeyID(tc.sseKeyID).SetACL(tc.acl)
+
+			err = mockS3.Copy(context.Background(), u, u, metadata)
+
+			if err != nil {
+				t.Errorf("Expected %v, but received %q", nil, err)
+			}
+		})
+	}
+}
+
+func TestS3PutEncryptionRequest(t *testing.T) {
+	testcases := []struct {
+		name     string
+		sse      string
+		sseKeyID string
+		acl      string
+
+		expectedSSE      string
+		expectedSSEKeyID string
+		expectedAcl      string
+	}{
+		{
+			name: "no encryption, no acl flag",
+		},
+		{
+			name:        "aws:kms encryption with server side generated keys",
+			sse:         "aws:kms",
+			expectedSSE: "aws:kms",
+		},
+		{
+			name:     "aws:kms encryption with user provided key",
+			sse:      "aws:kms",
+			sseKeyID: "sdkjn12SDdci#@#EFRFERTqW/ke",
+
+			expectedSSE:      "aws:kms",
+			expectedSSEKeyID: "sdkjn12SDdci#@#EFRFERTqW/ke",
+		},
+		{
+			name:        "AES256 encryption",
+			sse:         "AES256",
+			expectedSSE: "AES256",
+		},
+		{
+			name:     "provide key without encryption flag, shall be ignored",
+			sseKeyID: "1234567890",
+		},
+		{
+			name:        "acl flag with a value",
+			acl:         "bucket-owner-full-control",
+			expectedAcl: "bucket-owner-full-control",
+		},
+	}
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+
+			mockApi := s3.New(unit.Session)
+
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.UnmarshalError.Clear()
+			mockApi.Handlers.Send.Clear()
+
+			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}
+
+				params := r.Params
+				sse := valueAtPath(params, "ServerSideEncryption")
+				key := valueAtPath(params, "SSEKMSKeyId")
+
+				if !(sse == nil && tc.expectedSSE == "") {
+					assert.Equal(t, sse, tc.expectedSSE)
+				}
+				if !(key == nil && tc.expectedSSEKeyID == "") {
+					assert.Equal(t, key, tc.expectedSSEKeyID)
+				}
+
+				aclVal := valueAtPath(r.Params, "ACL")
+
+				if aclVal == nil && tc.expectedAcl == "" {
+					return
+				}
+				assert.Equal(t, aclVal, tc.expectedAcl)
+			})
+
+			mockS3 := &S3{
+				uploader: s3manager.NewUploaderWithClient(mockApi),
+			}
+
+			metadata := NewMetadata().SetSSE(tc.sse).SetSSEKeyID(tc.sseKeyID).SetACL(tc.acl)
+
+			err = mockS3.Put(context.Background(), bytes.NewReader([]byte("")), u, metadata, 1, 5242880)
+
+			if err != nil {
+				t.Errorf("Expected %v, but received %q", nil, err)
+			}
+		})
+	}
+}
+
+func TestS3PutContentTypeAndMetadataRequest(t *testing.T) {
+	testcases := []struct {
+		name               string
+		contentType        string
+		contentEncoding    string
+		contentDisposition string
+		contentLanguage    string
+		expires            string
+		metadata           map[string]string
+
+		expectedContentType string
+		expectedExpires     *time.Time
+		expectedMetadata    map[string]*string
+	}{
+		{
+			name:                "no content type, no metadata",
+			expectedContentType: "application/octet-stream",
+		},
+		{
+			name:                "content type",
+			contentType:         "text/html",
+			expectedContentType: "text/html",
+		},
+		{
+			name:                "user defined metadata",
+			metadata:            map[string]string{"owner": "john", "env": "prod"},
+			expectedContentType: "application/octet-stream",
+			expectedMetadata:    aws.StringMap(map[string]string{"owner": "john", "env": "prod"}),
+		},
+		{
+			name:                "content encoding",
+			contentType:         "text/plain",
+			contentEncoding:     "gzip",
+			expectedContentType: "text/plain",
+		},
+		{
+			name:                "content disposition and language",
+			contentDisposition:  `attachment; filename="report.pdf"`,
+			contentLanguage:     "en-US",
+			expectedContentType: "application/octet-stream",
+		},
+		{
+			name:                "expires",
+			expires:             "2024-10-01T20:30:00Z",
+			expectedContentType: "application/octet-stream",
+			expectedExpires:     aws.Time(time.Date(2024, 10, 1, 20, 30, 0, 0, time.UTC)),
+		},
+	}
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockApi := s3.New(unit.Session)
+
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.UnmarshalError.Clear()
+			mockApi.Handlers.Send.Clear()
+
+			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}
+
+				input := r.Params.(*s3.PutObjectInput)
+
+				assert.Equal(t, aws.StringValue(input.ContentType), tc.expectedContentType)
+				assert.Equal(t, aws.StringValue(input.ContentEncoding), tc.contentEncoding)
+				assert.Equal(t, aws.StringValue(input.ContentDisposition), tc.contentDisposition)
+				assert.Equal(t, aws.StringValue(input.ContentLanguage), tc.contentLanguage)
+				assert.Equal(t, aws.TimeValue(input.Expires), aws.TimeValue(tc.expectedExpires))
+				assert.DeepEqual(t, input.Metadata, tc.expectedMetadata)
+			})
+
+			mockS3 := &S3{
+				uploader: s3manager.NewUploaderWithClient(mockApi),
+			}
+
+			metadata := NewMetadata().SetContentType(tc.contentType)
+			if tc.contentEncoding != "" {
+				metadata.SetContentEncoding(tc.contentEncoding)
+			}
+			if tc.contentDisposition != "" {
+				metadata.SetContentDisposition(tc.contentDisposition)
+			}
+			if tc.contentLanguage != "" {
+				metadata.SetContentLanguage(tc.contentLanguage)
+			}
+			if tc.expires != "" {
+				metadata.SetExpires(tc.expires)
+			}
+			for key, value := range tc.metadata {
+				metadata.SetUserDefined(key, value)
+			}
+
+			err = mockS3.Put(context.Background(), bytes.NewReader([]byte("")), u, metadata, 1, 5242880)
+			if err != nil {
+				t.Errorf("Expected %v, but received %q", nil, err)
+			}
+		})
+	}
+}
+
+func TestS3TaggingAndACLRequest(t *testing.T) {
+	tags := map[string]string{"env": "prod", "team": "data & analytics"}
+
+	const expectedTagging = "env=prod&team=data+%26+analytics"
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name  string
+		run   func(s *S3) error
+		check func(t *testing.T, params interface{})
+	}{
+		{
+			name: "put",
+			run: func(s *S3) error {
+				metadata := NewMetadata().SetTagging(tags)
+				return s.Put(context.Background(), bytes.NewReader([]byte("")), u, metadata, 1, 5242880)
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.PutObjectInput)
+				assert.Equal(t, aws.StringValue(input.Tagging), expectedTagging)
+			},
+		},
+		{
+			name: "copy",
+			run: func(s *S3) error {
+				metadata := NewMetadata().SetTagging(tags)
+				return s.Copy(context.Background(), u, u, metadata)
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.CopyObjectInput)
+				assert.Equal(t, aws.StringValue(input.Tagging), expectedTagging)
+				assert.Equal(t, aws.StringValue(input.TaggingDirective), s3.TaggingDirectiveReplace)
+			},
+		},
+		{
+			name: "copy without tags",
+			run: func(s *S3) error {
+				return s.Copy(context.Background(), u, u, NewMetadata())
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.CopyObjectInput)
+				assert.Assert(t, input.Tagging == nil)
+				assert.Assert(t, input.TaggingDirective == nil)
+			},
+		},
+		{
+			name: "put acl",
+			run: func(s *S3) error {
+				return s.PutACL(context.Background(), u, "public-read")
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.PutObjectAclInput)
+				assert.Equal(t, aws.StringValue(input.ACL), "public-read")
+			},
+		},
+		{
+			name: "get tagging",
+			run: func(s *S3) error {
+				_, err := s.GetTagging(context.Background(), u)
+				return err
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.GetObjectTaggingInput)
+				assert.Equal(t, aws.StringValue(input.Bucket), "bucket")
+				assert.Equal(t, aws.StringValue(input.Key), "key")
+			},
+		},
+		{
+			name: "put tagging",
+			run: func(s *S3) error {
+				return s.PutTagging(context.Background(), u, tags)
+			},
+			check: func(t *testing.T, params interface{}) {
+				input := params.(*s3.PutObjectTaggingInput)
+				expected := []*s3.Tag{
+					{Key: aws.String("env"), Value: aws.String("prod")},
+					{Key: aws.String("team"), Value: aws.String("data & analytics")},
+				}
+				assert.DeepEqual(t, input.Tagging.TagSet, expected)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockApi := s3.New(unit.Session)
+
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.UnmarshalError.Clear()
+			mockApi.Handlers.Send.Clear()
+
+			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}
+
+				tc.check(t, r.Params)
+			})
+			mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				if r.Error != nil {
+					if awsErr, ok := r.Error.(awserr.Error); ok {
+						if awsErr.Code() == request.ErrCodeSerialization {
+							r.Error = nil
+						}
+					}
+				}
+			})
+
+			mockS3 := &S3{
+				api:      mockApi,
+				uploader: s3manager.NewUploaderWithClient(mockApi),
+			}
+
+			if err := tc.run(mockS3); err != nil {
+				t.Errorf("Expected %v, but received %q", nil, err)
+			}
+		})
+	}
+}
+
+func TestS3StatMetadata(t *testing.T) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	mockApi := s3.New(unit.Session)
+
+	mockApi.Handlers.Send.Clear()
+	mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+		header := http.Header{}
+		header.Set("Content-Length", "42")
+		header.Set("Content-Type", "text/plain")
+		header.Set("Content-Encoding", "gzip")
+		header.Set("Content-Disposition", "attachment")
+		header.Set("Content-Language", "en-US")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("ETag", `"etag"`)
+		header.Set("X-Amz-Storage-Class", "GLACIER")
+		header.Set("X-Amz-Meta-Owner", "john")
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+	})
+
+	mockS3 := &S3{api: mockApi}
+
+	obj, err := mockS3.Stat(context.Background(), u)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, obj.Size, int64(42))
+	assert.Equal(t, obj.Etag, "etag")
+	assert.Equal(t, obj.StorageClass, StorageClass("GLACIER"))
+	assert.Equal(t, obj.Metadata.ContentType(), "text/plain")
+	assert.Equal(t, obj.Metadata.ContentEncoding(), "gzip")
+	assert.Equal(t, obj.Metadata.ContentDisposition(), "attachment")
+	assert.Equal(t, obj.Metadata.ContentLanguage(), "en-US")
+	assert.Equal(t, obj.Metadata.CacheControl(), "no-cache")
+	assert.DeepEqual(t, obj.Metadata.UserDefined(), map[string]string{"Owner": "john"})
+}
+
+func TestS3Presign(t *testing.T) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	mockApi := s3.New(unit.Session)
+
+	// presigning must not send any request.
+	mockApi.Handlers.Send.Clear()
+	mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+		t.Errorf("unexpected request to %v", r.HTTPRequest.URL)
+	})
+
+	mockS3 := &S3{api: mockApi}
+
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		presigned, err := mockS3.Presign(context.Background(), u, method, 15*time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		parsed, err := urlpkg.Parse(presigned)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		assert.Equal(t, parsed.Path, "/key")
+		assert.Equal(t, parsed.Query().Get("X-Amz-Expires"), "900")
+		assert.Assert(t, parsed.Query().Get("X-Amz-Signature") != "")
+	}
+
+	_, err = mockS3.Presign(context.Background(), u, http.MethodDelete, time.Minute)
+	assert.Error(t, err, `unsupported method "DELETE"`)
+
+	// the request payer is signed, so the users of the url must send it.
+	mockS3.requestPayer = "requester"
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		presigned, err := mockS3.Presign(context.Background(), u, method, 15*time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		parsed, err := urlpkg.Parse(presigned)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		assert.Equal(t, parsed.Query().Get("X-Amz-SignedHeaders"), "host;x-amz-request-payer")
+	}
+}
+
+func TestS3listObjectsV2(t *testing.T) {
+	const (
+		numObjectsToReturn = 10100
+		numObjectsToIgnore = 1127
+
+		pre = "s3://bucket/key"
+	)
+
+	u, err := url.New(pre)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	mapReturnObjNameToModtime := map[string]time.Time{}
+	mapIgnoreObjNameToModtime := map[string]time.Time{}
+
+	s3objs := make([]*s3.Object, 0, numObjectsToIgnore+numObjectsToReturn)
+
+	for i := 0; i < numObjectsToReturn; i++ {
+		fname := fmt.Sprintf("%s/%d", pre, i)
+		now := time.Now()
+
+		mapReturnObjNameToModtime[pre+"/"+fname] = now
+		s3objs = append(s3objs, &s3.Object{
+			Key:          aws.String("key/" + fname),
+			LastModified: aws.Time(now),
+		})
+	}
+
+	for i := 0; i < numObjectsToIgnore; i++ {
+		fname := fmt.Sprintf("%s/%d", pre, numObjectsToReturn+i)
+		later := time.Now().Add(time.Second * 10)
+
+		mapIgnoreObjNameToModtime[pre+"/"+fname] = later
+		s3objs = append(s3objs, &s3.Object{
+			Key:          aws.String("key/" + fname),
+			LastModified: aws.Time(later),
+		})
+	}
+
+	// shuffle the objects array to remove possible assumptions about how objects
+	// are stored.
+	rand.Shuffle(len(s3objs), func(i, j int) {
+		s3objs[i], s3objs[j] = s3objs[j], s3objs[i]
+	})
+
+	mockApi := s3.New(unit.Session)
+
+	mockApi.Handlers.Unmarshal.Clear()
+	mockApi.Handlers.UnmarshalMeta.Clear()
+	mockApi.Handlers.UnmarshalError.Clear()
+	mockApi.Handlers.Send.Clear()
+
+	mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+
+		r.Data = &s3.ListObjectsV2Output{
+			Contents: s3objs,
+		}
+	})
+
+	mockS3 := &S3{
+		api: mockApi,
+	}
+
+	ouputCh := mockS3.listObjectsV2(context.Background(), u)
+
+	for obj := range ouputCh {
+		if _, ok := mapReturnObjNameToModtime[obj.String()]; ok {
+			delete(mapReturnObjNameToModtime, obj.String())
+			continue
+		}
+		t.Errorf("%v should not have been returned\n", obj)
+	}
+	assert.Equal(t, len(mapReturnObjNameToModtime), 0)
+}
+
+func TestSessionCreateAndCachingWithDifferentBuckets(t *testing.T) {
+	log.Init("error", false)
+	testcases := []struct {
+		bucket         string
+		alreadyCreated bool // sessions should not be created again if they already have been created before
+	}{
+		{bucket: "bucket"},
+		{bucket: "bucket", alreadyCreated: true},
+		{bucket: "test-bucket"},
+	}
+
+	sess := map[string]*session.Session{}
+
+	for _, tc := range testcases {
+		awsSess, err := globalSessionCache.newSession(context.Background(), Options{
+			bucket: tc.bucket,
+		})
+		if err != nil {
+			t.Error(err)
+		}
 
 		if tc.alreadyCreated {
 			_, ok := sess[tc.bucket]
@@ -783,122 +2329,734 @@ func TestSessionCreateAndCachingWithDifferentBuckets(t *testing.T) {
 	}
 }
 
-func TestSessionAutoRegionValidateCredentials(t *testing.T) {
-	awsSess := unit.Session
-	awsSess.Handlers.Unmarshal.Clear()
-	awsSess.Handlers.Send.Clear()
-	awsSess.Handlers.Send.PushBack(func(r *request.Request) {
-		header := http.Header{}
-		header.Set("X-Amz-Bucket-Region", "")
+func TestSessionAutoRegionValidateCredentials(t *testing.T) {
+	awsSess := unit.Session
+	awsSess.Handlers.Unmarshal.Clear()
+	awsSess.Handlers.Send.Clear()
+	awsSess.Handlers.Send.PushBack(func(r *request.Request) {
+		header := http.Header{}
+		header.Set("X-Amz-Bucket-Region", "")
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+		}
+
+		if r.Config.Credentials != awsSess.Config.Credentials {
+			t.Error("session credentials are expected to be used during HeadBucket request")
+		}
+	})
+
+	_ = setSessionRegion(context.Background(), awsSess, "bucket")
+}
+
+func TestSessionAutoRegion(t *testing.T) {
+	log.Init("error", false)
+
+	unitSession := func() *session.Session {
+		return session.Must(session.NewSession(&aws.Config{
+			Credentials: credentials.NewStaticCredentials("AKID", "SECRET", "SESSION"),
+			SleepDelay:  func(time.Duration) {},
+		}))
+	}
+
+	testcases := []struct {
+		name              string
+		bucket            string
+		region            string
+		status            int
+		expectedRegion    string
+		expectedErrorCode string
+	}{
+		{
+			name:           "NoLocationConstraint",
+			bucket:         "bucket",
+			region:         "",
+			status:         http.StatusOK,
+			expectedRegion: "us-east-1",
+		},
+		{
+			name:           "LocationConstraintDefaultRegion",
+			bucket:         "bucket",
+			region:         "us-east-1",
+			status:         http.StatusOK,
+			expectedRegion: "us-east-1",
+		},
+		{
+			name:           "LocationConstraintAnotherRegion",
+			bucket:         "bucket",
+			region:         "us-west-2",
+			status:         http.StatusOK,
+			expectedRegion: "us-west-2",
+		},
+		{
+			name:              "BucketNotFoundErrorMustFail",
+			bucket:            "bucket",
+			status:            http.StatusNotFound,
+			expectedRegion:    "us-east-1",
+			expectedErrorCode: "NotFound",
+		},
+		{
+			name:           "AccessDeniedErrorMustNotFail",
+			bucket:         "bucket",
+			status:         http.StatusForbidden,
+			expectedRegion: "us-east-1",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			awsSess := unitSession()
+			awsSess.Handlers.Unmarshal.Clear()
+			awsSess.Handlers.Send.Clear()
+			awsSess.Handlers.Send.PushBack(func(r *request.Request) {
+				header := http.Header{}
+				if tc.region != "" {
+					header.Set("X-Amz-Bucket-Region", tc.region)
+				}
+				r.HTTPResponse = &http.Response{
+					StatusCode: tc.status,
+					Header:     header,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}
+			})
+
+			err := setSessionRegion(context.Background(), awsSess, tc.bucket)
+			if tc.expectedErrorCode != "" && !errHasCode(err, tc.expectedErrorCode) {
+				t.Errorf("expected error code: %v, got error: %v", tc.expectedErrorCode, err)
+				return
+			}
+
+			if expected, got := tc.expectedRegion, aws.StringValue(awsSess.Config.Region); expected != got {
+				t.Errorf("expected: %v, got: %v", expected, got)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	client := newHTTPClient(Options{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 50,
+		TLSHandshakeTimeout: 5 * time.Second,
+		NoVerifySSL:         true,
+	})
+
+	transport := client.Transport.(*http.Transport)
+	assert.Equal(t, transport.MaxIdleConns, 100)
+	assert.Equal(t, transport.MaxIdleConnsPerHost, 50)
+	assert.Equal(t, transport.TLSHandshakeTimeout, 5*time.Second)
+	assert.Assert(t, transport.TLSClientConfig.InsecureSkipVerify)
+
+	// the default transport must not be modified.
+	assert.Assert(t, http.DefaultTransport.(*http.Transport) != transport)
+	assert.Equal(t, http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost, 0)
+}
+
+// BenchmarkHTTPClient compares the default transport with the client of a
+// session when many workers send requests to the same host. conns/op is the
+// number of connections opened per request.
+func BenchmarkHTTPClient(b *testing.B) {
+	const workers = 64
+
+	var conns int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	benchmarks := []struct {
+		name   string
+		client *http.Client
+	}{
+		{
+			name:   "default",
+			client: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		},
+		{
+			name:   "pool",
+			client: newHTTPClient(Options{MaxIdleConnsPerHost: workers}),
+		},
+	}
+
+	for _, bm := range benchmarks {
+		bm := bm
+		b.Run(bm.name, func(b *testing.B) {
+			defer bm.client.CloseIdleConnections()
+
+			requests := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range requests {
+						resp, err := bm.client.Get(server.URL)
+						if err != nil {
+							b.Error(err)
+							continue
+						}
+						io.Copy(ioutil.Discard, resp.Body)
+						resp.Body.Close()
+					}
+				}()
+			}
+
+			atomic.StoreInt64(&conns, 0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				requests <- struct{}{}
+			}
+			close(requests)
+			wg.Wait()
+			b.StopTimer()
+
+			b.ReportMetric(float64(atomic.LoadInt64(&conns))/float64(b.N), "conns/op")
+		})
+	}
+}
+
+func valueAtPath(i interface{}, s string) interface{} {
+	v, err := awsutil.ValuesAtPath(i, s)
+	if err != nil || len(v) == 0 {
+		return nil
+	}
+	if _, ok := v[0].(io.Reader); ok {
+		return v[0]
+	}
+
+	if rv := reflect.ValueOf(v[0]); rv.Kind() == reflect.Ptr {
+		return rv.Elem().Interface()
+	}
+
+	return v[0]
+}
+
+// BenchmarkNewRemoteClient measures the cost of creating the client of a
+// command, which is done for every object transferred by cp, mv and sync.
+func BenchmarkNewRemoteClient(b *testing.B) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := Options{
+		Endpoint:      "http://127.0.0.1",
+		NoSignRequest: true,
+	}
+	opts.SetRegion("us-east-1")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewRemoteClient(context.Background(), u, opts); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestCopyPartRanges(t *testing.T) {
+	const (
+		mb = 1024 * 1024
+		gb = 1024 * mb
+	)
+
+	testcases := []struct {
+		name     string
+		size     int64
+		partSize int64
+
+		expectedParts int
+		expectedFirst string
+		expectedLast  string
+	}{
+		{
+			name:          "single part",
+			size:          1,
+			partSize:      50 * mb,
+			expectedParts: 1,
+			expectedFirst: "bytes=0-0",
+			expectedLast:  "bytes=0-0",
+		},
+		{
+			name:          "copy limit",
+			size:          MaxCopyObjectSize,
+			partSize:      1 * gb,
+			expectedParts: 5,
+			expectedFirst: "bytes=0-1073741823",
+			expectedLast:  "bytes=4294967296-5368709119",
+		},
+		{
+			name:          "one byte over the copy limit",
+			size:          MaxCopyObjectSize + 1,
+			partSize:      1 * gb,
+			expectedParts: 6,
+			expectedFirst: "bytes=0-1073741823",
+			expectedLast:  "bytes=5368709120-5368709120",
+		},
+		{
+			name:          "one byte under a multiple of the part size",
+			size:          6*gb - 1,
+			partSize:      1 * gb,
+			expectedParts: 6,
+			expectedFirst: "bytes=0-1073741823",
+			expectedLast:  "bytes=5368709120-6442450942",
+		},
+		{
+			name:          "part size under the minimum",
+			size:          12 * mb,
+			partSize:      1 * mb,
+			expectedParts: 3,
+			expectedFirst: "bytes=0-5242879",
+			expectedLast:  "bytes=10485760-12582911",
+		},
+		{
+			name:          "part size over the maximum",
+			size:          12 * gb,
+			partSize:      10 * gb,
+			expectedParts: 3,
+			expectedFirst: "bytes=0-5368709119",
+			expectedLast:  "bytes=10737418240-12884901887",
+		},
+		{
+			name:          "maximum number of parts",
+			size:          s3manager.MaxUploadParts * 5 * mb,
+			partSize:      5 * mb,
+			expectedParts: s3manager.MaxUploadParts,
+			expectedFirst: "bytes=0-5242879",
+			expectedLast:  fmt.Sprintf("bytes=%d-%d", (s3manager.MaxUploadParts-1)*5*mb, s3manager.MaxUploadParts*5*mb-1),
+		},
+		{
+			name:          "part size raised to fit the maximum number of parts",
+			size:          s3manager.MaxUploadParts*5*mb + 1,
+			partSize:      5 * mb,
+			expectedParts: s3manager.MaxUploadParts,
+			expectedFirst: "bytes=0-5242880",
+			expectedLast:  fmt.Sprintf("bytes=%d-%d", (s3manager.MaxUploadParts-1)*(5*mb+1), s3manager.MaxUploadParts*5*mb),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ranges := copyPartRanges(tc.size, tc.partSize)
+
+			assert.Equal(t, len(ranges), tc.expectedParts)
+			assert.Equal(t, ranges[0], tc.expectedFirst)
+			assert.Equal(t, ranges[len(ranges)-1], tc.expectedLast)
+
+			// the ranges must cover the object without any gaps or overlaps.
+			var next int64
+			for _, r := range ranges {
+				var start, end int64
+				if _, err := fmt.Sscanf(r, "bytes=%d-%d", &start, &end); err != nil {
+					t.Fatalf("invalid range %q: %v", r, err)
+				}
+				assert.Equal(t, start, next)
+				assert.Assert(t, end >= start)
+				assert.Assert(t, end-start+1 <= maxCopyPartSize)
+				next = end + 1
+			}
+			assert.Equal(t, next, tc.size)
+		})
+	}
+}
+
+func TestS3MultipartCopy(t *testing.T) {
+	const (
+		mb   = 1024 * 1024
+		size = 12 * mb
+	)
+
+	from, err := url.New("s3://source/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	to, err := url.New("s3://destination/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name       string
+		failedPart int64
+
+		expectErr      bool
+		expectComplete bool
+		expectAbort    bool
+	}{
+		{
+			name:           "success",
+			expectComplete: true,
+		},
+		{
+			name:        "failed part",
+			failedPart:  2,
+			expectErr:   true,
+			expectAbort: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockApi := s3.New(unit.Session)
+
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.UnmarshalError.Clear()
+			mockApi.Handlers.Send.Clear()
+
+			var (
+				mu        sync.Mutex
+				ranges    []string
+				completed bool
+				aborted   bool
+			)
+
+			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch input := r.Params.(type) {
+				case *s3.CreateMultipartUploadInput:
+					assert.Equal(t, aws.StringValue(input.Bucket), "destination")
+					assert.Equal(t, aws.StringValue(input.ContentType), "text/plain")
+					assert.DeepEqual(t, input.Metadata, aws.StringMap(map[string]string{"owner": "john"}))
+					r.Data.(*s3.CreateMultipartUploadOutput).UploadId = aws.String("upload-id")
+				case *s3.UploadPartCopyInput:
+					assert.Equal(t, aws.StringValue(input.UploadId), "upload-id")
+					assert.Equal(t, aws.StringValue(input.CopySource), "source/key")
+					if aws.Int64Value(input.PartNumber) == tc.failedPart {
+						r.Error = awserr.New("InternalError", "part failed", nil)
+						return
+					}
+					ranges = append(ranges, aws.StringValue(input.CopySourceRange))
+					r.Data.(*s3.UploadPartCopyOutput).CopyPartResult = &s3.CopyPartResult{
+						ETag: aws.String(fmt.Sprintf("etag-%d", aws.Int64Value(input.PartNumber))),
+					}
+				case *s3.CompleteMultipartUploadInput:
+					completed = true
+					assert.Equal(t, aws.StringValue(input.UploadId), "upload-id")
+					parts := input.MultipartUpload.Parts
+					assert.Equal(t, len(parts), 3)
+					for i, part := range parts {
+						assert.Equal(t, aws.Int64Value(part.PartNumber), int64(i+1))
+						assert.Equal(t, aws.StringValue(part.ETag), fmt.Sprintf("etag-%d", i+1))
+					}
+				case *s3.AbortMultipartUploadInput:
+					aborted = true
+					assert.Equal(t, aws.StringValue(input.UploadId), "upload-id")
+				default:
+					t.Errorf("unexpected request %T", input)
+				}
+			})
+			mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				if r.Error != nil {
+					if awsErr, ok := r.Error.(awserr.Error); ok {
+						if awsErr.Code() == request.ErrCodeSerialization {
+							r.Error = nil
+						}
+					}
+				}
+			})
+
+			mockS3 := &S3{api: mockApi}
+
+			metadata := NewMetadata().SetContentType("text/plain")
+			metadata.SetUserDefined("owner", "john")
+
+			err := mockS3.MultipartCopy(context.Background(), from, to, metadata, size, 2, 5*mb)
+			if tc.expectErr {
+				assert.Assert(t, err != nil)
+			} else {
+				assert.NilError(t, err)
+				sort.Strings(ranges)
+				assert.DeepEqual(t, ranges, []string{
+					"bytes=0-5242879",
+					"bytes=10485760-12582911",
+					"bytes=5242880-10485759",
+				})
+			}
+			assert.Equal(t, completed, tc.expectComplete)
+			assert.Equal(t, aborted, tc.expectAbort)
+		})
+	}
+}
+
+func TestS3MultiDeletePartialFailure(t *testing.T) {
+	const (
+		numObjects = 1500
+		failedKey  = "key/42"
+	)
+
+	mockApi := s3.New(unit.Session)
+
+	mockApi.Handlers.Unmarshal.Clear()
+	mockApi.Handlers.UnmarshalMeta.Clear()
+	mockApi.Handlers.UnmarshalError.Clear()
+	mockApi.Handlers.Send.Clear()
+
+	mockApi.Handlers.Send.PushBack(func(r *request.Request) {
 		r.HTTPResponse = &http.Response{
 			StatusCode: http.StatusOK,
-			Header:     header,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
 		}
 
-		if r.Config.Credentials != awsSess.Config.Credentials {
-			t.Error("session credentials are expected to be used during HeadBucket request")
+		// the request succeeds, but one of the keys is not deleted.
+		input := r.Params.(*s3.DeleteObjectsInput)
+		output := r.Data.(*s3.DeleteObjectsOutput)
+		for _, obj := range input.Delete.Objects {
+			if aws.StringValue(obj.Key) == failedKey {
+				output.Errors = append(output.Errors, &s3.Error{
+					Key:     obj.Key,
+					Code:    aws.String("AccessDenied"),
+					Message: aws.String("Access Denied"),
+				})
+				continue
+			}
+			output.Deleted = append(output.Deleted, &s3.DeletedObject{Key: obj.Key})
 		}
 	})
 
-	_ = setSessionRegion(context.Background(), awsSess, "bucket")
-}
+	mockS3 := &S3{api: mockApi}
 
-func TestSessionAutoRegion(t *testing.T) {
-	log.Init("error", false)
+	urlch := make(chan *url.URL)
+	go func() {
+		defer close(urlch)
+		for i := 0; i < numObjects; i++ {
+			u, _ := url.New(fmt.Sprintf("s3://bucket/key/%d", i))
+			urlch <- u
+		}
+	}()
 
-	unitSession := func() *session.Session {
-		return session.Must(session.NewSession(&aws.Config{
-			Credentials: credentials.NewStaticCredentials("AKID", "SECRET", "SESSION"),
-			SleepDelay:  func(time.Duration) {},
-		}))
+	var (
+		deleted int
+		failed  []*Object
+	)
+	for obj := range mockS3.MultiDelete(context.Background(), urlch) {
+		if obj.Err != nil {
+			failed = append(failed, obj)
+			continue
+		}
+		deleted++
 	}
 
+	assert.Equal(t, deleted, numObjects-1)
+	assert.Equal(t, len(failed), 1)
+	assert.Equal(t, failed[0].URL.String(), "s3://bucket/"+failedKey)
+	assert.Equal(t, ErrorCode(failed[0].Err), "AccessDenied")
+	assert.Equal(t, failed[0].Err.Error(), "AccessDenied: Access Denied")
+}
+
+func TestS3PutObjectLockRequest(t *testing.T) {
+	retainUntil := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
 	testcases := []struct {
-		name              string
-		bucket            string
-		region            string
-		status            int
-		expectedRegion    string
-		expectedErrorCode string
+		name      string
+		mode      string
+		legalHold bool
+
+		expectedMode        *string
+		expectedRetainUntil *time.Time
+		expectedLegalHold   *string
 	}{
 		{
-			name:           "NoLocationConstraint",
-			bucket:         "bucket",
-			region:         "",
-			status:         http.StatusOK,
-			expectedRegion: "us-east-1",
-		},
-		{
-			name:           "LocationConstraintDefaultRegion",
-			bucket:         "bucket",
-			region:         "us-east-1",
-			status:         http.StatusOK,
-			expectedRegion: "us-east-1",
+			name: "no object lock",
 		},
 		{
-			name:           "LocationConstraintAnotherRegion",
-			bucket:         "bucket",
-			region:         "us-west-2",
-			status:         http.StatusOK,
-			expectedRegion: "us-west-2",
+			name:                "governance retention",
+			mode:                ObjectLockModeGovernance,
+			expectedMode:        aws.String("GOVERNANCE"),
+			expectedRetainUntil: aws.Time(retainUntil),
 		},
 		{
-			name:              "BucketNotFoundErrorMustFail",
-			bucket:            "bucket",
-			status:            http.StatusNotFound,
-			expectedRegion:    "us-east-1",
-			expectedErrorCode: "NotFound",
+			name:              "legal hold",
+			legalHold:         true,
+			expectedLegalHold: aws.String("ON"),
 		},
 		{
-			name:           "AccessDeniedErrorMustNotFail",
-			bucket:         "bucket",
-			status:         http.StatusForbidden,
-			expectedRegion: "us-east-1",
+			name:                "compliance retention and legal hold",
+			mode:                ObjectLockModeCompliance,
+			legalHold:           true,
+			expectedMode:        aws.String("COMPLIANCE"),
+			expectedRetainUntil: aws.Time(retainUntil),
+			expectedLegalHold:   aws.String("ON"),
 		},
 	}
 
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
 	for _, tc := range testcases {
+		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			awsSess := unitSession()
-			awsSess.Handlers.Unmarshal.Clear()
-			awsSess.Handlers.Send.Clear()
-			awsSess.Handlers.Send.PushBack(func(r *request.Request) {
-				header := http.Header{}
-				if tc.region != "" {
-					header.Set("X-Amz-Bucket-Region", tc.region)
-				}
+			mockApi := s3.New(unit.Session)
+
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.UnmarshalError.Clear()
+			mockApi.Handlers.Send.Clear()
+
+			var requests int
+			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
 				r.HTTPResponse = &http.Response{
-					StatusCode: tc.status,
-					Header:     header,
+					StatusCode: http.StatusOK,
 					Body:       ioutil.NopCloser(strings.NewReader("")),
 				}
+				requests++
+
+				input := r.Params.(*s3.PutObjectInput)
+
+				assert.DeepEqual(t, input.ObjectLockMode, tc.expectedMode)
+				assert.DeepEqual(t, input.ObjectLockRetainUntilDate, tc.expectedRetainUntil)
+				assert.DeepEqual(t, input.ObjectLockLegalHoldStatus, tc.expectedLegalHold)
 			})
 
-			err := setSessionRegion(context.Background(), awsSess, tc.bucket)
-			if tc.expectedErrorCode != "" && !errHasCode(err, tc.expectedErrorCode) {
-				t.Errorf("expected error code: %v, got error: %v", tc.expectedErrorCode, err)
-				return
+			mockS3 := &S3{
+				uploader: s3manager.NewUploaderWithClient(mockApi),
 			}
 
-			if expected, got := tc.expectedRegion, aws.StringValue(awsSess.Config.Region); expected != got {
-				t.Errorf("expected: %v, got: %v", expected, got)
+			metadata := NewMetadata().SetObjectLockLegalHold(tc.legalHold)
+			if tc.mode != "" {
+				metadata.SetObjectLock(tc.mode, retainUntil.Format(time.RFC3339))
 			}
+
+			err := mockS3.Put(context.Background(), bytes.NewReader([]byte("")), u, metadata, 1, 5242880)
+			assert.NilError(t, err)
+			assert.Equal(t, requests, 1)
 		})
 	}
 }
 
-func valueAtPath(i interface{}, s string) interface{} {
-	v, err := awsutil.ValuesAtPath(i, s)
-	if err != nil || len(v) == 0 {
-		return nil
-	}
-	if _, ok := v[0].(io.Reader); ok {
-		return v[0]
+func TestS3CopyObjectLockRequest(t *testing.T) {
+	retainUntil := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	src, _ := url.New("s3://bucket/key")
+	dst, _ := url.New("s3://bucket/copy")
+
+	mockApi := s3.New(unit.Session)
+
+	mockApi.Handlers.Unmarshal.Clear()
+	mockApi.Handlers.UnmarshalMeta.Clear()
+	mockApi.Handlers.UnmarshalError.Clear()
+	mockApi.Handlers.Send.Clear()
+
+	var requests int
+	mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+		requests++
+
+		input := r.Params.(*s3.CopyObjectInput)
+
+		assert.Equal(t, aws.StringValue(input.ObjectLockMode), "GOVERNANCE")
+		assert.Equal(t, aws.TimeValue(input.ObjectLockRetainUntilDate), retainUntil)
+		assert.Equal(t, aws.StringValue(input.ObjectLockLegalHoldStatus), "ON")
+		// object lock does not replace the metadata of the source.
+		assert.Assert(t, input.MetadataDirective == nil)
+	})
+	mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		if awsErr, ok := r.Error.(awserr.Error); ok && awsErr.Code() == request.ErrCodeSerialization {
+			r.Error = nil
+		}
+	})
+
+	mockS3 := &S3{api: mockApi}
+
+	metadata := NewMetadata().
+		SetObjectLock(ObjectLockModeGovernance, retainUntil.Format(time.RFC3339)).
+		SetObjectLockLegalHold(true)
+
+	err := mockS3.Copy(context.Background(), src, dst, metadata)
+	assert.NilError(t, err)
+	assert.Equal(t, requests, 1)
+}
+
+func TestS3PutSingleRequestUpToPartSize(t *testing.T) {
+	const partSize = 5 * 1024 * 1024
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if rv := reflect.ValueOf(v[0]); rv.Kind() == reflect.Ptr {
-		return rv.Elem().Interface()
+	testcases := []struct {
+		name            string
+		size            int
+		expectMultipart bool
+	}{
+		{name: "one byte under the part size", size: partSize - 1},
+		{name: "part size", size: partSize},
+		{name: "one byte over the part size", size: partSize + 1, expectMultipart: true},
 	}
 
-	return v[0]
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockApi := s3.New(unit.Session)
+
+			mockApi.Handlers.Unmarshal.Clear()
+			mockApi.Handlers.UnmarshalMeta.Clear()
+			mockApi.Handlers.UnmarshalError.Clear()
+			mockApi.Handlers.Send.Clear()
+
+			var (
+				mu        sync.Mutex
+				multipart bool
+			)
+			mockApi.Handlers.Send.PushBack(func(r *request.Request) {
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch r.Params.(type) {
+				case *s3.CreateMultipartUploadInput:
+					multipart = true
+					r.Data.(*s3.CreateMultipartUploadOutput).UploadId = aws.String("upload-id")
+				case *s3.UploadPartInput:
+					r.Data.(*s3.UploadPartOutput).ETag = aws.String("etag")
+				}
+			})
+			mockApi.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				if r.Error != nil {
+					if awsErr, ok := r.Error.(awserr.Error); ok {
+						if awsErr.Code() == request.ErrCodeSerialization {
+							r.Error = nil
+						}
+					}
+				}
+			})
+
+			mockS3 := &S3{
+				uploader: s3manager.NewUploaderWithClient(mockApi),
+			}
+
+			body := bytes.NewReader(make([]byte, tc.size))
+			err := mockS3.Put(context.Background(), body, u, NewMetadata(), 1, partSize)
+			assert.NilError(t, err)
+			assert.Equal(t, multipart, tc.expectMultipart)
+		})
+	}
 }
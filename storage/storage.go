@@ -5,9 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	urlpkg "net/url"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/s3"
+
 	"github.com/peak/s5cmd/storage/url"
 	"github.com/peak/s5cmd/strutil"
 )
@@ -18,8 +22,38 @@ var (
 
 	// ErrNoObjectFound indicates there are no objects found from a given directory.
 	ErrNoObjectFound = fmt.Errorf("no object found")
+
+	// ErrObjectNotModified indicates the ETag of a remote object matches the
+	// ETag given to Preconditions.IfNoneMatch.
+	ErrObjectNotModified = fmt.Errorf("object is not modified")
+
+	// ErrPreconditionFailed indicates the ETag of a remote object does not
+	// match the ETag given to Preconditions.IfMatch.
+	ErrPreconditionFailed = fmt.Errorf("object etag does not match")
 )
 
+// Preconditions are the conditions on the ETag of a remote object to download
+// it. The ETags can be given with or without the surrounding quotes.
+type Preconditions struct {
+	// IfMatch downloads the object only if its ETag matches.
+	IfMatch string
+	// IfNoneMatch downloads the object only if its ETag does not match.
+	IfNoneMatch string
+}
+
+// Check reports ErrPreconditionFailed or ErrObjectNotModified if the given
+// ETag of an object does not meet the preconditions.
+func (p Preconditions) Check(etag string) error {
+	etag = strings.Trim(etag, `"`)
+	if p.IfMatch != "" && strings.Trim(p.IfMatch, `"`) != etag {
+		return ErrPreconditionFailed
+	}
+	if p.IfNoneMatch != "" && strings.Trim(p.IfNoneMatch, `"`) == etag {
+		return ErrObjectNotModified
+	}
+	return nil
+}
+
 // Storage is an interface for storage operations that is common
 // to local filesystem and remote object storage.
 type Storage interface {
@@ -47,15 +81,10 @@ func NewLocalClient(opts Options) *Filesystem {
 }
 
 func NewRemoteClient(ctx context.Context, url *url.URL, opts Options) (*S3, error) {
-	newOpts := Options{
-		MaxRetries:    opts.MaxRetries,
-		Endpoint:      opts.Endpoint,
-		NoVerifySSL:   opts.NoVerifySSL,
-		DryRun:        opts.DryRun,
-		NoSignRequest: opts.NoSignRequest,
-		bucket:        url.Bucket,
-		region:        opts.region,
-	}
+	// the sessions are cached by their options, which include the bucket to
+	// detect its region.
+	newOpts := opts
+	newOpts.bucket = url.Bucket
 	return newS3Storage(ctx, newOpts)
 }
 
@@ -68,19 +97,52 @@ func NewClient(ctx context.Context, url *url.URL, opts Options) (Storage, error)
 
 // Options stores configuration for storage.
 type Options struct {
-	MaxRetries    int
-	Endpoint      string
+	MaxRetries       int
+	MaxRetryDuration time.Duration
+	// RetryBudget is the maximum ratio of the retries to the requests of all
+	// sessions, e.g. 0.1. There is no limit if it is zero.
+	RetryBudget float64
+	Endpoint    string
+	// UseAccelerate sends the requests to the S3 Transfer Acceleration
+	// endpoint. The endpoint is also used if Endpoint is set to it.
+	UseAccelerate bool
 	NoVerifySSL   bool
-	DryRun        bool
-	NoSignRequest bool
-	bucket        string
-	region        string
+	// MaxIdleConns and MaxIdleConnsPerHost limit the idle connections kept
+	// for reuse. Zero MaxIdleConns means no limit, zero MaxIdleConnsPerHost
+	// means the default of net/http.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// DialTimeout and TLSHandshakeTimeout override the timeouts of
+	// establishing a connection if they are not zero.
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	DryRun              bool
+	NoSignRequest       bool
+	// RequestPayer is sent with the requests to access requester pays
+	// buckets, i.e. "requester". It is empty if the bucket owner pays.
+	RequestPayer string
+	Profile      string
+	// AssumeRoleARN is the IAM role assumed with the credentials of the
+	// profile. ExternalID and RoleSessionName are optional parameters of
+	// assuming the role.
+	AssumeRoleARN   string
+	ExternalID      string
+	RoleSessionName string
+	LogLevel        string
+	bucket          string
+	region          string
 }
 
 func (o *Options) SetRegion(region string) {
 	o.region = region
 }
 
+// Region returns the region set by SetRegion. An empty region means the
+// region of the bucket is detected automatically.
+func (o Options) Region() string {
+	return o.region
+}
+
 // Object is a generic type which contains metadata for storage items.
 type Object struct {
 	URL          *url.URL     `json:"key,omitempty"`
@@ -90,6 +152,14 @@ type Object struct {
 	Size         int64        `json:"size,omitempty"`
 	StorageClass StorageClass `json:"storage_class,omitempty"`
 	Err          error        `json:"error,omitempty"`
+
+	// Metadata is the system and user defined metadata of the object. It is
+	// only set by Stat of remote objects.
+	Metadata Metadata `json:"metadata,omitempty"`
+
+	// IsDeleteMarker reports whether the object is a delete marker of a
+	// versioned bucket. It is only set by ListVersions.
+	IsDeleteMarker bool `json:"delete_marker,omitempty"`
 }
 
 // String returns the string representation of Object.
@@ -181,6 +251,35 @@ func (s StorageClass) IsGlacier() bool {
 	return s == "GLACIER"
 }
 
+// IsValid reports whether the storage class is well-formed. The storage class
+// is not checked against the storage classes known to the SDK, since S3 adds
+// new ones and S3-compatible stores may define their own.
+func (s StorageClass) IsValid() bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// IsValidACL reports whether the given ACL is one of the canned ACLs supported
+// by S3.
+func IsValidACL(acl string) bool {
+	for _, canned := range s3.ObjectCannedACL_Values() {
+		if acl == canned {
+			return true
+		}
+	}
+	return false
+}
+
 // notImplemented is a structure which is used on the unsupported operations.
 type notImplemented struct {
 	apiType string
@@ -244,6 +343,33 @@ func (m Metadata) SetContentType(contentType string) Metadata {
 	return m
 }
 
+func (m Metadata) ContentDisposition() string {
+	return m["ContentDisposition"]
+}
+
+func (m Metadata) SetContentDisposition(contentDisposition string) Metadata {
+	m["ContentDisposition"] = contentDisposition
+	return m
+}
+
+func (m Metadata) ContentLanguage() string {
+	return m["ContentLanguage"]
+}
+
+func (m Metadata) SetContentLanguage(contentLanguage string) Metadata {
+	m["ContentLanguage"] = contentLanguage
+	return m
+}
+
+func (m Metadata) ContentEncoding() string {
+	return m["ContentEncoding"]
+}
+
+func (m Metadata) SetContentEncoding(contentEncoding string) Metadata {
+	m["ContentEncoding"] = contentEncoding
+	return m
+}
+
 func (m Metadata) SSE() string {
 	return m["EncryptionMethod"]
 }
@@ -261,3 +387,83 @@ func (m Metadata) SetSSEKeyID(kid string) Metadata {
 	m["EncryptionKeyID"] = kid
 	return m
 }
+
+// The retention modes of object lock.
+const (
+	ObjectLockModeGovernance = s3.ObjectLockModeGovernance
+	ObjectLockModeCompliance = s3.ObjectLockModeCompliance
+)
+
+func (m Metadata) ObjectLockMode() string {
+	return m["ObjectLockMode"]
+}
+
+func (m Metadata) ObjectLockRetainUntil() string {
+	return m["ObjectLockRetainUntil"]
+}
+
+// SetObjectLock sets the object lock retention mode of the object and the
+// date in RFC3339 format until which it is retained.
+func (m Metadata) SetObjectLock(mode, retainUntil string) Metadata {
+	m["ObjectLockMode"] = mode
+	m["ObjectLockRetainUntil"] = retainUntil
+	return m
+}
+
+func (m Metadata) ObjectLockLegalHold() bool {
+	return m["ObjectLockLegalHold"] == s3.ObjectLockLegalHoldStatusOn
+}
+
+func (m Metadata) SetObjectLockLegalHold(legalHold bool) Metadata {
+	if legalHold {
+		m["ObjectLockLegalHold"] = s3.ObjectLockLegalHoldStatusOn
+	}
+	return m
+}
+
+func (m Metadata) Tagging() string {
+	return m["Tagging"]
+}
+
+// SetTagging sets the tags of the object, encoded as URL query parameters as
+// expected by S3.
+func (m Metadata) SetTagging(tags map[string]string) Metadata {
+	if len(tags) == 0 {
+		return m
+	}
+
+	values := urlpkg.Values{}
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	m["Tagging"] = values.Encode()
+	return m
+}
+
+// userMetadataPrefix is the prefix of user defined metadata keys to
+// distinguish them from the system defined ones.
+const userMetadataPrefix = "X-Amz-Meta-"
+
+// UserDefined returns the user defined metadata.
+func (m Metadata) UserDefined() map[string]string {
+	userMetadata := map[string]string{}
+	for key, value := range m {
+		if strings.HasPrefix(key, userMetadataPrefix) {
+			userMetadata[strings.TrimPrefix(key, userMetadataPrefix)] = value
+		}
+	}
+	return userMetadata
+}
+
+// SetUserDefined sets the user defined metadata of the key. The keys are case
+// insensitive, so a key which differs only in case is replaced.
+func (m Metadata) SetUserDefined(key, value string) Metadata {
+	key = userMetadataPrefix + key
+	for existing := range m {
+		if existing != key && strings.EqualFold(existing, key) {
+			delete(m, existing)
+		}
+	}
+	m[key] = value
+	return m
+}
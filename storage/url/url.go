@@ -13,8 +13,6 @@ import (
 )
 
 const (
-	globCharacters string = "?*"
-
 	// s3Scheme is the schema used on s3 URLs
 	s3Scheme string = "s3://"
 
@@ -23,8 +21,23 @@ const (
 
 	// matchAllRe is the regex to match everything
 	matchAllRe string = ".*"
+
+	// versionIDParam selects a version of a remote object, e.g.
+	// s3://bucket/key?versionId=abc
+	versionIDParam string = "?versionId="
+
+	// minBucketNameLength and maxBucketNameLength are the limits of bucket
+	// names. The maximum is the legacy limit of us-east-1, which is more
+	// permissive than the current limit of S3.
+	minBucketNameLength = 3
+	maxBucketNameLength = 255
 )
 
+// bucketNameRe matches the characters allowed in bucket names. Uppercase
+// letters and underscores are not allowed in new S3 buckets but they are
+// allowed in legacy S3 buckets and other S3 compatible storages.
+var bucketNameRe = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
 type urlType int
 
 const (
@@ -42,6 +55,16 @@ type URL struct {
 	Delimiter string
 	Prefix    string
 
+	// VersionID is the version of the remote object. It is empty for the
+	// latest version.
+	VersionID string
+
+	// StartAfter and MaxKeys are the listing parameters of remote prefixes.
+	// The keys up to and including StartAfter are not listed, and the listing
+	// stops after MaxKeys objects if it is positive.
+	StartAfter string
+	MaxKeys    int64
+
 	relativePath string
 	filter       string
 	filterRegex  *regexp.Regexp
@@ -61,6 +84,12 @@ func New(s string, opts ...Option) (*URL, error) {
 	split := strings.Split(s, "://")
 
 	if len(split) == 1 {
+		// catch typos such as "s3:/bucket/key" which would otherwise be
+		// treated as a local path.
+		if strings.HasPrefix(s, "s3:") {
+			return nil, fmt.Errorf("s3 url should start with %q", s3Scheme)
+		}
+
 		url := &URL{
 			Type:   localObject,
 			Scheme: "",
@@ -107,6 +136,10 @@ func New(s string, opts ...Option) (*URL, error) {
 		return nil, fmt.Errorf("bucket name cannot contain wildcards")
 	}
 
+	if err := validateBucketName(bucket); err != nil {
+		return nil, err
+	}
+
 	url := &URL{
 		Type:   remoteObject,
 		Scheme: "s3",
@@ -118,12 +151,67 @@ func New(s string, opts ...Option) (*URL, error) {
 		opt(url)
 	}
 
+	if err := url.setVersionID(); err != nil {
+		return nil, err
+	}
+
 	if err := url.setPrefixAndFilter(); err != nil {
 		return nil, err
 	}
 	return url, nil
 }
 
+// setVersionID splits the version id query parameter from the path of the
+// remote object. Keys are taken as is in raw mode.
+func (u *URL) setVersionID() error {
+	if u.raw {
+		return nil
+	}
+
+	loc := strings.LastIndex(u.Path, versionIDParam)
+	if loc < 0 {
+		return nil
+	}
+
+	key, versionID := u.Path[:loc], u.Path[loc+len(versionIDParam):]
+	if versionID == "" {
+		return fmt.Errorf("version id of %q can not be empty", key)
+	}
+
+	if key == "" || strings.HasSuffix(key, s3Separator) || hasGlobCharacter(key) {
+		return fmt.Errorf("version id can only be given for an object")
+	}
+
+	u.Path = key
+	u.VersionID = versionID
+	return nil
+}
+
+// validateBucketName checks the length and the characters of the given bucket
+// name.
+func validateBucketName(bucket string) error {
+	if len(bucket) < minBucketNameLength || len(bucket) > maxBucketNameLength {
+		return fmt.Errorf(
+			"bucket name %q must be between %d and %d characters long",
+			bucket, minBucketNameLength, maxBucketNameLength,
+		)
+	}
+
+	if !bucketNameRe.MatchString(bucket) {
+		return fmt.Errorf("bucket name %q can only contain letters, numbers, dots, hyphens and underscores", bucket)
+	}
+
+	first, last := bucket[0], bucket[len(bucket)-1]
+	if !isAlphanumeric(first) || !isAlphanumeric(last) {
+		return fmt.Errorf("bucket name %q must start and end with a letter or number", bucket)
+	}
+	return nil
+}
+
+func isAlphanumeric(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
 // IsRemote reports whether the object is stored on a remote storage system.
 func (u *URL) IsRemote() bool {
 	return u.Type == remoteObject
@@ -220,6 +308,13 @@ func (u *URL) remoteURL() string {
 //		regex: ^a/b/test./c/.*?\\.tsv$
 //		delimiter: ""
 //
+// Example:
+//		key: a/b/data[0-9].csv
+//		prefix: a/b/data
+//		filter: [0-9].csv
+//		regex: ^a/b/data[0-9]\\.csv$
+//		delimiter: ""
+//
 // It prepares delimiter, prefix and regex for regular strings.
 // These are used in S3 listing operations.
 // See: https://docs.aws.amazon.com/AmazonS3/latest/dev/ListingKeysHierarchy.html
@@ -236,7 +331,7 @@ func (u *URL) setPrefixAndFilter() error {
 		return nil
 	}
 
-	loc := strings.IndexAny(u.Path, globCharacters)
+	loc := globIndex(u.Path)
 	wildOperation := loc > -1
 	if !wildOperation {
 		u.Delimiter = s3Separator
@@ -248,9 +343,7 @@ func (u *URL) setPrefixAndFilter() error {
 
 	filterRegex := matchAllRe
 	if u.filter != "" {
-		filterRegex = regexp.QuoteMeta(u.filter)
-		filterRegex = strings.Replace(filterRegex, "\\?", ".", -1)
-		filterRegex = strings.Replace(filterRegex, "\\*", ".*?", -1)
+		filterRegex = globToRegex(u.filter)
 	}
 	filterRegex = regexp.QuoteMeta(u.Prefix) + filterRegex
 	r, err := regexp.Compile("^" + filterRegex + "$")
@@ -270,6 +363,10 @@ func (u *URL) Clone() *URL {
 		Delimiter: u.Delimiter,
 		Path:      u.Path,
 		Prefix:    u.Prefix,
+		VersionID: u.VersionID,
+
+		StartAfter: u.StartAfter,
+		MaxKeys:    u.MaxKeys,
 
 		relativePath: u.relativePath,
 		filter:       u.filter,
@@ -301,8 +398,12 @@ func (u *URL) Match(key string) bool {
 	return true
 }
 
-// String is the fmt.Stringer implementation of URL.
+// String is the fmt.Stringer implementation of URL. It includes the version
+// of the object if it is set.
 func (u *URL) String() string {
+	if u.VersionID != "" {
+		return u.Absolute() + versionIDParam + u.VersionID
+	}
 	return u.Absolute()
 }
 
@@ -311,6 +412,47 @@ func (u *URL) MarshalJSON() ([]byte, error) {
 	return json.Marshal(u.String())
 }
 
+// ExpandBraces returns the URLs of the brace expansions of u, e.g.
+// "s3://bucket/{a,b}/*" expands to "s3://bucket/a/*" and "s3://bucket/b/*".
+// It returns nil if u has no brace groups or it is in raw mode.
+func (u *URL) ExpandBraces() ([]*URL, error) {
+	if u.raw || braceIndex(u.Path) < 0 {
+		return nil, nil
+	}
+
+	var urls []*URL
+	for _, path := range expandBraces(u.Path) {
+		if u.IsRemote() {
+			path = s3Scheme + u.Bucket + s3Separator + path
+		}
+
+		expansion, err := New(path)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, expansion)
+	}
+	return urls, nil
+}
+
+// Relativize sets the relative path of object, which is listed for one of the
+// brace expansions of u, against u. So the objects of all expansions are
+// relative to the directory before the braces, e.g. "a/x" and "b/y" for
+// "{a,b}/*".
+func (u *URL) Relativize(object *URL) {
+	if u.IsRemote() {
+		object.relativePath = parseBatch(u.Prefix, object.Path)
+		return
+	}
+
+	dir := u.Prefix[:strings.LastIndexAny(u.Prefix, s3Separator+string(filepath.Separator))+1]
+	base, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	object.relativePath, _ = filepath.Rel(base, object.Absolute())
+}
+
 // IsWildcard reports whether if a string contains any wildcard chars.
 func (u *URL) IsWildcard() bool {
 	return !u.raw && hasGlobCharacter(u.Path)
@@ -368,11 +510,193 @@ func parseNonBatch(prefix string, key string) string {
 
 // hasGlobCharacter reports whether if a string contains any wildcard chars.
 func hasGlobCharacter(s string) bool {
-	return strings.ContainsAny(s, globCharacters)
+	return globIndex(s) > -1
+}
+
+// globIndex returns the index of the first wildcard in s, or -1 if there is
+// none. "*" and "?" are always wildcards, "[" is a wildcard only if it starts
+// a character class and "{" only if it starts a brace group. A literal "[" can
+// be matched with "[[]".
+func globIndex(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?':
+			return i
+		case '[':
+			if classEnd(s[i:]) > -1 {
+				return i
+			}
+		case '{':
+			if braceEnd(s[i:]) > -1 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// braceIndex returns the index of the first brace group in s, or -1 if there
+// is none. Braces in character classes are not groups.
+func braceIndex(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			if end := classEnd(s[i:]); end > -1 {
+				i += end
+			}
+		case '{':
+			if braceEnd(s[i:]) > -1 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// braceEnd returns the index of the "}" closing the brace group at the
+// beginning of s, or -1 if s does not start with a brace group. A group has a
+// comma outside of its nested braces, e.g. "{a,b}". Other braces, such as
+// "{a}", are literal characters.
+func braceEnd(s string) int {
+	depth := 0
+	hasComma := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				if hasComma {
+					return i
+				}
+				return -1
+			}
+		case ',':
+			if depth == 1 {
+				hasComma = true
+			}
+		}
+	}
+	return -1
+}
+
+// braceAlternatives splits the body of a brace group at the commas outside of
+// its nested braces.
+func braceAlternatives(body string) []string {
+	var alternatives []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alternatives = append(alternatives, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alternatives, body[start:])
+}
+
+// expandBraces returns the expansions of the brace groups in s in order and
+// without duplicates, e.g. "a{b,c{d,e}}f" expands to "abf", "acdf" and
+// "acef". s is returned as is if it has no brace groups.
+func expandBraces(s string) []string {
+	i := braceIndex(s)
+	if i < 0 {
+		return []string{s}
+	}
+	end := i + braceEnd(s[i:])
+
+	var expansions []string
+	seen := map[string]bool{}
+	for _, alternative := range braceAlternatives(s[i+1 : end]) {
+		// the rest of s is expanded for each alternative, which expands the
+		// nested and the adjacent groups.
+		for _, expansion := range expandBraces(s[:i] + alternative + s[end+1:]) {
+			if !seen[expansion] {
+				seen[expansion] = true
+				expansions = append(expansions, expansion)
+			}
+		}
+	}
+	return expansions
+}
+
+// classEnd returns the index of the "]" closing the character class at the
+// beginning of s, or -1 if s does not start with a character class. Like
+// filepath.Match, "^" negates the class and "]" right after the opening
+// bracket is a member of the class.
+func classEnd(s string) int {
+	i := 1
+	if i < len(s) && s[i] == '^' {
+		i++
+	}
+	if i < len(s) && s[i] == ']' {
+		i++
+	}
+	if j := strings.IndexByte(s[i:], ']'); j > -1 {
+		return i + j
+	}
+	return -1
+}
+
+// globToRegex converts a wildcard string to a regular expression. "*" matches
+// any sequence of characters including the separator, "?" matches a single
+// character, "[...]" matches a single character of the class and "{a,b}"
+// matches either of the alternatives.
+func globToRegex(s string) string {
+	var b strings.Builder
+	for {
+		i := globIndex(s)
+		if i < 0 {
+			b.WriteString(regexp.QuoteMeta(s))
+			return b.String()
+		}
+
+		b.WriteString(regexp.QuoteMeta(s[:i]))
+		switch s[i] {
+		case '*':
+			b.WriteString(".*?")
+			s = s[i+1:]
+		case '?':
+			b.WriteString(".")
+			s = s[i+1:]
+		case '[':
+			end := i + classEnd(s[i:])
+			class := s[i+1 : end]
+
+			b.WriteString("[")
+			if strings.HasPrefix(class, "^") {
+				b.WriteString("^")
+				class = class[1:]
+			}
+			// QuoteMeta does not escape "-", so ranges are kept.
+			b.WriteString(regexp.QuoteMeta(class))
+			b.WriteString("]")
+			s = s[end+1:]
+		case '{':
+			end := i + braceEnd(s[i:])
+
+			b.WriteString("(?:")
+			for j, alternative := range braceAlternatives(s[i+1 : end]) {
+				if j > 0 {
+					b.WriteString("|")
+				}
+				b.WriteString(globToRegex(alternative))
+			}
+			b.WriteString(")")
+			s = s[end+1:]
+		}
+	}
 }
 
 func (u *URL) EscapedPath() string {
-	sourceKey := strings.TrimPrefix(u.String(), "s3://")
+	sourceKey := strings.TrimPrefix(u.Absolute(), "s3://")
 	sourceKeyElements := strings.Split(sourceKey, "/")
 	for i, element := range sourceKeyElements {
 		sourceKeyElements[i] = url.QueryEscape(element)
@@ -1,8 +1,10 @@
 package url
 
 import (
+	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -25,6 +27,41 @@ func TestHasWild(t *testing.T) {
 			s:    "s3://a/?/c",
 			want: true,
 		},
+		{
+			name: "string_has_character_class",
+			s:    "s3://a/b[0-9]/c",
+			want: true,
+		},
+		{
+			name: "string_has_negated_character_class",
+			s:    "s3://a/b[^0-9]/c",
+			want: true,
+		},
+		{
+			name: "string_has_unclosed_bracket",
+			s:    "s3://a/b[0-9/c",
+			want: false,
+		},
+		{
+			name: "string_has_empty_brackets",
+			s:    "s3://a/b[]/c",
+			want: false,
+		},
+		{
+			name: "string_has_brace_group",
+			s:    "s3://a/{b,c}/d",
+			want: true,
+		},
+		{
+			name: "string_has_braces_without_comma",
+			s:    "s3://a/{b}/d",
+			want: false,
+		},
+		{
+			name: "string_has_unclosed_brace",
+			s:    "s3://a/{b,c/d",
+			want: false,
+		},
 		{
 			name: "string_has_no_wildcard",
 			s:    "s3://a/b/c",
@@ -83,6 +120,19 @@ func TestNew(t *testing.T) {
 			},
 			wantFilterRe: regexp.MustCompile(`^key/.*$`).String(),
 		},
+		{
+			name:   "url_with_version_id",
+			object: "s3://bucket/key?versionId=abc",
+			want: &URL{
+				Scheme:    "s3",
+				Bucket:    "bucket",
+				Path:      "key",
+				Prefix:    "key",
+				Delimiter: "/",
+				VersionID: "abc",
+			},
+			wantFilterRe: regexp.MustCompile(`^key.*$`).String(),
+		},
 		{
 			name:   "url_with_wildcard",
 			object: "s3://bucket/key/a/?/test/*",
@@ -118,6 +168,94 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewValidation(t *testing.T) {
+	tests := []struct {
+		object  string
+		wantErr string
+	}{
+		{object: "s3://bucket/key"},
+		{object: "s3://my.bucket-name_01/key"},
+		{object: "s3://LegacyBucket/key"},
+		{object: "s3://bucket//key"},
+		{object: "s3://abc"},
+		{object: "s3/bucket/key"},
+		{object: "bucket/key"},
+		{
+			object:  "s3:/bucket/key",
+			wantErr: `s3 url should start with "s3://"`,
+		},
+		{
+			object:  "s3:bucket/key",
+			wantErr: `s3 url should start with "s3://"`,
+		},
+		{
+			object:  "gs://bucket/key",
+			wantErr: `s3 url should start with "s3://"`,
+		},
+		{
+			object:  "s3://",
+			wantErr: "s3 url should have a bucket",
+		},
+		{
+			object:  "s3:///bucket/key",
+			wantErr: "s3 url should have a bucket",
+		},
+		{
+			object:  "s3://ab/key",
+			wantErr: `bucket name "ab" must be between 3 and 255 characters long`,
+		},
+		{
+			object:  "s3://" + strings.Repeat("a", 256),
+			wantErr: fmt.Sprintf(`bucket name %q must be between 3 and 255 characters long`, strings.Repeat("a", 256)),
+		},
+		{
+			object:  "s3://my bucket/key",
+			wantErr: `bucket name "my bucket" can only contain letters, numbers, dots, hyphens and underscores`,
+		},
+		{
+			object:  "s3://bucket:80/key",
+			wantErr: `bucket name "bucket:80" can only contain letters, numbers, dots, hyphens and underscores`,
+		},
+		{
+			object:  "s3://-bucket/key",
+			wantErr: `bucket name "-bucket" must start and end with a letter or number`,
+		},
+		{
+			object:  "s3://bucket./key",
+			wantErr: `bucket name "bucket." must start and end with a letter or number`,
+		},
+		{object: "s3://bucket/dir/key?versionId=abc"},
+		{
+			object:  "s3://bucket/key?versionId=",
+			wantErr: `version id of "key" can not be empty`,
+		},
+		{
+			object:  "s3://bucket/prefix/?versionId=abc",
+			wantErr: "version id can only be given for an object",
+		},
+		{
+			object:  "s3://bucket/*.txt?versionId=abc",
+			wantErr: "version id can only be given for an object",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.object, func(t *testing.T) {
+			_, err := New(tc.object)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestURLSetPrefixAndFilter(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -137,6 +275,58 @@ func TestURLSetPrefixAndFilter(t *testing.T) {
 				filterRegex: regexp.MustCompile("^a/b_c/.*?/de/.*?/test$"),
 			},
 		},
+		{
+			name: "wild_operation_with_character_classes",
+			before: &URL{
+				Path: "a/log-2023-0?-*.gz/data[0-9][^a-c].csv",
+			},
+			after: &URL{
+				Path:        "a/log-2023-0?-*.gz/data[0-9][^a-c].csv",
+				Prefix:      "a/log-2023-0",
+				Delimiter:   "",
+				filter:      "?-*.gz/data[0-9][^a-c].csv",
+				filterRegex: regexp.MustCompile(`^a/log-2023-0.-.*?\.gz/data[0-9][^a-c]\.csv$`),
+			},
+		},
+		{
+			name: "wild_operation_with_literal_brackets_in_prefix",
+			before: &URL{
+				Path: "a/b[c/*.txt",
+			},
+			after: &URL{
+				Path:        "a/b[c/*.txt",
+				Prefix:      "a/b[c/",
+				Delimiter:   "",
+				filter:      "*.txt",
+				filterRegex: regexp.MustCompile(`^a/b\[c/.*?\.txt$`),
+			},
+		},
+		{
+			name: "wild_operation_with_escaped_bracket",
+			before: &URL{
+				Path: "a/[[]b]/*",
+			},
+			after: &URL{
+				Path:        "a/[[]b]/*",
+				Prefix:      "a/",
+				Delimiter:   "",
+				filter:      "[[]b]/*",
+				filterRegex: regexp.MustCompile(`^a/[\[]b\]/.*?$`),
+			},
+		},
+		{
+			name: "not_wild_operation_with_unclosed_bracket",
+			before: &URL{
+				Path: "a/b[c/d",
+			},
+			after: &URL{
+				Path:        "a/b[c/d",
+				Prefix:      "a/b[c/d",
+				Delimiter:   "/",
+				filter:      "",
+				filterRegex: regexp.MustCompile(`^a/b\[c/d.*$`),
+			},
+		},
 		{
 			name: "not_wild_operation",
 			before: &URL{
@@ -237,6 +427,27 @@ func TestCheckMatch(t *testing.T) {
 				"invalid/dummy/b/1/c/file.tsv": {},
 			},
 		},
+		{
+			name: "match_if_key_matches_one_of_the_brace_alternatives",
+			url:  "s3://bucket/logs/{2021,2022}/*.log",
+			keys: map[string]matchResult{
+				"logs/2021/a.log":   {true, "2021/a.log"},
+				"logs/2022/b.log":   {true, "2022/b.log"},
+				"logs/2020/c.log":   {},
+				"logs/2021/a.txt":   {},
+				"logs/{2021}/a.log": {},
+			},
+		},
+		{
+			name: "match_nested_brace_alternatives",
+			url:  "s3://bucket/{a,b{c,d}}/*",
+			keys: map[string]matchResult{
+				"a/file":  {true, "a/file"},
+				"bc/file": {true, "bc/file"},
+				"bd/file": {true, "bd/file"},
+				"b/file":  {},
+			},
+		},
 		{
 			name: "not_match_if_multiple_wildcard_does_not_match_with_key",
 			url:  "s3://bucket/prefix/*/c/*.tsv",
@@ -245,6 +456,52 @@ func TestCheckMatch(t *testing.T) {
 				"prefix/dummy/a":          {},
 			},
 		},
+		{
+			name: "match_question_mark_with_single_character",
+			url:  "s3://bucket/key/log-2023-0?-*.gz",
+			keys: map[string]matchResult{
+				"key/log-2023-01-01.gz":  {true, "log-2023-01-01.gz"},
+				"key/log-2023-09-30.gz":  {true, "log-2023-09-30.gz"},
+				"key/log-2023-10-01.gz":  {},
+				"key/log-2023-011-01.gz": {},
+			},
+		},
+		{
+			name: "match_character_class",
+			url:  "s3://bucket/key/data[0-9].csv",
+			keys: map[string]matchResult{
+				"key/data0.csv":  {true, "data0.csv"},
+				"key/data9.csv":  {true, "data9.csv"},
+				"key/dataa.csv":  {},
+				"key/data10.csv": {},
+			},
+		},
+		{
+			name: "match_negated_character_class",
+			url:  "s3://bucket/key/data[^0-9].csv",
+			keys: map[string]matchResult{
+				"key/dataa.csv": {true, "dataa.csv"},
+				"key/data0.csv": {},
+			},
+		},
+		{
+			name: "match_character_class_with_special_characters",
+			url:  "s3://bucket/key/file[.*].txt",
+			keys: map[string]matchResult{
+				"key/file..txt": {true, "file..txt"},
+				"key/file*.txt": {true, "file*.txt"},
+				"key/filea.txt": {},
+			},
+		},
+		{
+			name: "match_literal_brackets",
+			url:  "s3://bucket/key/[[]old]/*.txt",
+			keys: map[string]matchResult{
+				"key/[old]/a.txt": {true, "[old]/a.txt"},
+				"key/o/a.txt":     {},
+				"key/old/a.txt":   {},
+			},
+		},
 		{
 			name: "not_match_if_single_wildcard_does_not_match_with_key",
 			url:  "s3://bucket/*.tsv",
@@ -275,6 +532,112 @@ func TestCheckMatch(t *testing.T) {
 	}
 }
 
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			name: "no_braces",
+			s:    "a/b/*.log",
+			want: []string{"a/b/*.log"},
+		},
+		{
+			name: "single_group",
+			s:    "logs/{2021,2022}/*.log",
+			want: []string{"logs/2021/*.log", "logs/2022/*.log"},
+		},
+		{
+			name: "adjacent_groups",
+			s:    "{a,b}{1,2}",
+			want: []string{"a1", "a2", "b1", "b2"},
+		},
+		{
+			name: "nested_groups",
+			s:    "x{a,b{c,d}}y",
+			want: []string{"xay", "xbcy", "xbdy"},
+		},
+		{
+			name: "empty_alternative",
+			s:    "file{,.bak}",
+			want: []string{"file", "file.bak"},
+		},
+		{
+			name: "duplicate_expansions",
+			s:    "{a,b,a}/{x,x}",
+			want: []string{"a/x", "b/x"},
+		},
+		{
+			name: "literal_braces_without_comma",
+			s:    "{a}/{b,c}",
+			want: []string{"{a}/b", "{a}/c"},
+		},
+		{
+			name: "unclosed_brace",
+			s:    "{a,b",
+			want: []string{"{a,b"},
+		},
+		{
+			name: "braces_in_character_class",
+			s:    "[{a,b}]",
+			want: []string{"[{a,b}]"},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, expandBraces(tc.s)); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestURLExpandBraces(t *testing.T) {
+	u, err := New("s3://bucket/logs/{2021,2022}/*/{a,b}.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expansions, err := u.ExpandBraces()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, expansion := range expansions {
+		got = append(got, expansion.String())
+	}
+	want := []string{
+		"s3://bucket/logs/2021/*/a.log",
+		"s3://bucket/logs/2021/*/b.log",
+		"s3://bucket/logs/2022/*/a.log",
+		"s3://bucket/logs/2022/*/b.log",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+
+	// the relative paths keep the directories after the braces.
+	object, err := New("s3://bucket/logs/2022/x/b.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u.Relativize(object)
+	if got, want := object.Relative(), "2022/x/b.log"; got != want {
+		t.Errorf("Relative() = %v, want %v", got, want)
+	}
+
+	raw, err := New("s3://bucket/{a,b}", WithRaw(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expansions, _ := raw.ExpandBraces(); expansions != nil {
+		t.Errorf("expected no expansions in raw mode, got %v", expansions)
+	}
+}
+
 func TestParseBatch(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -416,6 +779,11 @@ func TestURLWithMode(t *testing.T) {
 		{"s3://bucket/abc/deneme*.txt", true, "", ""},
 		{"deneme*.txt", false, "deneme", "*.txt"},
 		{"deneme*.txt", true, "", ""},
+		{"s3://bucket/file?.txt", false, "file", "?.txt"},
+		{"s3://bucket/file?.txt", true, "", ""},
+		{"s3://bucket/file[12].txt", false, "file", "[12].txt"},
+		{"s3://bucket/file[12].txt", true, "", ""},
+		{"s3://bucket/file{1,2}.txt", true, "", ""},
 	}
 	for _, tc := range tests {
 		url, err := New(tc.input, WithRaw(tc.raw))
@@ -430,5 +798,9 @@ func TestURLWithMode(t *testing.T) {
 		if url.filter != tc.filterExpected {
 			t.Errorf("%s: url filter %s does not match with expected filter %s\n", tc.input, url.Prefix, tc.filterExpected)
 		}
+
+		if tc.raw && url.IsWildcard() {
+			t.Errorf("%s: url should not be a wildcard in raw mode\n", tc.input)
+		}
 	}
 }